@@ -39,20 +39,32 @@ func Ping(c *gin.Context) {
 	})
 }
 
-// RegisterRoutes 注册API路由
-func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService interface{}, fileProcessorService interface{}, fileProcessorConfig interface{}, taskService interface{}) {
-	// 初始化审计服务
+// libraryReadPermissions 各资源库的查看权限，统计等跨资源库的接口拥有任一即可访问
+var libraryReadPermissions = []string{
+	"documents:read", "models:read", "assets:read", "textures:read", "projects:read",
+}
+
+// requireLibraryAdmin 按路径参数 library（asset|texture|model|document）校验对应资源库的管理权限
+func requireLibraryAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.RequirePermission(c.Param("library")+"s", "admin")(c)
+	}
+}
+
+// RegisterRoutes 注册API路由，auditService 由 AppCore 创建并在停机时停止（未启用审计时为 nil）
+func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService interface{}, fileProcessorService interface{}, fileProcessorConfig interface{}, taskService interface{}, auditService *audit.AuditService) {
+	// 初始化审计查询服务
 	auditConfig, _ := config.LoadAuditConfig()
-	var auditService *audit.AuditService
 	var auditQueryService *audit.QueryService
 	var auditArchiveService *audit.ArchiveService
 	var auditController *controllers.AuditController
 	
 	if auditConfig != nil && auditConfig.Enabled {
-		auditService = audit.NewAuditService(database.MustGetDB(), auditConfig)
-		auditQueryService = audit.NewQueryService(database.MustGetDB(), auditConfig)
 		auditArchiveService = audit.NewArchiveService(database.MustGetDB(), auditConfig)
-		auditController = controllers.NewAuditController(auditQueryService, auditArchiveService)
+		auditQueryService = audit.NewQueryService(database.MustGetDB(), auditConfig, auditArchiveService)
+		auditController = controllers.NewAuditController(auditQueryService, auditArchiveService,
+			audit.NewIntegrityService(database.MustGetDB(), auditConfig, auditArchiveService),
+			audit.NewExportService(auditConfig, auditQueryService))
 		
 		logger.Log.Info("审计服务已启动")
 	}
@@ -63,6 +75,9 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 	textureController := controllers.NewTextureController()
 	modelController := controllers.NewModelController(database.MustGetDB())
 	assetController := controllers.NewAssetController(database.MustGetDB())
+	similarityController := controllers.NewSimilarityController(database.MustGetDB())
+	tusController := controllers.NewTusController(database.MustGetDB())
+	shareController := controllers.NewShareController(database.MustGetDB())
 	
 	// 初始化JWT认证器
 	jwtAuth := middleware.NewJWTAuth()
@@ -70,12 +85,38 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 	// 初始化权限计算器
 	permCalculator := auth.NewPermissionCalculatorService(database.MustGetDB())
 	middleware.SetPermissionCalculator(permCalculator)
+	middleware.SetResourcePermissionChecker(permCalculator) // 文档、模型、项目、AI3D 任务可单独授权
+	
+	// 公开资源库（未登录可查看和下载），其余资源库需要登录并拥有对应权限
+	accessConfig, _ := config.LoadAccessConfig()
+	middleware.SetAnonymousRead(accessConfig.AnonymousRead)
+	if len(accessConfig.AnonymousRead) > 0 {
+		logger.Log.Infof("允许未登录访问的资源库: %s", strings.Join(accessConfig.AnonymousRead, ", "))
+	}
+	
+	// 初始化会话服务（访问 Token 必须绑定未撤销的登录会话）
+	sessionService := auth.NewSessionService(database.MustGetDB())
+	middleware.SetSessionValidator(sessionService)
+	
+	// 初始化个人访问令牌服务（DCC 插件、CI 等工具使用 pmt_ 开头的令牌调用接口）
+	tokenService := auth.NewTokenService(database.MustGetDB())
+	middleware.SetAPITokenAuthenticator(tokenService)
+	
+	// 初始化两步验证服务（角色可要求拥有者必须启用）
+	twoFactorService := auth.NewTwoFactorService(database.MustGetDB(), config.AppConfig.ProjectName)
 	
 	// 初始化认证和用户控制器
-	authController := controllers.NewAuthController(jwtAuth)
+	authController := controllers.NewAuthController(jwtAuth, sessionService, twoFactorService)
+	tokenController := controllers.NewTokenController(tokenService)
+	twoFactorController := controllers.NewTwoFactorController(authController, twoFactorService, auditService)
+	
+	// 初始化 OIDC 单点登录（未启用时登录页不显示单点登录入口）
+	oidcConfig, _ := config.LoadOIDCConfig()
+	oidcController := controllers.NewOIDCController(authController, auth.NewOIDCService(database.MustGetDB(), oidcConfig))
 	userController := controllers.NewUserController()
 	roleController := controllers.NewRoleController()
 	permissionController := controllers.NewPermissionController()
+	resourcePermissionController := controllers.NewResourcePermissionController(auth.NewResourcePermissionService(database.MustGetDB()))
 	
 	// 创建文档控制器（传递文件处理器服务和配置）
 	var documentController *controllers.DocumentController
@@ -89,6 +130,8 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 		documentController = controllers.NewDocumentController(database.MustGetDB(), nil, nil)
 	}
 	
+	webdavController := controllers.NewWebDAVController(database.MustGetDB(), documentController, tokenService, twoFactorService)
+	
 	imageController := controllers.NewImageController()
 	blueprintController := controllers.NewBlueprintController(database.MustGetDB())
 	projectController := controllers.NewProjectController(database.MustGetDB())
@@ -166,6 +209,9 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 		})
 	})
 
+	// 资源库静态文件按资源库的 read 权限校验（公开资源库未登录也可访问）
+	// 浏览器直接加载（img、iframe、新窗口预览）时无法携带 Authorization 头，通过登录时写入的 token Cookie 认证
+
 	// 贴图文件静态服务
 	// 如果启用了 NAS，使用 NAS 路径；否则使用本地路径
 	textureDir := "./static/textures"
@@ -178,7 +224,7 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 	}
 	
 	// 使用自定义处理器来支持 UNC 路径
-	router.GET("/textures/*filepath", func(c *gin.Context) {
+	router.GET("/textures/*filepath", jwtAuth.OptionalAuthMiddleware(), middleware.RequirePermission("textures", "read"), func(c *gin.Context) {
 		filepath := c.Param("filepath")
 		// 移除开头的斜杠
 		if len(filepath) > 0 && filepath[0] == '/' {
@@ -235,7 +281,7 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 	}
 	
 	// 使用自定义处理器来支持 UNC 路径
-	router.GET("/models/*filepath", func(c *gin.Context) {
+	router.GET("/models/*filepath", jwtAuth.OptionalAuthMiddleware(), middleware.RequirePermission("models", "read"), func(c *gin.Context) {
 		filepath := c.Param("filepath")
 		// 移除开头的斜杠
 		if len(filepath) > 0 && filepath[0] == '/' {
@@ -289,7 +335,7 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 	}
 	
 	// 使用自定义处理器来支持 UNC 路径
-	router.GET("/assets/*filepath", func(c *gin.Context) {
+	router.GET("/assets/*filepath", jwtAuth.OptionalAuthMiddleware(), middleware.RequirePermission("assets", "read"), func(c *gin.Context) {
 		filepath := c.Param("filepath")
 		// 移除开头的斜杠
 		if len(filepath) > 0 && filepath[0] == '/' {
@@ -343,7 +389,7 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 	}
 	
 	// 使用自定义处理器来支持 UNC 路径
-	router.GET("/hunyuan/*filepath", func(c *gin.Context) {
+	router.GET("/hunyuan/*filepath", jwtAuth.OptionalAuthMiddleware(), middleware.RequirePermission("ai3d", "read"), func(c *gin.Context) {
 		filepath := c.Param("filepath")
 		// 移除开头的斜杠
 		if len(filepath) > 0 && filepath[0] == '/' {
@@ -394,7 +440,7 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 		logger.Log.Infof("Meshy使用本地路径提供静态文件服务: %s", meshyDir)
 	}
 	
-	router.GET("/meshy/*filepath", func(c *gin.Context) {
+	router.GET("/meshy/*filepath", jwtAuth.OptionalAuthMiddleware(), middleware.RequirePermission("ai3d", "read"), func(c *gin.Context) {
 		filepath := c.Param("filepath")
 		if len(filepath) > 0 && filepath[0] == '/' {
 			filepath = filepath[1:]
@@ -440,7 +486,7 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 		logger.Log.Infof("项目管理使用本地路径提供静态文件服务: %s", projectDir)
 	}
 	
-	router.Static("/projects", projectDir)
+	router.Group("/projects", jwtAuth.OptionalAuthMiddleware(), middleware.RequirePermission("projects", "read")).Static("/", projectDir)
 	
 	// 项目历史版本静态服务
 	projectHistoryDir := "./static/project_histories"
@@ -451,7 +497,7 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 		logger.Log.Infof("项目历史版本使用本地路径提供静态文件服务: %s", projectHistoryDir)
 	}
 	
-	router.Static("/project_histories", projectHistoryDir)
+	router.Group("/project_histories", jwtAuth.OptionalAuthMiddleware(), middleware.RequirePermission("projects", "read")).Static("/", projectHistoryDir)
 
 	// 文件库静态服务
 	docConfig, _ := config.LoadDocumentConfig()
@@ -463,7 +509,8 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 		logger.Log.Infof("文件库使用本地路径提供静态文件服务: %s", documentDir)
 	}
 	
-	router.GET("/documents/*filepath", func(c *gin.Context) {
+	// 直接按路径读取文件会绕过文档的可见范围，只对文档管理员开放，其他用户通过 /api/documents/:id/download 下载
+	router.GET("/documents/*filepath", jwtAuth.AuthMiddleware(), middleware.RequirePermission("documents", "admin"), func(c *gin.Context) {
 		requestPath := c.Param("filepath")
 		if len(requestPath) > 0 && requestPath[0] == '/' {
 			requestPath = requestPath[1:]
@@ -497,152 +544,213 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 		c.File(fullPath)
 	})
 
+	// 分享链接公开访问（无需登录，有密码的分享通过 X-Share-Key 访问凭证校验）
+	shared := router.Group("/s")
+	{
+		shared.GET("/:token", shareController.PublicInfo)           // 查看分享信息
+		shared.POST("/:token/unlock", shareController.PublicUnlock) // 输入访问密码
+		shared.GET("/:token/list", shareController.PublicList)      // 浏览分享的文件夹
+		shared.GET("/:token/download", shareController.PublicDownload) // 下载分享的文件
+		shared.HEAD("/:token/download", shareController.PublicDownload) // 查询文件大小（不占用下载次数）
+	}
+
+	// 文件库 WebDAV（可挂载为网络驱动器，使用 Basic 认证登录现有账号）
+	for _, method := range controllers.WebDAVMethods {
+		router.Handle(method, controllers.WebDAVPrefix, webdavController.Handle)
+		router.Handle(method, controllers.WebDAVPrefix+"/*path", webdavController.Handle)
+	}
+
 	// 设置API路由组
 	api := router.Group("/api")
 	{
 		api.GET("/ping", Ping)
 
 		// 备份管理API
-		backup := api.Group("/backup")
+		backup := api.Group("/backup", jwtAuth.AuthMiddleware())
 		{
-			backup.GET("/status", backupController.GetStatus)                             // 获取备份状态
-			backup.POST("/trigger", backupController.TriggerManualBackup)                 // 手动触发全量备份
-			backup.POST("/database", backupController.TriggerDatabaseBackup)              // 手动触发数据库备份
-			backup.POST("/cdn", backupController.TriggerCDNBackup)                        // 手动触发CDN备份
-			backup.GET("/history", backupController.GetBackupHistory)                     // 获取备份历史
-			backup.POST("/restore/cdn/:backup_id", backupController.RestoreCDNFromBackup) // CDN文件恢复
+			backup.GET("/status", middleware.RequirePermission("backup", "read"), backupController.GetStatus)                             // 获取备份状态
+			backup.POST("/trigger", middleware.RequirePermission("backup", "create"), backupController.TriggerManualBackup)                 // 手动触发全量备份
+			backup.POST("/database", middleware.RequirePermission("backup", "create"), backupController.TriggerDatabaseBackup)              // 手动触发数据库备份
+			backup.POST("/cdn", middleware.RequirePermission("backup", "create"), backupController.TriggerCDNBackup)                        // 手动触发CDN备份
+			backup.GET("/history", middleware.RequirePermission("backup", "read"), backupController.GetBackupHistory)                     // 获取备份历史
+			backup.POST("/restore/cdn/:backup_id", middleware.RequirePermission("backup", "admin"), backupController.RestoreCDNFromBackup) // CDN文件恢复
 		}
 
 		// 安全管理API
-		security := api.Group("/security")
+		security := api.Group("/security", jwtAuth.AuthMiddleware())
 		{
-			security.GET("/status", securityController.GetStatus)                     // 获取安全状态
-			security.GET("/blocked-ips", securityController.GetBlockedIPs)            // 获取被封禁IP列表
-			security.POST("/unblock/:ip", securityController.UnblockIP)               // 解封IP地址
-			security.GET("/ip-stats", securityController.GetIPStats)                  // 获取IP统计信息
-			security.POST("/block/:ip", securityController.BlockIP)                   // 封禁IP地址
-			security.POST("/whitelist/:ip", securityController.AddToWhitelist)        // 添加IP到白名单
-			security.DELETE("/whitelist/:ip", securityController.RemoveFromWhitelist) // 从白名单移除IP
-			security.GET("/connections", securityController.GetConnections)           // 获取连接统计
+			security.GET("/status", middleware.RequirePermission("security", "read"), securityController.GetStatus)                     // 获取安全状态
+			security.GET("/blocked-ips", middleware.RequirePermission("security", "read"), securityController.GetBlockedIPs)            // 获取被封禁IP列表
+			security.POST("/unblock/:ip", middleware.RequirePermission("security", "admin"), securityController.UnblockIP)               // 解封IP地址
+			security.GET("/ip-stats", middleware.RequirePermission("security", "read"), securityController.GetIPStats)                  // 获取IP统计信息
+			security.POST("/block/:ip", middleware.RequirePermission("security", "admin"), securityController.BlockIP)                   // 封禁IP地址
+			security.POST("/whitelist/:ip", middleware.RequirePermission("security", "admin"), securityController.AddToWhitelist)        // 添加IP到白名单
+			security.DELETE("/whitelist/:ip", middleware.RequirePermission("security", "admin"), securityController.RemoveFromWhitelist) // 从白名单移除IP
+			security.GET("/connections", middleware.RequirePermission("security", "read"), securityController.GetConnections)           // 获取连接统计
 		}
 
 		// 贴图库管理API
-		textures := api.Group("/textures")
+		textures := api.Group("/textures", jwtAuth.OptionalAuthMiddleware())
 		{
-			textures.GET("", textureController.List)                                    // 获取贴图列表
-			textures.GET("/types", textureController.GetTextureTypes)                   // 获取所有贴图类型
-			textures.GET("/types/threejs", textureController.GetThreeJSTypes)           // 获取 Three.js 贴图类型
-			textures.GET("/analyze-types", textureController.AnalyzeTextureTypes)       // 分析所有贴图类型
-			textures.GET("/:assetId", textureController.GetDetail)                      // 获取贴图详情
-			textures.POST("/:assetId/use", textureController.RecordUse)                 // 记录使用次数
-			textures.POST("/sync", textureController.TriggerSync)                       // 触发同步
-			textures.GET("/sync/progress", textureController.GetSyncProgress)           // 获取同步进度
-			textures.GET("/sync/status/:logId", textureController.GetSyncStatus)        // 获取同步状态
-			textures.GET("/sync/logs", textureController.GetSyncLogs)                   // 获取同步日志
-			textures.POST("/download/:assetId", textureController.DownloadTexture)      // 触发材质下载（AmbientCG）
-			textures.GET("/download-status/:assetId", textureController.CheckDownloadStatus) // 检查下载状态
+			textures.GET("", middleware.RequirePermission("textures", "read"), textureController.List)                                    // 获取贴图列表
+			textures.GET("/types", middleware.RequirePermission("textures", "read"), textureController.GetTextureTypes)                   // 获取所有贴图类型
+			textures.GET("/types/threejs", middleware.RequirePermission("textures", "read"), textureController.GetThreeJSTypes)           // 获取 Three.js 贴图类型
+			textures.GET("/analyze-types", middleware.RequirePermission("textures", "read"), textureController.AnalyzeTextureTypes)       // 分析所有贴图类型
+			textures.GET("/metrics", middleware.RequirePermission("textures", "read"), textureController.GetMetrics)                      // 同步健康指标时间序列
+			textures.GET("/:assetId", middleware.RequirePermission("textures", "read"), textureController.GetDetail)                      // 获取贴图详情
+			textures.POST("/:assetId/use", middleware.RequirePermission("textures", "read"), textureController.RecordUse)                 // 记录使用次数
+			textures.GET("/:assetId/similar", middleware.RequirePermission("textures", "read"), textureController.FindSimilar)            // 查找相似材质（感知哈希）
+			textures.GET("/:assetId/export", middleware.RequirePermission("textures", "download"), textureController.ExportMaterial)          // 导出材质定义（gltf|threejs|mtlx）
+			textures.POST("/:assetId/export/prepare", middleware.RequirePermission("textures", "download"), textureController.PrepareExport) // 生成导出所需的法线 / ORM 贴图
+			textures.POST("/:assetId/normals/convert", middleware.RequirePermission("textures", "admin"), textureController.ConvertNormal)  // 转换法线贴图约定（gl|dx）
+			textures.POST("/normals/convert-all", middleware.RequirePermission("textures", "admin"), textureController.ConvertAllNormals)   // 批量补齐法线约定
+			textures.POST("/sync", middleware.RequirePermission("textures", "sync"), textureController.TriggerSync)                       // 触发同步
+			textures.GET("/sync/progress", middleware.RequirePermission("textures", "read"), textureController.GetSyncProgress)           // 获取同步进度
+			textures.GET("/sync/status/:logId", middleware.RequirePermission("textures", "read"), textureController.GetSyncStatus)        // 获取同步状态
+			textures.GET("/sync/logs", middleware.RequirePermission("textures", "read"), textureController.GetSyncLogs)                   // 获取同步日志
+			textures.POST("/download/:assetId", middleware.RequirePermission("textures", "sync"), textureController.DownloadTexture)      // 触发材质下载（AmbientCG）
+			textures.GET("/download-status/:assetId", middleware.RequirePermission("textures", "read"), textureController.CheckDownloadStatus) // 检查下载状态
 		}
 
 		// 标签管理API
-		tags := api.Group("/tags")
+		tags := api.Group("/tags", jwtAuth.OptionalAuthMiddleware())
 		{
-			tags.GET("", textureController.GetTags)                          // 获取标签列表
-			tags.GET("/:tagId/textures", textureController.GetTexturesByTag) // 根据标签获取贴图
+			tags.GET("", middleware.RequirePermission("textures", "read"), textureController.GetTags)                          // 获取标签列表
+			tags.GET("/:tagId/textures", middleware.RequirePermission("textures", "read"), textureController.GetTexturesByTag) // 根据标签获取贴图
 		}
 
 		// 模型库管理API
-		models := api.Group("/models")
+		models := api.Group("/models", jwtAuth.OptionalAuthMiddleware())
 		{
-			models.POST("/upload", modelController.Upload)                  // 上传模型
-			models.GET("", modelController.List)                            // 获取模型列表
-			models.GET("/search", modelController.Search)                   // 搜索模型
-			models.GET("/statistics", modelController.GetStatistics)        // 获取统计信息
-			models.GET("/popular", modelController.GetPopular)              // 获取热门模型
-			models.GET("/:id", modelController.GetDetail)                   // 获取模型详情
-			models.POST("/:id/use", modelController.IncrementUseCount)      // 记录使用次数
-			models.DELETE("/:id", modelController.Delete)                   // 删除模型
+			models.POST("/upload", middleware.RequirePermission("models", "upload"), middleware.LargeFileUpload(), modelController.Upload) // 上传模型（支持 model_upload_id/thumbnail_upload_id）
+			models.GET("", middleware.RequireResourceListPermission("models", "read"), modelController.List)                            // 获取模型列表
+			models.GET("/search", middleware.RequireResourceListPermission("models", "read"), modelController.Search)                   // 搜索模型
+			models.GET("/statistics", middleware.RequirePermission("models", "read"), modelController.GetStatistics)        // 获取统计信息
+			models.GET("/popular", middleware.RequireResourceListPermission("models", "read"), modelController.GetPopular)              // 获取热门模型
+			models.GET("/:id", middleware.RequireResourcePermission("models", "read"), modelController.GetDetail)                   // 获取模型详情
+			models.POST("/:id/use", middleware.RequireResourcePermission("models", "read"), modelController.IncrementUseCount)      // 记录使用次数
+			models.GET("/:id/similar", middleware.RequireResourcePermission("models", "read"), modelController.FindSimilar)         // 查找相似模型（预览图感知哈希）
+			models.DELETE("/:id", middleware.RequireResourcePermission("models", "delete"), modelController.Delete)                   // 删除模型
 		}
 
 		// 资产库管理API
-		assets := api.Group("/assets")
+		assets := api.Group("/assets", jwtAuth.OptionalAuthMiddleware())
 		{
-			assets.POST("/upload", assetController.Upload)                     // 上传资产
-			assets.GET("", assetController.List)                               // 获取资产列表
-			assets.GET("/statistics", assetController.GetStatistics)           // 获取统计信息
-			assets.GET("/statistics/by-type", assetController.GetStatisticsByType) // 按类型统计
-			assets.GET("/popular", assetController.GetPopular)                 // 获取热门资产
-			assets.GET("/:id", assetController.GetDetail)                      // 获取资产详情
-			assets.PUT("/:id", assetController.Update)                         // 更新资产信息
-			assets.DELETE("/:id", assetController.Delete)                      // 删除资产
-			assets.POST("/:id/use", assetController.IncrementUseCount)         // 记录使用次数
+			assets.POST("/upload", middleware.RequirePermission("assets", "upload"), middleware.LargeFileUpload(), assetController.Upload) // 上传资产（支持 file_upload_id）
+			assets.GET("", middleware.RequirePermission("assets", "read"), assetController.List)                               // 获取资产列表
+			assets.GET("/statistics", middleware.RequirePermission("assets", "read"), assetController.GetStatistics)           // 获取统计信息
+			assets.GET("/statistics/by-type", middleware.RequirePermission("assets", "read"), assetController.GetStatisticsByType) // 按类型统计
+			assets.GET("/popular", middleware.RequirePermission("assets", "read"), assetController.GetPopular)                 // 获取热门资产
+			assets.GET("/:id", middleware.RequirePermission("assets", "read"), assetController.GetDetail)                      // 获取资产详情
+			assets.PUT("/:id", middleware.RequirePermission("assets", "update"), assetController.Update)                         // 更新资产信息
+			assets.DELETE("/:id", middleware.RequirePermission("assets", "delete"), assetController.Delete)                      // 删除资产
+			assets.POST("/:id/use", middleware.RequirePermission("assets", "read"), assetController.IncrementUseCount)         // 记录使用次数
+			assets.GET("/:id/similar", middleware.RequirePermission("assets", "read"), assetController.FindSimilar)            // 查找相似资产（感知哈希）
 		}
 
 		// 文件库管理API（统一文件和文件夹）
-		documents := api.Group("/documents")
+		documents := api.Group("/documents", jwtAuth.OptionalAuthMiddleware()) // 按登录用户过滤可见文档，未登录只能访问公开文档
+		{
+			documents.POST("/upload", middleware.RequirePermission("documents", "upload"), middleware.LargeFileUpload(), documentController.Upload) // 上传文档（支持 file_upload_id）
+			documents.POST("/upload-folder", middleware.RequirePermission("documents", "upload"), documentController.UploadFolder)  // 上传文件夹（保持结构）
+			documents.POST("/folder", middleware.RequirePermission("documents", "create"), documentController.CreateFolder)         // 创建文件夹
+			documents.GET("", middleware.RequireResourceListPermission("documents", "read"), documentController.List)                         // 获取文档列表（支持parent_id过滤）
+			documents.GET("/statistics", middleware.RequireResourceListPermission("documents", "read"), documentController.GetStatistics)     // 获取统计信息
+			documents.GET("/popular", middleware.RequireResourceListPermission("documents", "read"), documentController.GetPopular)           // 获取热门文档
+			documents.POST("/move", middleware.RequirePermission("documents", "update"), documentController.Move)                   // 批量移动文档和文件夹
+			documents.POST("/copy", middleware.RequirePermission("documents", "create"), documentController.Copy)                   // 批量复制文档和文件夹（深度复制）
+			documents.POST("/bulk", middleware.RequirePermission("documents", "update"), documentController.Bulk)                   // 批量删除、打标签、设置部门/项目/公开
+			documents.POST("/archive", middleware.RequirePermission("documents", "download"), documentController.DownloadSelection)   // 多选打包下载（流式 zip）
+			documents.GET("/search", middleware.RequireResourceListPermission("documents", "read"), documentController.Search)                // 全文检索文档内容
+			documents.GET("/search/stats", middleware.RequirePermission("documents", "read"), documentController.GetSearchIndexStats) // 全文索引状态统计
+			documents.POST("/search/backfill", middleware.RequirePermission("documents", "admin"), documentController.BackfillSearchIndex) // 补建全文索引
+			documents.GET("/trash", middleware.RequirePermission("documents", "read"), documentController.ListTrash)              // 回收站列表
+			documents.DELETE("/trash", middleware.RequirePermission("documents", "delete"), documentController.EmptyTrash)          // 清空回收站
+			documents.POST("/trash/:id/restore", middleware.RequirePermission("documents", "delete"), documentController.RestoreTrash) // 还原回收站项目
+			documents.DELETE("/trash/:id", middleware.RequirePermission("documents", "delete"), documentController.PurgeTrash)      // 彻底删除回收站项目
+			documents.GET("/:id", middleware.RequireResourcePermission("documents", "read"), documentController.GetDetail)                // 获取文档详情
+			documents.PUT("/:id", middleware.RequireResourcePermission("documents", "update"), documentController.Update)                   // 更新文档信息
+			documents.DELETE("/:id", middleware.RequireResourcePermission("documents", "delete"), documentController.Delete)                // 删除文档（移入回收站，支持文件夹）
+			documents.GET("/:id/download", middleware.RequireResourcePermission("documents", "download"), documentController.Download)        // 下载文档
+			documents.GET("/:id/archive", middleware.RequireResourcePermission("documents", "download"), documentController.DownloadArchive)  // 文件夹打包下载（流式 zip）
+			documents.GET("/:id/entries", middleware.RequireResourcePermission("documents", "read"), documentController.ListEntries)      // 浏览压缩包目录树
+			documents.GET("/:id/entries/*path", middleware.RequireResourcePermission("documents", "download"), documentController.DownloadEntry) // 下载压缩包内单个文件
+			documents.POST("/:id/extract", middleware.RequirePermission("documents", "create"), documentController.Extract)         // 解压压缩包为新文件夹
+			documents.POST("/:id/refresh-thumbnail", middleware.RequireResourcePermission("documents", "update"), documentController.RefreshThumbnail) // 刷新缩略图
+			documents.GET("/:id/versions", middleware.RequireResourcePermission("documents", "read"), documentController.GetVersions)     // 获取版本列表
+			documents.POST("/:id/versions", middleware.RequireResourcePermission("documents", "upload"), middleware.LargeFileUpload(), documentController.UploadVersion) // 上传新版本（支持 file_upload_id）
+			documents.POST("/:id/versions/:versionId/restore", middleware.RequireResourcePermission("documents", "update"), documentController.RestoreVersion) // 恢复历史版本
+			documents.GET("/:id/logs", middleware.RequireResourcePermission("documents", "read"), documentController.GetAccessLogs)       // 获取访问日志
+			documents.GET("/:id/similar", middleware.RequireResourcePermission("documents", "read"), documentController.FindSimilar)      // 查找相似文档（感知哈希）
+		}
+
+		// 相似检索API
+		similarityGroup := api.Group("/similarity", jwtAuth.AuthMiddleware())
 		{
-			documents.POST("/upload", documentController.Upload)               // 上传文档
-			documents.POST("/upload-folder", documentController.UploadFolder)  // 上传文件夹（保持结构）
-			documents.POST("/folder", documentController.CreateFolder)         // 创建文件夹
-			documents.GET("", documentController.List)                         // 获取文档列表（支持parent_id过滤）
-			documents.GET("/statistics", documentController.GetStatistics)     // 获取统计信息
-			documents.GET("/popular", documentController.GetPopular)           // 获取热门文档
-			documents.GET("/:id", documentController.GetDetail)                // 获取文档详情
-			documents.PUT("/:id", documentController.Update)                   // 更新文档信息
-			documents.DELETE("/:id", documentController.Delete)                // 删除文档（支持级联删除文件夹）
-			documents.GET("/:id/download", documentController.Download)        // 下载文档
-			documents.POST("/:id/refresh-thumbnail", documentController.RefreshThumbnail) // 刷新缩略图
-			documents.GET("/:id/versions", documentController.GetVersions)     // 获取版本列表
-			documents.GET("/:id/logs", documentController.GetAccessLogs)       // 获取访问日志
+			similarityGroup.POST("/:library/rebuild", requireLibraryAdmin(), similarityController.Rebuild) // 补算感知哈希（asset|texture|model|document）
 		}
 
 		// AI 3D生成统一API（支持多平台）
-		ai3d := api.Group("/ai3d")
+		ai3d := api.Group("/ai3d", jwtAuth.OptionalAuthMiddleware())
 		{
 			// 使用统一控制器
 			if ai3dUnifiedController != nil {
-				ai3d.POST("/tasks", ai3dUnifiedController.SubmitTask)           // 提交任务（支持provider参数）
-				ai3d.GET("/tasks", ai3dUnifiedController.ListTasks)             // 任务列表
-				ai3d.GET("/tasks/:id", ai3dUnifiedController.GetTask)           // 获取任务详情
-				ai3d.POST("/tasks/:id/poll", ai3dUnifiedController.PollTask)    // 轮询任务
-				ai3d.DELETE("/tasks/:id", ai3dUnifiedController.DeleteTask)     // 删除任务
-				ai3d.GET("/config", ai3dUnifiedController.GetConfig)            // 获取配置
+				ai3d.POST("/tasks", middleware.RequirePermission("ai3d", "create"), ai3dUnifiedController.SubmitTask)           // 提交任务（支持provider参数）
+				ai3d.GET("/tasks", middleware.RequireResourceListPermission("ai3d", "read"), ai3dUnifiedController.ListTasks)             // 任务列表
+				ai3d.GET("/tasks/:id", middleware.RequireResourcePermission("ai3d", "read"), ai3dUnifiedController.GetTask)           // 获取任务详情
+				ai3d.POST("/tasks/:id/poll", middleware.RequireResourcePermission("ai3d", "read"), ai3dUnifiedController.PollTask)    // 轮询任务
+				ai3d.DELETE("/tasks/:id", middleware.RequireResourcePermission("ai3d", "delete"), ai3dUnifiedController.DeleteTask)     // 删除任务
+				ai3d.GET("/config", middleware.RequirePermission("ai3d", "read"), ai3dUnifiedController.GetConfig)            // 获取配置
 			} else {
 				// 如果服务未初始化，返回错误
-				ai3d.POST("/tasks", func(c *gin.Context) {
+				ai3d.POST("/tasks", middleware.RequirePermission("ai3d", "create"), func(c *gin.Context) {
 					response.Error(c, 500, "AI3D服务未初始化")
 				})
 			}
 		}
 
 		// 图片处理API
-		image := api.Group("/image")
+		image := api.Group("/image", jwtAuth.AuthMiddleware())
 		{
-			image.POST("/flipy-webp", imageController.FlipYAndToWebp) // 图片翻转并转换为WebP
+			image.POST("/flipy-webp", middleware.RequirePermission("textures", "read"), imageController.FlipYAndToWebp) // 图片翻转并转换为WebP
 		}
 
 		// AI蓝图生成API
-		blueprint := api.Group("/blueprint")
+		blueprint := api.Group("/blueprint", jwtAuth.AuthMiddleware())
 		{
-			blueprint.POST("/generate", blueprintController.Generate)   // 生成蓝图
-			blueprint.GET("/history", blueprintController.GetHistory)   // 获取生成历史
+			blueprint.POST("/generate", middleware.RequirePermission("ai3d", "create"), blueprintController.Generate) // 生成蓝图
+			blueprint.GET("/history", middleware.RequirePermission("ai3d", "read"), blueprintController.GetHistory)   // 获取生成历史
+		}
+
+		// 断点续传上传API（tus 1.0.0），完成后通过 *_upload_id 挂载到各资源库
+		// 上传完成前还不知道挂载到哪个资源库，拥有任一资源库上传权限即可；挂载时再按资源库校验
+		tus := api.Group("/uploads/tus", middleware.LargeFileUpload())
+		{
+			tus.OPTIONS("", tusController.Options) // 查询服务端能力（CORS 预检无法携带认证信息，不校验）
+			tusUpload := tus.Group("", jwtAuth.AuthMiddleware(), middleware.RequireAnyPermission("documents:upload", "models:upload", "assets:upload", "projects:upload"))
+			tusUpload.POST("", tusController.Create)       // 创建上传
+			tusUpload.HEAD("/:id", tusController.Head)     // 查询上传偏移量
+			tusUpload.PATCH("/:id", tusController.Patch)   // 上传分片
+			tusUpload.DELETE("/:id", tusController.Delete) // 终止上传
+			tusUpload.POST("/:id", tusController.MethodOverride) // X-HTTP-Method-Override: PATCH/DELETE
 		}
 
 		// 项目管理API
-		projects := api.Group("/projects")
+		projects := api.Group("/projects", jwtAuth.OptionalAuthMiddleware())
 		{
-			projects.GET("", projectController.GetProjects)                          // 获取项目列表
-			projects.POST("", projectController.CreateProject)                       // 创建项目
-			projects.GET("/:id", projectController.GetProject)                       // 获取项目详情
-			projects.DELETE("/:id", projectController.DeleteProject)                 // 删除项目
-			projects.POST("/:id/versions", projectController.UploadVersion)          // 上传版本
-			projects.GET("/:id/versions", projectController.GetVersionHistory)       // 获取版本历史
-			projects.POST("/:id/refresh-thumbnail", projectController.RefreshThumbnail) // 刷新缩略图
-			projects.GET("/versions/:versionId/download", projectController.DownloadVersion) // 下载版本
-			projects.POST("/versions/:versionId/rollback", projectController.RollbackVersion) // 回滚版本
+			projects.GET("", middleware.RequireResourceListPermission("projects", "read"), projectController.GetProjects)                          // 获取项目列表
+			projects.POST("", middleware.RequirePermission("projects", "create"), projectController.CreateProject)                       // 创建项目
+			projects.GET("/:id", middleware.RequireResourcePermission("projects", "read"), projectController.GetProject)                       // 获取项目详情
+			projects.DELETE("/:id", middleware.RequireResourcePermission("projects", "delete"), projectController.DeleteProject)                 // 删除项目
+			projects.POST("/:id/versions", middleware.RequireResourcePermission("projects", "upload"), middleware.LargeFileUpload(), projectController.UploadVersion) // 上传版本（支持 archive_upload_id）
+			projects.GET("/:id/versions", middleware.RequireResourcePermission("projects", "read"), projectController.GetVersionHistory)       // 获取版本历史
+			projects.POST("/:id/refresh-thumbnail", middleware.RequireResourcePermission("projects", "update"), projectController.RefreshThumbnail) // 刷新缩略图
+			projects.GET("/versions/:versionId/download", middleware.RequireResourcePermissionFunc("projects", "download", projectController.VersionProjectID), projectController.DownloadVersion) // 下载版本（按所属项目检查对象授权）
+			projects.POST("/versions/:versionId/rollback", middleware.RequireResourcePermissionFunc("projects", "update", projectController.VersionProjectID), projectController.RollbackVersion) // 回滚版本（按所属项目检查对象授权）
 		}
 
 		// 统计API
-		statistics := api.Group("/statistics")
+		statistics := api.Group("/statistics", jwtAuth.AuthMiddleware(), middleware.RequireAnyPermission(libraryReadPermissions...))
 		{
 			statistics.GET("/overview", statisticsController.GetOverview)                   // 获取统计概览
 			statistics.GET("/recent-activities", statisticsController.GetRecentActivities) // 获取最近活动
@@ -651,31 +759,33 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 
 		// 文件处理器API
 		if fileProcessorController != nil {
-			fileprocessor := api.Group("/fileprocessor")
+			fileprocessor := api.Group("/fileprocessor", jwtAuth.AuthMiddleware())
 			{
-				fileprocessor.GET("/formats", fileProcessorController.GetSupportedFormats)      // 获取支持的格式
-				fileprocessor.POST("/metadata", fileProcessorController.ExtractMetadata)        // 提取元数据
-				fileprocessor.POST("/thumbnail", fileProcessorController.GenerateThumbnail)     // 生成缩略图
-				fileprocessor.POST("/tasks", fileProcessorController.CreateTask)                // 创建任务
-				fileprocessor.GET("/tasks", fileProcessorController.ListTasks)                  // 列出任务
-				fileprocessor.GET("/tasks/:id", fileProcessorController.GetTask)                // 获取任务详情
-				fileprocessor.POST("/tasks/:id/cancel", fileProcessorController.CancelTask)     // 取消任务
-				fileprocessor.POST("/tasks/:id/retry", fileProcessorController.RetryTask)       // 重试任务
+				fileprocessor.GET("/formats", middleware.RequirePermission("documents", "read"), fileProcessorController.GetSupportedFormats)      // 获取支持的格式
+				fileprocessor.POST("/metadata", middleware.RequirePermission("documents", "upload"), fileProcessorController.ExtractMetadata)        // 提取元数据
+				fileprocessor.POST("/thumbnail", middleware.RequirePermission("documents", "upload"), fileProcessorController.GenerateThumbnail)     // 生成缩略图
+				fileprocessor.POST("/tasks", middleware.RequirePermission("documents", "upload"), fileProcessorController.CreateTask)                // 创建任务
+				fileprocessor.GET("/tasks", middleware.RequirePermission("documents", "read"), fileProcessorController.ListTasks)                  // 列出任务
+				fileprocessor.GET("/tasks/:id", middleware.RequirePermission("documents", "read"), fileProcessorController.GetTask)                // 获取任务详情
+				fileprocessor.POST("/tasks/:id/cancel", middleware.RequirePermission("documents", "admin"), fileProcessorController.CancelTask)     // 取消任务
+				fileprocessor.POST("/tasks/:id/retry", middleware.RequirePermission("documents", "admin"), fileProcessorController.RetryTask)       // 重试任务
 			}
 		}
 
 		// 审计日志API
 		if auditController != nil {
-			audit := api.Group("/audit")
+			audit := api.Group("/audit", jwtAuth.AuthMiddleware())
 			{
-				audit.GET("/logs", auditController.ListLogs)                                    // 查询审计日志列表
-				audit.GET("/logs/:id", auditController.GetLog)                                  // 获取单条审计日志
-				audit.GET("/users/:user_id/logs", auditController.GetUserLogs)                  // 获取用户的审计日志
-				audit.GET("/resources/:resource/:resource_id/logs", auditController.GetResourceLogs) // 获取资源的审计日志
-				audit.GET("/statistics", auditController.GetStatistics)                         // 获取统计信息
-				audit.POST("/archive", auditController.TriggerArchive)                          // 手动触发归档
-				audit.GET("/archive/statistics", auditController.GetArchiveStatistics)          // 获取归档统计信息
-				audit.GET("/archive/files", auditController.ListArchiveFiles)                   // 列出归档文件
+				audit.GET("/logs", middleware.RequirePermission("audit", "read"), auditController.ListLogs)                                    // 查询审计日志列表
+				audit.GET("/logs/:id", middleware.RequirePermission("audit", "read"), auditController.GetLog)                                  // 获取单条审计日志
+				audit.GET("/users/:user_id/logs", middleware.RequirePermission("audit", "read"), auditController.GetUserLogs)                  // 获取用户的审计日志
+				audit.GET("/resources/:resource/:resource_id/logs", middleware.RequirePermission("audit", "read"), auditController.GetResourceLogs) // 获取资源的审计日志
+				audit.GET("/statistics", middleware.RequirePermission("audit", "read"), auditController.GetStatistics)                         // 获取统计信息
+				audit.POST("/archive", middleware.RequirePermission("audit", "admin"), auditController.TriggerArchive)                          // 手动触发归档
+				audit.GET("/archive/statistics", middleware.RequirePermission("audit", "read"), auditController.GetArchiveStatistics)          // 获取归档统计信息
+				audit.GET("/archive/files", middleware.RequirePermission("audit", "read"), auditController.ListArchiveFiles)                   // 列出归档文件
+				audit.GET("/verify", middleware.RequirePermission("audit", "read"), auditController.VerifyChain)                               // 校验哈希链（防篡改）
+				audit.GET("/export", middleware.RequirePermission("audit", "export"), auditController.ExportLogs)                               // 导出审计日志（CSV/JSONL/CEF）
 			}
 		}
 
@@ -691,6 +801,35 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 			auth.POST("/change-password", jwtAuth.AuthMiddleware(), authController.ChangePassword) // 修改密码
 			auth.GET("/profile", jwtAuth.AuthMiddleware(), authController.GetProfile) // 获取个人信息
 			auth.POST("/check-permission", jwtAuth.AuthMiddleware(), authController.CheckPermission) // 检查权限
+			auth.POST("/check-resource-permission", jwtAuth.AuthMiddleware(), resourcePermissionController.Check) // 检查对象权限（角色权限或单独授权）
+			auth.GET("/oidc/config", oidcController.Config)           // 单点登录配置
+			auth.GET("/oidc/login", oidcController.Login)             // 跳转到身份提供方登录
+			auth.GET("/oidc/callback", oidcController.Callback)       // 身份提供方回调
+			auth.POST("/oidc/exchange", oidcController.Exchange)      // 使用登录票据换取Token
+			auth.POST("/2fa/verify", twoFactorController.Verify)      // 登录时提交两步验证码
+			auth.POST("/2fa/setup", jwtAuth.OptionalAuthMiddleware(), twoFactorController.Setup)   // 获取验证器绑定密钥（已登录或携带绑定挑战）
+			auth.POST("/2fa/enable", jwtAuth.OptionalAuthMiddleware(), twoFactorController.Enable) // 启用两步验证
+			auth.GET("/2fa", jwtAuth.AuthMiddleware(), twoFactorController.Status)                 // 两步验证状态
+			auth.POST("/2fa/disable", jwtAuth.AuthMiddleware(), twoFactorController.Disable)       // 关闭两步验证
+			auth.POST("/2fa/recovery-codes", jwtAuth.AuthMiddleware(), twoFactorController.RegenerateRecoveryCodes) // 重新生成恢复码
+			auth.GET("/sessions", jwtAuth.AuthMiddleware(), authController.ListSessions)      // 登录会话列表
+			auth.DELETE("/sessions", jwtAuth.AuthMiddleware(), authController.RevokeSessions) // 撤销其他全部会话
+			auth.DELETE("/sessions/:id", jwtAuth.AuthMiddleware(), authController.RevokeSession) // 撤销指定会话
+			auth.GET("/tokens", jwtAuth.AuthMiddleware(), tokenController.ListTokens)           // 个人访问令牌列表
+			auth.POST("/tokens", jwtAuth.AuthMiddleware(), tokenController.CreateToken)         // 创建个人访问令牌
+			auth.DELETE("/tokens/:id", jwtAuth.AuthMiddleware(), tokenController.RevokeToken)   // 撤销个人访问令牌
+			auth.GET("/service-accounts", jwtAuth.AuthMiddleware(), middleware.RequirePermission("users", "admin"), tokenController.ListServiceAccounts)   // 服务账号列表
+			auth.POST("/service-accounts", jwtAuth.AuthMiddleware(), middleware.RequirePermission("users", "admin"), tokenController.CreateServiceAccount) // 创建服务账号
+		}
+
+		// ==================== 分享链接管理路由 ====================
+		shares := api.Group("/shares")
+		shares.Use(jwtAuth.AuthMiddleware())
+		{
+			shares.POST("", shareController.Create)                // 创建分享链接（按资源类型检查 share 权限和可见性）
+			shares.GET("", shareController.List)                   // 分享链接列表（非管理员只返回自己创建的）
+			shares.DELETE("/:id", shareController.Revoke)          // 取消分享（创建者或管理员）
+			shares.GET("/:id/logs", shareController.GetAccessLogs) // 访问日志（创建者或管理员）
 		}
 
 		// ==================== 用户管理路由 ====================
@@ -706,7 +845,10 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 			users.POST("/:id/enable", middleware.RequirePermission("users", "admin"), userController.Enable)
 			users.POST("/:id/reset-password", middleware.RequirePermission("users", "admin"), userController.ResetPassword)
 			users.POST("/:id/roles", middleware.RequirePermission("users", "admin"), userController.AssignRoles)
+			users.POST("/:id/2fa/reset", middleware.RequirePermission("users", "admin"), twoFactorController.Reset)
 			users.GET("/:id/permissions", middleware.RequirePermission("users", "read"), userController.GetPermissions)
+			users.GET("/:id/permissions/explain", middleware.RequirePermission("users", "read"), userController.ExplainPermission) // 解释权限来源（?code=projects:delete）
+			users.GET("/permissions/matrix", middleware.RequireAllPermissions("users:read", "permissions:read"), userController.ExportAccessMatrix) // 导出角色与权限矩阵（?format=json|csv）
 		}
 
 		// ==================== 角色管理路由 ====================
@@ -719,6 +861,7 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 			roles.PUT("/:id", middleware.RequirePermission("roles", "update"), roleController.Update)
 			roles.DELETE("/:id", middleware.RequirePermission("roles", "delete"), roleController.Delete)
 			roles.POST("/:id/permissions", middleware.RequirePermission("roles", "admin"), roleController.AssignPermissions)
+			roles.PUT("/:id/two-factor", middleware.RequirePermission("roles", "admin"), roleController.SetTwoFactor)
 			roles.GET("/:id/permissions", middleware.RequirePermission("roles", "read"), roleController.GetPermissions)
 		}
 
@@ -748,6 +891,15 @@ func RegisterRoutes(router *gin.Engine, log *logrus.Logger, ai3dTaskService inte
 			permissionGroups.DELETE("/:id/permissions/:permission_id", middleware.RequirePermission("permissions", "admin"), permissionController.RemovePermissionFromGroup)
 		}
 
+		// 对象级授权（permissions:admin 或对应资源的 admin 权限可以授权和撤销）
+		resourcePermissions := api.Group("/resource-permissions", jwtAuth.AuthMiddleware())
+		{
+			resourcePermissions.POST("", resourcePermissionController.Grant)                              // 授予对象权限
+			resourcePermissions.DELETE("/:id", resourcePermissionController.Revoke)                      // 撤销对象权限
+			resourcePermissions.GET("/user/:userId", resourcePermissionController.ListByUser)            // 用户的对象授权
+			resourcePermissions.GET("/resource/:type/:id", resourcePermissionController.ListByResource) // 对象的授权列表
+		}
+
 		// ==================== 认证路由（示例）====================
 		// 初始化JWT认证器
 		_ = jwtAuth // 使用jwtAuth.AuthMiddleware()保护需要认证的路由
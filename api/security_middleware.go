@@ -289,16 +289,22 @@ func CorsMiddleware() gin.HandlerFunc {
 		}
 
 		// 设置允许的方法
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH, HEAD, "+
+			"PROPFIND, PROPPATCH, MKCOL, COPY, MOVE, LOCK, UNLOCK")
 		// 设置允许的头
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		// 设置暴露的头
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, "+
+			"Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, X-HTTP-Method-Override, "+
+			"Depth, Destination, Overwrite, If, Lock-Token, Timeout")
+		// 设置暴露的头（含 tus 断点续传响应头）
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length, Location, "+
+			"Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Metadata, Upload-Expires")
 		// 设置预检请求的有效期
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24小时
 
 		// 如果是预检请求，直接返回200
-		if c.Request.Method == "OPTIONS" {
+		// WebDAV 和 tus 客户端的 OPTIONS 不是预检，分别交给对应处理器响应 DAV 头和 Tus-* 能力头
+		if c.Request.Method == "OPTIONS" &&
+			(!isProtocolOptions(c.Request.URL.Path) || c.GetHeader("Access-Control-Request-Method") != "") {
 			c.AbortWithStatus(200)
 			return
 		}
@@ -308,6 +314,11 @@ func CorsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// isProtocolOptions 判断 OPTIONS 是否属于由处理器自行响应的协议（WebDAV、tus 能力查询）
+func isProtocolOptions(path string) bool {
+	return strings.HasPrefix(path, "/dav/") || strings.TrimSuffix(path, "/") == "/api/uploads/tus"
+}
+
 // isOriginAllowed 检查来源是否允许
 func isOriginAllowed(origin string) bool {
 	// 开发环境允许localhost
@@ -611,7 +622,10 @@ func RequestSizeLimitMiddleware(maxSize int64) gin.HandlerFunc {
 		// 文件上传路由跳过大小限制（在应用层验证）
 		if strings.HasPrefix(c.Request.URL.Path, "/api/documents/upload") ||
 		   strings.HasPrefix(c.Request.URL.Path, "/api/models/upload") ||
-		   strings.HasPrefix(c.Request.URL.Path, "/api/assets/upload") {
+		   strings.HasPrefix(c.Request.URL.Path, "/api/assets/upload") ||
+		   strings.HasPrefix(c.Request.URL.Path, "/api/uploads/tus") ||
+		   strings.HasPrefix(c.Request.URL.Path, "/dav/") ||
+		   (strings.HasPrefix(c.Request.URL.Path, "/api/documents/") && strings.HasSuffix(c.Request.URL.Path, "/versions")) {
 			c.Next()
 			return
 		}
@@ -0,0 +1,88 @@
+// Package config 资源库访问控制配置
+package config
+
+import (
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AccessYAMLConfig 访问控制 YAML 配置结构
+type AccessYAMLConfig struct {
+	Access struct {
+		AnonymousRead []string `yaml:"anonymous_read"`
+	} `yaml:"access"`
+}
+
+// AccessConfig 资源库访问控制配置
+type AccessConfig struct {
+	// AnonymousRead 允许未登录用户查看和下载的资源库（documents、models、assets、textures、projects、ai3d）
+	// 文件库未登录时仍只能看到公开文档；写操作和系统管理接口始终需要登录
+	AnonymousRead []string
+}
+
+// LoadAccessConfig 加载访问控制配置
+func LoadAccessConfig() (*AccessConfig, error) {
+	// 1. 加载默认配置
+	defaultConfig := getDefaultAccessConfig()
+
+	// 2. 尝试从 YAML 文件加载
+	yamlConfig := loadAccessYAML()
+	if yamlConfig != nil {
+		mergeAccessConfig(defaultConfig, yamlConfig)
+	}
+
+	// 3. 环境变量覆盖
+	applyAccessEnvOverrides(defaultConfig)
+
+	return defaultConfig, nil
+}
+
+// getDefaultAccessConfig 获取默认配置
+func getDefaultAccessConfig() *AccessConfig {
+	return &AccessConfig{
+		// 文件库原本允许未登录访问公开文档，默认保留
+		AnonymousRead: []string{"documents"},
+	}
+}
+
+// loadAccessYAML 从 YAML 文件加载配置
+func loadAccessYAML() *AccessYAMLConfig {
+	configFile := "configs/access.yaml"
+	if _, err := os.Stat(configFile); err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil
+	}
+
+	var yamlConfig AccessYAMLConfig
+	if err := yaml.Unmarshal(data, &yamlConfig); err != nil {
+		return nil
+	}
+
+	return &yamlConfig
+}
+
+// mergeAccessConfig 合并 YAML 配置到默认配置
+func mergeAccessConfig(config *AccessConfig, yamlConfig *AccessYAMLConfig) {
+	// 配置文件中的列表（包括空列表）覆盖默认值
+	if yamlConfig.Access.AnonymousRead != nil {
+		config.AnonymousRead = yamlConfig.Access.AnonymousRead
+	}
+}
+
+// applyAccessEnvOverrides 应用环境变量覆盖
+func applyAccessEnvOverrides(config *AccessConfig) {
+	if val, ok := os.LookupEnv("ACCESS_ANONYMOUS_READ"); ok {
+		config.AnonymousRead = nil
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				config.AnonymousRead = append(config.AnonymousRead, item)
+			}
+		}
+	}
+}
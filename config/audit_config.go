@@ -24,6 +24,25 @@ type AuditYAMLConfig struct {
 			Format               string `yaml:"format"`
 			Compression          bool   `yaml:"compression"`
 		} `yaml:"archive"`
+		Integrity struct {
+			SigningKey string `yaml:"signing_key"`
+		} `yaml:"integrity"`
+		Export struct {
+			Enabled bool     `yaml:"enabled"`
+			Hour    *int     `yaml:"hour"` // 未配置时为 nil，0 表示零点
+			Formats []string `yaml:"formats"`
+			Dir     string   `yaml:"dir"`
+		} `yaml:"export"`
+		Forwarding struct {
+			Enabled       bool              `yaml:"enabled"`
+			BufferSize    int               `yaml:"buffer_size"`
+			BatchSize     int               `yaml:"batch_size"`
+			FlushInterval int               `yaml:"flush_interval"`
+			MaxRetries    int               `yaml:"max_retries"`
+			RetryInterval int               `yaml:"retry_interval"`
+			SpoolDir      string            `yaml:"spool_dir"`
+			Sinks         []AuditSinkConfig `yaml:"sinks"`
+		} `yaml:"forwarding"`
 		Log struct {
 			Actions              []string `yaml:"actions"`
 			Resources            []string `yaml:"resources"`
@@ -48,6 +67,29 @@ type AuditYAMLConfig struct {
 	} `yaml:"audit"`
 }
 
+// AuditSinkConfig 审计日志外部接收端配置
+type AuditSinkConfig struct {
+	Name   string `yaml:"name"`   // 名称（用于日志和离线缓存文件名）
+	Type   string `yaml:"type"`   // syslog, http, file
+	Format string `yaml:"format"` // 消息格式：cef, json（syslog 和 file 使用）
+
+	// syslog（RFC 5424）
+	Network  string `yaml:"network"`  // tcp, udp
+	Address  string `yaml:"address"`  // 如 127.0.0.1:514
+	AppName  string `yaml:"app_name"` // APP-NAME 字段
+	Facility int    `yaml:"facility"` // 默认 13（log audit）
+
+	// http（POST JSON 数组）
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout int               `yaml:"timeout"` // 秒
+
+	// file（按大小轮转的本地文件）
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+}
+
 // AuditConfig 审计系统配置结构
 type AuditConfig struct {
 	// 基础配置
@@ -64,6 +106,25 @@ type AuditConfig struct {
 	ArchiveFormat           string
 	ArchiveCompression      bool
 
+	// 防篡改配置
+	IntegritySigningKey string // 归档清单签名密钥（HMAC-SHA256），未配置时拒绝归档
+
+	// 定时导出配置
+	ExportEnabled bool
+	ExportHour    int      // 每天几点导出前一天的日志
+	ExportFormats []string // csv, jsonl, cef
+	ExportDir     string
+
+	// 外部日志转发配置
+	ForwardEnabled       bool
+	ForwardBufferSize    int
+	ForwardBatchSize     int
+	ForwardFlushInterval int // 秒
+	ForwardMaxRetries    int
+	ForwardRetryInterval int    // 秒，每次重试翻倍
+	ForwardSpoolDir      string // 发送失败时的离线缓存目录，恢复后补发
+	ForwardSinks         []AuditSinkConfig
+
 	// 日志记录配置
 	LogActions             []string
 	LogResources           []string
@@ -86,6 +147,14 @@ type AuditConfig struct {
 	EnableFullTextSearch  bool
 }
 
+// insecureAuditSigningKey 旧版本内置的默认签名密钥（已公开），配置为它时视为未配置
+const insecureAuditSigningKey = "audit-signing-key-change-in-production"
+
+// SigningKeyConfigured 是否配置了可用的归档签名密钥
+func (c *AuditConfig) SigningKeyConfigured() bool {
+	return c.IntegritySigningKey != "" && c.IntegritySigningKey != insecureAuditSigningKey
+}
+
 // LoadAuditConfig 加载审计系统配置
 func LoadAuditConfig() (*AuditConfig, error) {
 	// 1. 加载默认配置
@@ -120,6 +189,21 @@ func getDefaultAuditConfig() *AuditConfig {
 		ArchiveFormat:       "json",
 		ArchiveCompression:  true,
 
+		// 定时导出配置
+		ExportEnabled: false,
+		ExportHour:    3, // 凌晨3点，在归档之后
+		ExportFormats: []string{"jsonl"},
+		ExportDir:     "data/audit_exports",
+
+		// 外部日志转发配置
+		ForwardEnabled:       false,
+		ForwardBufferSize:    1000,
+		ForwardBatchSize:     100,
+		ForwardFlushInterval: 5,
+		ForwardMaxRetries:    3,
+		ForwardRetryInterval: 2,
+		ForwardSpoolDir:      "data/audit_spool",
+
 		// 日志记录配置
 		LogActions: []string{
 			"login", "logout", "create", "update", "delete",
@@ -138,6 +222,7 @@ func getDefaultAuditConfig() *AuditConfig {
 		LogSensitiveFields: []string{
 			"password", "token", "secret", "api_key",
 			"access_token", "refresh_token",
+			"challenge_token", "recovery_code",
 		},
 		RecordRequestBody:   true,
 		MaxRequestBodySize:  10240, // 10KB
@@ -205,6 +290,45 @@ func mergeAuditConfig(config *AuditConfig, yamlConfig *AuditYAMLConfig) {
 	}
 	config.ArchiveCompression = audit.Archive.Compression
 
+	// 防篡改配置
+	if audit.Integrity.SigningKey != "" {
+		config.IntegritySigningKey = audit.Integrity.SigningKey
+	}
+
+	// 定时导出配置
+	config.ExportEnabled = audit.Export.Enabled
+	if audit.Export.Hour != nil {
+		config.ExportHour = *audit.Export.Hour
+	}
+	if len(audit.Export.Formats) > 0 {
+		config.ExportFormats = audit.Export.Formats
+	}
+	if audit.Export.Dir != "" {
+		config.ExportDir = audit.Export.Dir
+	}
+
+	// 外部日志转发配置
+	config.ForwardEnabled = audit.Forwarding.Enabled
+	if audit.Forwarding.BufferSize > 0 {
+		config.ForwardBufferSize = audit.Forwarding.BufferSize
+	}
+	if audit.Forwarding.BatchSize > 0 {
+		config.ForwardBatchSize = audit.Forwarding.BatchSize
+	}
+	if audit.Forwarding.FlushInterval > 0 {
+		config.ForwardFlushInterval = audit.Forwarding.FlushInterval
+	}
+	if audit.Forwarding.MaxRetries > 0 {
+		config.ForwardMaxRetries = audit.Forwarding.MaxRetries
+	}
+	if audit.Forwarding.RetryInterval > 0 {
+		config.ForwardRetryInterval = audit.Forwarding.RetryInterval
+	}
+	if audit.Forwarding.SpoolDir != "" {
+		config.ForwardSpoolDir = audit.Forwarding.SpoolDir
+	}
+	config.ForwardSinks = audit.Forwarding.Sinks
+
 	// 日志记录配置
 	if len(audit.Log.Actions) > 0 {
 		config.LogActions = audit.Log.Actions
@@ -297,6 +421,34 @@ func applyAuditEnvOverrides(config *AuditConfig) {
 		}
 	}
 
+	// 防篡改配置
+	if val := os.Getenv("AUDIT_SIGNING_KEY"); val != "" {
+		config.IntegritySigningKey = val
+	}
+
+	// 定时导出配置
+	if val := os.Getenv("AUDIT_EXPORT_ENABLED"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			config.ExportEnabled = b
+		}
+	}
+	if val := os.Getenv("AUDIT_EXPORT_FORMATS"); val != "" {
+		config.ExportFormats = strings.Split(val, ",")
+	}
+	if val := os.Getenv("AUDIT_EXPORT_DIR"); val != "" {
+		config.ExportDir = val
+	}
+
+	// 外部日志转发配置（接收端只能在 YAML 中配置）
+	if val := os.Getenv("AUDIT_FORWARD_ENABLED"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			config.ForwardEnabled = b
+		}
+	}
+	if val := os.Getenv("AUDIT_FORWARD_SPOOL_DIR"); val != "" {
+		config.ForwardSpoolDir = val
+	}
+
 	// 日志记录配置
 	if val := os.Getenv("AUDIT_LOG_ACTIONS"); val != "" {
 		config.LogActions = strings.Split(val, ",")
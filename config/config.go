@@ -239,6 +239,12 @@ type YAMLConfig struct {
 		MaxRetryTimes          int    `yaml:"max_retry_times"`
 		RetryInterval          int    `yaml:"retry_interval"`
 	} `yaml:"meshy"`
+
+	Upload struct {
+		StorageDir  string `yaml:"storage_dir"`
+		MaxSize     int64  `yaml:"max_size"`
+		ExpireHours int    `yaml:"expire_hours"`
+	} `yaml:"upload"`
 }
 
 // Config 应用程序配置结构
@@ -262,6 +268,7 @@ type Config struct {
 	AI3D          AI3DConfig     // AI 3D平台配置
 	Hunyuan       HunyuanConfig  // 混元3D配置
 	Meshy         MeshyConfig    // Meshy配置
+	Upload        UploadConfig   // 断点续传上传配置
 }
 
 // AI3DConfig AI 3D平台配置
@@ -371,6 +378,13 @@ type AssetConfig struct {
 	VideoThumbnailTime float64 // 视频截图时间点（秒）
 }
 
+// UploadConfig 断点续传上传（tus）配置
+type UploadConfig struct {
+	StorageDir  string // 分片暂存目录
+	MaxSize     int64  // 单个上传最大字节数
+	ExpireHours int    // 未完成上传的过期时间（小时）
+}
+
 // HunyuanConfig 混元3D配置
 type HunyuanConfig struct {
 	// API配置
@@ -533,6 +547,11 @@ func LoadConfig() error {
 			MaxRetryTimes:          getEnvAsIntOrDefault("MESHY_MAX_RETRY_TIMES", yamlConfig.Meshy.MaxRetryTimes),
 			RetryInterval:          getEnvAsIntOrDefault("MESHY_RETRY_INTERVAL", yamlConfig.Meshy.RetryInterval),
 		},
+		Upload: UploadConfig{
+			StorageDir:  getEnvOrDefault("UPLOAD_STORAGE_DIR", yamlConfig.Upload.StorageDir),
+			MaxSize:     getEnvAsInt64OrDefault("UPLOAD_MAX_SIZE", yamlConfig.Upload.MaxSize),
+			ExpireHours: getEnvAsIntOrDefault("UPLOAD_EXPIRE_HOURS", yamlConfig.Upload.ExpireHours),
+		},
 	}
 
 	// 3. 设置CDN基础路径（支持YAML和环境变量覆盖）
@@ -718,6 +737,11 @@ func loadYAMLConfig() *YAMLConfig {
 	defaultConfig.Meshy.MaxRetryTimes = 3
 	defaultConfig.Meshy.RetryInterval = 10
 
+	// 断点续传上传默认配置
+	defaultConfig.Upload.StorageDir = "static/uploads/tus"
+	defaultConfig.Upload.MaxSize = 20 * 1024 * 1024 * 1024 // 20GB
+	defaultConfig.Upload.ExpireHours = 24
+
 	// 尝试读取YAML配置文件
 	configFile := "config.yaml"
 	if _, err := os.Stat(configFile); err == nil {
@@ -800,6 +824,10 @@ func loadYAMLConfig() *YAMLConfig {
 				if yamlConfig.Meshy.StorageDir != "" {
 					defaultConfig.Meshy = yamlConfig.Meshy
 				}
+				// 断点续传上传配置
+				if yamlConfig.Upload.StorageDir != "" {
+					defaultConfig.Upload = yamlConfig.Upload
+				}
 			}
 		}
 	}
@@ -46,6 +46,18 @@ type DocumentYAMLConfig struct {
 			LogAccess        bool `yaml:"log_access"`
 			LogRetentionDays int  `yaml:"log_retention_days"`
 		} `yaml:"log"`
+		RecycleBin struct {
+			RetentionDays int `yaml:"retention_days"`
+		} `yaml:"recycle_bin"`
+		Archive struct {
+			MaxSize  int64 `yaml:"max_size"`
+			MaxFiles int   `yaml:"max_files"`
+		} `yaml:"archive"`
+		Extract struct {
+			MaxSize  int64 `yaml:"max_size"`
+			MaxFiles int   `yaml:"max_files"`
+			MaxRatio int64 `yaml:"max_ratio"`
+		} `yaml:"extract"`
 	} `yaml:"document"`
 }
 
@@ -89,6 +101,18 @@ type DocumentConfig struct {
 	LogEnabled       bool
 	LogAccess        bool
 	LogRetentionDays int
+
+	// 回收站配置
+	TrashRetentionDays int // 回收站保留天数，超期自动彻底删除（0 表示不自动清理）
+
+	// 打包下载配置
+	ArchiveMaxSize  int64 // 单次打包下载的文件总大小上限（字节）
+	ArchiveMaxFiles int   // 单次打包下载的文件数量上限
+
+	// 压缩包解压配置（解压到文件库，防止压缩炸弹）
+	ExtractMaxSize  int64 // 解压后的文件总大小上限（字节）
+	ExtractMaxFiles int   // 解压的文件数量上限
+	ExtractMaxRatio int64 // 单个文件解压后与压缩后大小之比的上限
 }
 
 // LoadDocumentConfig 加载文件库配置
@@ -154,6 +178,18 @@ func getDefaultDocumentConfig() *DocumentConfig {
 		LogEnabled:       true,
 		LogAccess:        true,
 		LogRetentionDays: 90,
+
+		// 回收站配置
+		TrashRetentionDays: 30,
+
+		// 打包下载配置
+		ArchiveMaxSize:  21474836480, // 20GB
+		ArchiveMaxFiles: 10000,
+
+		// 压缩包解压配置
+		ExtractMaxSize:  10737418240, // 10GB
+		ExtractMaxFiles: 10000,
+		ExtractMaxRatio: 100,
 	}
 }
 
@@ -241,6 +277,30 @@ func mergeDocumentConfig(config *DocumentConfig, yamlConfig *DocumentYAMLConfig)
 	if doc.Log.LogRetentionDays > 0 {
 		config.LogRetentionDays = doc.Log.LogRetentionDays
 	}
+
+	// 回收站配置
+	if doc.RecycleBin.RetentionDays > 0 {
+		config.TrashRetentionDays = doc.RecycleBin.RetentionDays
+	}
+
+	// 打包下载配置
+	if doc.Archive.MaxSize > 0 {
+		config.ArchiveMaxSize = doc.Archive.MaxSize
+	}
+	if doc.Archive.MaxFiles > 0 {
+		config.ArchiveMaxFiles = doc.Archive.MaxFiles
+	}
+
+	// 压缩包解压配置
+	if doc.Extract.MaxSize > 0 {
+		config.ExtractMaxSize = doc.Extract.MaxSize
+	}
+	if doc.Extract.MaxFiles > 0 {
+		config.ExtractMaxFiles = doc.Extract.MaxFiles
+	}
+	if doc.Extract.MaxRatio > 0 {
+		config.ExtractMaxRatio = doc.Extract.MaxRatio
+	}
 }
 
 // applyDocumentEnvOverrides 应用环境变量覆盖
@@ -327,5 +387,41 @@ func applyDocumentEnvOverrides(config *DocumentConfig) {
 			config.LogAccess = b
 		}
 	}
+
+	// 回收站配置
+	if val := os.Getenv("DOCUMENT_TRASH_RETENTION_DAYS"); val != "" {
+		if days, err := strconv.Atoi(val); err == nil {
+			config.TrashRetentionDays = days
+		}
+	}
+
+	// 打包下载配置
+	if val := os.Getenv("DOCUMENT_ARCHIVE_MAX_SIZE"); val != "" {
+		if size, err := strconv.ParseInt(val, 10, 64); err == nil {
+			config.ArchiveMaxSize = size
+		}
+	}
+	if val := os.Getenv("DOCUMENT_ARCHIVE_MAX_FILES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			config.ArchiveMaxFiles = n
+		}
+	}
+
+	// 压缩包解压配置
+	if val := os.Getenv("DOCUMENT_EXTRACT_MAX_SIZE"); val != "" {
+		if size, err := strconv.ParseInt(val, 10, 64); err == nil {
+			config.ExtractMaxSize = size
+		}
+	}
+	if val := os.Getenv("DOCUMENT_EXTRACT_MAX_FILES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			config.ExtractMaxFiles = n
+		}
+	}
+	if val := os.Getenv("DOCUMENT_EXTRACT_MAX_RATIO"); val != "" {
+		if ratio, err := strconv.ParseInt(val, 10, 64); err == nil {
+			config.ExtractMaxRatio = ratio
+		}
+	}
 }
 
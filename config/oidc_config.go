@@ -0,0 +1,220 @@
+// Package config OIDC 单点登录配置
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OIDCRoleMapping 身份提供方声明到本地角色的映射
+type OIDCRoleMapping struct {
+	Claim string   `yaml:"claim" json:"claim"` // 声明名称，如 groups，值可以是字符串或字符串数组
+	Value string   `yaml:"value" json:"value"` // 声明值，如 pm-admins
+	Roles []string `yaml:"roles" json:"roles"` // 映射到的本地角色编码，如 admin
+}
+
+// OIDCYAMLConfig OIDC YAML 配置结构
+type OIDCYAMLConfig struct {
+	OIDC struct {
+		Enabled         bool              `yaml:"enabled"`
+		ProviderName    string            `yaml:"provider_name"`
+		Issuer          string            `yaml:"issuer"`
+		ClientID        string            `yaml:"client_id"`
+		ClientSecret    string            `yaml:"client_secret"`
+		RedirectURL     string            `yaml:"redirect_url"`
+		FrontendURL     string            `yaml:"frontend_url"`
+		Scopes          []string          `yaml:"scopes"`
+		UsernameClaim   string            `yaml:"username_claim"`
+		AutoCreateUsers *bool             `yaml:"auto_create_users"`
+		LinkByEmail     *bool             `yaml:"link_by_email"`
+		RequireVerified *bool             `yaml:"require_verified_email"`
+		DefaultRoles    []string          `yaml:"default_roles"`
+		SyncRoles       bool              `yaml:"sync_roles"`
+		RoleMappings    []OIDCRoleMapping `yaml:"role_mappings"`
+		HTTPTimeout     int               `yaml:"http_timeout"`
+	} `yaml:"oidc"`
+}
+
+// OIDCConfig OIDC 单点登录配置
+type OIDCConfig struct {
+	Enabled      bool
+	ProviderName string // 登录页按钮显示的名称
+	Issuer       string // 身份提供方地址，用于发现 /.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // 回调地址，需在身份提供方登记，如 https://pm.example.com/api/auth/oidc/callback
+	FrontendURL  string // 登录完成后跳转的前端地址，附带一次性登录票据
+	Scopes       []string
+
+	// 用户配置
+	UsernameClaim   string // 用作用户名的声明，默认 preferred_username
+	AutoCreateUsers bool   // 首次登录时自动创建用户
+	LinkByEmail     bool   // 按邮箱关联已有的本地账号
+	RequireVerified bool   // 按邮箱关联时要求 email_verified 为 true
+
+	// 角色配置
+	DefaultRoles []string          // 自动创建用户且没有匹配的映射时分配的角色
+	SyncRoles    bool              // 每次登录按映射结果重新设置角色（映射结果为空时不修改）
+	RoleMappings []OIDCRoleMapping // 声明到角色的映射
+
+	HTTPTimeout int // 请求身份提供方的超时（秒）
+}
+
+// LoadOIDCConfig 加载 OIDC 配置
+func LoadOIDCConfig() (*OIDCConfig, error) {
+	// 1. 加载默认配置
+	defaultConfig := getDefaultOIDCConfig()
+
+	// 2. 尝试从 YAML 文件加载
+	yamlConfig := loadOIDCYAML()
+	if yamlConfig != nil {
+		mergeOIDCConfig(defaultConfig, yamlConfig)
+	}
+
+	// 3. 环境变量覆盖
+	applyOIDCEnvOverrides(defaultConfig)
+
+	return defaultConfig, nil
+}
+
+// getDefaultOIDCConfig 获取默认配置
+func getDefaultOIDCConfig() *OIDCConfig {
+	return &OIDCConfig{
+		Enabled:         false,
+		ProviderName:    "单点登录",
+		Scopes:          []string{"openid", "profile", "email"},
+		UsernameClaim:   "preferred_username",
+		AutoCreateUsers: true,
+		LinkByEmail:     true,
+		RequireVerified: true,
+		DefaultRoles:    []string{"viewer"},
+		SyncRoles:       false,
+		HTTPTimeout:     10,
+	}
+}
+
+// loadOIDCYAML 从 YAML 文件加载配置
+func loadOIDCYAML() *OIDCYAMLConfig {
+	configFile := "configs/oidc.yaml"
+	if _, err := os.Stat(configFile); err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil
+	}
+
+	var yamlConfig OIDCYAMLConfig
+	if err := yaml.Unmarshal(data, &yamlConfig); err != nil {
+		return nil
+	}
+
+	return &yamlConfig
+}
+
+// mergeOIDCConfig 合并 YAML 配置到默认配置
+func mergeOIDCConfig(config *OIDCConfig, yamlConfig *OIDCYAMLConfig) {
+	oidc := yamlConfig.OIDC
+
+	config.Enabled = oidc.Enabled
+	if oidc.ProviderName != "" {
+		config.ProviderName = oidc.ProviderName
+	}
+	if oidc.Issuer != "" {
+		config.Issuer = oidc.Issuer
+	}
+	if oidc.ClientID != "" {
+		config.ClientID = oidc.ClientID
+	}
+	if oidc.ClientSecret != "" {
+		config.ClientSecret = oidc.ClientSecret
+	}
+	if oidc.RedirectURL != "" {
+		config.RedirectURL = oidc.RedirectURL
+	}
+	if oidc.FrontendURL != "" {
+		config.FrontendURL = oidc.FrontendURL
+	}
+	if len(oidc.Scopes) > 0 {
+		config.Scopes = oidc.Scopes
+	}
+
+	// 用户配置
+	if oidc.UsernameClaim != "" {
+		config.UsernameClaim = oidc.UsernameClaim
+	}
+	if oidc.AutoCreateUsers != nil {
+		config.AutoCreateUsers = *oidc.AutoCreateUsers
+	}
+	if oidc.LinkByEmail != nil {
+		config.LinkByEmail = *oidc.LinkByEmail
+	}
+	if oidc.RequireVerified != nil {
+		config.RequireVerified = *oidc.RequireVerified
+	}
+
+	// 角色配置
+	if len(oidc.DefaultRoles) > 0 {
+		config.DefaultRoles = oidc.DefaultRoles
+	}
+	config.SyncRoles = oidc.SyncRoles
+	if len(oidc.RoleMappings) > 0 {
+		config.RoleMappings = oidc.RoleMappings
+	}
+
+	if oidc.HTTPTimeout > 0 {
+		config.HTTPTimeout = oidc.HTTPTimeout
+	}
+}
+
+// applyOIDCEnvOverrides 应用环境变量覆盖
+func applyOIDCEnvOverrides(config *OIDCConfig) {
+	if val := os.Getenv("OIDC_ENABLED"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			config.Enabled = b
+		}
+	}
+	if val := os.Getenv("OIDC_PROVIDER_NAME"); val != "" {
+		config.ProviderName = val
+	}
+	if val := os.Getenv("OIDC_ISSUER"); val != "" {
+		config.Issuer = val
+	}
+	if val := os.Getenv("OIDC_CLIENT_ID"); val != "" {
+		config.ClientID = val
+	}
+	if val := os.Getenv("OIDC_CLIENT_SECRET"); val != "" {
+		config.ClientSecret = val
+	}
+	if val := os.Getenv("OIDC_REDIRECT_URL"); val != "" {
+		config.RedirectURL = val
+	}
+	if val := os.Getenv("OIDC_FRONTEND_URL"); val != "" {
+		config.FrontendURL = val
+	}
+	if val := os.Getenv("OIDC_SCOPES"); val != "" {
+		config.Scopes = strings.Split(val, ",")
+	}
+	if val := os.Getenv("OIDC_AUTO_CREATE_USERS"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			config.AutoCreateUsers = b
+		}
+	}
+	if val := os.Getenv("OIDC_LINK_BY_EMAIL"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			config.LinkByEmail = b
+		}
+	}
+	if val := os.Getenv("OIDC_DEFAULT_ROLES"); val != "" {
+		config.DefaultRoles = strings.Split(val, ",")
+	}
+	if val := os.Getenv("OIDC_SYNC_ROLES"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			config.SyncRoles = b
+		}
+	}
+}
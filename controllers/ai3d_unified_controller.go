@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
 	"go_wails_project_manager/models/ai3d"
 	ai3dService "go_wails_project_manager/services/ai3d"
 	"go_wails_project_manager/response"
@@ -182,7 +183,13 @@ func (c *AI3DUnifiedController) ListTasks(ctx *gin.Context) {
 		"keyword":  ctx.Query("keyword"),
 	}
 
-	tasks, total, err := c.taskService.ListTasks(page, pageSize, filters)
+	// 没有 AI3D 查看权限的用户只能看到被单独授权的任务
+	ids, ok := visibleResourceIDs(ctx, models.ResourceAI3D)
+	if !ok {
+		return
+	}
+
+	tasks, total, err := c.taskService.ListTasks(page, pageSize, filters, ids)
 	if err != nil {
 		response.Error(ctx, http.StatusInternalServerError, "查询失败")
 		return
@@ -3,7 +3,11 @@ package controllers
 import (
 	"go_wails_project_manager/config"
 	"go_wails_project_manager/response"
+	"go_wails_project_manager/logger"
 	"go_wails_project_manager/services/asset"
+	"go_wails_project_manager/services/palette"
+	"go_wails_project_manager/services/similarity"
+	"go_wails_project_manager/services/upload"
 	"net/http"
 	"strconv"
 	"strings"
@@ -14,15 +18,19 @@ import (
 
 // AssetController 资产控制器
 type AssetController struct {
-	uploadService *asset.UploadService
-	queryService  *asset.QueryService
+	uploadService     *asset.UploadService
+	queryService      *asset.QueryService
+	similarityService *similarity.Service
+	tusService        *upload.TusService
 }
 
 // NewAssetController 创建资产控制器
 func NewAssetController(db *gorm.DB) *AssetController {
 	return &AssetController{
-		uploadService: asset.NewUploadService(db, &config.AppConfig.Asset),
-		queryService:  asset.NewQueryService(db),
+		uploadService:     asset.NewUploadService(db, &config.AppConfig.Asset),
+		queryService:      asset.NewQueryService(db),
+		similarityService: similarity.NewService(db, logger.Log),
+		tusService:        upload.NewTusService(db, logger.Log),
 	}
 }
 
@@ -31,18 +39,21 @@ func NewAssetController(db *gorm.DB) *AssetController {
 // @Tags 资产管理
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "资产文件"
+// @Param file formData file false "资产文件"
+// @Param file_upload_id formData string false "已完成的断点续传上传ID（替代 file）"
 // @Param name formData string true "资产名称"
 // @Param description formData string false "描述"
 // @Param category formData string false "分类"
 // @Param tags formData string false "标签(逗号分隔)"
+// @Param check_similar formData boolean false "是否检查近似重复（返回 near_duplicates）"
+// @Param similar_threshold formData int false "近似重复阈值（汉明距离）" default(10)
 // @Success 200 {object} response.Response
 // @Router /api/assets/upload [post]
 func (c *AssetController) Upload(ctx *gin.Context) {
 	// 获取上传文件
-	file, err := ctx.FormFile("file")
+	file, uploadID, err := formUpload(ctx, c.tusService, "file")
 	if err != nil {
-		response.Error(ctx, http.StatusBadRequest, "未找到上传文件")
+		response.Error(ctx, http.StatusBadRequest, uploadErrorMessage(err, "未找到上传文件"))
 		return
 	}
 	
@@ -72,10 +83,39 @@ func (c *AssetController) Upload(ctx *gin.Context) {
 		response.Error(ctx, http.StatusInternalServerError, "上传失败: "+err.Error())
 		return
 	}
+	releaseUpload(ctx, c.tusService, uploadID)
+	
+	// 按需返回近似重复提示
+	if matches := nearDuplicates(ctx, c.similarityService, similarity.LibraryAsset, uploadedAsset.PerceptualHash, uploadedAsset.ID, nil); matches != nil {
+		response.SuccessWithMsg(ctx, "上传成功", gin.H{
+			"asset":           uploadedAsset,
+			"near_duplicates": matches,
+		})
+		return
+	}
 	
 	response.SuccessWithMsg(ctx, "上传成功", uploadedAsset)
 }
 
+// FindSimilar 查找相似资产
+// @Summary 查找相似资产
+// @Description 基于感知哈希（dHash）查找视觉相似的资产，可识别缩放、重新编码后的副本
+// @Tags 资产管理
+// @Produce json
+// @Param id path int true "资产ID"
+// @Param threshold query int false "相似度阈值（汉明距离 0-32）" default(10)
+// @Success 200 {object} response.Response
+// @Router /api/assets/{id}/similar [get]
+func (c *AssetController) FindSimilar(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的资产ID")
+		return
+	}
+	
+	respondSimilar(ctx, c.similarityService, similarity.LibraryAsset, uint(id), nil)
+}
+
 // List 资产列表
 // @Summary 资产列表
 // @Tags 资产管理
@@ -88,6 +128,8 @@ func (c *AssetController) Upload(ctx *gin.Context) {
 // @Param keyword query string false "关键词"
 // @Param sortBy query string false "排序字段"
 // @Param sortOrder query string false "排序方向"
+// @Param color query string false "主色（#aabbcc）"
+// @Param tolerance query number false "颜色容差（Lab ΔE）" default(20)
 // @Success 200 {object} response.Response
 // @Router /api/assets [get]
 func (c *AssetController) List(ctx *gin.Context) {
@@ -103,6 +145,16 @@ func (c *AssetController) List(ctx *gin.Context) {
 		Keyword:   ctx.Query("keyword"),
 		SortBy:    ctx.Query("sortBy"),
 		SortOrder: ctx.Query("sortOrder"),
+		Color:     ctx.Query("color"),
+	}
+	
+	// 解析主色过滤
+	if filters.Color != "" {
+		if _, _, _, err := palette.ParseHex(filters.Color); err != nil {
+			response.Error(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		filters.Tolerance, _ = strconv.ParseFloat(ctx.Query("tolerance"), 64)
 	}
 	
 	// 解析标签
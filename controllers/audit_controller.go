@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"go_wails_project_manager/logger"
 	"go_wails_project_manager/response"
 	"go_wails_project_manager/services/audit"
+	"net/http"
 	"strconv"
 	"time"
 
@@ -11,15 +13,19 @@ import (
 
 // AuditController 审计控制器
 type AuditController struct {
-	queryService   *audit.QueryService
-	archiveService *audit.ArchiveService
+	queryService     *audit.QueryService
+	archiveService   *audit.ArchiveService
+	integrityService *audit.IntegrityService
+	exportService    *audit.ExportService
 }
 
 // NewAuditController 创建审计控制器
-func NewAuditController(queryService *audit.QueryService, archiveService *audit.ArchiveService) *AuditController {
+func NewAuditController(queryService *audit.QueryService, archiveService *audit.ArchiveService, integrityService *audit.IntegrityService, exportService *audit.ExportService) *AuditController {
 	return &AuditController{
-		queryService:   queryService,
-		archiveService: archiveService,
+		queryService:     queryService,
+		archiveService:   archiveService,
+		integrityService: integrityService,
+		exportService:    exportService,
 	}
 }
 
@@ -37,9 +43,41 @@ func NewAuditController(queryService *audit.QueryService, archiveService *audit.
 // @Param status_code query int false "状态码"
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
+// @Param include_archived query bool false "是否包含归档文件中的日志（默认 true）"
 // @Success 200 {object} response.Response
 // @Router /api/audit/logs [get]
 func (ctrl *AuditController) ListLogs(c *gin.Context) {
+	filter := parseAuditFilter(c)
+
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if ps, err := strconv.Atoi(pageSize); err == nil {
+			filter.PageSize = ps
+		}
+	}
+
+	// 查询日志
+	logs, total, err := ctrl.queryService.List(filter)
+	if err != nil {
+		response.InternalServerError(c, "查询审计日志失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"logs":  logs,
+		"total": total,
+		"page":  filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// parseAuditFilter 解析列表和导出共用的过滤参数（无效的参数被忽略）
+func parseAuditFilter(c *gin.Context) audit.AuditFilter {
 	filter := audit.AuditFilter{
 		Username: c.Query("username"),
 		UserIP:   c.Query("user_ip"),
@@ -80,31 +118,13 @@ func (ctrl *AuditController) ListLogs(c *gin.Context) {
 		}
 	}
 
-	if page := c.Query("page"); page != "" {
-		if p, err := strconv.Atoi(page); err == nil {
-			filter.Page = p
-		}
-	}
-
-	if pageSize := c.Query("page_size"); pageSize != "" {
-		if ps, err := strconv.Atoi(pageSize); err == nil {
-			filter.PageSize = ps
+	if includeArchived := c.Query("include_archived"); includeArchived != "" {
+		if b, err := strconv.ParseBool(includeArchived); err == nil {
+			filter.LiveOnly = !b
 		}
 	}
 
-	// 查询日志
-	logs, total, err := ctrl.queryService.List(filter)
-	if err != nil {
-		response.InternalServerError(c, "查询审计日志失败")
-		return
-	}
-
-	response.Success(c, gin.H{
-		"logs":  logs,
-		"total": total,
-		"page":  filter.Page,
-		"page_size": filter.PageSize,
-	})
+	return filter
 }
 
 // GetLog 获取单条审计日志
@@ -291,3 +311,99 @@ func (ctrl *AuditController) ListArchiveFiles(c *gin.Context) {
 		"count": len(files),
 	})
 }
+
+// VerifyChain 校验审计日志哈希链
+// @Summary 校验审计日志哈希链（数据库和归档文件），报告第一处断裂
+// @Tags 审计
+// @Param from query string false "开始时间（RFC3339 或 2006-01-02，默认7天前）"
+// @Param to query string false "结束时间（RFC3339 或 2006-01-02，默认当前时间）"
+// @Success 200 {object} response.Response
+// @Router /api/audit/verify [get]
+func (ctrl *AuditController) VerifyChain(c *gin.Context) {
+	from := time.Now().AddDate(0, 0, -7)
+	to := time.Now()
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		t, err := parseVerifyTime(fromStr, false)
+		if err != nil {
+			response.BadRequest(c, "无效的开始时间")
+			return
+		}
+		from = t
+	}
+
+	if toStr := c.Query("to"); toStr != "" {
+		t, err := parseVerifyTime(toStr, true)
+		if err != nil {
+			response.BadRequest(c, "无效的结束时间")
+			return
+		}
+		to = t
+	}
+
+	report, err := ctrl.integrityService.Verify(from, to)
+	if err != nil {
+		if err == audit.ErrInvalidVerifyRange || err == audit.ErrVerifyRangeTooLarge {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.InternalServerError(c, "校验审计日志失败")
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// parseVerifyTime 解析 RFC3339 时间或日期，endOfDay 为 true 时日期取当天结束时刻
+func parseVerifyTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// ExportLogs 导出审计日志
+// @Summary 按过滤条件导出审计日志（CSV、JSON Lines 或 CEF），按时间正序
+// @Tags 审计
+// @Param format query string false "导出格式：csv、jsonl、cef（默认 jsonl）"
+// @Param user_id query int false "用户ID"
+// @Param username query string false "用户名"
+// @Param user_ip query string false "用户IP"
+// @Param action query string false "操作类型"
+// @Param resource query string false "资源类型"
+// @Param resource_id query int false "资源ID"
+// @Param start_time query string false "开始时间"
+// @Param end_time query string false "结束时间"
+// @Param status_code query int false "状态码"
+// @Param include_archived query bool false "是否包含归档文件中的日志（默认 true）"
+// @Success 200 {file} file
+// @Router /api/audit/export [get]
+func (ctrl *AuditController) ExportLogs(c *gin.Context) {
+	format := c.DefaultQuery("format", audit.ExportFormatJSONL)
+	if !audit.IsExportFormat(format) {
+		response.BadRequest(c, audit.ErrExportFormatInvalid.Error())
+		return
+	}
+
+	filter := parseAuditFilter(c)
+
+	filename := "audit-" + time.Now().Format("20060102150405") + audit.ExportFileExt(format)
+	c.Header("Content-Type", audit.ExportContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if format == audit.ExportFormatCSV {
+		c.Writer.WriteString("\xEF\xBB\xBF") // UTF-8 BOM，Excel 打开时中文不乱码
+	}
+
+	// 响应头已发出，导出中途失败只能记录日志
+	if _, err := ctrl.exportService.Export(c.Writer, format, filter); err != nil {
+		logger.Log.Errorf("导出审计日志失败: %v", err)
+	}
+}
@@ -5,20 +5,28 @@ import (
 	"go_wails_project_manager/middleware"
 	"go_wails_project_manager/models"
 	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/auth"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AuthController 认证控制器
 type AuthController struct {
-	jwtAuth *middleware.JWTAuth
+	jwtAuth          *middleware.JWTAuth
+	sessionService   *auth.SessionService
+	twoFactorService *auth.TwoFactorService
 }
 
 // NewAuthController 创建认证控制器
-func NewAuthController(jwtAuth *middleware.JWTAuth) *AuthController {
+func NewAuthController(jwtAuth *middleware.JWTAuth, sessionService *auth.SessionService, twoFactorService *auth.TwoFactorService) *AuthController {
 	return &AuthController{
-		jwtAuth: jwtAuth,
+		jwtAuth:          jwtAuth,
+		sessionService:   sessionService,
+		twoFactorService: twoFactorService,
 	}
 }
 
@@ -98,16 +106,17 @@ func (ac *AuthController) Register(c *gin.Context) {
 		db.Model(&user).Association("Roles").Append(&viewerRole)
 	}
 
-	// 生成 Token
-	token, err := ac.jwtAuth.GenerateToken(user.ID, user.Username, models.RoleViewer)
+	// 创建会话并生成 Token
+	tokens, err := ac.startSession(c, &user, models.RoleViewer)
 	if err != nil {
 		response.InternalServerError(c, "生成Token失败")
 		return
 	}
 
 	response.Success(c, gin.H{
-		"user":  user.ToResponse(),
-		"token": token,
+		"user":          user.ToResponse(),
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
 	})
 }
 
@@ -138,6 +147,11 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
+	if user.IsServiceAccount() {
+		response.Forbidden(c, "服务账号不能登录，请使用访问令牌")
+		return
+	}
+
 	if user.IsLocked() {
 		response.Forbidden(c, "账号已被锁定，请稍后再试")
 		return
@@ -145,20 +159,53 @@ func (ac *AuthController) Login(c *gin.Context) {
 
 	// 验证密码
 	if !middleware.CheckPassword(req.Password, user.Password) {
-		// 记录登录失败
-		user.LoginFailCount++
-		if user.LoginFailCount >= 5 {
-			// 锁定30分钟
-			lockUntil := time.Now().Add(30 * time.Minute)
-			user.LockedUntil = &lockUntil
-			user.Status = models.UserStatusLocked
-		}
-		db.Save(&user)
-
+		recordLoginFailure(db, &user)
 		response.Unauthorized(c, "用户名或密码错误")
 		return
 	}
 
+	ac.completeLogin(c, db, &user)
+}
+
+// completeLogin 身份验证通过后继续登录（账号密码和单点登录共用）
+// 已启用两步验证时返回挑战令牌，角色要求两步验证但未绑定时返回绑定挑战，否则直接签发 Token
+func (ac *AuthController) completeLogin(c *gin.Context, db *gorm.DB, user *models.User) {
+	if ac.twoFactorService != nil {
+		enabled, err := ac.twoFactorService.Enabled(user.ID)
+		if err != nil {
+			response.InternalServerError(c, "查询两步验证状态失败")
+			return
+		}
+		if enabled {
+			response.Success(c, gin.H{
+				"mfa_required":    true,
+				"challenge_token": ac.twoFactorService.NewChallenge(user.ID, auth.ChallengeVerify),
+				"methods":         []string{"totp", "recovery_code"},
+				"expires_in":      ac.twoFactorService.ChallengeTTL(),
+			})
+			return
+		}
+
+		required, err := ac.twoFactorService.Required(user.ID)
+		if err != nil {
+			response.InternalServerError(c, "查询两步验证状态失败")
+			return
+		}
+		if required {
+			response.Success(c, gin.H{
+				"mfa_setup_required": true,
+				"challenge_token":    ac.twoFactorService.NewChallenge(user.ID, auth.ChallengeEnroll),
+				"expires_in":         ac.twoFactorService.ChallengeTTL(),
+			})
+			return
+		}
+	}
+
+	ac.finishLogin(c, db, user, nil)
+}
+
+// finishLogin 重置登录失败次数、创建会话并返回 Token，extra 中的字段会合并到响应
+func (ac *AuthController) finishLogin(c *gin.Context, db *gorm.DB, user *models.User, extra gin.H) {
 	// 登录成功，重置失败次数
 	now := time.Now()
 	user.LoginFailCount = 0
@@ -168,7 +215,7 @@ func (ac *AuthController) Login(c *gin.Context) {
 	if user.Status == models.UserStatusLocked {
 		user.Status = models.UserStatusActive
 	}
-	db.Save(&user)
+	db.Save(user)
 
 	// 获取用户主要角色
 	roleCode := models.RoleViewer
@@ -176,33 +223,77 @@ func (ac *AuthController) Login(c *gin.Context) {
 		roleCode = user.Roles[0].Code
 	}
 
-	// 生成 Token
-	token, err := ac.jwtAuth.GenerateToken(user.ID, user.Username, roleCode)
+	// 创建会话并生成 Token（访问 Token 和刷新 Token 都绑定会话）
+	tokens, err := ac.startSession(c, user, roleCode)
 	if err != nil {
 		response.InternalServerError(c, "生成Token失败")
 		return
 	}
 
-	// 生成刷新 Token
-	refreshToken, err := ac.jwtAuth.GenerateRefreshToken(user.ID)
+	data := gin.H{
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"token_type":    tokens.TokenType,
+		"user":          user.ToResponse(),
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+	response.Success(c, data)
+}
+
+// recordLoginFailure 记录登录失败（密码或两步验证码错误），连续失败 5 次锁定 30 分钟
+func recordLoginFailure(db *gorm.DB, user *models.User) {
+	user.LoginFailCount++
+	if user.LoginFailCount >= 5 {
+		lockUntil := time.Now().Add(30 * time.Minute)
+		user.LockedUntil = &lockUntil
+		user.Status = models.UserStatusLocked
+	}
+	db.Save(user)
+}
+
+// startSession 创建登录会话并生成绑定会话的 Token
+func (ac *AuthController) startSession(c *gin.Context, user *models.User, role string) (*middleware.TokenResponse, error) {
+	session, refreshID, err := ac.sessionService.Create(user.ID, user.Username, c.Request.UserAgent(), c.ClientIP(), ac.jwtAuth.RefreshTime())
 	if err != nil {
-		response.InternalServerError(c, "生成刷新Token失败")
-		return
+		return nil, err
 	}
+	tokens, err := ac.jwtAuth.GenerateTokenResponse(user.ID, user.Username, role, session.SessionID, refreshID)
+	if err != nil {
+		return nil, err
+	}
+	setTokenCookie(c, tokens)
+	return tokens, nil
+}
 
-	response.Success(c, gin.H{
-		"access_token":  token,
-		"refresh_token": refreshToken,
-		"expires_in":    24 * 3600, // 24小时
-		"token_type":    "Bearer",
-		"user":          user.ToResponse(),
+// setTokenCookie 将访问 Token 写入 Cookie，浏览器直接加载资源库静态文件（img、iframe）时用于认证
+// SameSite=Strict 防止跨站请求携带
+func setTokenCookie(c *gin.Context, tokens *middleware.TokenResponse) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "token",
+		Value:    tokens.AccessToken,
+		Path:     "/",
+		MaxAge:   int(tokens.ExpiresIn),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
 	})
 }
 
-// Logout 登出
+// Logout 登出（撤销当前会话，已签发的 Token 立即失效）
 func (ac *AuthController) Logout(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	
+	// 撤销当前会话
+	if sessionID := middleware.GetSessionID(c); sessionID != "" {
+		if err := ac.sessionService.Revoke(sessionID, models.SessionRevokedLogout); err != nil {
+			response.InternalServerError(c, "登出失败")
+			return
+		}
+	}
+
 	// 清除权限缓存
 	middleware.InvalidatePermissionCache(userID)
 	
@@ -213,28 +304,71 @@ func (ac *AuthController) Logout(c *gin.Context) {
 }
 
 // RefreshToken 刷新Token
+// 刷新 Token 单次有效：每次刷新同时返回新的刷新 Token，已使用过的刷新 Token 再次出现时撤销整个会话
 func (ac *AuthController) RefreshToken(c *gin.Context) {
-	tokenString := ac.jwtAuth.ExtractToken(c)
-	if tokenString == "" {
-		response.Unauthorized(c, "缺少token")
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	c.ShouldBindJSON(&req)
+	if req.RefreshToken == "" {
+		response.BadRequest(c, "缺少刷新token")
 		return
 	}
 
-	newToken, err := ac.jwtAuth.RefreshToken(tokenString)
+	claims, err := ac.jwtAuth.ParseRefreshToken(req.RefreshToken)
 	if err != nil {
-		response.Unauthorized(c, "刷新token失败")
+		response.Unauthorized(c, "刷新token无效或已过期")
 		return
 	}
 
-	response.Success(c, gin.H{
-		"access_token": newToken,
-		"expires_in":   24 * 3600,
-		"token_type":   "Bearer",
-	})
+	session, refreshID, err := ac.sessionService.Rotate(claims.SessionID, claims.ID, c.ClientIP(), ac.jwtAuth.RefreshTime())
+	if err != nil {
+		switch err {
+		case auth.ErrSessionNotFound, auth.ErrSessionRevoked, auth.ErrSessionExpired, auth.ErrRefreshTokenReused:
+			response.Unauthorized(c, err.Error())
+		default:
+			response.InternalServerError(c, "刷新token失败")
+		}
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return
+	}
+
+	// 重新读取用户状态和角色，禁用或锁定的用户不能续期
+	var user models.User
+	if err := db.Preload("Roles").First(&user, session.UserID).Error; err != nil ||
+		user.Status == models.UserStatusDisabled || user.IsLocked() {
+		ac.sessionService.Revoke(session.SessionID, models.SessionRevokedUserDisabled)
+		response.Unauthorized(c, "账号不可用，请重新登录")
+		return
+	}
+
+	roleCode := models.RoleViewer
+	if len(user.Roles) > 0 {
+		roleCode = user.Roles[0].Code
+	}
+
+	tokens, err := ac.jwtAuth.GenerateTokenResponse(user.ID, user.Username, roleCode, session.SessionID, refreshID)
+	if err != nil {
+		response.InternalServerError(c, "生成Token失败")
+		return
+	}
+	setTokenCookie(c, tokens)
+
+	response.Success(c, tokens)
 }
 
 // ChangePassword 修改密码
 func (ac *AuthController) ChangePassword(c *gin.Context) {
+	if middleware.IsAPITokenRequest(c) {
+		response.Forbidden(c, "访问令牌不能修改密码，请登录后操作")
+		return
+	}
+
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, "请求参数错误")
@@ -274,6 +408,9 @@ func (ac *AuthController) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 撤销其他设备上的会话，保留当前会话
+	ac.sessionService.RevokeUser(userID, models.SessionRevokedPasswordChange, middleware.GetSessionID(c))
+
 	response.SuccessWithMsg(c, "密码修改成功", nil)
 }
 
@@ -323,10 +460,90 @@ func (ac *AuthController) CheckPermission(c *gin.Context) {
 	}
 
 	required := req.Resource + ":" + req.Action
-	hasPermission := middleware.MatchPermission(perms, required)
+	hasPermission := middleware.MatchPermission(perms, required) && middleware.TokenScopeAllows(c, required)
 
 	response.Success(c, gin.H{
 		"has_permission": hasPermission,
 		"permission":     required,
 	})
 }
+
+// ListSessions 获取登录会话
+// 默认返回当前用户的有效会话；拥有 users:admin 权限时可通过 user_id 查看指定用户，all=true 查看全部用户
+func (ac *AuthController) ListSessions(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	targetID := userID
+	if c.Query("all") == "true" {
+		targetID = 0
+	} else if id, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil && id > 0 {
+		targetID = uint(id)
+	}
+	if targetID != userID && !ac.isUserAdmin(c) {
+		response.Forbidden(c, "权限不足")
+		return
+	}
+
+	sessions, err := ac.sessionService.List(targetID, middleware.GetSessionID(c))
+	if err != nil {
+		response.InternalServerError(c, "获取会话失败")
+		return
+	}
+
+	response.Success(c, sessions)
+}
+
+// RevokeSession 撤销指定会话（自己的会话，或拥有 users:admin 权限时任意用户的会话）
+func (ac *AuthController) RevokeSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的会话ID")
+		return
+	}
+
+	session, err := ac.sessionService.Get(uint(id))
+	if err != nil {
+		response.NotFound(c, "会话不存在")
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	if session.UserID != userID && !ac.isUserAdmin(c) {
+		response.NotFound(c, "会话不存在")
+		return
+	}
+
+	if err := ac.sessionService.Revoke(session.SessionID, models.SessionRevokedManual); err != nil {
+		response.InternalServerError(c, "撤销会话失败")
+		return
+	}
+
+	response.SuccessWithMsg(c, "会话已撤销", nil)
+}
+
+// RevokeSessions 撤销当前用户的其他全部会话；拥有 users:admin 权限时可通过 user_id 撤销指定用户的全部会话
+func (ac *AuthController) RevokeSessions(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	targetID := userID
+	exceptSessionID := middleware.GetSessionID(c)
+	if id, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil && id > 0 && uint(id) != userID {
+		if !ac.isUserAdmin(c) {
+			response.Forbidden(c, "权限不足")
+			return
+		}
+		targetID = uint(id)
+		exceptSessionID = ""
+	}
+
+	count, err := ac.sessionService.RevokeUser(targetID, models.SessionRevokedManual, exceptSessionID)
+	if err != nil {
+		response.InternalServerError(c, "撤销会话失败")
+		return
+	}
+
+	response.SuccessWithMsg(c, "会话已撤销", gin.H{"revoked": count})
+}
+
+// isUserAdmin 当前请求是否拥有用户管理权限
+func (ac *AuthController) isUserAdmin(c *gin.Context) bool {
+	return middleware.HasPermission(c, models.ResourceUsers+":"+models.ActionAdmin)
+}
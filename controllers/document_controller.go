@@ -2,11 +2,22 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
 	"go_wails_project_manager/response"
 	"go_wails_project_manager/services/document"
 	"go_wails_project_manager/services/fileprocessor"
+	"go_wails_project_manager/services/similarity"
+	"go_wails_project_manager/services/upload"
+	"go_wails_project_manager/utils"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
 	"strings"
 
@@ -18,8 +29,16 @@ import (
 type DocumentController struct {
 	uploadService        *document.UploadService
 	queryService         *document.QueryService
+	trashService         *document.TrashService
+	searchService        *document.SearchService
+	batchService         *document.BatchService
+	archiveService       *document.ArchiveService
+	extractService       *document.ExtractService
+	accessService        *document.AccessService
 	config               *config.DocumentConfig
 	fileProcessorService *fileprocessor.FileProcessorService
+	similarityService    *similarity.Service
+	tusService           *upload.TusService
 }
 
 // NewDocumentController 创建文档控制器
@@ -40,11 +59,24 @@ func NewDocumentController(db *gorm.DB, fpService *fileprocessor.FileProcessorSe
 		uploadService.SetFileProcessorConfig(fpConfig)
 	}
 
+	searchService := document.NewSearchService(db, docConfig, logger.Log)
+	searchService.SetFileProcessorConfig(fpConfig)
+
+	trashService := document.NewTrashService(db, logger.Log, docConfig)
+
 	return &DocumentController{
 		uploadService:        uploadService,
 		queryService:         document.NewQueryService(db),
+		trashService:         trashService,
+		searchService:        searchService,
+		batchService:         document.NewBatchService(db, uploadService, trashService),
+		archiveService:       document.NewArchiveService(db, docConfig),
+		extractService:       document.NewExtractService(db, docConfig, uploadService),
+		accessService:        document.NewAccessService(db, docConfig, logger.Log),
 		config:               docConfig,
 		fileProcessorService: fpService,
+		similarityService:    similarity.NewService(db, logger.Log),
+		tusService:           upload.NewTusService(db, logger.Log),
 	}
 }
 
@@ -53,7 +85,8 @@ func NewDocumentController(db *gorm.DB, fpService *fileprocessor.FileProcessorSe
 // @Tags 文件库
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "文档文件"
+// @Param file formData file false "文档文件"
+// @Param file_upload_id formData string false "已完成的断点续传上传ID（替代 file）"
 // @Param name formData string true "文档名称"
 // @Param description formData string false "描述"
 // @Param category formData string false "分类"
@@ -62,13 +95,15 @@ func NewDocumentController(db *gorm.DB, fpService *fileprocessor.FileProcessorSe
 // @Param project formData string false "项目"
 // @Param is_public formData boolean false "是否公开"
 // @Param version formData string false "版本号"
+// @Param check_similar formData boolean false "是否检查近似重复（仅图片文件，返回 near_duplicates）"
+// @Param similar_threshold formData int false "近似重复阈值（汉明距离）" default(10)
 // @Success 200 {object} response.Response
 // @Router /api/documents/upload [post]
 func (c *DocumentController) Upload(ctx *gin.Context) {
 	// 获取上传文件
-	file, err := ctx.FormFile("file")
+	file, uploadID, err := formUpload(ctx, c.tusService, "file")
 	if err != nil {
-		response.Error(ctx, http.StatusBadRequest, "未找到上传文件")
+		response.Error(ctx, http.StatusBadRequest, uploadErrorMessage(err, "未找到上传文件"))
 		return
 	}
 
@@ -92,13 +127,18 @@ func (c *DocumentController) Upload(ctx *gin.Context) {
 			metadata.ParentID = &pid
 		}
 	}
+	viewer, ok := c.authorizeEdit(ctx, models.ActionUpload, parentIDs(metadata.ParentID)...)
+	if !ok {
+		return
+	}
 
 	// 解析标签
 	if tagsStr := ctx.PostForm("tags"); tagsStr != "" {
 		metadata.Tags = strings.Split(tagsStr, ",")
 	}
 
-	// 解析是否公开
+	// 解析是否公开（未指定时使用配置的默认值）
+	metadata.IsPublic = c.config.DefaultPublic
 	if isPublicStr := ctx.PostForm("is_public"); isPublicStr != "" {
 		metadata.IsPublic = isPublicStr == "true" || isPublicStr == "1"
 	}
@@ -115,6 +155,16 @@ func (c *DocumentController) Upload(ctx *gin.Context) {
 		response.Error(ctx, http.StatusInternalServerError, "上传失败: "+err.Error())
 		return
 	}
+	releaseUpload(ctx, c.tusService, uploadID)
+
+	// 按需返回近似重复提示（非图片文件的感知哈希在预览图生成后才可用）
+	if matches := nearDuplicates(ctx, c.similarityService, similarity.LibraryDocument, uploadedDoc.PerceptualHash, uploadedDoc.ID, c.visibleMatches(viewer)); matches != nil {
+		response.SuccessWithMsg(ctx, "上传成功", gin.H{
+			"document":        uploadedDoc,
+			"near_duplicates": matches,
+		})
+		return
+	}
 
 	response.SuccessWithMsg(ctx, "上传成功", uploadedDoc)
 }
@@ -169,6 +219,9 @@ func (c *DocumentController) UploadFolder(ctx *gin.Context) {
 			parentID = &p
 		}
 	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpload, parentIDs(parentID)...); !ok {
+		return
+	}
 
 	// 获取元数据
 	metadata := document.FolderUploadMetadata{
@@ -187,7 +240,7 @@ func (c *DocumentController) UploadFolder(ctx *gin.Context) {
 	}
 
 	// 上传文件夹
-	result, err := c.uploadService.UploadFolder(files, filePaths, metadata)
+	result, err := c.uploadService.UploadFolder(upload.FromFileHeaders(files), filePaths, metadata)
 	if err != nil {
 		response.Error(ctx, http.StatusInternalServerError, "上传失败: "+err.Error())
 		return
@@ -217,6 +270,9 @@ func (c *DocumentController) CreateFolder(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
 		return
 	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpload, parentIDs(req.ParentID)...); !ok {
+		return
+	}
 
 	// 创建文件夹记录
 	folder, err := c.uploadService.CreateFolder(req.Name, req.Description, req.ParentID, req.Department, req.Project, ctx.GetString("username"), ctx.ClientIP())
@@ -281,6 +337,13 @@ func (c *DocumentController) List(ctx *gin.Context) {
 		filters.IsPublic = &isPublic
 	}
 
+	// 只返回当前用户可见的文档
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+	filters.Viewer = viewer
+
 	// 查询
 	documents, total, err := c.queryService.List(page, pageSize, filters)
 	if err != nil {
@@ -304,6 +367,9 @@ func (c *DocumentController) GetDetail(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
 		return
 	}
+	if _, ok := c.authorize(ctx, uint(id)); !ok {
+		return
+	}
 
 	doc, metadata, err := c.queryService.GetDetail(uint(id))
 	if err != nil {
@@ -326,7 +392,30 @@ func (c *DocumentController) GetDetail(ctx *gin.Context) {
 	response.Success(ctx, result)
 }
 
-// Delete 删除文档
+// FindSimilar 查找相似文档
+// @Summary 查找相似文档
+// @Description 基于图片或预览图的感知哈希查找视觉相似的文档
+// @Tags 文件库
+// @Produce json
+// @Param id path int true "文档ID"
+// @Param threshold query int false "相似度阈值（汉明距离 0-32）" default(10)
+// @Success 200 {object} response.Response
+// @Router /api/documents/{id}/similar [get]
+func (c *DocumentController) FindSimilar(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+	viewer, ok := c.authorize(ctx, uint(id))
+	if !ok {
+		return
+	}
+
+	respondSimilar(ctx, c.similarityService, similarity.LibraryDocument, uint(id), c.visibleMatches(viewer))
+}
+
+// Delete 删除文档（移入回收站，文件夹连同所有子项）
 // @Summary 删除文档
 // @Tags 文件库
 // @Produce json
@@ -339,22 +428,404 @@ func (c *DocumentController) Delete(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
 		return
 	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionDelete, uint(id)); !ok {
+		return
+	}
 
-	if err := c.queryService.Delete(uint(id)); err != nil {
+	if err := c.trashService.MoveToTrash(uint(id), ctx.GetString("username")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(ctx, http.StatusNotFound, "文档不存在")
+			return
+		}
 		response.Error(ctx, http.StatusInternalServerError, "删除失败: "+err.Error())
 		return
 	}
 
-	response.SuccessWithMsg(ctx, "删除成功", nil)
+	response.SuccessWithMsg(ctx, "已移入回收站", nil)
+}
+
+// Search 全文检索文档内容
+// @Summary 全文检索文档内容
+// @Description 检索 PDF、Word、Excel、PPT、TXT、Markdown 文档正文，多个关键词以空格分隔（需同时命中），返回命中页码和高亮片段
+// @Tags 文件库
+// @Produce json
+// @Param q query string true "关键词"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(20)
+// @Success 200 {object} response.Response
+// @Router /api/documents/search [get]
+func (c *DocumentController) Search(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+
+	hits, total, err := c.searchService.Search(ctx.Query("q"), page, pageSize, viewer)
+	if err != nil {
+		if err == document.ErrEmptySearchQuery {
+			response.Error(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.Error(ctx, http.StatusInternalServerError, "搜索失败: "+err.Error())
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"items":    hits,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// BackfillSearchIndex 为已有文档补建全文索引
+// @Summary 补建全文索引
+// @Description 在后台为尚未建立全文索引的文档提取文本
+// @Tags 文件库
+// @Produce json
+// @Success 200 {object} response.Response
+// @Router /api/documents/search/backfill [post]
+func (c *DocumentController) BackfillSearchIndex(ctx *gin.Context) {
+	if c.searchService.IsBackfilling() {
+		response.Error(ctx, http.StatusConflict, document.ErrBackfillRunning.Error())
+		return
+	}
+
+	go func() {
+		if _, err := c.searchService.Backfill(); err != nil && err != document.ErrBackfillRunning {
+			logger.Log.Errorf("补建全文索引失败: %v", err)
+		}
+	}()
+
+	response.SuccessWithMsg(ctx, "已开始补建全文索引", nil)
+}
+
+// GetSearchIndexStats 全文索引状态统计
+// @Summary 全文索引状态统计
+// @Tags 文件库
+// @Produce json
+// @Success 200 {object} response.Response
+// @Router /api/documents/search/stats [get]
+func (c *DocumentController) GetSearchIndexStats(ctx *gin.Context) {
+	stats, err := c.searchService.IndexStats()
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"statuses":    stats,
+		"backfilling": c.searchService.IsBackfilling(),
+	})
+}
+
+// batchTargetRequest 移动/复制请求
+type batchTargetRequest struct {
+	IDs            []uint `json:"ids" binding:"required"`
+	TargetParentID *uint  `json:"target_parent_id"` // 为空表示根目录
+}
+
+// Move 批量移动文档和文件夹
+// @Summary 批量移动文档和文件夹
+// @Description 文件的历史版本随之移动；不能把文件夹移动到自身或其子文件夹中。逐项返回结果，单项失败不影响其他项
+// @Tags 文件库
+// @Accept json
+// @Produce json
+// @Param body body batchTargetRequest true "文档ID列表和目标文件夹"
+// @Success 200 {object} response.Response
+// @Router /api/documents/move [post]
+func (c *DocumentController) Move(ctx *gin.Context) {
+	var req batchTargetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpdate, req.IDs...); !ok {
+		return
+	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpload, parentIDs(req.TargetParentID)...); !ok {
+		return
+	}
+
+	result, err := c.batchService.Move(req.IDs, req.TargetParentID, ctx.GetString("username"), ctx.ClientIP())
+	if err != nil {
+		c.batchError(ctx, err, "移动失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, fmt.Sprintf("已移动 %d 项，失败 %d 项", result.Succeeded, result.Failed), result)
+}
+
+// Copy 批量复制文档和文件夹
+// @Summary 批量复制文档和文件夹
+// @Description 文件夹深度复制（含所有文件），仅复制文件的最新版本；与目标文件夹中已有项目重名时自动追加“(副本)”后缀
+// @Tags 文件库
+// @Accept json
+// @Produce json
+// @Param body body batchTargetRequest true "文档ID列表和目标文件夹"
+// @Success 200 {object} response.Response
+// @Router /api/documents/copy [post]
+func (c *DocumentController) Copy(ctx *gin.Context) {
+	var req batchTargetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if _, ok := c.authorize(ctx, req.IDs...); !ok {
+		return
+	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpload, parentIDs(req.TargetParentID)...); !ok {
+		return
+	}
+
+	result, err := c.batchService.Copy(req.IDs, req.TargetParentID, ctx.GetString("username"), ctx.ClientIP())
+	if err != nil {
+		c.batchError(ctx, err, "复制失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, fmt.Sprintf("已复制 %d 项，失败 %d 项", result.Succeeded, result.Failed), result)
+}
+
+// bulkRequest 批量操作请求
+type bulkRequest struct {
+	IDs        []uint   `json:"ids" binding:"required"`
+	Action     string   `json:"action" binding:"required"` // delete, tag, untag, update
+	Tags       []string `json:"tags"`                      // tag/untag 使用
+	Department *string  `json:"department"`                // update 使用，不传表示不修改
+	Project    *string  `json:"project"`
+	IsPublic   *bool    `json:"is_public"`
+}
+
+// Bulk 批量操作文档
+// @Summary 批量操作文档
+// @Description action: delete（移入回收站）、tag（追加标签）、untag（移除标签）、update（设置 department/project/is_public）
+// @Tags 文件库
+// @Accept json
+// @Produce json
+// @Param body body bulkRequest true "批量操作参数"
+// @Success 200 {object} response.Response
+// @Router /api/documents/bulk [post]
+func (c *DocumentController) Bulk(ctx *gin.Context) {
+	var req bulkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	action := models.ActionUpdate
+	if req.Action == "delete" {
+		action = models.ActionDelete
+	}
+	if _, ok := c.authorizeEdit(ctx, action, req.IDs...); !ok {
+		return
+	}
+
+	result, err := c.batchService.Bulk(req.IDs, document.BulkRequest{
+		Action:     req.Action,
+		Tags:       req.Tags,
+		Department: req.Department,
+		Project:    req.Project,
+		IsPublic:   req.IsPublic,
+		Operator:   ctx.GetString("username"),
+		OperatorIP: ctx.ClientIP(),
+	})
+	if err != nil {
+		c.batchError(ctx, err, "批量操作失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, fmt.Sprintf("成功 %d 项，失败 %d 项", result.Succeeded, result.Failed), result)
+}
+
+// batchError 批量操作错误响应
+func (c *DocumentController) batchError(ctx *gin.Context, err error, msg string) {
+	switch err {
+	case document.ErrEmptyBatch, document.ErrTooManyBatchItems, document.ErrInvalidBulkAction, document.ErrEmptyBulkUpdate:
+		response.Error(ctx, http.StatusBadRequest, err.Error())
+	case document.ErrInvalidTargetFolder:
+		response.Error(ctx, http.StatusNotFound, err.Error())
+	default:
+		response.Error(ctx, http.StatusInternalServerError, msg+": "+err.Error())
+	}
+}
+
+// ListTrash 回收站列表
+// @Summary 回收站列表
+// @Tags 文件库
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(20)
+// @Param keyword query string false "关键词"
+// @Success 200 {object} response.Response
+// @Router /api/documents/trash [get]
+func (c *DocumentController) ListTrash(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+
+	items, total, err := c.trashService.List(page, pageSize, ctx.Query("keyword"), viewer)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"items":          items,
+		"total":          total,
+		"page":           page,
+		"pageSize":       pageSize,
+		"retention_days": c.config.TrashRetentionDays,
+	})
+}
+
+// RestoreTrash 还原回收站项目
+// @Summary 还原回收站项目
+// @Description 还原到原文件夹；原文件夹已删除时还原到 target_parent_id 指定的文件夹或根目录，重名时自动重命名
+// @Tags 文件库
+// @Accept json
+// @Produce json
+// @Param id path int true "文档ID"
+// @Param body body object false "{ target_parent_id: 目标文件夹ID，0 表示根目录 }"
+// @Success 200 {object} response.Response
+// @Router /api/documents/trash/{id}/restore [post]
+func (c *DocumentController) RestoreTrash(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionDelete, uint(id)); !ok {
+		return
+	}
+
+	var req struct {
+		TargetParentID *uint `json:"target_parent_id"`
+	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+			return
+		}
+	}
+	if req.TargetParentID != nil && *req.TargetParentID > 0 {
+		if _, ok := c.authorizeEdit(ctx, models.ActionUpload, *req.TargetParentID); !ok {
+			return
+		}
+	}
+
+	result, err := c.trashService.Restore(uint(id), req.TargetParentID)
+	if err != nil {
+		c.trashError(ctx, err, "还原失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "还原成功", result)
+}
+
+// PurgeTrash 彻底删除回收站项目
+// @Summary 彻底删除回收站项目
+// @Tags 文件库
+// @Produce json
+// @Param id path int true "文档ID"
+// @Success 200 {object} response.Response
+// @Router /api/documents/trash/{id} [delete]
+func (c *DocumentController) PurgeTrash(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionDelete, uint(id)); !ok {
+		return
+	}
+
+	if err := c.trashService.Purge(uint(id)); err != nil {
+		c.trashError(ctx, err, "删除失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "已彻底删除", nil)
+}
+
+// EmptyTrash 清空回收站
+// @Summary 清空回收站
+// @Description 需要文档管理权限（documents:admin）
+// @Tags 文件库
+// @Produce json
+// @Success 200 {object} response.Response
+// @Router /api/documents/trash [delete]
+func (c *DocumentController) EmptyTrash(ctx *gin.Context) {
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+	if !viewer.Admin {
+		response.Error(ctx, http.StatusForbidden, "清空回收站需要文档管理权限")
+		return
+	}
+
+	count, err := c.trashService.Empty()
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "清空回收站失败: "+err.Error())
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "回收站已清空", gin.H{"purged": count})
+}
+
+// trashError 回收站操作错误响应
+func (c *DocumentController) trashError(ctx *gin.Context, err error, msg string) {
+	switch err {
+	case document.ErrTrashItemNotFound:
+		response.Error(ctx, http.StatusNotFound, err.Error())
+	case document.ErrNotTrashRoot, document.ErrInvalidRestoreTarget:
+		response.Error(ctx, http.StatusBadRequest, err.Error())
+	default:
+		response.Error(ctx, http.StatusInternalServerError, msg+": "+err.Error())
+	}
+}
+
+// updateDocumentRequest 更新文档信息请求，只允许修改以下字段，不传表示不修改
+type updateDocumentRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Category    *string `json:"category"`
+	Tags        *string `json:"tags"` // 逗号分隔
+	Department  *string `json:"department"`
+	Project     *string `json:"project"`
+	IsPublic    *bool   `json:"is_public"`
 }
 
 // Update 更新文档信息
 // @Summary 更新文档信息
+// @Description 可修改 name、description、category、tags、department、project、is_public，需要是上传者、同部门或被授予 update 权限
 // @Tags 文件库
 // @Accept json
 // @Produce json
 // @Param id path int true "文档ID"
-// @Param body body object true "更新内容"
+// @Param body body updateDocumentRequest true "更新内容"
 // @Success 200 {object} response.Response
 // @Router /api/documents/{id} [put]
 func (c *DocumentController) Update(ctx *gin.Context) {
@@ -363,14 +834,34 @@ func (c *DocumentController) Update(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
 		return
 	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpdate, uint(id)); !ok {
+		return
+	}
 
-	var updates map[string]interface{}
-	if err := ctx.ShouldBindJSON(&updates); err != nil {
+	var req updateDocumentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		response.Error(ctx, http.StatusBadRequest, "请求参数错误")
 		return
 	}
 
-	if err := c.queryService.Update(uint(id), updates); err != nil {
+	err = c.queryService.Update(uint(id), document.UpdateInput{
+		Name:        req.Name,
+		Description: req.Description,
+		Category:    req.Category,
+		Tags:        req.Tags,
+		Department:  req.Department,
+		Project:     req.Project,
+		IsPublic:    req.IsPublic,
+	})
+	switch err {
+	case nil:
+	case document.ErrEmptyDocumentUpdate, document.ErrEmptyDocumentName:
+		response.Error(ctx, http.StatusBadRequest, err.Error())
+		return
+	case gorm.ErrRecordNotFound:
+		response.Error(ctx, http.StatusNotFound, "文档不存在")
+		return
+	default:
 		response.Error(ctx, http.StatusInternalServerError, "更新失败: "+err.Error())
 		return
 	}
@@ -391,6 +882,9 @@ func (c *DocumentController) Download(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
 		return
 	}
+	if _, ok := c.authorize(ctx, uint(id)); !ok {
+		return
+	}
 
 	doc, _, err := c.queryService.GetDetail(uint(id))
 	if err != nil {
@@ -405,6 +899,255 @@ func (c *DocumentController) Download(ctx *gin.Context) {
 	ctx.FileAttachment(doc.FilePath, doc.Name)
 }
 
+// DownloadArchive 将文件夹打包为 zip 下载
+// @Summary 打包下载文件夹
+// @Description 流式生成 zip（保持目录结构，不生成临时文件），受打包大小和文件数量限制
+// @Tags 文件库
+// @Produce application/zip
+// @Param id path int true "文件夹ID"
+// @Success 200 {file} binary
+// @Router /api/documents/{id}/archive [get]
+func (c *DocumentController) DownloadArchive(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+	if _, ok := c.authorize(ctx, uint(id)); !ok {
+		return
+	}
+
+	c.streamArchive(ctx, []uint{uint(id)})
+}
+
+// DownloadSelection 将选中的文件和文件夹打包为 zip 下载
+// @Summary 打包下载多选文档
+// @Description 流式生成 zip（文件夹保持目录结构，不生成临时文件），受打包大小和文件数量限制
+// @Tags 文件库
+// @Accept json
+// @Produce application/zip
+// @Param body body object true "{ ids: 文档ID列表 }"
+// @Success 200 {file} binary
+// @Router /api/documents/archive [post]
+func (c *DocumentController) DownloadSelection(ctx *gin.Context) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if _, ok := c.authorize(ctx, req.IDs...); !ok {
+		return
+	}
+
+	c.streamArchive(ctx, req.IDs)
+}
+
+// streamArchive 生成打包计划并流式输出 zip
+func (c *DocumentController) streamArchive(ctx *gin.Context, ids []uint) {
+	plan, err := c.archiveService.Plan(ids)
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			response.Error(ctx, http.StatusNotFound, "文档不存在")
+		case document.ErrArchiveTooLarge:
+			response.Error(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("%s（最大 %.2f GB）",
+				err.Error(), float64(c.config.ArchiveMaxSize)/1024/1024/1024))
+		case document.ErrArchiveTooManyFiles:
+			response.Error(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("%s（最多 %d 个）", err.Error(), c.config.ArchiveMaxFiles))
+		case document.ErrEmptyBatch, document.ErrTooManyBatchItems, document.ErrArchiveEmpty, document.ErrArchiveHistoryVersion:
+			response.Error(ctx, http.StatusBadRequest, err.Error())
+		default:
+			response.Error(ctx, http.StatusInternalServerError, "打包失败: "+err.Error())
+		}
+		return
+	}
+
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`,
+		strings.ReplaceAll(plan.Name, `"`, "_"), url.PathEscape(plan.Name)))
+	ctx.Status(http.StatusOK)
+
+	result, err := c.archiveService.WriteZip(ctx.Writer, plan)
+	if err != nil {
+		// 响应已开始输出，只能记录日志
+		logger.Log.Errorf("写入打包文件失败: %v", err)
+	}
+	c.archiveService.RecordDownload(plan, result, ctx.GetString("username"), ctx.ClientIP())
+}
+
+// ListEntries 列出压缩包的目录树
+// @Summary 浏览压缩包
+// @Description 返回压缩包内的目录树（名称、大小、修改时间），目前只支持 zip
+// @Tags 文件库
+// @Produce json
+// @Param id path int true "压缩包文档ID"
+// @Success 200 {object} response.Response
+// @Router /api/documents/{id}/entries [get]
+func (c *DocumentController) ListEntries(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+	if _, ok := c.authorize(ctx, uint(id)); !ok {
+		return
+	}
+
+	listing, err := c.extractService.ListEntries(uint(id))
+	if err != nil {
+		c.extractError(ctx, err, "读取压缩包失败")
+		return
+	}
+
+	response.Success(ctx, listing)
+}
+
+// DownloadEntry 下载压缩包中的单个文件
+// @Summary 下载压缩包内文件
+// @Description 流式输出压缩包中的单个文件，不解压整个压缩包；inline=true 时图片、PDF 和纯文本在浏览器内直接打开，其他类型仍然下载
+// @Tags 文件库
+// @Produce application/octet-stream
+// @Param id path int true "压缩包文档ID"
+// @Param path path string true "压缩包内路径"
+// @Param inline query boolean false "是否内嵌显示"
+// @Success 200 {file} binary
+// @Router /api/documents/{id}/entries/{path} [get]
+func (c *DocumentController) DownloadEntry(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+	if _, ok := c.authorize(ctx, uint(id)); !ok {
+		return
+	}
+
+	reader, entry, err := c.extractService.OpenEntry(uint(id), ctx.Param("path"))
+	if err != nil {
+		c.extractError(ctx, err, "读取压缩包失败")
+		return
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(entry.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if ctx.Query("inline") == "true" && inlineSafe(contentType) {
+		disposition = "inline"
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`,
+		disposition, strings.ReplaceAll(entry.Name, `"`, "_"), url.PathEscape(entry.Name)))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.DataFromReader(http.StatusOK, entry.Size, contentType, reader, nil)
+
+	c.extractService.RecordEntryDownload(uint(id), ctx.GetString("username"), ctx.ClientIP())
+}
+
+// inlineContentTypes 可以在浏览器内直接打开的类型
+// 压缩包内容不受上传检查，HTML、SVG 等可执行脚本的类型内嵌打开会在本站域名下运行（存储型 XSS），一律作为附件下载
+var inlineContentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/bmp":       true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// inlineSafe 检查 MIME 类型（忽略 charset 等参数）能否内嵌打开
+func inlineSafe(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && inlineContentTypes[mediaType]
+}
+
+// Extract 将压缩包解压为文件库中的新文件夹
+// @Summary 解压压缩包到文件库
+// @Description 解压为新文件夹（保持目录结构），检查非法路径（zip-slip）和解压大小、文件数量、压缩比限制（防止压缩炸弹）
+// @Tags 文件库
+// @Accept json
+// @Produce json
+// @Param id path int true "压缩包文档ID"
+// @Param body body object false "{ parent_id: 目标文件夹ID（默认压缩包所在文件夹，0 表示根目录）, name: 新文件夹名称 }"
+// @Success 200 {object} response.Response
+// @Router /api/documents/{id}/extract [post]
+func (c *DocumentController) Extract(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+
+	var req struct {
+		ParentID *uint  `json:"parent_id"`
+		Name     string `json:"name"`
+	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+			return
+		}
+	}
+
+	if _, ok := c.authorize(ctx, uint(id)); !ok {
+		return
+	}
+
+	parentID := req.ParentID
+	if parentID == nil {
+		archive, _, err := c.queryService.GetDetail(uint(id))
+		if err != nil {
+			response.Error(ctx, http.StatusNotFound, "文档不存在")
+			return
+		}
+		parentID = archive.ParentID
+	} else if *parentID == 0 {
+		parentID = nil
+	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpload, parentIDs(parentID)...); !ok {
+		return
+	}
+
+	result, err := c.extractService.Extract(uint(id), document.ExtractOptions{
+		ParentID:   parentID,
+		Name:       req.Name,
+		UploadedBy: ctx.GetString("username"),
+		UploadIP:   ctx.ClientIP(),
+	})
+	if err != nil {
+		c.extractError(ctx, err, "解压失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "解压完成", result)
+}
+
+// extractError 压缩包浏览和解压的错误响应
+func (c *DocumentController) extractError(ctx *gin.Context, err error, prefix string) {
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		response.Error(ctx, http.StatusNotFound, "文档不存在")
+	case err == document.ErrArchiveFileMissing, err == document.ErrArchiveEntryNotFound:
+		response.Error(ctx, http.StatusNotFound, err.Error())
+	case err == document.ErrNotArchive, err == document.ErrArchiveUnsupported, err == document.ErrArchiveCorrupt,
+		err == document.ErrArchiveNoFiles, err == document.ErrInvalidTargetFolder,
+		errors.Is(err, utils.ErrUnsafeArchivePath), errors.Is(err, utils.ErrArchiveCompressionRatio):
+		response.Error(ctx, http.StatusBadRequest, err.Error())
+	case err == utils.ErrArchiveTooManyEntries:
+		response.Error(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("%s（最多 %d 个）", err.Error(), c.config.ExtractMaxFiles))
+	case err == utils.ErrArchiveUncompressedSize:
+		response.Error(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("%s（最大 %.2f GB）",
+			err.Error(), float64(c.config.ExtractMaxSize)/1024/1024/1024))
+	default:
+		response.Error(ctx, http.StatusInternalServerError, prefix+": "+err.Error())
+	}
+}
+
 // GetStatistics 获取统计信息
 // @Summary 获取统计信息
 // @Tags 文件库
@@ -421,6 +1164,12 @@ func (c *DocumentController) GetStatistics(ctx *gin.Context) {
 		Project:    ctx.Query("project"),
 	}
 
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+	filters.Viewer = viewer
+
 	stats, err := c.queryService.GetStatistics(filters)
 	if err != nil {
 		response.Error(ctx, http.StatusInternalServerError, "查询失败: "+err.Error())
@@ -447,6 +1196,12 @@ func (c *DocumentController) GetPopular(ctx *gin.Context) {
 		Project:    ctx.Query("project"),
 	}
 
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return
+	}
+	filters.Viewer = viewer
+
 	documents, err := c.queryService.GetPopular(limit, filters)
 	if err != nil {
 		response.Error(ctx, http.StatusInternalServerError, "查询失败: "+err.Error())
@@ -472,6 +1227,9 @@ func (c *DocumentController) GetAccessLogs(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
 		return
 	}
+	if _, ok := c.authorize(ctx, uint(id)); !ok {
+		return
+	}
 
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
@@ -500,6 +1258,9 @@ func (c *DocumentController) RefreshThumbnail(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
 		return
 	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpdate, uint(id)); !ok {
+		return
+	}
 
 	// 调用 uploadService 的 RegenerateThumbnail 方法
 	if err := c.uploadService.RegenerateThumbnail(uint(id)); err != nil {
@@ -523,6 +1284,9 @@ func (c *DocumentController) GetVersions(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
 		return
 	}
+	if _, ok := c.authorize(ctx, uint(id)); !ok {
+		return
+	}
 
 	versions, err := c.queryService.GetVersions(uint(id))
 	if err != nil {
@@ -533,6 +1297,98 @@ func (c *DocumentController) GetVersions(ctx *gin.Context) {
 	response.Success(ctx, versions)
 }
 
+// UploadVersion 上传新版本
+// @Summary 上传文档新版本
+// @Description 基于当前最新版本创建新版本，旧版本转为历史版本并重新生成预览图
+// @Tags 文件库
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "文档ID（任意版本）"
+// @Param file formData file false "新版本文件"
+// @Param file_upload_id formData string false "已完成的断点续传上传ID（替代 file）"
+// @Param version formData string false "版本号（为空时自动递增）"
+// @Success 200 {object} response.Response
+// @Router /api/documents/{id}/versions [post]
+func (c *DocumentController) UploadVersion(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpdate, uint(id)); !ok {
+		return
+	}
+
+	file, uploadID, err := formUpload(ctx, c.tusService, "file")
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, uploadErrorMessage(err, "未找到上传文件"))
+		return
+	}
+
+	version, err := c.uploadService.UploadVersion(uint(id), file, document.VersionMetadata{
+		Version:    ctx.PostForm("version"),
+		UploadedBy: ctx.GetString("username"),
+		UploadIP:   ctx.ClientIP(),
+	})
+	if err != nil {
+		c.versionError(ctx, err, "上传失败")
+		return
+	}
+	releaseUpload(ctx, c.tusService, uploadID)
+
+	response.SuccessWithMsg(ctx, "新版本上传成功", version)
+}
+
+// RestoreVersion 恢复历史版本
+// @Summary 恢复历史版本
+// @Description 复制历史版本的文件创建一个新的最新版本，保留完整版本历史
+// @Tags 文件库
+// @Produce json
+// @Param id path int true "文档ID（任意版本）"
+// @Param versionId path int true "要恢复的历史版本ID"
+// @Success 200 {object} response.Response
+// @Router /api/documents/{id}/versions/{versionId}/restore [post]
+func (c *DocumentController) RestoreVersion(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的文档ID")
+		return
+	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpdate, uint(id)); !ok {
+		return
+	}
+	versionID, err := strconv.ParseUint(ctx.Param("versionId"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的版本ID")
+		return
+	}
+
+	version, err := c.uploadService.RestoreVersion(uint(id), uint(versionID), ctx.GetString("username"), ctx.ClientIP())
+	if err != nil {
+		c.versionError(ctx, err, "恢复失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "版本恢复成功", version)
+}
+
+// versionError 版本管理错误响应
+func (c *DocumentController) versionError(ctx *gin.Context, err error, msg string) {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		response.Error(ctx, http.StatusNotFound, "文档不存在")
+	case document.ErrVersionControlDisabled:
+		response.Error(ctx, http.StatusForbidden, err.Error())
+	case document.ErrVersionConflict:
+		response.Error(ctx, http.StatusConflict, err.Error())
+	case document.ErrFolderHasNoVersion, document.ErrSameAsLatestVersion,
+		document.ErrVersionNotInHistory, document.ErrAlreadyLatestVersion:
+		response.Error(ctx, http.StatusBadRequest, err.Error())
+	default:
+		response.Error(ctx, http.StatusInternalServerError, msg+": "+err.Error())
+	}
+}
+
 // RefreshFolderStats 刷新文件夹统计信息
 // @Summary 刷新文件夹统计信息
 // @Tags 文件库
@@ -548,6 +1404,9 @@ func (c *DocumentController) RefreshFolderStats(ctx *gin.Context) {
 		response.Error(ctx, http.StatusBadRequest, "无效的文件夹ID")
 		return
 	}
+	if _, ok := c.authorizeEdit(ctx, models.ActionUpdate, uint(id)); !ok {
+		return
+	}
 
 	// 调用 queryService 的刷新统计方法
 	if err := c.queryService.RefreshFolderStats(uint(id)); err != nil {
@@ -558,32 +1417,108 @@ func (c *DocumentController) RefreshFolderStats(ctx *gin.Context) {
 	response.SuccessWithMsg(ctx, "文件夹统计已刷新", nil)
 }
 
-// RegisterDocumentRoutes 注册文档路由
-func RegisterDocumentRoutes(router *gin.Engine, db *gorm.DB, fpService *fileprocessor.FileProcessorService, fpConfig *fileprocessor.Config) {
-	controller := NewDocumentController(db, fpService, fpConfig)
+// viewer 加载当前用户的文件库可见范围（未登录时只能看到公开文档）
+func (c *DocumentController) viewer(ctx *gin.Context) (*document.Viewer, bool) {
+	return loadDocumentViewer(ctx, c.accessService)
+}
+
+// loadDocumentViewer 加载当前用户的文件库可见范围，失败时写入错误响应
+func loadDocumentViewer(ctx *gin.Context, accessService *document.AccessService) (*document.Viewer, bool) {
+	viewer, err := documentViewer(ctx, accessService)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "加载用户权限失败: "+err.Error())
+		return nil, false
+	}
+	return viewer, true
+}
+
+// documentViewer 按当前用户的权限（访问令牌的权限范围同样生效）加载文件库可见范围，REST 和 WebDAV 共用
+func documentViewer(ctx *gin.Context, accessService *document.AccessService) (*document.Viewer, error) {
+	userID := middleware.GetUserID(ctx)
+	admin := middleware.HasPermission(ctx, models.ResourceDocuments+":"+models.ActionAdmin)
+
+	viewer, err := accessService.LoadViewer(userID, middleware.GetUsername(ctx), admin)
+	if err != nil {
+		return nil, err
+	}
+	// 没有文件库查看权限、仅凭单独授权访问的用户
+	viewer.GrantsOnly = userID > 0 && !admin && !middleware.HasPermission(ctx, models.ResourceDocuments+":"+models.ActionRead)
+	return viewer, nil
+}
 
-	api := router.Group("/api/documents")
-	{
-		// 基础操作
-		api.POST("/upload", controller.Upload)
-		api.POST("/upload-folder", controller.UploadFolder)
-		api.POST("/folder", controller.CreateFolder) // 创建文件夹
-		api.GET("", controller.List)
-		api.GET("/:id", controller.GetDetail)
-		api.PUT("/:id", controller.Update)
-		api.DELETE("/:id", controller.Delete)
+// authorize 检查当前用户能否访问指定的文档（包括回收站中的文档），失败时写入错误响应
+func (c *DocumentController) authorize(ctx *gin.Context, ids ...uint) (*document.Viewer, bool) {
+	viewer, ok := c.viewer(ctx)
+	if !ok {
+		return nil, false
+	}
+	if len(ids) == 0 || viewer.Admin {
+		return viewer, true
+	}
 
-		// 文件操作
-		api.GET("/:id/download", controller.Download)
-		api.POST("/:id/refresh-thumbnail", controller.RefreshThumbnail) // 刷新缩略图
-		api.POST("/:id/refresh-stats", controller.RefreshFolderStats)   // 刷新文件夹统计
+	switch err := c.accessService.CanViewAll(viewer, ids); err {
+	case nil:
+		return viewer, true
+	case gorm.ErrRecordNotFound:
+		response.Error(ctx, http.StatusNotFound, "文档不存在")
+	case document.ErrDocumentForbidden:
+		response.Error(ctx, http.StatusForbidden, err.Error())
+	default:
+		response.Error(ctx, http.StatusInternalServerError, "权限检查失败: "+err.Error())
+	}
+	return nil, false
+}
 
-		// 版本管理
-		api.GET("/:id/versions", controller.GetVersions)
+// authorizeEdit 检查当前用户能否对指定文档执行修改类操作：先检查可见性，再检查是否为上传者、
+// 同部门或单独授予了该操作（documents:admin 不受限制），失败时写入错误响应
+func (c *DocumentController) authorizeEdit(ctx *gin.Context, action string, ids ...uint) (*document.Viewer, bool) {
+	viewer, ok := c.authorize(ctx, ids...)
+	if !ok || len(ids) == 0 || viewer.Admin {
+		return viewer, ok
+	}
+
+	switch err := c.accessService.CanEditAll(viewer, ids, action); err {
+	case nil:
+		return viewer, true
+	case document.ErrDocumentReadOnly:
+		response.Error(ctx, http.StatusForbidden, err.Error())
+	default:
+		response.Error(ctx, http.StatusInternalServerError, "权限检查失败: "+err.Error())
+	}
+	return nil, false
+}
+
+// visibleMatches 去掉当前用户不可见的相似文档
+func (c *DocumentController) visibleMatches(viewer *document.Viewer) matchFilter {
+	return func(matches []similarity.Match) []similarity.Match {
+		if viewer.Admin || len(matches) == 0 {
+			return matches
+		}
+
+		ids := make([]uint, len(matches))
+		for i, match := range matches {
+			ids[i] = match.ID
+		}
+		visible, err := c.accessService.VisibleIDs(viewer, ids)
+		if err != nil {
+			logger.Log.Warnf("过滤相似文档失败: %v", err)
+			return []similarity.Match{}
+		}
+
+		result := make([]similarity.Match, 0, len(matches))
+		for _, match := range matches {
+			if visible[match.ID] {
+				result = append(result, match)
+			}
+		}
+		return result
+	}
+}
 
-		// 统计和日志
-		api.GET("/statistics", controller.GetStatistics)
-		api.GET("/popular", controller.GetPopular)
-		api.GET("/:id/logs", controller.GetAccessLogs)
+// parentIDs 非根目录的父文件夹ID（nil 或 0 表示根目录，无需检查）
+func parentIDs(parentID *uint) []uint {
+	if parentID == nil || *parentID == 0 {
+		return nil
 	}
+	return []uint{*parentID}
 }
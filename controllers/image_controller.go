@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go_wails_project_manager/response"
+	"go_wails_project_manager/utils"
 )
 
 // ImageController 图片处理控制器
@@ -25,12 +26,14 @@ func NewImageController() *ImageController {
 // @Accept multipart/form-data
 // @Produce json
 // @Param flip query bool false "是否翻转Y轴" default(false)
+// @Param invertGreen query bool false "是否反转绿色通道（法线贴图 DirectX/OpenGL 互转）" default(false)
 // @Param files formData file true "图片文件（可多个）"
 // @Success 200 {object} response.Response
 // @Router /api/image/flipy-webp [post]
 func (c *ImageController) FlipYAndToWebp(ctx *gin.Context) {
 	// 获取翻转参数
 	flip := ctx.DefaultQuery("flip", "false") == "true"
+	invertGreen := ctx.DefaultQuery("invertGreen", "false") == "true"
 
 	// 获取上传的文件
 	form, err := ctx.MultipartForm()
@@ -79,6 +82,11 @@ func (c *ImageController) FlipYAndToWebp(ctx *gin.Context) {
 			img = flipImageY(img)
 		}
 
+		// 如果需要反转绿色通道
+		if invertGreen {
+			img = utils.InvertGreenChannel(img)
+		}
+
 		// 转换为PNG（暂时不支持WebP，因为需要CGO）
 		var buf bytes.Buffer
 		if err := png.Encode(&buf, img); err != nil {
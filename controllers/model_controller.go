@@ -8,34 +8,42 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
 	"go_wails_project_manager/response"
 	modelService "go_wails_project_manager/services/model"
+	"go_wails_project_manager/services/similarity"
+	"go_wails_project_manager/services/upload"
 )
 
 type ModelController struct {
-	uploadService *modelService.UploadService
-	queryService  *modelService.QueryService
+	uploadService     *modelService.UploadService
+	queryService      *modelService.QueryService
+	similarityService *similarity.Service
+	tusService        *upload.TusService
 }
 
 func NewModelController(db *gorm.DB) *ModelController {
 	return &ModelController{
-		uploadService: modelService.NewUploadService(db, &config.AppConfig.Model),
-		queryService:  modelService.NewQueryService(db),
+		uploadService:     modelService.NewUploadService(db, &config.AppConfig.Model),
+		queryService:      modelService.NewQueryService(db),
+		similarityService: similarity.NewService(db, logger.Log),
+		tusService:        upload.NewTusService(db, logger.Log),
 	}
 }
 
 // Upload 上传模型
 func (c *ModelController) Upload(ctx *gin.Context) {
 	// 获取文件
-	modelFile, err := ctx.FormFile("model")
+	modelFile, modelUploadID, err := formUpload(ctx, c.tusService, "model")
 	if err != nil {
-		response.Error(ctx, http.StatusBadRequest, "缺少模型文件")
+		response.Error(ctx, http.StatusBadRequest, uploadErrorMessage(err, "缺少模型文件"))
 		return
 	}
 
-	thumbnailFile, err := ctx.FormFile("thumbnail")
+	thumbnailFile, thumbnailUploadID, err := formUpload(ctx, c.tusService, "thumbnail")
 	if err != nil {
-		response.Error(ctx, http.StatusBadRequest, "缺少预览图")
+		response.Error(ctx, http.StatusBadRequest, uploadErrorMessage(err, "缺少预览图"))
 		return
 	}
 
@@ -86,6 +94,7 @@ func (c *ModelController) Upload(ctx *gin.Context) {
 	model, err := c.uploadService.UploadSingle(modelFile, thumbnailFile, metadata)
 	if err != nil {
 		if err == modelService.ErrDuplicateFile {
+			releaseUpload(ctx, c.tusService, modelUploadID, thumbnailUploadID)
 			response.Success(ctx, gin.H{
 				"message": "文件已存在",
 				"model":   model,
@@ -103,10 +112,35 @@ func (c *ModelController) Upload(ctx *gin.Context) {
 		response.Error(ctx, http.StatusInternalServerError, "上传失败")
 		return
 	}
+	releaseUpload(ctx, c.tusService, modelUploadID, thumbnailUploadID)
+
+	// 按需返回近似重复提示（基于预览图）
+	if matches := nearDuplicates(ctx, c.similarityService, similarity.LibraryModel, model.PerceptualHash, model.ID, nil); matches != nil {
+		response.Success(ctx, gin.H{
+			"model":           model,
+			"near_duplicates": matches,
+		})
+		return
+	}
 
 	response.Success(ctx, model)
 }
 
+// FindSimilar 查找预览图相似的模型
+func (c *ModelController) FindSimilar(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的ID")
+		return
+	}
+
+	ids, ok := visibleResourceIDs(ctx, models.ResourceModels)
+	if !ok {
+		return
+	}
+	respondSimilar(ctx, c.similarityService, similarity.LibraryModel, uint(id), grantedMatches(ids))
+}
+
 // List 模型列表
 func (c *ModelController) List(ctx *gin.Context) {
 	// 分页参数
@@ -128,6 +162,13 @@ func (c *ModelController) List(ctx *gin.Context) {
 		SortOrder: ctx.DefaultQuery("sortOrder", "desc"),
 	}
 
+	// 没有模型库查看权限的用户只能看到被单独授权的模型
+	ids, ok := visibleResourceIDs(ctx, models.ResourceModels)
+	if !ok {
+		return
+	}
+	filters.IDs = ids
+
 	// 标签过滤
 	if tagsStr := ctx.Query("tags"); tagsStr != "" {
 		filters.Tags = strings.Split(tagsStr, ",")
@@ -193,7 +234,12 @@ func (c *ModelController) Search(ctx *gin.Context) {
 		pageSize = 20
 	}
 
-	models, total, err := c.queryService.Search(keyword, page, pageSize)
+	ids, ok := visibleResourceIDs(ctx, models.ResourceModels)
+	if !ok {
+		return
+	}
+
+	models, total, err := c.queryService.Search(keyword, page, pageSize, ids)
 	if err != nil {
 		response.Error(ctx, http.StatusInternalServerError, "搜索失败")
 		return
@@ -269,7 +315,12 @@ func (c *ModelController) GetPopular(ctx *gin.Context) {
 		limit = 10
 	}
 
-	models, err := c.queryService.GetPopular(limit)
+	ids, ok := visibleResourceIDs(ctx, models.ResourceModels)
+	if !ok {
+		return
+	}
+
+	models, err := c.queryService.GetPopular(limit, ids)
 	if err != nil {
 		response.Error(ctx, http.StatusInternalServerError, "查询失败")
 		return
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"go_wails_project_manager/database"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/auth"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie 绑定登录请求和浏览器的 Cookie，防止登录 CSRF
+const oidcStateCookie = "oidc_state"
+
+// OIDCController OIDC 单点登录控制器
+type OIDCController struct {
+	authController *AuthController
+	oidcService    *auth.OIDCService
+}
+
+// NewOIDCController 创建单点登录控制器
+func NewOIDCController(authController *AuthController, oidcService *auth.OIDCService) *OIDCController {
+	return &OIDCController{
+		authController: authController,
+		oidcService:    oidcService,
+	}
+}
+
+// OIDCExchangeRequest 使用登录票据换取 Token 的请求
+type OIDCExchangeRequest struct {
+	Ticket string `json:"ticket" binding:"required"`
+}
+
+// Config 获取单点登录配置（登录页据此显示单点登录按钮）
+func (oc *OIDCController) Config(c *gin.Context) {
+	response.Success(c, gin.H{
+		"enabled":       oc.oidcService.Enabled(),
+		"provider_name": oc.oidcService.ProviderName(),
+		"login_url":     "/api/auth/oidc/login",
+	})
+}
+
+// Login 跳转到身份提供方登录
+// redirect 参数为登录完成后前端要打开的站内路径
+func (oc *OIDCController) Login(c *gin.Context) {
+	authURL, state, err := oc.oidcService.AuthURL(c.Query("redirect"))
+	if err == auth.ErrOIDCDisabled {
+		response.NotFound(c, err.Error())
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	oc.setStateCookie(c, state, 600)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback 身份提供方回调，完成后跳转到前端并附带一次性登录票据（失败时附带 oidc_error）
+func (oc *OIDCController) Callback(c *gin.Context) {
+	state := c.Query("state")
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	oc.setStateCookie(c, "", -1)
+
+	if idpError := c.Query("error"); idpError != "" {
+		logger.Log.Warnf("OIDC 身份提供方返回错误: %s %s", idpError, c.Query("error_description"))
+		oc.redirectError(c, "身份提供方拒绝了登录请求")
+		return
+	}
+	if state == "" || cookieState != state {
+		oc.redirectError(c, auth.ErrOIDCState.Error())
+		return
+	}
+
+	user, redirect, err := oc.oidcService.HandleCallback(state, c.Query("code"), c.ClientIP())
+	if err != nil {
+		oc.redirectError(c, err.Error())
+		return
+	}
+	if user.Status == models.UserStatusDisabled {
+		oc.redirectError(c, "账号已被禁用")
+		return
+	}
+
+	params := url.Values{}
+	params.Set("oidc_ticket", oc.oidcService.IssueTicket(user.ID))
+	if redirect != "" {
+		params.Set("redirect", redirect)
+	}
+	c.Redirect(http.StatusFound, oc.oidcService.FrontendURL(params))
+}
+
+// Exchange 使用一次性登录票据换取 Token，响应与账号密码登录一致（包括两步验证挑战）
+func (oc *OIDCController) Exchange(c *gin.Context) {
+	var req OIDCExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	userID, err := oc.oidcService.RedeemTicket(req.Ticket)
+	if err != nil {
+		response.Unauthorized(c, err.Error())
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return
+	}
+
+	var user models.User
+	if err := db.Preload("Roles").First(&user, userID).Error; err != nil {
+		response.Unauthorized(c, "用户不存在")
+		return
+	}
+	if user.Status == models.UserStatusDisabled {
+		response.Forbidden(c, "账号已被禁用")
+		return
+	}
+	if user.IsLocked() {
+		response.Forbidden(c, "账号已被锁定，请稍后再试")
+		return
+	}
+
+	// 与账号密码登录一致：启用了两步验证时同样需要输入验证码
+	oc.authController.completeLogin(c, db, &user)
+}
+
+// redirectError 跳转到前端并附带错误信息
+func (oc *OIDCController) redirectError(c *gin.Context, message string) {
+	params := url.Values{}
+	params.Set("oidc_error", message)
+	c.Redirect(http.StatusFound, oc.oidcService.FrontendURL(params))
+}
+
+// setStateCookie 设置或清除 state Cookie（SameSite=Lax，身份提供方跳转回来时会携带）
+func (oc *OIDCController) setStateCookie(c *gin.Context, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
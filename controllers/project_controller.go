@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"go_wails_project_manager/database"
 	"go_wails_project_manager/logger"
+	"go_wails_project_manager/middleware"
 	"go_wails_project_manager/models"
 	"go_wails_project_manager/response"
 	"go_wails_project_manager/services"
+	"go_wails_project_manager/services/upload"
+	"go_wails_project_manager/utils"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -18,12 +21,14 @@ import (
 )
 
 type ProjectController struct {
-	service *services.ProjectService
+	service    *services.ProjectService
+	tusService *upload.TusService
 }
 
 func NewProjectController(db *gorm.DB) *ProjectController {
 	return &ProjectController{
-		service: services.NewProjectService(db),
+		service:    services.NewProjectService(db),
+		tusService: upload.NewTusService(db, logger.Log),
 	}
 }
 
@@ -33,7 +38,13 @@ func (pc *ProjectController) GetProjects(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	keyword := c.Query("keyword")
 
-	projects, total, err := pc.service.GetProjects(page, pageSize, keyword)
+	// 没有项目库查看权限的用户只能看到被单独授权的项目
+	ids, ok := visibleResourceIDs(c, models.ResourceProjects)
+	if !ok {
+		return
+	}
+
+	projects, total, err := pc.service.GetProjects(page, pageSize, keyword, ids)
 	if err != nil {
 		response.Error(c, response.CodeInternalServerError, "获取项目列表失败")
 		return
@@ -117,46 +128,78 @@ func (pc *ProjectController) UploadVersion(c *gin.Context) {
 	username := c.PostForm("username")
 	description := c.PostForm("description")
 	versionType := c.PostForm("version_type")
-	filePathsJSON := c.PostForm("file_paths")
 
 	if username == "" || versionType == "" {
 		response.Error(c, response.CodeBadRequest, "username和version_type不能为空")
 		return
 	}
 
+	// 创建临时目录
+	tempDir := filepath.Join(os.TempDir(), uuid.New().String())
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		response.Error(c, response.CodeInternalServerError, "创建临时目录失败")
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	// 断点续传上传的压缩包（archive_upload_id）直接解压，否则读取 multipart 文件列表
+	archiveUploadID := c.PostForm("archive_upload_id")
+	if archiveUploadID != "" {
+		archivePath, err := pc.tusService.FilePath(archiveUploadID, middleware.GetUserID(c))
+		if err != nil {
+			response.Error(c, response.CodeBadRequest, uploadErrorMessage(err, "获取上传文件失败"))
+			return
+		}
+		if err := utils.ExtractArchive(archivePath, tempDir); err != nil {
+			logger.Log.Errorf("解压版本压缩包失败: %v", err)
+			response.Error(c, response.CodeBadRequest, "解压版本压缩包失败")
+			return
+		}
+	} else if !pc.saveVersionFiles(c, tempDir) {
+		return
+	}
+
+	// 上传版本
+	uploadIP := c.ClientIP()
+	version, err := pc.service.UploadVersion(uint(projectID), username, description, versionType, tempDir, uploadIP)
+	if err != nil {
+		response.Error(c, response.CodeInternalServerError, "上传版本失败")
+		return
+	}
+	releaseUpload(c, pc.tusService, archiveUploadID)
+
+	response.Success(c, version)
+}
+
+// saveVersionFiles 将 multipart 文件列表按前端传来的相对路径保存到临时目录，失败时已写入响应
+func (pc *ProjectController) saveVersionFiles(c *gin.Context, tempDir string) bool {
+	filePathsJSON := c.PostForm("file_paths")
+
 	// 解析文件路径列表
 	var filePaths []string
 	if err := json.Unmarshal([]byte(filePathsJSON), &filePaths); err != nil {
 		response.Error(c, response.CodeBadRequest, "文件路径解析失败")
-		return
+		return false
 	}
 
 	// 获取上传的文件
 	form, err := c.MultipartForm()
 	if err != nil {
 		response.Error(c, response.CodeBadRequest, "获取文件失败")
-		return
+		return false
 	}
 
 	files := form.File["files"]
 	if len(files) == 0 {
 		response.Error(c, response.CodeBadRequest, "没有上传文件")
-		return
+		return false
 	}
 
 	if len(files) != len(filePaths) {
 		response.Error(c, response.CodeBadRequest, "文件数量与路径数量不匹配")
-		return
+		return false
 	}
 
-	// 创建临时目录
-	tempDir := filepath.Join(os.TempDir(), uuid.New().String())
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		response.Error(c, response.CodeInternalServerError, "创建临时目录失败")
-		return
-	}
-	defer os.RemoveAll(tempDir)
-
 	// 保存文件到临时目录（使用前端传来的路径信息）
 	// 需要去掉第一层目录（文件夹容器名）
 	var rootFolder string
@@ -191,25 +234,17 @@ func (pc *ProjectController) UploadVersion(c *gin.Context) {
 		// 创建目录
 		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 			response.Error(c, response.CodeInternalServerError, "创建目录失败")
-			return
+			return false
 		}
 
 		// 保存文件
 		if err := c.SaveUploadedFile(file, destPath); err != nil {
 			response.Error(c, response.CodeInternalServerError, "保存文件失败")
-			return
+			return false
 		}
 	}
 
-	// 上传版本
-	uploadIP := c.ClientIP()
-	version, err := pc.service.UploadVersion(uint(projectID), username, description, versionType, tempDir, uploadIP)
-	if err != nil {
-		response.Error(c, response.CodeInternalServerError, "上传版本失败")
-		return
-	}
-
-	response.Success(c, version)
+	return true
 }
 
 // GetVersionHistory 获取版本历史
@@ -225,6 +260,16 @@ func (pc *ProjectController) GetVersionHistory(c *gin.Context) {
 	response.Success(c, versions)
 }
 
+// VersionProjectID 由路径参数 versionId 查询所属项目ID，用于版本接口的对象授权检查（版本不存在时返回 0）
+func (pc *ProjectController) VersionProjectID(c *gin.Context) uint {
+	versionID, _ := strconv.ParseUint(c.Param("versionId"), 10, 32)
+	version, err := pc.service.GetVersion(uint(versionID))
+	if err != nil {
+		return 0
+	}
+	return version.ProjectID
+}
+
 // DownloadVersion 下载版本
 func (pc *ProjectController) DownloadVersion(c *gin.Context) {
 	versionID, _ := strconv.ParseUint(c.Param("versionId"), 10, 32)
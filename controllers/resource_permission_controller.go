@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/auth"
+	"go_wails_project_manager/services/similarity"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResourcePermissionController 对象级授权控制器（为单个文档、模型、项目或 AI3D 任务授权）
+type ResourcePermissionController struct {
+	service *auth.ResourcePermissionService
+}
+
+// NewResourcePermissionController 创建对象级授权控制器
+func NewResourcePermissionController(service *auth.ResourcePermissionService) *ResourcePermissionController {
+	return &ResourcePermissionController{service: service}
+}
+
+// GrantResourcePermissionRequest 授予对象权限请求
+type GrantResourcePermissionRequest struct {
+	UserID       uint       `json:"user_id" binding:"required"`
+	ResourceType string     `json:"resource_type" binding:"required"` // documents, models, projects, ai3d
+	ResourceID   uint       `json:"resource_id" binding:"required"`
+	Permission   string     `json:"permission" binding:"required"` // read, download, upload, update, delete, share, *
+	ExpiresAt    *time.Time `json:"expires_at"`                    // 为空表示永不过期
+}
+
+// CheckResourcePermissionRequest 检查对象权限请求
+type CheckResourcePermissionRequest struct {
+	ResourceType string `json:"resource_type" binding:"required"`
+	ResourceID   uint   `json:"resource_id" binding:"required"`
+	Permission   string `json:"permission" binding:"required"`
+}
+
+// Grant 授予对象权限（需要 permissions:admin 或对应资源的 admin 权限）
+func (rc *ResourcePermissionController) Grant(c *gin.Context) {
+	var req GrantResourcePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+	if !rc.canManage(c, req.ResourceType) {
+		response.Forbidden(c, "权限不足")
+		return
+	}
+
+	grant, err := rc.service.Grant(auth.GrantResourcePermissionInput{
+		UserID:       req.UserID,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Permission:   req.Permission,
+		ExpiresAt:    req.ExpiresAt,
+		GrantedBy:    middleware.GetUserID(c),
+	})
+	switch err {
+	case nil:
+		response.SuccessWithMsg(c, "授权成功", grant)
+	case auth.ErrResourceTypeNotGrantable, auth.ErrResourceActionInvalid, auth.ErrExpiresInPast:
+		response.BadRequest(c, err.Error())
+	case auth.ErrGranteeNotFound, auth.ErrResourceNotFound:
+		response.NotFound(c, err.Error())
+	default:
+		response.InternalServerError(c, "授权失败")
+	}
+}
+
+// Revoke 撤销对象权限
+func (rc *ResourcePermissionController) Revoke(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的授权ID")
+		return
+	}
+
+	grant, err := rc.service.Get(uint(id))
+	if err != nil {
+		if err == auth.ErrResourcePermissionNotFound {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.InternalServerError(c, "获取授权失败")
+		return
+	}
+	if !rc.canManage(c, grant.ResourceType) {
+		response.Forbidden(c, "权限不足")
+		return
+	}
+
+	if err := rc.service.Revoke(grant.ID); err != nil && err != auth.ErrResourcePermissionNotFound {
+		response.InternalServerError(c, "撤销授权失败")
+		return
+	}
+
+	response.SuccessWithMsg(c, "授权已撤销", nil)
+}
+
+// ListByUser 获取用户的对象授权（本人或拥有 permissions:read 权限）
+func (rc *ResourcePermissionController) ListByUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+	if uint(userID) != middleware.GetUserID(c) &&
+		!middleware.HasPermission(c, models.ResourcePermissions+":"+models.ActionRead) {
+		response.Forbidden(c, "权限不足")
+		return
+	}
+
+	grants, err := rc.service.GetByUser(uint(userID))
+	if err != nil {
+		response.InternalServerError(c, "获取授权失败")
+		return
+	}
+
+	response.Success(c, grants)
+}
+
+// ListByResource 获取对象的授权列表
+func (rc *ResourcePermissionController) ListByResource(c *gin.Context) {
+	resourceType := c.Param("type")
+	resourceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的资源ID")
+		return
+	}
+	if !auth.IsGrantableResource(resourceType) {
+		response.BadRequest(c, auth.ErrResourceTypeNotGrantable.Error())
+		return
+	}
+	if !middleware.HasPermission(c, models.ResourcePermissions+":"+models.ActionRead) && !rc.canManage(c, resourceType) {
+		response.Forbidden(c, "权限不足")
+		return
+	}
+
+	grants, err := rc.service.GetByResource(resourceType, uint(resourceID))
+	if err != nil {
+		response.InternalServerError(c, "获取授权失败")
+		return
+	}
+
+	response.Success(c, grants)
+}
+
+// Check 检查当前用户能否对指定对象执行操作（角色权限或对象授权）
+func (rc *ResourcePermissionController) Check(c *gin.Context) {
+	var req CheckResourcePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	allowed, err := middleware.HasResourcePermission(c, req.ResourceType, req.ResourceID, req.Permission)
+	if err != nil {
+		response.InternalServerError(c, "获取权限失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"has_permission": allowed,
+		"permission":     req.ResourceType + ":" + req.Permission,
+		"resource_id":    req.ResourceID,
+	})
+}
+
+// canManage 能否管理该类资源的授权：permissions:admin 或资源本身的 admin 权限
+func (rc *ResourcePermissionController) canManage(c *gin.Context, resourceType string) bool {
+	return middleware.HasPermission(c, models.ResourcePermissions+":"+models.ActionAdmin) ||
+		(auth.IsGrantableResource(resourceType) && middleware.HasPermission(c, resourceType+":"+models.ActionAdmin))
+}
+
+// visibleResourceIDs 列表接口需要限制的对象ID（nil 表示不限制），失败时写入错误响应
+func visibleResourceIDs(c *gin.Context, resourceType string) ([]uint, bool) {
+	ids, err := middleware.VisibleResourceIDs(c, resourceType, models.ActionRead)
+	if err != nil {
+		response.InternalServerError(c, "获取权限失败")
+		return nil, false
+	}
+	return ids, true
+}
+
+// grantedMatches 只保留被单独授权的相似结果，ids 为 nil 时不过滤
+func grantedMatches(ids []uint) matchFilter {
+	if ids == nil {
+		return nil
+	}
+	granted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		granted[id] = true
+	}
+	return func(matches []similarity.Match) []similarity.Match {
+		result := make([]similarity.Match, 0, len(matches))
+		for _, match := range matches {
+			if granted[match.ID] {
+				result = append(result, match)
+			}
+		}
+		return result
+	}
+}
@@ -25,6 +25,7 @@ type CreateRoleRequest struct {
 	Description         string `json:"description"`
 	PermissionIDs       []uint `json:"permission_ids"`
 	PermissionGroupIDs  []uint `json:"permission_group_ids"`
+	RequireTwoFactor    bool   `json:"require_two_factor"`
 }
 
 // UpdateRoleRequest 更新角色请求
@@ -33,6 +34,11 @@ type UpdateRoleRequest struct {
 	Description string `json:"description"`
 }
 
+// SetTwoFactorRequest 设置角色是否要求两步验证的请求
+type SetTwoFactorRequest struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
 // AssignPermissionsRequest 分配权限请求
 type AssignPermissionsRequest struct {
 	PermissionIDs      []uint `json:"permission_ids"`
@@ -104,6 +110,7 @@ func (rc *RoleController) Create(c *gin.Context) {
 		Name:        req.Name,
 		Description: req.Description,
 		IsSystem:    false,
+		RequireTwoFactor: req.RequireTwoFactor,
 	}
 
 	if err := db.Create(&role).Error; err != nil {
@@ -296,6 +303,37 @@ func (rc *RoleController) AssignPermissions(c *gin.Context) {
 	response.SuccessWithMsg(c, "权限分配成功", nil)
 }
 
+// SetTwoFactor 设置拥有该角色的用户是否必须启用两步验证（系统角色也可以设置）
+// 尚未绑定验证器的用户下次登录时需要先完成绑定
+func (rc *RoleController) SetTwoFactor(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	var req SetTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return
+	}
+
+	var role models.Role
+	if err := db.First(&role, id).Error; err != nil {
+		response.NotFound(c, "角色不存在")
+		return
+	}
+
+	if err := db.Model(&role).Update("require_two_factor", *req.Required).Error; err != nil {
+		response.InternalServerError(c, "更新失败")
+		return
+	}
+
+	response.Success(c, role)
+}
+
 // GetPermissions 获取角色的权限
 func (rc *RoleController) GetPermissions(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -0,0 +1,459 @@
+package controllers
+
+import (
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/document"
+	"go_wails_project_manager/services/share"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// shareKeyHeader 输入密码后获得的访问凭证请求头（也可通过 ?key= 传递，便于浏览器直接下载）
+const shareKeyHeader = "X-Share-Key"
+
+// ShareController 分享链接控制器
+type ShareController struct {
+	service       *share.Service
+	accessService *document.AccessService
+}
+
+// NewShareController 创建分享链接控制器
+func NewShareController(db *gorm.DB) *ShareController {
+	docConfig, err := config.LoadDocumentConfig()
+	if err != nil {
+		docConfig = &config.DocumentConfig{}
+	}
+
+	return &ShareController{
+		service:       share.NewService(db, docConfig, logger.Log),
+		accessService: document.NewAccessService(db, docConfig, logger.Log),
+	}
+}
+
+// createShareRequest 创建分享请求
+type createShareRequest struct {
+	ResourceType   string     `json:"resource_type" binding:"required"` // document, asset, model
+	ResourceID     uint       `json:"resource_id" binding:"required"`
+	Password       string     `json:"password"`         // 为空表示无需密码
+	ExpiresAt      *time.Time `json:"expires_at"`       // 过期时间（RFC3339），与 expires_in_hours 二选一
+	ExpiresInHours int        `json:"expires_in_hours"` // 有效小时数
+	MaxDownloads   int        `json:"max_downloads"`    // 最大下载次数，0 表示不限制
+}
+
+// Create 创建分享链接
+// @Summary 创建分享链接
+// @Description 为文档/文件夹、资产或模型创建公开链接，可设置访问密码、过期时间和最大下载次数；文件夹分享支持只读浏览
+// @Description 需要对应资源的 share 权限（如 assets:share，单独授权同样生效），文档还必须对当前用户可见
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Param body body createShareRequest true "分享参数"
+// @Success 200 {object} response.Response
+// @Router /api/shares [post]
+func (c *ShareController) Create(ctx *gin.Context) {
+	var req createShareRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if !c.canShare(ctx, req.ResourceType, req.ResourceID) {
+		return
+	}
+
+	expiresAt := req.ExpiresAt
+	if expiresAt == nil && req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+
+	link, err := c.service.Create(share.CreateRequest{
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Password:     req.Password,
+		ExpiresAt:    expiresAt,
+		MaxDownloads: req.MaxDownloads,
+		CreatedBy:    ctx.GetString("username"),
+	})
+	if err != nil {
+		c.shareError(ctx, err, "创建分享失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "创建成功", gin.H{
+		"share": link,
+		"path":  "/s/" + link.Token,
+	})
+}
+
+// List 分享链接列表
+// @Summary 分享链接列表
+// @Tags 分享
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(20)
+// @Param resource_type query string false "资源类型（document/asset/model）"
+// @Param resource_id query int false "资源ID"
+// @Param mine query boolean false "只看自己创建的（非管理员只能看到自己创建的分享）"
+// @Param include_revoked query boolean false "包含已取消的分享"
+// @Success 200 {object} response.Response
+// @Router /api/shares [get]
+func (c *ShareController) List(ctx *gin.Context) {
+	page, pageSize := sharePaging(ctx)
+	resourceID, _ := strconv.ParseUint(ctx.Query("resource_id"), 10, 32)
+
+	filters := share.ListFilters{
+		ResourceType:   ctx.Query("resource_type"),
+		ResourceID:     uint(resourceID),
+		IncludeRevoked: ctx.Query("include_revoked") == "true",
+	}
+	if ctx.Query("mine") == "true" {
+		filters.CreatedBy = ctx.GetString("username")
+	}
+
+	// 只有对应资源的管理员能看到其他人创建的分享
+	for _, resourceType := range share.ResourceTypes() {
+		if middleware.HasPermission(ctx, share.PermissionResource(resourceType)+":"+models.ActionAdmin) {
+			filters.ManagedTypes = append(filters.ManagedTypes, resourceType)
+		}
+	}
+	if len(filters.ManagedTypes) < len(share.ResourceTypes()) {
+		filters.Owner = ctx.GetString("username")
+	}
+
+	links, total, err := c.service.List(page, pageSize, filters)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"items":    links,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// Revoke 取消分享链接
+// @Summary 取消分享链接
+// @Tags 分享
+// @Produce json
+// @Param id path int true "分享ID"
+// @Success 200 {object} response.Response
+// @Router /api/shares/{id} [delete]
+func (c *ShareController) Revoke(ctx *gin.Context) {
+	link, ok := c.manageable(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Revoke(link.ID, ctx.GetString("username")); err != nil {
+		c.shareError(ctx, err, "取消分享失败")
+		return
+	}
+
+	response.SuccessWithMsg(ctx, "已取消分享", nil)
+}
+
+// GetAccessLogs 分享链接访问日志
+// @Summary 分享链接访问日志
+// @Tags 分享
+// @Produce json
+// @Param id path int true "分享ID"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(20)
+// @Success 200 {object} response.Response
+// @Router /api/shares/{id}/logs [get]
+func (c *ShareController) GetAccessLogs(ctx *gin.Context) {
+	link, ok := c.manageable(ctx)
+	if !ok {
+		return
+	}
+	page, pageSize := sharePaging(ctx)
+
+	logs, total, err := c.service.AccessLogs(link.ID, page, pageSize)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "查询失败: "+err.Error())
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"items":    logs,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// ==================== 公开访问（无需登录）====================
+
+// PublicInfo 查看分享信息
+// @Summary 查看分享
+// @Description 有密码的分享需先调用 unlock 获取访问凭证，通过 X-Share-Key 请求头或 key 参数传递
+// @Tags 分享
+// @Produce json
+// @Param token path string true "分享 token"
+// @Success 200 {object} response.Response
+// @Router /s/{token} [get]
+func (c *ShareController) PublicInfo(ctx *gin.Context) {
+	link, err := c.open(ctx)
+	if err == nil {
+		var info *share.ShareInfo
+		if info, err = c.service.Info(link); err == nil {
+			c.service.RecordAccess(link, share.AccessView, 0, nil, shareAccess(ctx))
+			response.Success(ctx, info)
+			return
+		}
+	}
+
+	c.service.RecordAccess(link, share.AccessView, 0, err, shareAccess(ctx))
+	c.publicError(ctx, link, err)
+}
+
+// PublicUnlock 输入分享密码
+// @Summary 输入分享密码
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Param token path string true "分享 token"
+// @Param body body object true "{ password: 访问密码 }"
+// @Success 200 {object} response.Response
+// @Router /s/{token}/unlock [post]
+func (c *ShareController) PublicUnlock(ctx *gin.Context) {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.Error(ctx, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	link, err := c.service.Open(ctx.Param("token"))
+	if err == nil {
+		var key string
+		var expires time.Time
+		if key, expires, err = c.service.Unlock(link, req.Password); err == nil {
+			c.service.RecordAccess(link, share.AccessUnlock, 0, nil, shareAccess(ctx))
+			response.Success(ctx, gin.H{
+				"key":        key,
+				"expires_at": expires,
+			})
+			return
+		}
+	}
+
+	c.service.RecordAccess(link, share.AccessUnlock, 0, err, shareAccess(ctx))
+	c.publicError(ctx, link, err)
+}
+
+// PublicList 浏览分享的文件夹
+// @Summary 浏览分享的文件夹
+// @Tags 分享
+// @Produce json
+// @Param token path string true "分享 token"
+// @Param folder_id query int false "子文件夹ID，默认为分享的文件夹"
+// @Success 200 {object} response.Response
+// @Router /s/{token}/list [get]
+func (c *ShareController) PublicList(ctx *gin.Context) {
+	folderID, _ := strconv.ParseUint(ctx.Query("folder_id"), 10, 32)
+
+	link, err := c.open(ctx)
+	if err == nil {
+		var folder *share.SharedItem
+		var items []*share.SharedItem
+		if folder, items, err = c.service.ListFolder(link, uint(folderID)); err == nil {
+			c.service.RecordAccess(link, share.AccessList, folder.ID, nil, shareAccess(ctx))
+			response.Success(ctx, gin.H{
+				"folder": folder,
+				"items":  items,
+			})
+			return
+		}
+	}
+
+	c.service.RecordAccess(link, share.AccessList, uint(folderID), err, shareAccess(ctx))
+	c.publicError(ctx, link, err)
+}
+
+// PublicDownload 下载分享的文件
+// @Summary 下载分享的文件
+// @Description 每次完整下载占用一次下载次数（HEAD 和 Range 续传请求不占用）；文件夹分享需通过 item_id 指定文件夹内的文件
+// @Tags 分享
+// @Produce octet-stream
+// @Param token path string true "分享 token"
+// @Param item_id query int false "文件夹分享中的文件ID"
+// @Param key query string false "访问凭证（有密码的分享）"
+// @Success 200 {file} binary
+// @Router /s/{token}/download [get]
+func (c *ShareController) PublicDownload(ctx *gin.Context) {
+	itemID, _ := strconv.ParseUint(ctx.Query("item_id"), 10, 32)
+
+	link, err := c.open(ctx)
+	if err == nil {
+		var file *share.DownloadFile
+		count := ctx.Request.Method != http.MethodHead && ctx.GetHeader("Range") == ""
+		if file, err = c.service.Download(link, uint(itemID), count); err == nil {
+			defer file.File.Close()
+			if count {
+				c.service.RecordAccess(link, share.AccessDownload, file.ItemID, nil, shareAccess(ctx))
+			}
+			ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`,
+				strings.ReplaceAll(file.Name, `"`, "_"), url.PathEscape(file.Name)))
+			var modTime time.Time
+			if stat, err := file.File.Stat(); err == nil {
+				modTime = stat.ModTime()
+			}
+			http.ServeContent(ctx.Writer, ctx.Request, file.Name, modTime, file.File)
+			return
+		}
+	}
+
+	c.service.RecordAccess(link, share.AccessDownload, uint(itemID), err, shareAccess(ctx))
+	c.publicError(ctx, link, err)
+}
+
+// canShare 检查当前用户能否分享指定资源，失败时写入错误响应
+// 需要对应资源的 share 权限（或该对象的单独授权），文档还必须对当前用户可见
+func (c *ShareController) canShare(ctx *gin.Context, resourceType string, resourceID uint) bool {
+	resource := share.PermissionResource(resourceType)
+	if resource == "" {
+		response.Error(ctx, http.StatusBadRequest, share.ErrInvalidResourceType.Error())
+		return false
+	}
+
+	allowed, err := middleware.HasResourcePermission(ctx, resource, resourceID, models.ActionShare)
+	if err != nil {
+		response.Error(ctx, http.StatusInternalServerError, "获取权限失败")
+		return false
+	}
+	if !allowed {
+		response.Error(ctx, http.StatusForbidden, "无权分享该资源")
+		return false
+	}
+	if resourceType != models.ResourceDocument {
+		return true
+	}
+
+	viewer, ok := loadDocumentViewer(ctx, c.accessService)
+	if !ok {
+		return false
+	}
+	if viewer.Admin {
+		return true
+	}
+	switch err := c.accessService.CanView(viewer, resourceID); err {
+	case nil:
+		return true
+	case gorm.ErrRecordNotFound:
+		response.Error(ctx, http.StatusNotFound, share.ErrResourceNotFound.Error())
+	case document.ErrDocumentForbidden:
+		response.Error(ctx, http.StatusForbidden, err.Error())
+	default:
+		response.Error(ctx, http.StatusInternalServerError, "权限检查失败: "+err.Error())
+	}
+	return false
+}
+
+// manageable 获取路径中的分享链接，只有创建者和对应资源的管理员可以管理，失败时写入错误响应
+func (c *ShareController) manageable(ctx *gin.Context) (*models.ShareLink, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的分享ID")
+		return nil, false
+	}
+
+	link, err := c.service.Get(uint(id))
+	if err != nil {
+		c.shareError(ctx, err, "查询分享失败")
+		return nil, false
+	}
+	if link.CreatedBy != ctx.GetString("username") &&
+		!middleware.HasPermission(ctx, share.PermissionResource(link.ResourceType)+":"+models.ActionAdmin) {
+		response.Error(ctx, http.StatusForbidden, "只能管理自己创建的分享")
+		return nil, false
+	}
+	return link, true
+}
+
+// open 打开分享并校验访问凭证
+func (c *ShareController) open(ctx *gin.Context) (*models.ShareLink, error) {
+	link, err := c.service.Open(ctx.Param("token"))
+	if err != nil {
+		return link, err
+	}
+
+	key := ctx.GetHeader(shareKeyHeader)
+	if key == "" {
+		key = ctx.Query("key")
+	}
+	return link, c.service.Authorize(link, key)
+}
+
+// publicError 公开访问错误响应（需要密码时返回 has_password 提示前端输入密码）
+func (c *ShareController) publicError(ctx *gin.Context, link *models.ShareLink, err error) {
+	switch err {
+	case share.ErrShareNotFound, share.ErrResourceNotFound:
+		response.Error(ctx, http.StatusNotFound, err.Error())
+	case share.ErrShareRevoked, share.ErrShareExpired:
+		response.Error(ctx, http.StatusGone, err.Error())
+	case share.ErrPasswordRequired, share.ErrWrongPassword:
+		ctx.JSON(http.StatusOK, response.NewResponse(http.StatusUnauthorized, err.Error(), gin.H{
+			"password_required": true,
+			"name":              link.Name,
+		}))
+	case share.ErrDownloadLimitReached:
+		response.Error(ctx, http.StatusForbidden, err.Error())
+	case share.ErrItemOutsideShare:
+		response.Error(ctx, http.StatusForbidden, err.Error())
+	case share.ErrNotFolderShare, share.ErrCannotDownloadFolder:
+		response.Error(ctx, http.StatusBadRequest, err.Error())
+	default:
+		logger.Log.Errorf("[分享] 访问失败: %v", err)
+		response.Error(ctx, http.StatusInternalServerError, "访问失败")
+	}
+}
+
+// shareError 管理接口错误响应
+func (c *ShareController) shareError(ctx *gin.Context, err error, msg string) {
+	switch err {
+	case share.ErrInvalidResourceType, share.ErrInvalidExpiry:
+		response.Error(ctx, http.StatusBadRequest, err.Error())
+	case share.ErrResourceNotFound, share.ErrShareNotFound:
+		response.Error(ctx, http.StatusNotFound, err.Error())
+	default:
+		response.Error(ctx, http.StatusInternalServerError, msg+": "+err.Error())
+	}
+}
+
+// sharePaging 解析分页参数
+func sharePaging(ctx *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// shareAccess 访问者信息
+func shareAccess(ctx *gin.Context) share.AccessContext {
+	return share.AccessContext{
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/similarity"
+	"go_wails_project_manager/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SimilarityController 相似图片检索控制器
+type SimilarityController struct {
+	service *similarity.Service
+}
+
+// NewSimilarityController 创建相似图片检索控制器
+func NewSimilarityController(db *gorm.DB) *SimilarityController {
+	return &SimilarityController{
+		service: similarity.NewService(db, logger.Log),
+	}
+}
+
+// Rebuild 补算资源库中缺失的感知哈希和主色
+// @Summary 补算感知哈希和主色
+// @Description 后台为历史数据计算感知哈希（资产原图、材质预览图、模型预览图、文档图片或预览图），材质和资产同时提取主色
+// @Tags 相似检索
+// @Param library path string true "资源库: asset|texture|model|document"
+// @Success 200 {object} response.Response
+// @Router /api/similarity/{library}/rebuild [post]
+func (c *SimilarityController) Rebuild(ctx *gin.Context) {
+	library := ctx.Param("library")
+	if library != similarity.LibraryAsset && library != similarity.LibraryTexture &&
+		library != similarity.LibraryModel && library != similarity.LibraryDocument {
+		response.Error(ctx, http.StatusBadRequest, "未知的资源库，可选: asset|texture|model|document")
+		return
+	}
+
+	go func() {
+		if _, _, err := c.service.Backfill(library); err != nil {
+			logger.Log.Errorf("补算感知哈希失败: %v", err)
+		}
+		if _, _, err := c.service.BackfillPalettes(library); err != nil {
+			logger.Log.Errorf("补算主色失败: %v", err)
+		}
+	}()
+
+	response.Success(ctx, gin.H{
+		"message": "感知哈希和主色补算已在后台启动",
+		"library": library,
+	})
+}
+
+// similarityThreshold 解析相似度阈值参数（汉明距离）
+func similarityThreshold(ctx *gin.Context) int {
+	value := ctx.Query("threshold")
+	if value == "" {
+		value = ctx.PostForm("similar_threshold")
+	}
+	threshold, err := strconv.Atoi(value)
+	if err != nil {
+		return utils.DefaultSimilarityThreshold
+	}
+	return utils.NormalizeSimilarityThreshold(threshold)
+}
+
+// matchFilter 过滤相似结果（如去掉当前用户不可见的资源），nil 表示不过滤
+type matchFilter = similarity.Filter
+
+// respondSimilar 返回与指定资源相似的资源列表
+func respondSimilar(ctx *gin.Context, service *similarity.Service, library string, id uint, filter matchFilter) {
+	threshold := similarityThreshold(ctx)
+
+	matches, err := service.FindSimilar(library, id, threshold, filter)
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			response.Error(ctx, http.StatusNotFound, "资源不存在")
+		case similarity.ErrNoPerceptualHash:
+			response.Error(ctx, http.StatusConflict, "资源尚未计算感知哈希")
+		default:
+			logger.Log.Errorf("查找相似资源失败: %v", err)
+			response.Error(ctx, http.StatusInternalServerError, "查询失败")
+		}
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"id":        id,
+		"threshold": threshold,
+		"list":      matches,
+		"total":     len(matches),
+	})
+}
+
+// nearDuplicates 上传时按需检查近似重复（check_similar=true 时启用），失败时返回 nil
+func nearDuplicates(ctx *gin.Context, service *similarity.Service, library, hash string, id uint, filter matchFilter) []similarity.Match {
+	if ctx.PostForm("check_similar") != "true" && ctx.Query("check_similar") != "true" {
+		return nil
+	}
+
+	matches, err := service.FindByHash(library, hash, similarityThreshold(ctx), id, filter)
+	if err != nil {
+		logger.Log.Warnf("检查近似重复失败: %v", err)
+		return nil
+	}
+	return matches
+}
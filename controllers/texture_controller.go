@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"encoding/json"
 	"fmt"
 	"go_wails_project_manager/config"
 	"go_wails_project_manager/database"
 	"go_wails_project_manager/logger"
 	"go_wails_project_manager/models"
 	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/palette"
+	"go_wails_project_manager/services/similarity"
 	"go_wails_project_manager/services/texture"
 	"net/http"
 	"strconv"
@@ -17,20 +20,28 @@ import (
 
 // TextureController 贴图控制器
 type TextureController struct {
-	db           *gorm.DB
-	syncService  *texture.SyncService
-	queryService *texture.QueryService
-	tagService   *texture.TagService
+	db                *gorm.DB
+	syncService       *texture.SyncService
+	queryService      *texture.QueryService
+	tagService        *texture.TagService
+	exportService     *texture.ExportService
+	normalService     *texture.NormalService
+	metricsService    *texture.MetricsService
+	similarityService *similarity.Service
 }
 
 // NewTextureController 创建贴图控制器
 func NewTextureController() *TextureController {
 	db := database.MustGetDB()
 	return &TextureController{
-		db:           db,
-		syncService:  texture.GetGlobalSyncService(),
-		queryService: texture.NewQueryService(db),
-		tagService:   texture.NewTagService(db),
+		db:                db,
+		syncService:       texture.GetGlobalSyncService(),
+		queryService:      texture.NewQueryService(db),
+		tagService:        texture.NewTagService(db),
+		exportService:     texture.NewExportService(db, logger.Log),
+		normalService:     texture.NewNormalService(db, logger.Log),
+		metricsService:    texture.NewMetricsService(db, logger.Log),
+		similarityService: similarity.NewService(db, logger.Log),
 	}
 }
 
@@ -44,6 +55,9 @@ func NewTextureController() *TextureController {
 // @Param syncStatus query int false "同步状态: 0=未同步 1=同步中 2=已同步 3=失败"
 // @Param textureType query string false "原始贴图类型: Diffuse|Rough|Normal 等"
 // @Param threeJSType query string false "Three.js 贴图类型: map|normalMap|roughnessMap 等"
+// @Param normalConvention query string false "法线约定: gl|dx，只返回该约定的法线贴图"
+// @Param color query string false "主色（#aabbcc），按预览图主色筛选"
+// @Param tolerance query number false "颜色容差（Lab ΔE）" default(20)
 // @Success 200 {object} response.Response
 // @Router /api/textures [get]
 func (c *TextureController) List(ctx *gin.Context) {
@@ -54,6 +68,11 @@ func (c *TextureController) List(ctx *gin.Context) {
 	syncStatusStr := ctx.Query("syncStatus")
 	textureType := ctx.Query("textureType")
 	threeJSType := ctx.Query("threeJSType")
+	normalConvention, err := texture.ParseNormalConvention(ctx.Query("normalConvention"))
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的法线约定，可选: gl|dx")
+		return
+	}
 
 	filters := map[string]interface{}{}
 	if keyword != "" {
@@ -73,6 +92,15 @@ func (c *TextureController) List(ctx *gin.Context) {
 	if threeJSType != "" {
 		filters["threejs_type"] = threeJSType
 	}
+	if color := ctx.Query("color"); color != "" {
+		if _, _, _, err := palette.ParseHex(color); err != nil {
+			response.Error(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		tolerance, _ := strconv.ParseFloat(ctx.Query("tolerance"), 64)
+		filters["color"] = color
+		filters["tolerance"] = tolerance
+	}
 
 	textures, total, err := c.queryService.List(page, pageSize, filters)
 	if err != nil {
@@ -89,15 +117,15 @@ func (c *TextureController) List(ctx *gin.Context) {
 	result := make([]TextureWithFiles, len(textures))
 	db := database.MustGetDB()
 	
-	for i, texture := range textures {
-		result[i].Texture = texture
+	for i, item := range textures {
+		result[i].Texture = item
 		
 		// 查询关联的文件
 		var files []models.File
-		db.Where("related_id = ? AND related_type = ?", texture.ID, "Texture").
+		db.Where("related_id = ? AND related_type = ?", item.ID, "Texture").
 			Order("file_type ASC").
 			Find(&files)
-		result[i].Files = files
+		result[i].Files = texture.FilterNormalFiles(files, normalConvention)
 	}
 
 	response.Success(ctx, gin.H{
@@ -159,6 +187,181 @@ func (c *TextureController) RecordUse(ctx *gin.Context) {
 	})
 }
 
+// FindSimilar 查找预览图相似的材质
+// @Summary 查找相似材质
+// @Description 基于预览图感知哈希查找视觉相似的材质（可用于跨数据源去重）
+// @Tags Texture
+// @Param assetId path string true "材质ID"
+// @Param threshold query int false "相似度阈值（汉明距离 0-32）" default(10)
+// @Success 200 {object} response.Response
+// @Router /api/textures/{assetId}/similar [get]
+func (c *TextureController) FindSimilar(ctx *gin.Context) {
+	item, err := c.queryService.GetByAssetID(ctx.Param("assetId"))
+	if err != nil {
+		response.Error(ctx, http.StatusNotFound, "材质不存在")
+		return
+	}
+
+	respondSimilar(ctx, c.similarityService, similarity.LibraryTexture, item.ID, nil)
+}
+
+// PrepareExport 生成导出材质所需的贴图
+// @Summary 准备材质导出
+// @Description 生成导出所需的贴图：由另一约定转换缺失的法线贴图，glTF 合并 AO / 粗糙度 / 金属度为 ORM 贴图，已存在时直接返回
+// @Tags Texture
+// @Param assetId path string true "材质ID"
+// @Param format query string false "导出格式: gltf|threejs|mtlx" default(gltf)
+// @Param normal query string false "法线约定: gl|dx（glTF / Three.js 始终使用 gl）" default(gl)
+// @Success 200 {object} response.Response
+// @Router /api/textures/{assetId}/export/prepare [post]
+func (c *TextureController) PrepareExport(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", texture.ExportFormatGLTF)
+	normalConvention, err := texture.ParseNormalConvention(ctx.Query("normal"))
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的法线约定，可选: gl|dx")
+		return
+	}
+
+	preparation, err := c.exportService.Prepare(ctx.Param("assetId"), format, normalConvention)
+	if err != nil {
+		respondExportError(ctx, err)
+		return
+	}
+	response.Success(ctx, preparation)
+}
+
+// respondExportError 材质导出错误响应
+func respondExportError(ctx *gin.Context, err error) {
+	switch {
+	case err == texture.ErrUnsupportedExportFormat:
+		response.Error(ctx, http.StatusBadRequest, "不支持的导出格式，可选: gltf|threejs|mtlx")
+	case err == texture.ErrTextureNotDownloaded:
+		response.Error(ctx, http.StatusConflict, "材质尚未下载，请先下载材质")
+	case err == texture.ErrExportNotPrepared:
+		response.Error(ctx, http.StatusConflict, "导出所需的贴图尚未生成，请先调用 POST /api/textures/{assetId}/export/prepare")
+	case err == texture.ErrNoExportableMaps:
+		response.Error(ctx, http.StatusNotFound, "材质没有可导出的贴图")
+	case err == gorm.ErrRecordNotFound:
+		response.Error(ctx, http.StatusNotFound, "材质不存在")
+	default:
+		logger.Log.Errorf("导出材质失败: %v", err)
+		response.Error(ctx, http.StatusInternalServerError, "导出失败")
+	}
+}
+
+// ExportMaterial 导出材质定义
+// @Summary 导出材质定义
+// @Description 根据贴图映射生成 glTF / Three.js / MaterialX 材质定义，package=zip 时打包材质定义和引用的贴图。
+// @Description 只读取已有的贴图，缺少指定约定的法线贴图或 glTF 的 ORM 贴图时返回 409，需先调用 export/prepare 生成
+// @Tags Texture
+// @Param assetId path string true "材质ID"
+// @Param format query string false "导出格式: gltf|threejs|mtlx" default(gltf)
+// @Param package query string false "打包方式: zip"
+// @Param normal query string false "法线约定: gl|dx（glTF / Three.js 始终使用 gl）" default(gl)
+// @Success 200 {object} response.Response
+// @Router /api/textures/{assetId}/export [get]
+func (c *TextureController) ExportMaterial(ctx *gin.Context) {
+	assetID := ctx.Param("assetId")
+	format := ctx.DefaultQuery("format", texture.ExportFormatGLTF)
+	packaged := ctx.Query("package") == "zip"
+	normalConvention, err := texture.ParseNormalConvention(ctx.Query("normal"))
+	if err != nil {
+		response.Error(ctx, http.StatusBadRequest, "无效的法线约定，可选: gl|dx")
+		return
+	}
+
+	export, err := c.exportService.Export(assetID, format, packaged, normalConvention)
+	if err != nil {
+		respondExportError(ctx, err)
+		return
+	}
+
+	if packaged {
+		ctx.Header("Content-Type", "application/zip")
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s.zip"`, export.AssetID, export.Format))
+		if err := c.exportService.WriteZip(ctx.Writer, export); err != nil {
+			logger.Log.Errorf("写入材质压缩包失败: %v", err)
+		}
+		return
+	}
+
+	var definition interface{} = string(export.Definition)
+	if export.Format != texture.ExportFormatMtlx {
+		definition = json.RawMessage(export.Definition)
+	}
+
+	response.Success(ctx, gin.H{
+		"asset_id":   export.AssetID,
+		"format":     export.Format,
+		"file_name":  export.FileName,
+		"definition": definition,
+		"maps":       export.Maps,
+		"unmapped":   export.Unmapped,
+	})
+}
+
+// ConvertNormal 生成指定约定的法线贴图
+// @Summary 转换法线贴图约定
+// @Description 反转绿色通道，由 DirectX / OpenGL 法线贴图生成另一约定，结果作为材质文件保存
+// @Tags Texture
+// @Param assetId path string true "材质ID"
+// @Param convention query string true "目标约定: gl|dx"
+// @Success 200 {object} response.Response
+// @Router /api/textures/{assetId}/normals/convert [post]
+func (c *TextureController) ConvertNormal(ctx *gin.Context) {
+	assetID := ctx.Param("assetId")
+	convention, err := texture.ParseNormalConvention(ctx.Query("convention"))
+	if err != nil || convention == "" {
+		response.Error(ctx, http.StatusBadRequest, "无效的法线约定，可选: gl|dx")
+		return
+	}
+
+	var item models.Texture
+	if err := c.db.Where("asset_id = ?", assetID).First(&item).Error; err != nil {
+		response.Error(ctx, http.StatusNotFound, "材质不存在")
+		return
+	}
+	if !item.DownloadCompleted {
+		response.Error(ctx, http.StatusConflict, "材质尚未下载，请先下载材质")
+		return
+	}
+
+	file, err := c.normalService.EnsureConvention(&item, convention)
+	if err != nil {
+		if err == texture.ErrNoNormalMap {
+			response.Error(ctx, http.StatusNotFound, "材质没有法线贴图")
+			return
+		}
+		logger.Log.Errorf("转换法线贴图失败: %v", err)
+		response.Error(ctx, http.StatusInternalServerError, "转换失败")
+		return
+	}
+
+	response.Success(ctx, gin.H{
+		"asset_id":   item.AssetID,
+		"convention": convention,
+		"file":       file,
+	})
+}
+
+// ConvertAllNormals 为所有已下载材质补齐缺失的法线约定
+// @Summary 批量转换法线贴图约定
+// @Description 后台为只有一种法线约定的已下载材质生成另一约定
+// @Tags Texture
+// @Success 200 {object} response.Response
+// @Router /api/textures/normals/convert-all [post]
+func (c *TextureController) ConvertAllNormals(ctx *gin.Context) {
+	go func() {
+		if _, _, err := c.normalService.ConvertAll(); err != nil {
+			logger.Log.Errorf("批量转换法线贴图失败: %v", err)
+		}
+	}()
+
+	response.Success(ctx, gin.H{
+		"message": "法线贴图转换已在后台启动",
+	})
+}
+
 // GetTags 获取标签列表
 // @Summary 获取标签列表
 // @Tags Texture
@@ -363,6 +566,30 @@ func (c *TextureController) GetSyncLogs(ctx *gin.Context) {
 	})
 }
 
+// GetMetrics 获取材质同步健康指标
+// @Summary 获取材质同步健康指标
+// @Description 返回每日同步/下载指标时间序列，以及各数据源的失败率
+// @Tags Texture
+// @Param from query string false "开始日期 YYYY-MM-DD，默认最近 30 天"
+// @Param to query string false "结束日期 YYYY-MM-DD，默认今天"
+// @Success 200 {object} response.Response
+// @Router /api/textures/metrics [get]
+func (c *TextureController) GetMetrics(ctx *gin.Context) {
+	series, err := c.metricsService.Series(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		switch err {
+		case texture.ErrInvalidMetricsDate, texture.ErrInvalidMetricsRange:
+			response.Error(ctx, http.StatusBadRequest, err.Error())
+		default:
+			logger.Log.Errorf("查询材质指标失败: %v", err)
+			response.Error(ctx, http.StatusInternalServerError, "查询失败")
+		}
+		return
+	}
+
+	response.Success(ctx, series)
+}
+
 // GetTextureTypes 获取所有贴图类型
 // @Summary 获取所有贴图类型
 // @Tags Texture
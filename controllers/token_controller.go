@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"go_wails_project_manager/database"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/auth"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenController 个人访问令牌和服务账号控制器
+type TokenController struct {
+	tokenService *auth.TokenService
+}
+
+// NewTokenController 创建访问令牌控制器
+func NewTokenController(tokenService *auth.TokenService) *TokenController {
+	return &TokenController{tokenService: tokenService}
+}
+
+// CreateTokenRequest 创建访问令牌请求
+type CreateTokenRequest struct {
+	Name        string     `json:"name" binding:"required,max=100"`
+	Description string     `json:"description" binding:"max=500"`
+	Scopes      []string   `json:"scopes" binding:"required"` // 权限范围，如 ["models:create", "projects:upload"]
+	AllowedIPs  []string   `json:"allowed_ips"`               // IP 或 CIDR 白名单，为空不限制
+	ExpiresAt   *time.Time `json:"expires_at"`                // 为空表示永不过期
+	UserID      uint       `json:"user_id"`                   // 为服务账号创建令牌（需要 users:admin 权限），为空时为自己创建
+}
+
+// CreateServiceAccountRequest 创建服务账号请求
+type CreateServiceAccountRequest struct {
+	Username   string `json:"username" binding:"required,min=3,max=50"`
+	RealName   string `json:"real_name"`
+	Department string `json:"department"`
+	RoleIDs    []uint `json:"role_ids"`
+}
+
+// ListTokens 获取访问令牌
+// 默认返回当前用户的令牌；拥有 users:admin 权限时可通过 user_id 查看指定用户，all=true 查看全部
+func (tc *TokenController) ListTokens(c *gin.Context) {
+	if !tc.requireSession(c) {
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	targetID := userID
+	if c.Query("all") == "true" {
+		targetID = 0
+	} else if id, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil && id > 0 {
+		targetID = uint(id)
+	}
+	if targetID != userID && !tc.isUserAdmin(c) {
+		response.Forbidden(c, "权限不足")
+		return
+	}
+
+	tokens, err := tc.tokenService.List(targetID)
+	if err != nil {
+		response.InternalServerError(c, "获取访问令牌失败")
+		return
+	}
+
+	response.Success(c, tokens)
+}
+
+// CreateToken 创建访问令牌，明文令牌只在本次响应中返回
+func (tc *TokenController) CreateToken(c *gin.Context) {
+	if !tc.requireSession(c) {
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	ownerID := userID
+	if req.UserID > 0 && req.UserID != userID {
+		if !tc.isUserAdmin(c) {
+			response.Forbidden(c, "权限不足")
+			return
+		}
+		ownerID = req.UserID
+	}
+
+	var owner models.User
+	if err := db.First(&owner, ownerID).Error; err != nil {
+		response.NotFound(c, "用户不存在")
+		return
+	}
+	// 管理员只能为服务账号代建令牌，不能冒用其他普通用户的身份
+	if owner.ID != userID && !owner.IsServiceAccount() {
+		response.BadRequest(c, "只能为自己或服务账号创建访问令牌")
+		return
+	}
+	if owner.Status == models.UserStatusDisabled {
+		response.BadRequest(c, "账号已被禁用")
+		return
+	}
+
+	ownerPerms, err := middleware.GetUserPermissions(owner.ID)
+	if err != nil {
+		response.InternalServerError(c, "获取权限失败")
+		return
+	}
+
+	token, plain, err := tc.tokenService.Create(&owner, ownerPerms, auth.CreateTokenInput{
+		Name:        req.Name,
+		Description: req.Description,
+		Scopes:      req.Scopes,
+		AllowedIPs:  req.AllowedIPs,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   middleware.GetUsername(c),
+	})
+	switch err {
+	case nil:
+	case auth.ErrScopesRequired, auth.ErrInvalidScope, auth.ErrScopeNotGranted,
+		auth.ErrInvalidAllowedIP, auth.ErrExpiresInPast:
+		response.BadRequest(c, err.Error())
+		return
+	default:
+		response.InternalServerError(c, "创建访问令牌失败")
+		return
+	}
+
+	response.SuccessWithMsg(c, "访问令牌已创建，请立即保存，之后将无法再次查看", gin.H{
+		"token":      plain,
+		"token_info": token,
+	})
+}
+
+// RevokeToken 撤销访问令牌（自己的令牌，或拥有 users:admin 权限时任意令牌）
+func (tc *TokenController) RevokeToken(c *gin.Context) {
+	if !tc.requireSession(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的令牌ID")
+		return
+	}
+
+	token, err := tc.tokenService.Get(uint(id))
+	if err != nil {
+		response.NotFound(c, "访问令牌不存在")
+		return
+	}
+	if token.UserID != middleware.GetUserID(c) && !tc.isUserAdmin(c) {
+		response.NotFound(c, "访问令牌不存在")
+		return
+	}
+
+	if err := tc.tokenService.Revoke(token.ID); err != nil {
+		response.InternalServerError(c, "撤销访问令牌失败")
+		return
+	}
+
+	response.SuccessWithMsg(c, "访问令牌已撤销", nil)
+}
+
+// ListServiceAccounts 获取服务账号列表
+func (tc *TokenController) ListServiceAccounts(c *gin.Context) {
+	users, err := tc.tokenService.ListServiceAccounts()
+	if err != nil {
+		response.InternalServerError(c, "获取服务账号失败")
+		return
+	}
+
+	list := make([]gin.H, 0, len(users))
+	for i := range users {
+		list = append(list, gin.H{
+			"user":  users[i].ToResponse(),
+			"roles": users[i].Roles,
+		})
+	}
+	response.Success(c, list)
+}
+
+// CreateServiceAccount 创建服务账号（只能使用访问令牌认证，不能登录）
+func (tc *TokenController) CreateServiceAccount(c *gin.Context) {
+	if !tc.requireSession(c) {
+		return
+	}
+
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	user, err := tc.tokenService.CreateServiceAccount(auth.CreateServiceAccountInput{
+		Username:   req.Username,
+		RealName:   req.RealName,
+		Department: req.Department,
+		RoleIDs:    req.RoleIDs,
+	})
+	if err == auth.ErrServiceAccountExists {
+		response.Error(c, 409, err.Error())
+		return
+	}
+	if err != nil {
+		response.InternalServerError(c, "创建服务账号失败")
+		return
+	}
+
+	response.Success(c, user.ToResponse())
+}
+
+// requireSession 令牌和服务账号管理必须使用登录会话，访问令牌不能再创建或撤销令牌
+func (tc *TokenController) requireSession(c *gin.Context) bool {
+	if middleware.IsAPITokenRequest(c) {
+		response.Forbidden(c, "访问令牌不能管理令牌，请登录后操作")
+		return false
+	}
+	return true
+}
+
+// isUserAdmin 当前请求是否拥有用户管理权限
+func (tc *TokenController) isUserAdmin(c *gin.Context) bool {
+	return middleware.HasPermission(c, models.ResourceUsers+":"+models.ActionAdmin)
+}
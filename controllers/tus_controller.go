@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/upload"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// tusContentType PATCH 请求体类型
+const tusContentType = "application/offset+octet-stream"
+
+// TusController tus 断点续传控制器
+// tus 客户端依赖 HTTP 状态码和响应头，因此这里不使用统一的 response 包装
+type TusController struct {
+	service *upload.TusService
+}
+
+// NewTusController 创建 tus 断点续传控制器
+func NewTusController(db *gorm.DB) *TusController {
+	return &TusController{
+		service: upload.NewTusService(db, logger.Log),
+	}
+}
+
+// Options 返回服务端支持的 tus 版本和扩展
+// @Summary tus 能力查询
+// @Tags 断点续传
+// @Success 204
+// @Router /api/uploads/tus [options]
+func (c *TusController) Options(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", upload.TusVersion)
+	ctx.Header("Tus-Version", upload.TusVersion)
+	ctx.Header("Tus-Extension", upload.TusExtensions)
+	if maxSize := c.service.MaxSize(); maxSize > 0 {
+		ctx.Header("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Create 创建上传，支持 creation-with-upload（请求体携带首个分片）
+// @Summary 创建断点续传上传
+// @Tags 断点续传
+// @Param Upload-Length header int true "文件总字节数"
+// @Param Upload-Metadata header string false "元数据，如 filename base64(name)"
+// @Success 201
+// @Router /api/uploads/tus [post]
+func (c *TusController) Create(ctx *gin.Context) {
+	if !c.checkVersion(ctx) {
+		return
+	}
+
+	length, err := strconv.ParseInt(ctx.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		c.abort(ctx, http.StatusBadRequest, upload.ErrInvalidLength)
+		return
+	}
+
+	record, err := c.service.Create(length, ctx.GetHeader("Upload-Metadata"), middleware.GetUserID(ctx))
+	if err != nil {
+		c.abortWithError(ctx, err)
+		return
+	}
+
+	if ctx.GetHeader("Content-Type") == tusContentType && ctx.Request.ContentLength != 0 {
+		written, err := c.service.WriteChunk(record.ID, record.UserID, 0, ctx.Request.Body)
+		if written != nil {
+			record = written
+		}
+		if err != nil {
+			logger.Log.Warnf("创建上传时写入首个分片失败 %s: %v", record.ID, err)
+		}
+	}
+
+	ctx.Header("Location", strings.TrimSuffix(ctx.Request.URL.Path, "/")+"/"+record.ID)
+	c.writeOffset(ctx, record)
+	ctx.Status(http.StatusCreated)
+}
+
+// Head 查询上传偏移量，客户端据此续传
+// @Summary 查询断点续传进度
+// @Tags 断点续传
+// @Param id path string true "上传ID"
+// @Success 200
+// @Router /api/uploads/tus/{id} [head]
+func (c *TusController) Head(ctx *gin.Context) {
+	if !c.checkVersion(ctx) {
+		return
+	}
+
+	record, err := c.service.Get(ctx.Param("id"), middleware.GetUserID(ctx))
+	if err != nil {
+		c.abortWithError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Upload-Length", strconv.FormatInt(record.Length, 10))
+	if record.Metadata != "" {
+		ctx.Header("Upload-Metadata", record.Metadata)
+	}
+	c.writeOffset(ctx, record)
+	ctx.Status(http.StatusOK)
+}
+
+// Patch 从 Upload-Offset 处追加分片
+// @Summary 上传分片
+// @Tags 断点续传
+// @Param id path string true "上传ID"
+// @Param Upload-Offset header int true "当前偏移量"
+// @Success 204
+// @Router /api/uploads/tus/{id} [patch]
+func (c *TusController) Patch(ctx *gin.Context) {
+	if !c.checkVersion(ctx) {
+		return
+	}
+	if ctx.GetHeader("Content-Type") != tusContentType {
+		c.abort(ctx, http.StatusUnsupportedMediaType, nil)
+		return
+	}
+
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.abort(ctx, http.StatusBadRequest, upload.ErrOffsetMismatch)
+		return
+	}
+
+	record, err := c.service.WriteChunk(ctx.Param("id"), middleware.GetUserID(ctx), offset, ctx.Request.Body)
+	if err != nil && record == nil {
+		c.abortWithError(ctx, err)
+		return
+	}
+	if err != nil {
+		// 连接中断：已写入部分已保存，客户端通过 HEAD 获取偏移量后续传
+		c.writeOffset(ctx, record)
+		c.abort(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.writeOffset(ctx, record)
+	ctx.Status(http.StatusNoContent)
+}
+
+// Delete 终止上传
+// @Summary 终止断点续传上传
+// @Tags 断点续传
+// @Param id path string true "上传ID"
+// @Success 204
+// @Router /api/uploads/tus/{id} [delete]
+func (c *TusController) Delete(ctx *gin.Context) {
+	if !c.checkVersion(ctx) {
+		return
+	}
+
+	if err := c.service.Terminate(ctx.Param("id"), middleware.GetUserID(ctx)); err != nil {
+		c.abortWithError(ctx, err)
+		return
+	}
+	ctx.Header("Tus-Resumable", upload.TusVersion)
+	ctx.Status(http.StatusNoContent)
+}
+
+// MethodOverride 处理带 X-HTTP-Method-Override 的 POST（供不支持 PATCH/DELETE 的客户端使用）
+// @Summary 以 POST 代替 PATCH/DELETE
+// @Tags 断点续传
+// @Param id path string true "上传ID"
+// @Param X-HTTP-Method-Override header string true "PATCH 或 DELETE"
+// @Success 204
+// @Router /api/uploads/tus/{id} [post]
+func (c *TusController) MethodOverride(ctx *gin.Context) {
+	switch strings.ToUpper(ctx.GetHeader("X-HTTP-Method-Override")) {
+	case http.MethodPatch:
+		c.Patch(ctx)
+	case http.MethodDelete:
+		c.Delete(ctx)
+	default:
+		ctx.Header("Allow", "HEAD, PATCH, DELETE")
+		c.abort(ctx, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+// checkVersion 校验 Tus-Resumable 头
+func (c *TusController) checkVersion(ctx *gin.Context) bool {
+	if ctx.GetHeader("Tus-Resumable") != upload.TusVersion {
+		ctx.Header("Tus-Version", upload.TusVersion)
+		ctx.AbortWithStatus(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// writeOffset 写入偏移量和过期时间响应头
+func (c *TusController) writeOffset(ctx *gin.Context, record *models.TusUpload) {
+	ctx.Header("Tus-Resumable", upload.TusVersion)
+	ctx.Header("Upload-Offset", strconv.FormatInt(record.Offset, 10))
+	ctx.Header("Upload-Expires", record.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// abortWithError 按 tus 协议映射错误状态码
+func (c *TusController) abortWithError(ctx *gin.Context, err error) {
+	switch err {
+	case upload.ErrUploadNotFound:
+		c.abort(ctx, http.StatusNotFound, err)
+	case upload.ErrInvalidLength, upload.ErrInvalidMetadata:
+		c.abort(ctx, http.StatusBadRequest, err)
+	case upload.ErrUploadTooLarge:
+		c.abort(ctx, http.StatusRequestEntityTooLarge, err)
+	case upload.ErrOffsetMismatch:
+		c.abort(ctx, http.StatusConflict, err)
+	case upload.ErrUploadLocked:
+		c.abort(ctx, http.StatusLocked, err)
+	default:
+		logger.Log.Errorf("断点续传处理失败: %v", err)
+		c.abort(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// abort 返回状态码和纯文本错误信息
+func (c *TusController) abort(ctx *gin.Context, status int, err error) {
+	ctx.Header("Tus-Resumable", upload.TusVersion)
+	if err == nil {
+		ctx.AbortWithStatus(status)
+		return
+	}
+	ctx.Abort()
+	ctx.String(status, err.Error())
+}
+
+// formUpload 获取表单上传文件：优先使用当前用户已完成的断点续传上传（<field>_upload_id），否则读取 multipart 文件
+// 返回的 uploadID 非空时，调用方在处理成功后应调用 releaseUpload 删除暂存文件
+func formUpload(ctx *gin.Context, tus *upload.TusService, field string) (*upload.File, string, error) {
+	if uploadID := ctx.PostForm(field + "_upload_id"); uploadID != "" {
+		file, err := tus.Open(uploadID, middleware.GetUserID(ctx))
+		if err != nil {
+			return nil, "", err
+		}
+		return file, uploadID, nil
+	}
+
+	header, err := ctx.FormFile(field)
+	if err != nil {
+		return nil, "", err
+	}
+	return upload.FromFileHeader(header), "", nil
+}
+
+// releaseUpload 资源入库后释放当前用户的断点续传暂存文件
+func releaseUpload(ctx *gin.Context, tus *upload.TusService, uploadIDs ...string) {
+	for _, id := range uploadIDs {
+		if id == "" {
+			continue
+		}
+		if err := tus.Terminate(id, middleware.GetUserID(ctx)); err != nil && err != upload.ErrUploadNotFound {
+			logger.Log.Warnf("释放断点续传上传失败 %s: %v", id, err)
+		}
+	}
+}
+
+// uploadErrorMessage 获取上传文件失败时的提示信息
+func uploadErrorMessage(err error, fallback string) string {
+	switch err {
+	case upload.ErrUploadNotFound, upload.ErrUploadIncomplete:
+		return err.Error()
+	}
+	return fallback
+}
@@ -0,0 +1,382 @@
+package controllers
+
+import (
+	"go_wails_project_manager/database"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/audit"
+	"go_wails_project_manager/services/auth"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorController TOTP 两步验证控制器
+type TwoFactorController struct {
+	authController   *AuthController
+	twoFactorService *auth.TwoFactorService
+	auditService     *audit.AuditService // 未启用审计时为 nil
+}
+
+// NewTwoFactorController 创建两步验证控制器
+func NewTwoFactorController(authController *AuthController, twoFactorService *auth.TwoFactorService, auditService *audit.AuditService) *TwoFactorController {
+	return &TwoFactorController{
+		authController:   authController,
+		twoFactorService: twoFactorService,
+		auditService:     auditService,
+	}
+}
+
+// TwoFactorSetupRequest 获取绑定密钥请求（登录时被要求绑定验证器的用户携带挑战令牌）
+type TwoFactorSetupRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// TwoFactorEnableRequest 启用两步验证请求
+type TwoFactorEnableRequest struct {
+	Code           string `json:"code" binding:"required"`
+	ChallengeToken string `json:"challenge_token"` // 登录时绑定，启用后直接完成登录
+}
+
+// TwoFactorVerifyRequest 登录时提交两步验证码的请求，验证码和恢复码二选一
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorCodeRequest 敏感操作前确认验证码的请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Status 获取当前用户的两步验证状态
+func (tc *TwoFactorController) Status(c *gin.Context) {
+	status, err := tc.twoFactorService.Status(middleware.GetUserID(c))
+	if err != nil {
+		response.InternalServerError(c, "查询两步验证状态失败")
+		return
+	}
+	response.Success(c, status)
+}
+
+// Setup 生成验证器绑定密钥和二维码地址
+// 已登录用户直接调用；登录时被要求绑定的用户携带 challenge_token 调用
+func (tc *TwoFactorController) Setup(c *gin.Context) {
+	var req TwoFactorSetupRequest
+	c.ShouldBindJSON(&req)
+
+	user, ok := tc.resolveUser(c, req.ChallengeToken)
+	if !ok {
+		return
+	}
+
+	setup, err := tc.twoFactorService.Setup(user)
+	if err == auth.ErrTwoFactorAlreadyEnabled {
+		response.Error(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		response.InternalServerError(c, "生成绑定密钥失败")
+		return
+	}
+
+	response.Success(c, setup)
+}
+
+// Enable 提交验证器生成的验证码启用两步验证，恢复码明文只在本次响应中返回
+// 携带 challenge_token 时启用后直接完成登录，响应与登录一致并附带 recovery_codes
+func (tc *TwoFactorController) Enable(c *gin.Context) {
+	var req TwoFactorEnableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	user, ok := tc.resolveUser(c, req.ChallengeToken)
+	if !ok {
+		return
+	}
+
+	codes, err := tc.twoFactorService.Enable(user.ID, req.Code)
+	switch err {
+	case nil:
+	case auth.ErrTwoFactorCodeInvalid, auth.ErrTwoFactorNotSetup:
+		response.BadRequest(c, err.Error())
+		return
+	case auth.ErrTwoFactorAlreadyEnabled:
+		response.Error(c, http.StatusConflict, err.Error())
+		return
+	default:
+		response.InternalServerError(c, "启用两步验证失败")
+		return
+	}
+
+	tc.audit(c, user.ID, user.Username, models.ActionTwoFactorEnable, user.ID)
+
+	if req.ChallengeToken != "" {
+		tc.twoFactorService.ConsumeChallenge(req.ChallengeToken)
+		db, err := database.GetDB()
+		if err != nil {
+			response.InternalServerError(c, "数据库连接失败")
+			return
+		}
+		tc.authController.finishLogin(c, db, user, gin.H{"recovery_codes": codes})
+		return
+	}
+
+	response.SuccessWithMsg(c, "两步验证已启用，请妥善保存恢复码，之后将无法再次查看", gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// Verify 登录时使用验证码或恢复码完成两步验证，成功后返回与登录一致的响应
+func (tc *TwoFactorController) Verify(c *gin.Context) {
+	var req TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	userID, err := tc.twoFactorService.ChallengeUser(req.ChallengeToken, auth.ChallengeVerify)
+	if err != nil {
+		response.Unauthorized(c, err.Error())
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return
+	}
+
+	var user models.User
+	if err := db.Preload("Roles").First(&user, userID).Error; err != nil {
+		response.Unauthorized(c, "用户不存在")
+		return
+	}
+	if user.Status == models.UserStatusDisabled {
+		tc.twoFactorService.ConsumeChallenge(req.ChallengeToken)
+		response.Forbidden(c, "账号已被禁用")
+		return
+	}
+	if user.IsLocked() {
+		tc.twoFactorService.ConsumeChallenge(req.ChallengeToken)
+		response.Forbidden(c, "账号已被锁定，请稍后再试")
+		return
+	}
+
+	_, usedRecovery, err := tc.twoFactorService.Verify(req.ChallengeToken, req.Code, req.RecoveryCode)
+	switch err {
+	case nil:
+	case auth.ErrTwoFactorCodeInvalid, auth.ErrTwoFactorTooManyAttempts:
+		// 验证码错误与密码错误一起计入登录失败次数
+		recordLoginFailure(db, &user)
+		response.Unauthorized(c, err.Error())
+		return
+	case auth.ErrTwoFactorChallenge, auth.ErrTwoFactorNotEnabled:
+		response.Unauthorized(c, auth.ErrTwoFactorChallenge.Error())
+		return
+	default:
+		response.InternalServerError(c, "两步验证失败")
+		return
+	}
+
+	var extra gin.H
+	if usedRecovery {
+		tc.audit(c, user.ID, user.Username, models.ActionTwoFactorRecovery, user.ID)
+		if status, err := tc.twoFactorService.Status(user.ID); err == nil {
+			extra = gin.H{"recovery_codes_remaining": status.RecoveryCodesRemaining}
+		}
+	}
+	tc.authController.finishLogin(c, db, &user, extra)
+}
+
+// RegenerateRecoveryCodes 作废现有恢复码并生成新的一组（需要验证码确认）
+func (tc *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	if !tc.requireSession(c) {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	if !tc.checkCode(c, userID, req.Code) {
+		return
+	}
+
+	codes, err := tc.twoFactorService.RegenerateRecoveryCodes(userID)
+	if err != nil {
+		response.InternalServerError(c, "生成恢复码失败")
+		return
+	}
+
+	tc.audit(c, userID, middleware.GetUsername(c), models.ActionTwoFactorRegenerate, userID)
+	response.SuccessWithMsg(c, "恢复码已重新生成，旧的恢复码已失效", gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// Disable 关闭两步验证（需要验证码确认，所属角色要求两步验证时不能关闭）
+func (tc *TwoFactorController) Disable(c *gin.Context) {
+	if !tc.requireSession(c) {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	required, err := tc.twoFactorService.Required(userID)
+	if err != nil {
+		response.InternalServerError(c, "查询两步验证状态失败")
+		return
+	}
+	if required {
+		response.Forbidden(c, auth.ErrTwoFactorRequiredByRole.Error())
+		return
+	}
+	if !tc.checkCode(c, userID, req.Code) {
+		return
+	}
+
+	if err := tc.twoFactorService.Disable(userID); err != nil {
+		response.InternalServerError(c, "关闭两步验证失败")
+		return
+	}
+
+	tc.audit(c, userID, middleware.GetUsername(c), models.ActionTwoFactorDisable, userID)
+	response.SuccessWithMsg(c, "两步验证已关闭", nil)
+}
+
+// Reset 管理员重置用户的两步验证（用户丢失验证器且没有恢复码时使用）
+// 所属角色要求两步验证的用户下次登录时需要重新绑定
+func (tc *TwoFactorController) Reset(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return
+	}
+
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		response.NotFound(c, "用户不存在")
+		return
+	}
+
+	enabled, err := tc.twoFactorService.Enabled(user.ID)
+	if err != nil {
+		response.InternalServerError(c, "查询两步验证状态失败")
+		return
+	}
+	if !enabled {
+		response.BadRequest(c, auth.ErrTwoFactorNotEnabled.Error())
+		return
+	}
+
+	if err := tc.twoFactorService.Disable(user.ID); err != nil {
+		response.InternalServerError(c, "重置两步验证失败")
+		return
+	}
+
+	tc.audit(c, middleware.GetUserID(c), middleware.GetUsername(c), models.ActionTwoFactorReset, user.ID)
+	response.SuccessWithMsg(c, "两步验证已重置", nil)
+}
+
+// resolveUser 确定操作的用户：携带挑战令牌时为待绑定的登录用户，否则为当前登录用户
+func (tc *TwoFactorController) resolveUser(c *gin.Context, challengeToken string) (*models.User, bool) {
+	var userID uint
+	if challengeToken != "" {
+		id, err := tc.twoFactorService.ChallengeUser(challengeToken, auth.ChallengeEnroll)
+		if err != nil {
+			response.Unauthorized(c, err.Error())
+			return nil, false
+		}
+		userID = id
+	} else {
+		if middleware.GetUserID(c) == 0 {
+			response.Unauthorized(c, "未登录")
+			return nil, false
+		}
+		if !tc.requireSession(c) {
+			return nil, false
+		}
+		userID = middleware.GetUserID(c)
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return nil, false
+	}
+
+	var user models.User
+	if err := db.Preload("Roles").First(&user, userID).Error; err != nil {
+		response.Unauthorized(c, "用户不存在")
+		return nil, false
+	}
+	if user.Status == models.UserStatusDisabled {
+		response.Forbidden(c, "账号已被禁用")
+		return nil, false
+	}
+	return &user, true
+}
+
+// checkCode 校验验证码，失败时写入响应
+func (tc *TwoFactorController) checkCode(c *gin.Context, userID uint, code string) bool {
+	err := tc.twoFactorService.CheckCode(userID, code)
+	switch err {
+	case nil:
+		return true
+	case auth.ErrTwoFactorCodeInvalid, auth.ErrTwoFactorNotEnabled:
+		response.BadRequest(c, err.Error())
+	default:
+		response.InternalServerError(c, "校验验证码失败")
+	}
+	return false
+}
+
+// requireSession 两步验证管理必须使用登录会话，访问令牌不能修改
+func (tc *TwoFactorController) requireSession(c *gin.Context) bool {
+	if middleware.IsAPITokenRequest(c) {
+		response.Forbidden(c, "访问令牌不能管理两步验证，请登录后操作")
+		return false
+	}
+	return true
+}
+
+// audit 记录两步验证的审计日志（启用、关闭、重置和使用恢复码）
+func (tc *TwoFactorController) audit(c *gin.Context, actorID uint, actorName, action string, targetID uint) {
+	if tc.auditService == nil {
+		return
+	}
+	tc.auditService.Log(&models.AuditLog{
+		UserID:     &actorID,
+		Username:   actorName,
+		UserIP:     c.ClientIP(),
+		Action:     action,
+		Resource:   models.ResourceUser,
+		ResourceID: &targetID,
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		StatusCode: http.StatusOK,
+		UserAgent:  c.Request.UserAgent(),
+		CreatedAt:  time.Now(),
+	})
+}
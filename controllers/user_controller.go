@@ -1,13 +1,18 @@
 package controllers
 
 import (
+	"errors"
 	"go_wails_project_manager/database"
+	"go_wails_project_manager/logger"
 	"go_wails_project_manager/middleware"
 	"go_wails_project_manager/models"
 	"go_wails_project_manager/response"
+	"go_wails_project_manager/services/auth"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // UserController 用户管理控制器
@@ -20,20 +25,22 @@ func NewUserController() *UserController {
 
 // CreateUserRequest 创建用户请求
 type CreateUserRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Password string `json:"password" binding:"required,min=6"`
-	Email    string `json:"email" binding:"required,email"`
-	Phone    string `json:"phone"`
-	RealName string `json:"real_name"`
-	RoleIDs  []uint `json:"role_ids"`
+	Username   string `json:"username" binding:"required,min=3,max=50"`
+	Password   string `json:"password" binding:"required,min=6"`
+	Email      string `json:"email" binding:"required,email"`
+	Phone      string `json:"phone"`
+	RealName   string `json:"real_name"`
+	Department string `json:"department"`
+	RoleIDs    []uint `json:"role_ids"`
 }
 
 // UpdateUserRequest 更新用户请求
 type UpdateUserRequest struct {
-	Email    string `json:"email" binding:"omitempty,email"`
-	Phone    string `json:"phone"`
-	RealName string `json:"real_name"`
-	Avatar   string `json:"avatar"`
+	Email      string  `json:"email" binding:"omitempty,email"`
+	Phone      string  `json:"phone"`
+	RealName   string  `json:"real_name"`
+	Avatar     string  `json:"avatar"`
+	Department *string `json:"department"` // 传空字符串表示移出部门
 }
 
 // List 获取用户列表
@@ -123,12 +130,13 @@ func (uc *UserController) Create(c *gin.Context) {
 
 	// 创建用户
 	user := models.User{
-		Username: req.Username,
-		Password: hashedPassword,
-		Email:    req.Email,
-		Phone:    req.Phone,
-		RealName: req.RealName,
-		Status:   models.UserStatusActive,
+		Username:   req.Username,
+		Password:   hashedPassword,
+		Email:      req.Email,
+		Phone:      req.Phone,
+		RealName:   req.RealName,
+		Department: req.Department,
+		Status:     models.UserStatusActive,
 	}
 
 	if err := db.Create(&user).Error; err != nil {
@@ -212,6 +220,9 @@ func (uc *UserController) Update(c *gin.Context) {
 	if req.Avatar != "" {
 		user.Avatar = req.Avatar
 	}
+	if req.Department != nil {
+		user.Department = *req.Department
+	}
 
 	if err := db.Save(&user).Error; err != nil {
 		response.InternalServerError(c, "更新失败")
@@ -257,8 +268,9 @@ func (uc *UserController) Delete(c *gin.Context) {
 		return
 	}
 
-	// 清除权限缓存
+	// 清除权限缓存并撤销全部会话
 	middleware.InvalidatePermissionCache(uint(id))
+	auth.NewSessionService(db).RevokeUser(uint(id), models.SessionRevokedUserDisabled, "")
 
 	response.SuccessWithMsg(c, "删除成功", nil)
 }
@@ -285,6 +297,9 @@ func (uc *UserController) Disable(c *gin.Context) {
 		return
 	}
 
+	// 撤销全部会话，已签发的 Token 立即失效
+	auth.NewSessionService(db).RevokeUser(user.ID, models.SessionRevokedUserDisabled, "")
+
 	response.SuccessWithMsg(c, "用户已禁用", nil)
 }
 
@@ -354,6 +369,9 @@ func (uc *UserController) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	// 撤销全部会话，需要使用新密码重新登录
+	auth.NewSessionService(db).RevokeUser(user.ID, models.SessionRevokedPasswordChange, "")
+
 	response.SuccessWithMsg(c, "密码重置成功", nil)
 }
 
@@ -416,3 +434,63 @@ func (uc *UserController) GetPermissions(c *gin.Context) {
 		"permissions": perms,
 	})
 }
+
+// ExplainPermission 解释用户为什么拥有（或没有）某个权限，返回命中的全部授予路径
+// 可选 resource_id 同时列出该对象上的单独授权
+func (uc *UserController) ExplainPermission(c *gin.Context) {
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	resourceID, _ := strconv.ParseUint(c.Query("resource_id"), 10, 32)
+
+	explanation, err := auth.NewPermissionCalculatorService(db).ExplainPermission(uint(id), c.Query("code"), uint(resourceID))
+	switch {
+	case err == nil:
+		response.Success(c, explanation)
+	case err == auth.ErrInvalidPermissionCode:
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.NotFound(c, "用户不存在")
+	default:
+		response.InternalServerError(c, "获取权限失败")
+	}
+}
+
+// ExportAccessMatrix 导出全部用户的角色与权限矩阵（format=json|csv，默认 json），用于定期权限审查
+func (uc *UserController) ExportAccessMatrix(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		response.BadRequest(c, "导出格式只支持 json 或 csv")
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		response.InternalServerError(c, "数据库连接失败")
+		return
+	}
+
+	matrix, err := auth.NewPermissionCalculatorService(db).AccessMatrix()
+	if err != nil {
+		response.InternalServerError(c, "生成权限矩阵失败")
+		return
+	}
+
+	if format == "json" {
+		response.Success(c, matrix)
+		return
+	}
+
+	filename := "access-matrix-" + matrix.GeneratedAt.Format("20060102") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	c.Writer.WriteString("\xEF\xBB\xBF") // UTF-8 BOM，Excel 打开时中文不乱码
+	if err := matrix.WriteCSV(c.Writer); err != nil {
+		logger.Log.Errorf("导出权限矩阵失败: %v", err)
+	}
+}
@@ -0,0 +1,401 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/auth"
+	"go_wails_project_manager/services/document"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/webdav"
+	"gorm.io/gorm"
+)
+
+const (
+	// WebDAVPrefix 文件库 WebDAV 挂载路径
+	WebDAVPrefix = "/dav/documents"
+	// webdavAuthCacheTTL 认证成功后缓存凭据的时间，避免每个请求都做 bcrypt 校验
+	webdavAuthCacheTTL = 5 * time.Minute
+	// webdavAuthCacheMax 凭据缓存的最大条数
+	webdavAuthCacheMax = 1024
+	webdavRealm        = `Basic realm="Document Library", charset="UTF-8"`
+)
+
+// WebDAVMethods WebDAV 需要注册的 HTTP 方法
+var WebDAVMethods = []string{
+	"OPTIONS", "GET", "HEAD", "PUT", "DELETE",
+	"MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK", "PROPFIND", "PROPPATCH",
+}
+
+// webdavActions WebDAV 方法需要的文件库权限，与 REST 接口一致
+// LOCK/UNLOCK 由客户端在写入前后发出，按上传处理
+var webdavActions = map[string]string{
+	"OPTIONS":   models.ActionRead,
+	"PROPFIND":  models.ActionRead,
+	"GET":       models.ActionDownload,
+	"HEAD":      models.ActionDownload,
+	"PUT":       models.ActionUpload,
+	"LOCK":      models.ActionUpload,
+	"UNLOCK":    models.ActionUpload,
+	"MKCOL":     models.ActionCreate,
+	"COPY":      models.ActionCreate,
+	"MOVE":      models.ActionUpdate,
+	"PROPPATCH": models.ActionUpdate,
+	"DELETE":    models.ActionDelete,
+}
+
+// webdavCredential 缓存的认证结果
+type webdavCredential struct {
+	userID       uint
+	username     string
+	passwordHash string // 认证时的密码哈希，修改密码后缓存不再命中
+	expiresAt    time.Time
+}
+
+// WebDAVController 文件库 WebDAV 控制器（使用 HTTP Basic 认证登录现有账号）
+// 密码可以是账号密码或个人访问令牌；启用两步验证的账号只能使用访问令牌
+type WebDAVController struct {
+	db               *gorm.DB
+	service          *document.WebDAVService
+	accessService    *document.AccessService
+	tokenService     *auth.TokenService
+	twoFactorService *auth.TwoFactorService
+	lockSystem       webdav.LockSystem
+
+	authMu    sync.Mutex
+	authCache map[string]*webdavCredential // 用户名:密码摘要 -> 认证结果
+}
+
+// NewWebDAVController 创建 WebDAV 控制器
+func NewWebDAVController(db *gorm.DB, docController *DocumentController, tokenService *auth.TokenService, twoFactorService *auth.TwoFactorService) *WebDAVController {
+	controller := &WebDAVController{
+		db: db,
+		service: document.NewWebDAVService(db, docController.config,
+			docController.uploadService, docController.trashService, docController.batchService),
+		accessService:    docController.accessService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		lockSystem:       webdav.NewMemLS(),
+		authCache:        make(map[string]*webdavCredential),
+	}
+
+	// 修改密码、禁用、全部下线后缓存的凭据立即失效
+	auth.OnUserRevoked(controller.invalidateUser)
+	return controller
+}
+
+// Handle 处理全部 WebDAV 请求
+// @Summary 文件库 WebDAV
+// @Description 支持 PROPFIND、GET、PUT、MKCOL、MOVE、COPY、DELETE、LOCK 等方法，使用 Basic 认证
+// @Tags 文件库
+// @Router /dav/documents/{path} [get]
+func (c *WebDAVController) Handle(ctx *gin.Context) {
+	userID, username, ok := c.authenticate(ctx)
+	if !ok {
+		ctx.Header("WWW-Authenticate", webdavRealm)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ctx.Set("user_id", userID)
+	ctx.Set("username", username)
+
+	// 可见范围与 REST 接口一致（包括访问令牌的权限范围和仅凭单独授权访问的用户）
+	viewer, err := documentViewer(ctx, c.accessService)
+	if err != nil {
+		logger.Log.Warnf("WebDAV 加载用户权限失败: user=%s, error=%v", username, err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	if !c.authorize(ctx, viewer) {
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	if ctx.Request.Method == "COPY" {
+		c.copy(ctx, viewer)
+		return
+	}
+
+	handler := &webdav.Handler{
+		Prefix:     WebDAVPrefix,
+		FileSystem: c.service.FileSystem(viewer, ctx.ClientIP()),
+		LockSystem: c.lockSystem,
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				logger.Log.Warnf("WebDAV %s %s 失败: user=%s, error=%v", r.Method, r.URL.Path, username, err)
+			}
+		},
+	}
+	handler.ServeHTTP(ctx.Writer, ctx.Request)
+
+	if ctx.Request.Method == http.MethodGet && ctx.Writer.Status() == http.StatusOK {
+		c.service.RecordDownload(viewer, ctx.ClientIP(), strings.TrimPrefix(ctx.Request.URL.Path, WebDAVPrefix))
+	}
+}
+
+// authorize 按请求方法检查文件库权限，规则与 REST 接口一致
+// 角色权限和单独授权按对象检查；修改类操作还要求文档在用户的可修改范围内（公开文档、项目文档只读），
+// 新建、移动和复制要求目标文件夹可修改
+func (c *WebDAVController) authorize(ctx *gin.Context, viewer *document.Viewer) bool {
+	method := ctx.Request.Method
+	action, ok := webdavActions[method]
+	if !ok {
+		return false
+	}
+
+	name := strings.TrimPrefix(ctx.Request.URL.Path, WebDAVPrefix)
+	target, err := c.service.Resolve(viewer, name)
+	if err != nil && !os.IsNotExist(err) {
+		return false
+	}
+
+	switch method {
+	case "PUT", "LOCK", "UNLOCK":
+		// 覆盖已有文件等同于上传新版本
+		if target != nil {
+			return c.allowed(ctx, target, action) && c.editable(viewer, target, models.ActionUpdate)
+		}
+		return c.allowed(ctx, nil, action) && c.canCreateIn(viewer, name)
+	case "MKCOL":
+		return c.allowed(ctx, nil, action) && c.canCreateIn(viewer, name)
+	case "DELETE", "PROPPATCH":
+		return c.allowed(ctx, target, action) && c.editable(viewer, target, action)
+	case "MOVE":
+		return c.allowed(ctx, target, action) && c.editable(viewer, target, action) &&
+			c.destinationAllowed(ctx, viewer)
+	case "COPY":
+		// 复制需要创建权限和源文档的读取权限
+		return middleware.HasPermission(ctx, models.ResourceDocuments+":"+models.ActionCreate) &&
+			c.allowed(ctx, target, models.ActionRead) && c.destinationAllowed(ctx, viewer)
+	default:
+		return c.allowed(ctx, target, action)
+	}
+}
+
+// destinationAllowed 检查 MOVE、COPY 的目标：目标文件夹需要可修改，
+// 覆盖已存在的目标时会先把目标移入回收站，还需要目标的删除权限
+func (c *WebDAVController) destinationAllowed(ctx *gin.Context, viewer *document.Viewer) bool {
+	destination, err := url.Parse(ctx.GetHeader("Destination"))
+	if err != nil {
+		// 由处理程序返回错误
+		return true
+	}
+
+	dst := strings.TrimPrefix(destination.Path, WebDAVPrefix)
+	if !c.canCreateIn(viewer, dst) {
+		return false
+	}
+	if strings.EqualFold(ctx.GetHeader("Overwrite"), "F") {
+		return true
+	}
+	existing, err := c.service.Resolve(viewer, dst)
+	if err != nil || existing == nil {
+		return true
+	}
+	return c.allowed(ctx, existing, models.ActionDelete) && c.editable(viewer, existing, models.ActionDelete)
+}
+
+// allowed 检查对文档（nil 表示根目录或尚不存在）的权限
+func (c *WebDAVController) allowed(ctx *gin.Context, target *models.Document, action string) bool {
+	var resourceID uint
+	if target != nil {
+		resourceID = target.ID
+	}
+	allowed, err := middleware.HasResourcePermission(ctx, models.ResourceDocuments, resourceID, action)
+	if err != nil {
+		logger.Log.Warnf("WebDAV 权限检查失败: user=%s, error=%v", middleware.GetUsername(ctx), err)
+		return false
+	}
+	return allowed
+}
+
+// editable 检查文档（nil 表示根目录或尚不存在）是否在用户的可修改范围内
+func (c *WebDAVController) editable(viewer *document.Viewer, target *models.Document, action string) bool {
+	if target == nil {
+		return true
+	}
+	err := c.accessService.CanEditAll(viewer, []uint{target.ID}, action)
+	if err != nil && err != document.ErrDocumentReadOnly {
+		logger.Log.Warnf("WebDAV 权限检查失败: user=%s, error=%v", viewer.Username, err)
+	}
+	return err == nil
+}
+
+// canCreateIn 检查能否在路径所在的文件夹下新建（需要上传到该文件夹的权限）
+// 父文件夹不存在时放行，由处理程序返回 409
+func (c *WebDAVController) canCreateIn(viewer *document.Viewer, name string) bool {
+	parent, err := c.service.ResolveParent(viewer, name)
+	if err != nil {
+		return err == document.ErrDavParentNotFound || err == document.ErrDavRootReadonly
+	}
+	return c.editable(viewer, parent, models.ActionUpload)
+}
+
+// copy 处理 COPY 请求
+// 复制通过批量复制服务完成，不使用 webdav 包逐个文件重新上传的实现（会被重复文件检查拒绝）
+func (c *WebDAVController) copy(ctx *gin.Context, viewer *document.Viewer) {
+	destination, err := url.Parse(ctx.GetHeader("Destination"))
+	if err != nil || destination.Host != "" && destination.Host != ctx.Request.Host {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+	if !strings.HasPrefix(destination.Path, WebDAVPrefix) {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	src := strings.TrimPrefix(ctx.Request.URL.Path, WebDAVPrefix)
+	dst := strings.TrimPrefix(destination.Path, WebDAVPrefix)
+	overwrite := !strings.EqualFold(ctx.GetHeader("Overwrite"), "F")
+	depthZero := ctx.GetHeader("Depth") == "0"
+
+	created, err := c.service.Copy(viewer, ctx.ClientIP(), src, dst, overwrite, depthZero)
+	switch err {
+	case nil:
+		if created {
+			ctx.Status(http.StatusCreated)
+		} else {
+			ctx.Status(http.StatusNoContent)
+		}
+	case document.ErrDavSourceNotFound:
+		ctx.AbortWithStatus(http.StatusNotFound)
+	case document.ErrDavParentNotFound:
+		ctx.AbortWithStatus(http.StatusConflict)
+	case document.ErrDavDestinationExist:
+		ctx.AbortWithStatus(http.StatusPreconditionFailed)
+	case document.ErrDavRootReadonly:
+		ctx.AbortWithStatus(http.StatusForbidden)
+	default:
+		logger.Log.Warnf("WebDAV COPY %s -> %s 失败: user=%s, error=%v", src, dst, viewer.Username, err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// authenticate 校验 Basic 认证，规则与登录接口一致（禁用、锁定、连续失败锁定）
+// 密码以 pmt_ 开头时按个人访问令牌认证，令牌的权限范围同样生效
+func (c *WebDAVController) authenticate(ctx *gin.Context) (uint, string, bool) {
+	username, password, ok := ctx.Request.BasicAuth()
+	if !ok || username == "" {
+		return 0, "", false
+	}
+
+	if strings.HasPrefix(password, middleware.APITokenPrefix) {
+		claims, err := c.tokenService.AuthenticateAPIToken(password, ctx.ClientIP())
+		if err != nil || claims.Username != username {
+			return 0, "", false
+		}
+		ctx.Set("claims", claims)
+		return claims.UserID, claims.Username, true
+	}
+
+	digest := sha256.Sum256([]byte(password))
+	key := username + ":" + hex.EncodeToString(digest[:])
+
+	c.authMu.Lock()
+	cached, hit := c.authCache[key]
+	if hit && time.Now().After(cached.expiresAt) {
+		delete(c.authCache, key)
+		hit = false
+	}
+	c.authMu.Unlock()
+	if hit {
+		// 缓存期间被禁用、锁定、修改密码或启用两步验证的账号立即失效
+		var user models.User
+		if err := c.db.First(&user, cached.userID).Error; err != nil || !webdavUserUsable(&user) ||
+			user.Password != cached.passwordHash || c.requiresTwoFactor(user.ID) {
+			c.invalidateUser(cached.userID)
+			return 0, "", false
+		}
+		return cached.userID, cached.username, true
+	}
+
+	var user models.User
+	if err := c.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return 0, "", false
+	}
+	if !webdavUserUsable(&user) {
+		return 0, "", false
+	}
+
+	if !middleware.CheckPassword(password, user.Password) {
+		recordLoginFailure(c.db, &user)
+		return 0, "", false
+	}
+
+	if user.LoginFailCount > 0 || user.Status == models.UserStatusLocked {
+		user.LoginFailCount = 0
+		user.LockedUntil = nil
+		user.Status = models.UserStatusActive
+		c.db.Save(&user)
+	}
+
+	// 启用（或角色要求启用）两步验证的账号不能只凭密码访问，需使用个人访问令牌
+	if c.requiresTwoFactor(user.ID) {
+		logger.Log.Infof("WebDAV 拒绝密码认证（账号已启用两步验证，请使用访问令牌）: user=%s", user.Username)
+		return 0, "", false
+	}
+
+	c.authMu.Lock()
+	if len(c.authCache) >= webdavAuthCacheMax {
+		c.evictLocked()
+	}
+	c.authCache[key] = &webdavCredential{
+		userID:       user.ID,
+		username:     user.Username,
+		passwordHash: user.Password,
+		expiresAt:    time.Now().Add(webdavAuthCacheTTL),
+	}
+	c.authMu.Unlock()
+
+	return user.ID, user.Username, true
+}
+
+// webdavUserUsable 账号能否登录 WebDAV（未禁用、未锁定、不是服务账号）
+func webdavUserUsable(user *models.User) bool {
+	return user.Status != models.UserStatusDisabled && !user.IsLocked() && !user.IsServiceAccount()
+}
+
+// requiresTwoFactor 账号是否已启用或被要求启用两步验证（查询失败时按需要处理）
+func (c *WebDAVController) requiresTwoFactor(userID uint) bool {
+	enabled, err := c.twoFactorService.Enabled(userID)
+	if err != nil || enabled {
+		return true
+	}
+	required, err := c.twoFactorService.Required(userID)
+	return err != nil || required
+}
+
+// evictLocked 缓存已满时清理过期凭据，仍然已满则全部清空（调用方持有 authMu）
+func (c *WebDAVController) evictLocked() {
+	now := time.Now()
+	for key, cached := range c.authCache {
+		if now.After(cached.expiresAt) {
+			delete(c.authCache, key)
+		}
+	}
+	if len(c.authCache) >= webdavAuthCacheMax {
+		c.authCache = make(map[string]*webdavCredential)
+	}
+}
+
+// invalidateUser 清除用户缓存的凭据
+func (c *WebDAVController) invalidateUser(userID uint) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	for key, cached := range c.authCache {
+		if cached.userID == userID {
+			delete(c.authCache, key)
+		}
+	}
+}
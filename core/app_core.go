@@ -13,9 +13,12 @@ import (
 	ai3dService "go_wails_project_manager/services/ai3d"
 	"go_wails_project_manager/services/ai3d/adapters"
 	"go_wails_project_manager/services/audit"
+	"go_wails_project_manager/services/auth"
+	"go_wails_project_manager/services/document"
 	"go_wails_project_manager/services/fileprocessor"
 	"go_wails_project_manager/services/task"
 	textureServices "go_wails_project_manager/services/texture"
+	"go_wails_project_manager/services/upload"
 	"os"
 
 	"github.com/gin-gonic/gin"
@@ -24,16 +27,23 @@ import (
 
 // AppCore 应用程序核心结构
 type AppCore struct {
-	Server                  *server.Server
-	Log                     *logrus.Logger
-	BackupScheduler         *services.BackupScheduler
-	AuditArchiveScheduler   *audit.ArchiveScheduler
-	TextureSyncService      *textureServices.SyncService
-	AI3DTaskService         *ai3dService.TaskService
-	FileProcessorService    *fileprocessor.FileProcessorService
-	FileProcessorConfig     *fileprocessor.Config
-	TaskService             *task.TaskService
-	IsRunning               bool
+	Server                    *server.Server
+	Log                       *logrus.Logger
+	BackupScheduler           *services.BackupScheduler
+	AuditService              *audit.AuditService
+	AuditArchiveScheduler     *audit.ArchiveScheduler
+	AuditExportScheduler      *audit.ExportScheduler
+	TextureSyncService        *textureServices.SyncService
+	TextureMetricsService     *textureServices.MetricsService
+	TusUploadService          *upload.TusService
+	DocumentTrashService      *document.TrashService
+	ResourcePermissionService *auth.ResourcePermissionService
+	DocumentSearchService     *document.SearchService
+	AI3DTaskService           *ai3dService.TaskService
+	FileProcessorService      *fileprocessor.FileProcessorService
+	FileProcessorConfig       *fileprocessor.Config
+	TaskService               *task.TaskService
+	IsRunning                 bool
 }
 
 // NewAppCore 创建新的应用核心实例
@@ -105,6 +115,12 @@ func (a *AppCore) InitDatabases() error {
 		return err
 	}
 
+	// 初始化贴图同步指标（启动每日汇总任务，与同步调度器相互独立）
+	if err := a.InitTextureMetricsService(); err != nil {
+		a.Log.Errorf("贴图同步指标初始化失败: %v", err)
+		return err
+	}
+
 	// 初始化AI3D服务
 	if err := a.InitAI3DService(); err != nil {
 		a.Log.Errorf("AI3D服务初始化失败: %v", err)
@@ -123,6 +139,29 @@ func (a *AppCore) InitDatabases() error {
 		return err
 	}
 
+	// 初始化断点续传上传服务
+	if err := a.InitUploadService(); err != nil {
+		a.Log.Errorf("断点续传上传服务初始化失败: %v", err)
+		return err
+	}
+
+	// 初始化文件库回收站
+	if err := a.InitDocumentTrashService(); err != nil {
+		a.Log.Errorf("文件库回收站初始化失败: %v", err)
+		return err
+	}
+
+	// 初始化对象级授权（启动过期授权清理任务）
+	if err := a.InitResourcePermissionService(); err != nil {
+		a.Log.Errorf("对象级授权服务初始化失败: %v", err)
+		return err
+	}
+
+	if err := a.InitDocumentSearchService(); err != nil {
+		a.Log.Errorf("文件库全文检索初始化失败: %v", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -341,6 +380,13 @@ func (a *AppCore) InitAuditService() error {
 		return err
 	}
 
+	if !auditConfig.SigningKeyConfigured() {
+		a.Log.Error("审计归档签名密钥未配置或仍为旧版默认值，归档将被拒绝；请设置 audit.integrity.signing_key 或环境变量 AUDIT_SIGNING_KEY")
+	}
+
+	// 创建审计日志写入服务（由 HTTP 中间件使用，停机时在 HTTP 服务器之后停止）
+	a.AuditService = audit.NewAuditService(db, auditConfig)
+
 	// 创建审计归档调度器
 	a.AuditArchiveScheduler = audit.NewArchiveScheduler(db, auditConfig)
 
@@ -349,10 +395,110 @@ func (a *AppCore) InitAuditService() error {
 		return err
 	}
 
+	// 创建并启动定时导出调度器
+	a.AuditExportScheduler = audit.NewExportScheduler(db, auditConfig)
+	if err := a.AuditExportScheduler.Start(); err != nil {
+		return err
+	}
+
 	a.Log.Info("审计服务初始化成功")
 	return nil
 }
 
+// InitUploadService 初始化断点续传上传服务（启动过期上传清理任务）
+func (a *AppCore) InitUploadService() error {
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+
+	a.TusUploadService = upload.NewTusService(db, a.Log)
+	if _, err := a.TusUploadService.CleanupExpired(); err != nil {
+		a.Log.Warnf("清理过期上传失败: %v", err)
+	}
+	a.TusUploadService.StartCleanupJob()
+
+	a.Log.Infof("断点续传上传服务初始化成功，暂存目录: %s", config.AppConfig.Upload.StorageDir)
+	return nil
+}
+
+// InitDocumentTrashService 初始化文件库回收站（启动过期项目自动清理任务）
+func (a *AppCore) InitDocumentTrashService() error {
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+
+	docConfig, err := config.LoadDocumentConfig()
+	if err != nil {
+		return err
+	}
+
+	a.DocumentTrashService = document.NewTrashService(db, a.Log, docConfig)
+	if _, err := a.DocumentTrashService.PurgeExpired(); err != nil {
+		a.Log.Warnf("清理回收站过期项目失败: %v", err)
+	}
+	a.DocumentTrashService.StartPurgeJob()
+
+	a.Log.Infof("文件库回收站初始化成功，保留天数: %d", docConfig.TrashRetentionDays)
+	return nil
+}
+
+// InitTextureMetricsService 初始化贴图同步指标（启动每日汇总任务）
+func (a *AppCore) InitTextureMetricsService() error {
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+
+	a.TextureMetricsService = textureServices.NewMetricsService(db, a.Log)
+	a.TextureMetricsService.StartRollupJob()
+
+	a.Log.Info("贴图同步指标汇总任务已启动")
+	return nil
+}
+
+// InitResourcePermissionService 初始化对象级授权服务（启动过期授权清理任务）
+func (a *AppCore) InitResourcePermissionService() error {
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+
+	a.ResourcePermissionService = auth.NewResourcePermissionService(db)
+	if _, err := a.ResourcePermissionService.CleanExpired(); err != nil {
+		a.Log.Warnf("清理过期资源授权失败: %v", err)
+	}
+	a.ResourcePermissionService.StartCleanupJob()
+
+	a.Log.Info("对象级授权服务初始化成功")
+	return nil
+}
+
+// InitDocumentSearchService 初始化文件库全文检索（后台为已有文档补建索引）
+func (a *AppCore) InitDocumentSearchService() error {
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+
+	docConfig, err := config.LoadDocumentConfig()
+	if err != nil {
+		return err
+	}
+
+	a.DocumentSearchService = document.NewSearchService(db, docConfig, a.Log)
+	a.DocumentSearchService.SetFileProcessorConfig(a.FileProcessorConfig)
+	go func() {
+		if _, err := a.DocumentSearchService.Backfill(); err != nil {
+			a.Log.Warnf("补建全文索引失败: %v", err)
+		}
+	}()
+
+	a.Log.Info("文件库全文检索初始化成功")
+	return nil
+}
+
 // StartServer 启动HTTP服务器
 func (a *AppCore) StartServer() error {
 	// 创建并启动 Gin 服务器
@@ -362,7 +508,7 @@ func (a *AppCore) StartServer() error {
 	// 添加自定义路由
 	a.Server.AddRoutes(func(router *gin.Engine) {
 		// 注册所有 API 路由（传递AI3D服务、文件处理器服务和配置）
-		api.RegisterRoutes(router, a.Log, a.AI3DTaskService, a.FileProcessorService, a.FileProcessorConfig, a.TaskService)
+		api.RegisterRoutes(router, a.Log, a.AI3DTaskService, a.FileProcessorService, a.FileProcessorConfig, a.TaskService, a.AuditService)
 	})
 
 	err := a.Server.Start()
@@ -439,6 +585,33 @@ func (a *AppCore) Shutdown() {
 		a.Log.Info("✅ 审计归档调度器已停止")
 	}
 
+	// 停止审计导出调度器
+	if a.AuditExportScheduler != nil {
+		a.Log.Info("⏳ 正在停止审计导出调度器...")
+		a.AuditExportScheduler.Stop()
+		a.Log.Info("✅ 审计导出调度器已停止")
+	}
+
+	// 停止贴图指标汇总任务
+	if a.TextureMetricsService != nil {
+		a.TextureMetricsService.StopRollupJob()
+	}
+
+	// 停止过期上传清理任务
+	if a.TusUploadService != nil {
+		a.TusUploadService.StopCleanupJob()
+	}
+
+	// 停止回收站过期清理任务
+	if a.DocumentTrashService != nil {
+		a.DocumentTrashService.StopPurgeJob()
+	}
+
+	// 停止过期授权清理任务
+	if a.ResourcePermissionService != nil {
+		a.ResourcePermissionService.StopCleanupJob()
+	}
+
 	// 6. 停止 HTTP 服务器
 	if err := a.StopServer(); err != nil {
 		a.Log.Errorf("❌ 停止服务器失败: %v", err)
@@ -446,6 +619,13 @@ func (a *AppCore) Shutdown() {
 		a.Log.Info("✅ HTTP服务器已停止")
 	}
 
+	// 停止审计服务（写入缓冲区中剩余的日志，在关闭数据库之前）
+	if a.AuditService != nil {
+		a.Log.Info("⏳ 正在写入剩余审计日志...")
+		a.AuditService.Stop()
+		a.Log.Info("✅ 审计服务已停止")
+	}
+
 	// 6. 关闭数据库连接
 	a.Log.Info("⏳ 正在关闭数据库连接...")
 	if err := database.Close(); err != nil {
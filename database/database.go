@@ -103,6 +103,12 @@ func RunMigrations() error {
 		&models.TextureSyncLog{},
 		&models.DownloadQueue{},
 		&models.TextureMetrics{},
+		&models.TextureSourceMetrics{},
+		&models.TextureSyncEvent{},
+		&models.ColorPalette{},   // 材质/资产主色调色板
+		&models.TusUpload{},      // 断点续传上传记录
+		&models.ShareLink{},      // 分享链接
+		&models.ShareAccessLog{}, // 分享链接访问日志
 		// 模型库相关表
 		&models.Model{},
 		&models.ModelTag{},
@@ -117,6 +123,7 @@ func RunMigrations() error {
 		&models.DocumentMetadata{},
 		&models.DocumentAccessLog{},
 		&models.DocumentMetrics{},
+		&models.DocumentTextIndex{}, // 全文索引状态
 		// 项目管理相关表
 		&models.Project{},
 		&models.ProjectVersion{},
@@ -137,6 +144,14 @@ func RunMigrations() error {
 		&models.Permission{},
 		&models.PermissionGroup{},
 		&models.ResourcePermission{},
+		&models.UserSession{},
+		&models.APIToken{},
+		&models.UserIdentity{},
+		&models.UserTOTP{},
+		&models.UserRecoveryCode{},
+		// 审计日志（哈希链字段需要随模型更新）
+		&models.AuditLog{},
+		&models.AuditChainHead{},
 	)
 	if err != nil {
 		return err
@@ -153,6 +168,11 @@ func RunMigrations() error {
 		logger.Log.Warnf("创建贴图库索引失败: %v", err)
 	}
 
+	// 创建文件库全文索引
+	if err := createDocumentSearchIndex(); err != nil {
+		logger.Log.Warnf("创建文件库全文索引失败: %v", err)
+	}
+
 	// 创建默认系统配置
 	if err := createDefaultSystemConfigs(); err != nil {
 		logger.Log.Warnf("创建默认系统配置失败: %v", err)
@@ -205,6 +225,11 @@ func RunMigrations() error {
 	return nil
 }
 
+// createDocumentSearchIndex 创建文件库全文索引（FTS5 虚拟表，trigram 分词支持中文子串检索）
+func createDocumentSearchIndex() error {
+	return db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS document_fts USING fts5(content, document_id UNINDEXED, page UNINDEXED, tokenize='trigram')").Error
+}
+
 // createTextureIndexes 创建贴图库相关索引
 func createTextureIndexes() error {
 	// 创建 texture_tag 联合唯一索引
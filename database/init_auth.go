@@ -57,6 +57,7 @@ func initSystemPermissions(db *gorm.DB) error {
 		{Code: "models:delete", Name: "删除模型", Resource: "models", Action: "delete", IsSystem: true},
 		{Code: "models:download", Name: "下载模型", Resource: "models", Action: "download", IsSystem: true},
 		{Code: "models:upload", Name: "上传模型", Resource: "models", Action: "upload", IsSystem: true},
+		{Code: "models:share", Name: "分享模型", Resource: "models", Action: "share", IsSystem: true},
 		{Code: "models:admin", Name: "模型管理", Resource: "models", Action: "admin", IsSystem: true},
 
 		// 资产权限
@@ -66,6 +67,7 @@ func initSystemPermissions(db *gorm.DB) error {
 		{Code: "assets:delete", Name: "删除资产", Resource: "assets", Action: "delete", IsSystem: true},
 		{Code: "assets:download", Name: "下载资产", Resource: "assets", Action: "download", IsSystem: true},
 		{Code: "assets:upload", Name: "上传资产", Resource: "assets", Action: "upload", IsSystem: true},
+		{Code: "assets:share", Name: "分享资产", Resource: "assets", Action: "share", IsSystem: true},
 		{Code: "assets:admin", Name: "资产管理", Resource: "assets", Action: "admin", IsSystem: true},
 
 		// 贴图权限
@@ -79,6 +81,8 @@ func initSystemPermissions(db *gorm.DB) error {
 		{Code: "projects:create", Name: "创建项目", Resource: "projects", Action: "create", IsSystem: true},
 		{Code: "projects:update", Name: "更新项目", Resource: "projects", Action: "update", IsSystem: true},
 		{Code: "projects:delete", Name: "删除项目", Resource: "projects", Action: "delete", IsSystem: true},
+		{Code: "projects:upload", Name: "上传项目版本", Resource: "projects", Action: "upload", IsSystem: true},
+		{Code: "projects:download", Name: "下载项目版本", Resource: "projects", Action: "download", IsSystem: true},
 		{Code: "projects:admin", Name: "项目管理", Resource: "projects", Action: "admin", IsSystem: true},
 
 		// AI3D权限
@@ -108,6 +112,20 @@ func initSystemPermissions(db *gorm.DB) error {
 		{Code: "permissions:delete", Name: "删除权限", Resource: "permissions", Action: "delete", IsSystem: true},
 		{Code: "permissions:admin", Name: "权限管理", Resource: "permissions", Action: "admin", IsSystem: true},
 
+		// 备份权限
+		{Code: "backup:read", Name: "查看备份", Resource: "backup", Action: "read", IsSystem: true},
+		{Code: "backup:create", Name: "触发备份", Resource: "backup", Action: "create", IsSystem: true},
+		{Code: "backup:admin", Name: "恢复备份", Resource: "backup", Action: "admin", IsSystem: true},
+
+		// 安全权限
+		{Code: "security:read", Name: "查看安全状态", Resource: "security", Action: "read", IsSystem: true},
+		{Code: "security:admin", Name: "安全管理", Resource: "security", Action: "admin", Description: "封禁、解封 IP 和维护白名单", IsSystem: true},
+
+		// 审计权限
+		{Code: "audit:read", Name: "查看审计日志", Resource: "audit", Action: "read", IsSystem: true},
+		{Code: "audit:admin", Name: "审计管理", Resource: "audit", Action: "admin", Description: "手动归档审计日志", IsSystem: true},
+		{Code: "audit:export", Name: "导出审计日志", Resource: "audit", Action: "export", Description: "导出审计日志（CSV、JSON Lines、CEF）", IsSystem: true},
+
 		// 通配符权限 - 全局操作权限
 		{Code: "*:read", Name: "全局读权限", Resource: "*", Action: "read", Description: "所有资源的查看权限", IsSystem: true},
 		{Code: "*:create", Name: "全局创建权限", Resource: "*", Action: "create", Description: "所有资源的创建权限", IsSystem: true},
@@ -163,7 +181,7 @@ func initSystemPermissionGroups(db *gorm.DB) error {
 				Description: "模型的完整管理权限",
 				IsSystem:    true,
 			},
-			Permissions: []string{"models:read", "models:create", "models:update", "models:delete", "models:download", "models:upload"},
+			Permissions: []string{"models:read", "models:create", "models:update", "models:delete", "models:download", "models:upload", "models:share"},
 		},
 		{
 			Group: models.PermissionGroup{
@@ -172,7 +190,7 @@ func initSystemPermissionGroups(db *gorm.DB) error {
 				Description: "资产的完整管理权限",
 				IsSystem:    true,
 			},
-			Permissions: []string{"assets:read", "assets:create", "assets:update", "assets:delete", "assets:download", "assets:upload"},
+			Permissions: []string{"assets:read", "assets:create", "assets:update", "assets:delete", "assets:download", "assets:upload", "assets:share"},
 		},
 		{
 			Group: models.PermissionGroup{
@@ -186,7 +204,7 @@ func initSystemPermissionGroups(db *gorm.DB) error {
 				"models:read", "models:download",
 				"assets:read", "assets:download",
 				"textures:read", "textures:download",
-				"projects:read",
+				"projects:read", "projects:download",
 			},
 		},
 		{
@@ -201,7 +219,7 @@ func initSystemPermissionGroups(db *gorm.DB) error {
 				"models:read", "models:create", "models:update", "models:download", "models:upload",
 				"assets:read", "assets:create", "assets:update", "assets:download", "assets:upload",
 				"textures:read", "textures:download",
-				"projects:read", "projects:create", "projects:update",
+				"projects:read", "projects:create", "projects:update", "projects:upload", "projects:download",
 				"ai3d:read", "ai3d:create",
 			},
 		},
@@ -216,6 +234,9 @@ func initSystemPermissionGroups(db *gorm.DB) error {
 				"users:read", "users:create", "users:update", "users:delete",
 				"roles:read", "roles:create", "roles:update", "roles:delete",
 				"permissions:read", "permissions:create", "permissions:update", "permissions:delete",
+				"backup:read", "backup:create", "backup:admin",
+				"security:read", "security:admin",
+				"audit:read", "audit:admin", "audit:export",
 			},
 		},
 		{
@@ -254,14 +275,17 @@ func initSystemPermissionGroups(db *gorm.DB) error {
 			if err := db.Create(&item.Group).Error; err != nil {
 				return err
 			}
-
-			// 关联权限
-			var permissions []models.Permission
-			db.Where("code IN ?", item.Permissions).Find(&permissions)
-			if len(permissions) > 0 {
-				db.Model(&item.Group).Association("Permissions").Append(permissions)
-			}
+			existing = item.Group
 			logger.Log.Debugf("创建权限组: %s", item.Group.Code)
+		} else if !existing.IsSystem {
+			continue
+		}
+
+		// 关联权限（已有的系统权限组补齐新增的默认权限，升级后新接口的权限对原有角色生效）
+		var permissions []models.Permission
+		db.Where("code IN ?", item.Permissions).Find(&permissions)
+		if len(permissions) > 0 {
+			db.Model(&existing).Association("Permissions").Append(permissions)
 		}
 	}
 
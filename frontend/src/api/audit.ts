@@ -17,6 +17,10 @@ export interface AuditLog {
   error_msg?: string;
   user_agent?: string;
   created_at: string;
+  seq: number;
+  prev_hash: string;
+  hash: string;
+  archived?: boolean; // 来自归档文件
 }
 
 export interface AuditFilter {
@@ -31,6 +35,7 @@ export interface AuditFilter {
   status_code?: number;
   page?: number;
   page_size?: number;
+  include_archived?: boolean; // 默认包含归档文件中的日志
 }
 
 export interface AuditStatistics {
@@ -41,6 +46,37 @@ export interface AuditStatistics {
   top_ips: Array<{ ip: string; count: number }>;
 }
 
+export interface AuditChainBreak {
+  seq: number;
+  log_id: number;
+  created_at: string;
+  source: string; // database 或归档文件路径
+  reason: string;
+}
+
+export interface AuditArchiveCheck {
+  file: string;
+  record_count: number;
+  signed: boolean;
+  valid: boolean;
+  reason?: string;
+}
+
+export interface AuditVerifyReport {
+  from: string;
+  to: string;
+  verified: boolean;
+  checked: number;
+  database_records: number;
+  archived_records: number;
+  unchained: number;
+  first_seq: number;
+  last_seq: number;
+  archives: AuditArchiveCheck[];
+  breaks: number;
+  first_break?: AuditChainBreak;
+}
+
 // 查询审计日志列表
 export const getAuditLogs = (filter: AuditFilter) => {
   return http.get<{
@@ -105,3 +141,26 @@ export const listArchiveFiles = (startDate?: string, endDate?: string) => {
     params: { start_date: startDate, end_date: endDate },
   });
 };
+
+// 校验审计日志哈希链（防篡改）
+export const verifyAuditChain = (from?: string, to?: string) => {
+  return http.get<AuditVerifyReport>("/audit/verify", {
+    params: { from, to },
+  });
+};
+
+export type AuditExportFormat = "csv" | "jsonl" | "cef";
+
+// 导出审计日志（返回下载地址，按时间正序，需要 audit:export 权限）
+export const exportAuditLogs = (
+  format: AuditExportFormat,
+  filter: Omit<AuditFilter, "page" | "page_size"> = {}
+) => {
+  const params = new URLSearchParams({ format });
+  Object.entries(filter).forEach(([key, value]) => {
+    if (value !== undefined && value !== "") {
+      params.append(key, String(value));
+    }
+  });
+  return `/audit/export?${params.toString()}`;
+};
@@ -15,7 +15,8 @@ export interface RegisterRequest {
   real_name?: string;
 }
 
-export interface TokenResponse {
+// 启用两步验证的账号登录时返回 LoginChallenge 中的字段而不是 Token
+export interface TokenResponse extends Partial<LoginChallenge> {
   access_token: string;
   refresh_token?: string;
   expires_in: number;
@@ -33,6 +34,81 @@ export interface ChangePasswordRequest {
   new_password: string;
 }
 
+export interface UserSession {
+  id: number;
+  session_id: string;
+  user_id: number;
+  username: string;
+  device: string;
+  user_agent: string;
+  login_ip: string;
+  last_seen_at: string;
+  last_seen_ip: string;
+  refresh_count: number;
+  expires_at: string;
+  created_at: string;
+  current: boolean;
+}
+
+export interface APIToken {
+  id: number;
+  name: string;
+  description?: string;
+  user_id: number;
+  username: string;
+  prefix: string;
+  scopes: string;
+  allowed_ips: string;
+  expires_at: string | null;
+  last_used_at?: string;
+  last_used_ip?: string;
+  created_by: string;
+  created_at: string;
+}
+
+export interface CreateAPITokenRequest {
+  name: string;
+  description?: string;
+  scopes: string[];
+  allowed_ips?: string[];
+  expires_at?: string;
+  user_id?: number;
+}
+
+export interface CreateServiceAccountRequest {
+  username: string;
+  real_name?: string;
+  department?: string;
+  role_ids?: number[];
+}
+
+export interface LoginChallenge {
+  mfa_required?: boolean; // 需要输入两步验证码
+  mfa_setup_required?: boolean; // 所属角色要求两步验证，需要先绑定验证器
+  challenge_token: string;
+  methods?: string[];
+  expires_in: number;
+}
+
+export interface TwoFactorStatus {
+  enabled: boolean;
+  required: boolean;
+  confirmed_at?: string;
+  last_used_at?: string;
+  recovery_codes_remaining: number;
+}
+
+export interface TwoFactorSetup {
+  secret: string;
+  provisioning_uri: string;
+}
+
+export interface TwoFactorVerifyRequest {
+  challenge_token: string;
+  code?: string;
+  recovery_code?: string;
+}
+
 export interface CheckPermissionRequest {
   resource: string;
   action: string;
@@ -84,14 +160,10 @@ export const logout = () => {
 };
 
 /**
- * 刷新 Token
+ * 刷新 Token（刷新 Token 单次有效，需保存响应中新的 refresh_token）
  */
-export const refreshToken = () => {
-  return http.post<{
-    access_token: string;
-    expires_in: number;
-    token_type: string;
-  }>("auth/refresh");
+export const refreshToken = (refresh_token: string) => {
+  return http.post<TokenResponse>("auth/refresh", { refresh_token });
 };
 
 /**
@@ -114,3 +186,118 @@ export const getProfile = () => {
 export const checkPermission = (data: CheckPermissionRequest) => {
   return http.post<CheckPermissionResponse>("auth/check-permission", data);
 };
+
+/**
+ * 获取登录会话（管理员可传 user_id 或 all）
+ */
+export const getSessions = (params?: { user_id?: number; all?: boolean }) => {
+  return http.get<UserSession[]>("auth/sessions", { params });
+};
+
+/**
+ * 撤销指定会话
+ */
+export const revokeSession = (id: number) => {
+  return http.delete(`auth/sessions/${id}`);
+};
+
+/**
+ * 撤销其他全部会话（管理员可传 user_id 撤销指定用户的全部会话）
+ */
+export const revokeSessions = (params?: { user_id?: number }) => {
+  return http.delete<{ revoked: number }>("auth/sessions", { params });
+};
+
+/**
+ * 获取个人访问令牌（管理员可传 user_id 或 all）
+ */
+export const getAPITokens = (params?: { user_id?: number; all?: boolean }) => {
+  return http.get<APIToken[]>("auth/tokens", { params });
+};
+
+/**
+ * 创建个人访问令牌（明文令牌只返回一次）
+ */
+export const createAPIToken = (data: CreateAPITokenRequest) => {
+  return http.post<{ token: string; token_info: APIToken }>("auth/tokens", data);
+};
+
+/**
+ * 撤销个人访问令牌
+ */
+export const revokeAPIToken = (id: number) => {
+  return http.delete(`auth/tokens/${id}`);
+};
+
+/**
+ * 获取服务账号列表
+ */
+export const getServiceAccounts = () => {
+  return http.get("auth/service-accounts");
+};
+
+/**
+ * 创建服务账号
+ */
+export const createServiceAccount = (data: CreateServiceAccountRequest) => {
+  return http.post("auth/service-accounts", data);
+};
+
+/**
+ * 获取单点登录配置
+ */
+export const getOIDCConfig = () => {
+  return http.get<{ enabled: boolean; provider_name: string; login_url: string }>("auth/oidc/config");
+};
+
+/**
+ * 使用单点登录回调带回的一次性票据换取 Token
+ */
+export const exchangeOIDCTicket = (ticket: string) => {
+  return http.post<TokenResponse>("auth/oidc/exchange", { ticket });
+};
+
+/**
+ * 获取两步验证状态
+ */
+export const getTwoFactorStatus = () => {
+  return http.get<TwoFactorStatus>("auth/2fa");
+};
+
+/**
+ * 获取验证器绑定密钥（登录时被要求绑定的用户传入 challenge_token）
+ */
+export const setupTwoFactor = (challenge_token?: string) => {
+  return http.post<TwoFactorSetup>("auth/2fa/setup", { challenge_token });
+};
+
+/**
+ * 启用两步验证，返回的恢复码只显示一次（传入 challenge_token 时同时完成登录）
+ */
+export const enableTwoFactor = (code: string, challenge_token?: string) => {
+  return http.post<Partial<TokenResponse> & { recovery_codes: string[] }>("auth/2fa/enable", {
+    code,
+    challenge_token,
+  });
+};
+
+/**
+ * 登录时提交两步验证码或恢复码
+ */
+export const verifyTwoFactor = (data: TwoFactorVerifyRequest) => {
+  return http.post<TokenResponse & { recovery_codes_remaining?: number }>("auth/2fa/verify", data);
+};
+
+/**
+ * 重新生成恢复码（旧的全部失效）
+ */
+export const regenerateRecoveryCodes = (code: string) => {
+  return http.post<{ recovery_codes: string[] }>("auth/2fa/recovery-codes", { code });
+};
+
+/**
+ * 关闭两步验证
+ */
+export const disableTwoFactor = (code: string) => {
+  return http.post("auth/2fa/disable", { code });
+};
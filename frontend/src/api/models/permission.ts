@@ -70,6 +70,32 @@ export interface UpdatePermissionGroupRequest {
   description?: string;
 }
 
+/** 可单独授权的资源类型 */
+export type GrantableResourceType = "documents" | "models" | "projects" | "ai3d";
+
+export interface ResourcePermission {
+  id: number;
+  user_id: number;
+  resource_type: GrantableResourceType;
+  resource_id: number;
+  /** read、download、upload、update、delete、share，* 表示全部操作 */
+  permission: string;
+  granted_by: number;
+  expires_at?: string;
+  created_at: string;
+  updated_at: string;
+  user?: { id: number; username: string; real_name?: string };
+}
+
+export interface GrantResourcePermissionRequest {
+  user_id: number;
+  resource_type: GrantableResourceType;
+  resource_id: number;
+  permission: string;
+  /** 为空表示永不过期 */
+  expires_at?: string;
+}
+
 // ==================== 权限管理 API ====================
 
 /**
@@ -181,3 +207,52 @@ export const removePermissionFromGroup = (
     `permission-groups/${groupId}/permissions/${permissionId}`,
   );
 };
+
+// ==================== 对象级授权 API ====================
+
+/**
+ * 授予对象权限（同一用户、对象和操作重复授权时更新过期时间）
+ */
+export const grantResourcePermission = (data: GrantResourcePermissionRequest) => {
+  return http.post<ResourcePermission>("resource-permissions", data);
+};
+
+/**
+ * 撤销对象权限
+ */
+export const revokeResourcePermission = (id: number) => {
+  return http.delete(`resource-permissions/${id}`);
+};
+
+/**
+ * 获取用户的对象授权
+ */
+export const getUserResourcePermissions = (userId: number) => {
+  return http.get<ResourcePermission[]>(`resource-permissions/user/${userId}`);
+};
+
+/**
+ * 获取对象的授权列表
+ */
+export const getResourcePermissions = (
+  resourceType: GrantableResourceType,
+  resourceId: number,
+) => {
+  return http.get<ResourcePermission[]>(
+    `resource-permissions/resource/${resourceType}/${resourceId}`,
+  );
+};
+
+/**
+ * 检查当前用户能否对指定对象执行操作（角色权限或对象授权）
+ */
+export const checkResourcePermission = (
+  resourceType: GrantableResourceType,
+  resourceId: number,
+  permission: string,
+) => {
+  return http.post<{ has_permission: boolean; permission: string }>(
+    "auth/check-resource-permission",
+    { resource_type: resourceType, resource_id: resourceId, permission },
+  );
+};
@@ -51,6 +51,51 @@ export interface UserPermissionsResponse {
   permission_groups?: any[];
 }
 
+export interface PermissionSourceRef {
+  id: number;
+  code: string;
+  name: string;
+}
+
+export interface PermissionGrantPath {
+  type: "role" | "role_group" | "user" | "user_group";
+  role?: PermissionSourceRef;
+  group?: PermissionSourceRef;
+  /** 实际授予的权限代码（可能是通配符） */
+  permission: string;
+  match: "exact" | "resource_wildcard" | "action_wildcard" | "all";
+  path: string;
+}
+
+export interface PermissionExplanation {
+  user_id: number;
+  username: string;
+  status: string;
+  code: string;
+  granted: boolean;
+  /** 账号已禁用或锁定时为 false */
+  active: boolean;
+  paths: PermissionGrantPath[];
+  resource_id?: number;
+  resource_grants?: any[];
+}
+
+export interface AccessMatrix {
+  generated_at: string;
+  permissions: string[];
+  users: {
+    user_id: number;
+    username: string;
+    real_name: string;
+    department: string;
+    status: string;
+    account_type: string;
+    roles: string[];
+    /** 权限代码 -> 授予路径 */
+    permissions: Record<string, string[]>;
+  }[];
+}
+
 // ==================== API 方法 ====================
 
 /**
@@ -126,3 +171,23 @@ export const assignRoles = (id: number, roleIds: number[]) => {
 export const getUserPermissions = (id: number) => {
   return http.get<UserPermissionsResponse>(`users/${id}/permissions`);
 };
+
+/**
+ * 解释用户为什么拥有（或没有）某个权限
+ */
+export const explainUserPermission = (
+  id: number,
+  code: string,
+  resourceId?: number,
+) => {
+  return http.get<PermissionExplanation>(`users/${id}/permissions/explain`, {
+    params: { code, resource_id: resourceId },
+  });
+};
+
+/**
+ * 获取全部用户的角色与权限矩阵
+ */
+export const getAccessMatrix = () => {
+  return http.get<AccessMatrix>("users/permissions/matrix");
+};
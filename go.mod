@@ -21,6 +21,8 @@ require (
 	github.com/swaggo/swag v1.8.12
 	github.com/tencentyun/cos-go-sdk-v5 v0.7.71
 	github.com/wailsapp/wails/v2 v2.10.1
+	golang.org/x/net v0.42.0
+	golang.org/x/text v0.28.0
 	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/sqlite v1.6.0
 	gorm.io/gorm v1.30.0
@@ -80,9 +82,7 @@ require (
 	golang.org/x/arch v0.17.0 // indirect
 	golang.org/x/crypto v0.40.0
 	golang.org/x/image v0.30.0
-	golang.org/x/net v0.42.0 // indirect
 	golang.org/x/sys v0.40.0
-	golang.org/x/text v0.28.0 // indirect
 	golang.org/x/tools v0.35.0 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
 	modernc.org/libc v1.22.5 // indirect
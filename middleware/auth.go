@@ -2,7 +2,10 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,8 +23,44 @@ var (
 	ErrExpiredToken     = errors.New("token已过期")
 	ErrMissingToken     = errors.New("缺少token")
 	ErrInvalidSignature = errors.New("签名验证失败")
+	ErrSessionRequired  = errors.New("会话已失效，请重新登录")
+	ErrAPITokenDisabled = errors.New("未启用访问令牌认证")
 )
 
+// 令牌类型（刷新令牌不能当作访问令牌使用）
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeAPI     = "api" // 个人访问令牌
+)
+
+// APITokenPrefix 个人访问令牌前缀，以此开头的令牌按访问令牌而不是 JWT 认证
+const APITokenPrefix = "pmt_"
+
+// SessionValidator 会话校验接口，设置后访问令牌必须绑定未撤销的会话
+type SessionValidator interface {
+	ValidateSession(sessionID string, userID uint, ip string) error
+}
+
+var sessionValidator SessionValidator
+
+// SetSessionValidator 设置会话校验器
+func SetSessionValidator(validator SessionValidator) {
+	sessionValidator = validator
+}
+
+// APITokenAuthenticator 个人访问令牌认证接口，返回令牌对应的用户和权限范围
+type APITokenAuthenticator interface {
+	AuthenticateAPIToken(token, ip string) (*Claims, error)
+}
+
+var apiTokenAuthenticator APITokenAuthenticator
+
+// SetAPITokenAuthenticator 设置个人访问令牌认证器
+func SetAPITokenAuthenticator(authenticator APITokenAuthenticator) {
+	apiTokenAuthenticator = authenticator
+}
+
 // JWTConfig JWT配置
 type JWTConfig struct {
 	SecretKey     string        // 密钥
@@ -44,9 +83,12 @@ var DefaultJWTConfig = JWTConfig{
 
 // Claims 自定义JWT声明
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    uint     `json:"user_id"`
+	Username  string   `json:"username,omitempty"`
+	Role      string   `json:"role,omitempty"`
+	SessionID string   `json:"sid,omitempty"` // 登录会话ID
+	TokenType string   `json:"typ,omitempty"` // access, refresh, api（旧令牌为空，按访问令牌处理）
+	Scopes    []string `json:"scp,omitempty"` // 个人访问令牌的权限范围
 	jwt.RegisteredClaims
 }
 
@@ -64,14 +106,22 @@ func NewJWTAuth(config ...JWTConfig) *JWTAuth {
 	return &JWTAuth{config: cfg}
 }
 
-// GenerateToken 生成JWT token
+// GenerateToken 生成JWT token（不绑定会话，仅用于未启用会话校验的场景）
 func (j *JWTAuth) GenerateToken(userID uint, username, role string) (string, error) {
+	return j.GenerateSessionToken(userID, username, role, "")
+}
+
+// GenerateSessionToken 生成绑定登录会话的访问token
+func (j *JWTAuth) GenerateSessionToken(userID uint, username, role, sessionID string) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
+		TokenType: TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.config.ExpireTime)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -83,22 +133,62 @@ func (j *JWTAuth) GenerateToken(userID uint, username, role string) (string, err
 	return token.SignedString([]byte(j.config.SecretKey))
 }
 
-// GenerateRefreshToken 生成刷新token
-func (j *JWTAuth) GenerateRefreshToken(userID uint) (string, error) {
+// GenerateRefreshToken 生成刷新token，refreshID 作为 jti，由会话记录其摘要，每次刷新轮换
+func (j *JWTAuth) GenerateRefreshToken(userID uint, sessionID, refreshID string) (string, error) {
 	now := time.Now()
-	claims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(now.Add(j.config.RefreshTime)),
-		IssuedAt:  jwt.NewNumericDate(now),
-		Subject:   string(rune(userID)),
-		Issuer:    j.config.Issuer,
+	claims := Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenType: TokenTypeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        refreshID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.config.RefreshTime)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Issuer:    j.config.Issuer,
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(j.config.SecretKey))
 }
 
-// ParseToken 解析JWT token
+// ExpireTime 访问token有效期
+func (j *JWTAuth) ExpireTime() time.Duration {
+	return j.config.ExpireTime
+}
+
+// RefreshTime 刷新token有效期
+func (j *JWTAuth) RefreshTime() time.Duration {
+	return j.config.RefreshTime
+}
+
+// ParseToken 解析访问token（拒绝刷新token）
 func (j *JWTAuth) ParseToken(tokenString string) (*Claims, error) {
+	claims, err := j.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType == TokenTypeRefresh {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ParseRefreshToken 解析刷新token
+func (j *JWTAuth) ParseRefreshToken(tokenString string) (*Claims, error) {
+	claims, err := j.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeRefresh || claims.SessionID == "" || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// parse 校验签名和有效期并解析声明
+func (j *JWTAuth) parse(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrInvalidSignature
@@ -120,21 +210,6 @@ func (j *JWTAuth) ParseToken(tokenString string) (*Claims, error) {
 	return nil, ErrInvalidToken
 }
 
-// RefreshToken 刷新token
-func (j *JWTAuth) RefreshToken(tokenString string) (string, error) {
-	claims, err := j.ParseToken(tokenString)
-	if err != nil && !errors.Is(err, ErrExpiredToken) {
-		return "", err
-	}
-
-	// 即使token过期，只要在刷新时间内就可以刷新
-	if claims != nil {
-		return j.GenerateToken(claims.UserID, claims.Username, claims.Role)
-	}
-
-	return "", ErrInvalidToken
-}
-
 // AuthMiddleware 认证中间件
 func (j *JWTAuth) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -145,7 +220,7 @@ func (j *JWTAuth) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := j.ParseToken(tokenString)
+		claims, err := j.authenticate(c, tokenString)
 		if err != nil {
 			logger.Log.Warnf("JWT认证失败: %v", err)
 			response.Unauthorized(c, err.Error())
@@ -154,10 +229,7 @@ func (j *JWTAuth) AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// 将用户信息存入上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
-		c.Set("claims", claims)
+		setClaims(c, claims)
 
 		c.Next()
 	}
@@ -168,17 +240,60 @@ func (j *JWTAuth) OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := j.extractToken(c)
 		if tokenString != "" {
-			if claims, err := j.ParseToken(tokenString); err == nil {
-				c.Set("user_id", claims.UserID)
-				c.Set("username", claims.Username)
-				c.Set("role", claims.Role)
-				c.Set("claims", claims)
+			if claims, err := j.authenticate(c, tokenString); err == nil {
+				setClaims(c, claims)
 			}
 		}
 		c.Next()
 	}
 }
 
+// authenticate 认证请求携带的令牌：个人访问令牌交给令牌认证器，其余按 JWT 解析并校验会话
+func (j *JWTAuth) authenticate(c *gin.Context, tokenString string) (*Claims, error) {
+	if strings.HasPrefix(tokenString, APITokenPrefix) {
+		if apiTokenAuthenticator == nil {
+			return nil, ErrAPITokenDisabled
+		}
+		return apiTokenAuthenticator.AuthenticateAPIToken(tokenString, c.ClientIP())
+	}
+
+	claims, err := j.ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSession(c, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validateSession 校验访问token绑定的会话（未设置会话校验器时不校验）
+func validateSession(c *gin.Context, claims *Claims) error {
+	if sessionValidator == nil {
+		return nil
+	}
+	if claims.SessionID == "" {
+		return ErrSessionRequired
+	}
+	return sessionValidator.ValidateSession(claims.SessionID, claims.UserID, c.ClientIP())
+}
+
+// setClaims 将用户信息存入上下文
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+	c.Set("session_id", claims.SessionID)
+	c.Set("claims", claims)
+}
+
+// newTokenID 生成随机的 token ID
+func newTokenID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
 // RoleMiddleware 角色验证中间件
 func (j *JWTAuth) RoleMiddleware(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -247,6 +362,22 @@ func GetUsername(c *gin.Context) string {
 	return ""
 }
 
+// GetSessionID 从上下文获取登录会话ID
+func GetSessionID(c *gin.Context) string {
+	if sessionID, exists := c.Get("session_id"); exists {
+		return sessionID.(string)
+	}
+	return ""
+}
+
+// IsAPITokenRequest 当前请求是否使用个人访问令牌认证
+func IsAPITokenRequest(c *gin.Context) bool {
+	if claims, exists := c.Get("claims"); exists {
+		return claims.(*Claims).TokenType == TokenTypeAPI
+	}
+	return false
+}
+
 // GetUserRole 从上下文获取用户角色
 func GetUserRole(c *gin.Context) string {
 	if role, exists := c.Get("role"); exists {
@@ -279,16 +410,19 @@ type TokenResponse struct {
 	TokenType    string `json:"token_type"`
 }
 
-// GenerateTokenResponse 生成token响应
-func (j *JWTAuth) GenerateTokenResponse(userID uint, username, role string) (*TokenResponse, error) {
-	accessToken, err := j.GenerateToken(userID, username, role)
+// GenerateTokenResponse 生成token响应，refreshID 为空时不生成刷新token（无会话）
+func (j *JWTAuth) GenerateTokenResponse(userID uint, username, role, sessionID, refreshID string) (*TokenResponse, error) {
+	accessToken, err := j.GenerateSessionToken(userID, username, role, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := j.GenerateRefreshToken(userID)
-	if err != nil {
-		return nil, err
+	var refreshToken string
+	if refreshID != "" {
+		refreshToken, err = j.GenerateRefreshToken(userID, sessionID, refreshID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &TokenResponse{
@@ -322,7 +456,7 @@ func (j *JWTAuth) LoginHandler(validateUser func(username, password string) (uin
 			return
 		}
 
-		tokenResp, err := j.GenerateTokenResponse(userID, req.Username, role)
+		tokenResp, err := j.GenerateTokenResponse(userID, req.Username, role, "", "")
 		if err != nil {
 			response.InternalServerError(c, "生成token失败")
 			return
@@ -332,29 +466,6 @@ func (j *JWTAuth) LoginHandler(validateUser func(username, password string) (uin
 	}
 }
 
-// RefreshHandler 刷新token处理器
-func (j *JWTAuth) RefreshHandler() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		tokenString := j.extractToken(c)
-		if tokenString == "" {
-			response.Unauthorized(c, "缺少token")
-			return
-		}
-
-		newToken, err := j.RefreshToken(tokenString)
-		if err != nil {
-			response.Unauthorized(c, "刷新token失败")
-			return
-		}
-
-		response.Success(c, gin.H{
-			"access_token": newToken,
-			"expires_in":   int64(j.config.ExpireTime.Seconds()),
-			"token_type":   j.config.TokenHeadName,
-		})
-	}
-}
-
 // LogoutHandler 登出处理器
 func (j *JWTAuth) LogoutHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -1,17 +1,21 @@
 package middleware
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
 // LargeFileUpload 大文件上传中间件
-// 移除请求体大小限制，支持大文件上传
+// 取消服务器默认的读写超时（5 分钟），避免多 GB 文件或慢速网络下上传被中途断开
 func LargeFileUpload() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Gin 默认没有请求体大小限制
-		// 这个中间件主要用于确保没有其他中间件添加了限制
-		// 并且可以在这里添加自定义的验证逻辑
-		
+		rc := http.NewResponseController(c.Writer)
+		// 部分 ResponseWriter 不支持设置超时，忽略错误即可
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+
 		c.Next()
 	}
 }
@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"go_wails_project_manager/response"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,11 +31,50 @@ func SetPermissionCalculator(calculator PermissionCalculator) {
 	permCalculator = calculator
 }
 
+// ResourcePermissionChecker 对象级授权检查接口（resource_permissions 表中单独授予的权限）
+type ResourcePermissionChecker interface {
+	HasResourceGrant(userID uint, resourceType string, resourceID uint, permission string) (bool, error)
+	GrantedResourceIDs(userID uint, resourceType, permission string) ([]uint, error)
+}
+
+var resourceChecker ResourcePermissionChecker
+
+// SetResourcePermissionChecker 设置对象级授权检查器，未设置时只按角色权限判断
+func SetResourcePermissionChecker(checker ResourcePermissionChecker) {
+	resourceChecker = checker
+}
+
+// anonymousRead 允许未登录查看和下载的资源（公开资源库）
+var anonymousRead = map[string]bool{}
+
+// SetAnonymousRead 设置允许未登录查看和下载的资源，只对 read 和 download 操作生效
+func SetAnonymousRead(resources []string) {
+	allowed := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		allowed[resource] = true
+	}
+	anonymousRead = allowed
+}
+
+// AnonymousAllows 未登录请求是否允许执行指定权限
+func AnonymousAllows(required string) bool {
+	parts := strings.Split(required, ":")
+	if len(parts) != 2 || !anonymousRead[parts[0]] {
+		return false
+	}
+	return parts[1] == "read" || parts[1] == "download"
+}
+
 // RequirePermission 权限验证中间件
+// 未登录请求只有在资源开启了匿名只读（SetAnonymousRead）且操作为 read/download 时放行
 func RequirePermission(resource, action string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := GetUserID(c)
 		if userID == 0 {
+			if AnonymousAllows(resource + ":" + action) {
+				c.Next()
+				return
+			}
 			response.Unauthorized(c, "请先登录")
 			c.Abort()
 			return
@@ -50,7 +90,7 @@ func RequirePermission(resource, action string) gin.HandlerFunc {
 
 		// 检查权限
 		required := fmt.Sprintf("%s:%s", resource, action)
-		if !MatchPermission(perms, required) {
+		if !MatchPermission(perms, required) || !TokenScopeAllows(c, required) {
 			response.Forbidden(c, "权限不足")
 			c.Abort()
 			return
@@ -65,6 +105,12 @@ func RequireAnyPermission(permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := GetUserID(c)
 		if userID == 0 {
+			for _, perm := range permissions {
+				if AnonymousAllows(perm) {
+					c.Next()
+					return
+				}
+			}
 			response.Unauthorized(c, "请先登录")
 			c.Abort()
 			return
@@ -78,7 +124,7 @@ func RequireAnyPermission(permissions ...string) gin.HandlerFunc {
 		}
 
 		for _, perm := range permissions {
-			if MatchPermission(userPerms, perm) {
+			if MatchPermission(userPerms, perm) && TokenScopeAllows(c, perm) {
 				c.Next()
 				return
 			}
@@ -107,7 +153,7 @@ func RequireAllPermissions(permissions ...string) gin.HandlerFunc {
 		}
 
 		for _, perm := range permissions {
-			if !MatchPermission(userPerms, perm) {
+			if !MatchPermission(userPerms, perm) || !TokenScopeAllows(c, perm) {
 				response.Forbidden(c, "权限不足")
 				c.Abort()
 				return
@@ -118,6 +164,145 @@ func RequireAllPermissions(permissions ...string) gin.HandlerFunc {
 	}
 }
 
+// RequireResourcePermission 对象级权限验证，路径参数 id 为对象ID
+// 拥有角色权限（resource:action）或被单独授予了该对象的权限时放行
+func RequireResourcePermission(resource, action string) gin.HandlerFunc {
+	return RequireResourcePermissionFunc(resource, action, func(c *gin.Context) uint {
+		resourceID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+		return uint(resourceID)
+	})
+}
+
+// RequireResourcePermissionFunc 对象级权限验证，对象ID由 resolve 从请求中解析（如由版本ID查询所属项目）
+// resolve 返回 0 表示找不到对象，此时只检查角色权限，由处理函数返回不存在
+func RequireResourcePermissionFunc(resource, action string, resolve func(c *gin.Context) uint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserID(c)
+		if userID == 0 {
+			if AnonymousAllows(resource + ":" + action) {
+				c.Next()
+				return
+			}
+			response.Unauthorized(c, "请先登录")
+			c.Abort()
+			return
+		}
+
+		allowed, err := HasResourcePermission(c, resource, resolve(c), action)
+		if err != nil {
+			response.InternalServerError(c, "获取权限失败")
+			c.Abort()
+			return
+		}
+		if !allowed {
+			response.Forbidden(c, "无权访问此资源")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireResourceListPermission 列表接口权限验证
+// 没有角色权限但被单独授予过该类资源中任一对象的权限时也放行，列表由 VisibleResourceIDs 过滤
+func RequireResourceListPermission(resource, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserID(c)
+		if userID == 0 {
+			if AnonymousAllows(resource + ":" + action) {
+				c.Next()
+				return
+			}
+			response.Unauthorized(c, "请先登录")
+			c.Abort()
+			return
+		}
+
+		ids, err := VisibleResourceIDs(c, resource, action)
+		if err != nil {
+			response.InternalServerError(c, "获取权限失败")
+			c.Abort()
+			return
+		}
+		if ids != nil && len(ids) == 0 {
+			response.Forbidden(c, "权限不足")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasResourcePermission 当前请求能否对指定对象执行操作（角色权限或对象授权，个人访问令牌还要在权限范围内）
+func HasResourcePermission(c *gin.Context, resource string, resourceID uint, action string) (bool, error) {
+	required := resource + ":" + action
+	if !TokenScopeAllows(c, required) {
+		return false, nil
+	}
+	userID := GetUserID(c)
+	if userID == 0 {
+		return false, nil
+	}
+
+	perms, err := GetUserPermissions(userID)
+	if err != nil {
+		return false, err
+	}
+	if MatchPermission(perms, required) {
+		return true, nil
+	}
+	if resourceChecker == nil || resourceID == 0 {
+		return false, nil
+	}
+	return resourceChecker.HasResourceGrant(userID, resource, resourceID, action)
+}
+
+// VisibleResourceIDs 返回列表接口需要限制的对象ID
+// 拥有角色权限或匿名访问时返回 nil（不限制），否则返回被单独授权的对象ID（可能为空）
+func VisibleResourceIDs(c *gin.Context, resource, action string) ([]uint, error) {
+	userID := GetUserID(c)
+	if userID == 0 || HasPermission(c, resource+":"+action) {
+		return nil, nil
+	}
+	if resourceChecker == nil || !TokenScopeAllows(c, resource+":"+action) {
+		return []uint{}, nil
+	}
+
+	ids, err := resourceChecker.GrantedResourceIDs(userID, resource, action)
+	if err != nil {
+		return nil, err
+	}
+	if ids == nil {
+		ids = []uint{}
+	}
+	return ids, nil
+}
+
+// HasPermission 当前请求是否拥有指定权限（用户权限，个人访问令牌还要在权限范围内）
+func HasPermission(c *gin.Context, required string) bool {
+	userID := GetUserID(c)
+	if userID == 0 {
+		return false
+	}
+	perms, err := GetUserPermissions(userID)
+	return err == nil && MatchPermission(perms, required) && TokenScopeAllows(c, required)
+}
+
+// TokenScopeAllows 个人访问令牌的权限范围是否包含指定权限（非令牌请求不受限制）
+func TokenScopeAllows(c *gin.Context, required string) bool {
+	value, exists := c.Get("claims")
+	if !exists {
+		return true
+	}
+	claims := value.(*Claims)
+	if claims.TokenType != TokenTypeAPI {
+		return true
+	}
+	return MatchPermission(claims.Scopes, required)
+}
+
 // GetUserPermissions 获取用户所有权限
 func GetUserPermissions(userID uint) ([]string, error) {
 	// 检查缓存
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIToken 个人访问令牌（供 DCC 插件、CI 等工具调用接口，只保存令牌摘要）
+type APIToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"size:100" json:"name"`
+	Description string     `gorm:"size:500" json:"description,omitempty"`
+	UserID      uint       `gorm:"index" json:"user_id"` // 令牌所属用户（普通用户或服务账号）
+	Username    string     `gorm:"size:50" json:"username"`
+	Prefix      string     `gorm:"size:20;index" json:"prefix"`  // 令牌前缀，用于识别令牌（如 pmt_1a2b3c4d）
+	TokenHash   string     `gorm:"size:64;uniqueIndex" json:"-"` // 完整令牌的 SHA-256
+	Scopes      string     `gorm:"type:text" json:"scopes"`      // 权限范围，逗号分隔，如 models:create,projects:upload
+	AllowedIPs  string     `gorm:"type:text" json:"allowed_ips"` // 允许的来源 IP 或 CIDR，逗号分隔，为空不限制
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"`      // 为空表示永不过期
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  string     `gorm:"size:50" json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedBy   string     `gorm:"size:50" json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// ScopeList 权限范围列表
+func (t *APIToken) ScopeList() []string {
+	return splitList(t.Scopes)
+}
+
+// AllowedIPList 允许的来源列表
+func (t *APIToken) AllowedIPList() []string {
+	return splitList(t.AllowedIPs)
+}
+
+// IsExpired 是否已过期
+func (t *APIToken) IsExpired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// IsActive 令牌是否有效（未撤销且未过期）
+func (t *APIToken) IsActive() bool {
+	return t.RevokedAt == nil && !t.IsExpired()
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
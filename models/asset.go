@@ -21,10 +21,12 @@ type Asset struct {
 	FileSize      int64      `json:"file_size"` // 字节
 	FilePath      string     `gorm:"size:512" json:"file_path"` // 资产文件相对路径
 	FileHash      string     `gorm:"size:64;index" json:"file_hash"` // MD5
+	PerceptualHash string    `gorm:"size:16;index" json:"perceptual_hash"` // dHash，用于相似图片检测
 	Format        string     `gorm:"size:20" json:"format"` // jpg, png, webp, mp4, webm
 	
 	// 预览图
 	ThumbnailPath string     `gorm:"size:512" json:"thumbnail_path"` // 缩略图路径
+	DominantColors string    `gorm:"size:100" json:"dominant_colors"` // 主色，逗号分隔，如: "#8a3b2c,#c9a27e"
 	
 	// 使用统计
 	UseCount      int        `gorm:"default:0;index" json:"use_count"`
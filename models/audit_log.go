@@ -22,6 +22,13 @@ type AuditLog struct {
 	ErrorMsg     string    `gorm:"type:text" json:"error_msg,omitempty"`              // 错误信息
 	UserAgent    string    `gorm:"size:512" json:"user_agent,omitempty"`              // 用户代理
 	CreatedAt    time.Time `gorm:"index" json:"created_at"`                           // 创建时间
+
+	// 哈希链（防篡改）：每条记录的哈希覆盖自身内容和上一条记录的哈希
+	Seq      uint64 `gorm:"index" json:"seq"`              // 链上序号，从 1 开始连续递增（0 表示启用哈希链之前的旧记录）
+	PrevHash string `gorm:"size:64" json:"prev_hash"`      // 上一条记录的哈希（第一条为空）
+	Hash     string `gorm:"size:64;index" json:"hash"`     // 本条记录的 SHA-256 哈希
+
+	Archived bool `gorm:"-" json:"archived,omitempty"` // 查询结果来自归档文件（不入库）
 }
 
 // TableName 指定表名
@@ -29,6 +36,20 @@ func (AuditLog) TableName() string {
 	return "audit_logs"
 }
 
+// AuditChainHead 审计哈希链链头（只有一行），记录最后写入的序号和哈希
+// 归档会删除数据库中的旧记录，链头保证新记录始终接在上一条之后，也用于发现链尾记录被删除
+type AuditChainHead struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	LastSeq   uint64    `json:"last_seq"`
+	LastHash  string    `gorm:"size:64" json:"last_hash"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (AuditChainHead) TableName() string {
+	return "audit_chain_heads"
+}
+
 // 操作类型常量
 const (
 	ActionLogin    = "login"     // 登录
@@ -44,6 +65,13 @@ const (
 	ActionShare    = "share"     // 分享
 	ActionExport   = "export"    // 导出
 	ActionImport   = "import"    // 导入
+
+	// 两步验证
+	ActionTwoFactorEnable     = "2fa_enable"     // 启用两步验证
+	ActionTwoFactorDisable    = "2fa_disable"    // 关闭两步验证
+	ActionTwoFactorReset      = "2fa_reset"      // 管理员重置两步验证
+	ActionTwoFactorRecovery   = "2fa_recovery"   // 使用恢复码登录
+	ActionTwoFactorRegenerate = "2fa_regenerate" // 重新生成恢复码
 )
 
 // 资源类型常量
@@ -54,4 +82,5 @@ const (
 	ResourceProject  = "project"  // 项目
 	ResourceModel    = "model"    // 模型
 	ResourceTexture  = "texture"  // 材质
+	ResourceAsset    = "asset"    // 资产
 )
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ColorPalette 主色调色板表（材质预览图、资产图片的 k-means 主色）
+type ColorPalette struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	OwnerType string    `gorm:"size:20;index:idx_palette_owner" json:"owner_type"` // texture, asset
+	OwnerID   uint      `gorm:"index:idx_palette_owner" json:"owner_id"`
+	Rank      int       `json:"rank"`                   // 按占比排序，0 为最主要颜色
+	Hex       string    `gorm:"size:7" json:"hex"`      // #aabbcc
+	L         float64   `gorm:"column:lab_l" json:"l"`  // CIE Lab 亮度
+	A         float64   `gorm:"column:lab_a" json:"a"`  // CIE Lab 红绿分量
+	B         float64   `gorm:"column:lab_b" json:"b"`  // CIE Lab 黄蓝分量
+	Weight    float64   `json:"weight"`                 // 像素占比 0-1
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -31,6 +31,7 @@ type Document struct {
 	FileSize      int64  `json:"file_size,omitempty"`                      // 字节
 	FilePath      string `gorm:"size:512" json:"file_path,omitempty"`      // 相对路径：documents/2026/02/09/1/file.pdf
 	FileHash      string `gorm:"size:64;index" json:"file_hash,omitempty"` // MD5
+	PerceptualHash string `gorm:"size:16;index" json:"perceptual_hash,omitempty"` // 图片或预览图 dHash，用于相似检测
 	Format        string `gorm:"size:20" json:"format,omitempty"`          // pdf, docx, mp4, zip
 
 	// 预览（相对路径，仅文件有效）
@@ -59,6 +60,11 @@ type Document struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
+	// 回收站（软删除）
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	TrashRootID *uint          `gorm:"index" json:"trash_root_id,omitempty"` // 随哪个项目一起被删除（回收站顶层项目的ID）
+	DeletedBy   string         `gorm:"size:100" json:"deleted_by,omitempty"`
+
 	// 虚拟字段，不存储到数据库
 	FileURL           string   `gorm:"-" json:"file_url,omitempty"`
 	ThumbnailURL      string   `gorm:"-" json:"thumbnail_url,omitempty"`
@@ -89,9 +95,9 @@ func (d *Document) AfterFind(tx *gorm.DB) error {
 	return nil
 }
 
-// AfterCreate GORM 钩子：创建后更新父文件夹统计
+// AfterCreate GORM 钩子：创建后更新父文件夹统计（历史版本不计入统计）
 func (d *Document) AfterCreate(tx *gorm.DB) error {
-	if d.ParentID != nil {
+	if d.ParentID != nil && d.IsLatest {
 		// 更新直接父文件夹的子项数量
 		if err := updateParentChildCount(tx, *d.ParentID); err != nil {
 			return err
@@ -104,9 +110,9 @@ func (d *Document) AfterCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// AfterDelete GORM 钩子：删除后更新父文件夹统计
+// AfterDelete GORM 钩子：删除后更新父文件夹统计（历史版本不计入统计）
 func (d *Document) AfterDelete(tx *gorm.DB) error {
-	if d.ParentID != nil {
+	if d.ParentID != nil && d.IsLatest {
 		// 更新直接父文件夹的子项数量
 		if err := updateParentChildCount(tx, *d.ParentID); err != nil {
 			return err
@@ -156,10 +162,60 @@ func (d *Document) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
-// updateParentChildCount 更新父文件夹的子项数量
+// AttachToParent 将文档（文件夹含递归统计）重新计入父文件夹统计，用于回收站还原
+func AttachToParent(tx *gorm.DB, d *Document) error {
+	if d.ParentID == nil || !d.IsLatest {
+		return nil
+	}
+	if err := updateParentChildCount(tx, *d.ParentID); err != nil {
+		return err
+	}
+	if d.IsFolder {
+		return updateAncestorStats(tx, *d.ParentID, d.TotalSize, d.TotalCount)
+	}
+	return updateAncestorStats(tx, *d.ParentID, d.FileSize, 1)
+}
+
+// DetachFromParent 将文件从父文件夹统计中扣除，用于上传新版本时旧版本转为历史版本
+func DetachFromParent(tx *gorm.DB, d *Document) error {
+	if d.ParentID == nil || d.IsFolder {
+		return nil
+	}
+	if err := updateParentChildCount(tx, *d.ParentID); err != nil {
+		return err
+	}
+	return updateAncestorStats(tx, *d.ParentID, -d.FileSize, -1)
+}
+
+// MoveToParent 将文档（文件夹含递归统计）移动到新的父文件夹，同时更新新旧父文件夹的统计
+// 不经过 BeforeUpdate 钩子，调用方需保证 d 的统计字段为最新值
+func MoveToParent(tx *gorm.DB, d *Document, parentID *uint) error {
+	oldParentID := d.ParentID
+	if err := tx.Model(&Document{}).Where("id = ?", d.ID).UpdateColumn("parent_id", parentID).Error; err != nil {
+		return err
+	}
+
+	if oldParentID != nil && d.IsLatest {
+		if err := updateParentChildCount(tx, *oldParentID); err != nil {
+			return err
+		}
+		sizeChange, countChange := -d.FileSize, -1
+		if d.IsFolder {
+			sizeChange, countChange = -d.TotalSize, -d.TotalCount
+		}
+		if err := updateAncestorStats(tx, *oldParentID, sizeChange, countChange); err != nil {
+			return err
+		}
+	}
+
+	d.ParentID = parentID
+	return AttachToParent(tx, d)
+}
+
+// updateParentChildCount 更新父文件夹的子项数量（仅统计最新版本）
 func updateParentChildCount(tx *gorm.DB, parentID uint) error {
 	var count int64
-	if err := tx.Model(&Document{}).Where("parent_id = ?", parentID).Count(&count).Error; err != nil {
+	if err := tx.Model(&Document{}).Where("parent_id = ? AND is_latest = ?", parentID, true).Count(&count).Error; err != nil {
 		return err
 	}
 	return tx.Model(&Document{}).Where("id = ?", parentID).Update("child_count", count).Error
@@ -217,7 +273,7 @@ func calculateFolderStatsRecursive(tx *gorm.DB, folderID uint, totalSize *int64,
 	// 查询直接子项
 	var children []Document
 	if err := tx.Select("id", "is_folder", "file_size").
-		Where("parent_id = ?", folderID).
+		Where("parent_id = ? AND is_latest = ?", folderID, true).
 		Find(&children).Error; err != nil {
 		return err
 	}
@@ -336,6 +392,27 @@ type DocumentMetrics struct {
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// DocumentTextIndex 全文索引状态表（正文存储在 FTS5 虚拟表 document_fts 中）
+type DocumentTextIndex struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	DocumentID uint      `gorm:"uniqueIndex" json:"document_id"`
+	Status     string    `gorm:"size:20;index" json:"status"` // indexed, empty, failed, unsupported
+	PageCount  int       `json:"page_count"`
+	CharCount  int       `json:"char_count"`
+	ErrorMsg   string    `gorm:"type:text" json:"error_msg,omitempty"`
+	IndexedAt  time.Time `json:"indexed_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// 全文索引状态常量
+const (
+	TextIndexIndexed     = "indexed"     // 已索引
+	TextIndexEmpty       = "empty"       // 无可提取的文本（如扫描件）
+	TextIndexFailed      = "failed"      // 提取失败
+	TextIndexUnsupported = "unsupported" // 格式不支持
+)
+
 // 文档类型常量
 const (
 	TypeFolder   = "folder"   // 文件夹
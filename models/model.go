@@ -21,6 +21,7 @@ type Model struct {
 	FileSize      int64      `json:"file_size"` // 字节
 	FilePath      string     `gorm:"size:512" json:"file_path"` // 模型文件相对路径
 	FileHash      string     `gorm:"size:64;index" json:"file_hash"` // MD5
+	PerceptualHash string    `gorm:"size:16;index" json:"perceptual_hash"` // 预览图 dHash，用于相似检测
 	
 	// 预览图
 	ThumbnailPath string     `gorm:"size:512" json:"thumbnail_path"` // 缩略图路径
@@ -34,12 +34,16 @@ const (
 	ResourceUsers       = "users"
 	ResourceRoles       = "roles"
 	ResourcePermissions = "permissions"
+	ResourceBackup      = "backup"   // 备份和恢复
+	ResourceSecurity    = "security" // IP 封禁和白名单
+	ResourceAudit       = "audit"    // 审计日志
 	ResourceAll         = "*" // 所有资源
 )
 
 // 操作类型常量（使用 audit_log.go 中已定义的常量）
-// ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionDownload, ActionUpload, ActionShare 已在 audit_log.go 中定义
+// ActionCreate, ActionUpdate, ActionDelete, ActionDownload, ActionUpload, ActionShare 已在 audit_log.go 中定义
 const (
+	ActionRead  = "read"
 	ActionAdmin = "admin"
 	ActionAll   = "*" // 所有操作
 )
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // ResourcePermission 资源权限表
@@ -20,7 +22,7 @@ type ResourcePermission struct {
 	UpdatedAt    time.Time  `json:"updated_at"`
 	
 	// 关联
-	User         User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	User         *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 // TableName 指定表名
@@ -28,6 +30,16 @@ func (ResourcePermission) TableName() string {
 	return "resource_permissions"
 }
 
+// DeleteResourcePermissions 删除对象的全部单独授权
+// 对象被删除时调用，避免授权残留，以及 ID 被复用后旧授权落到新对象上
+func DeleteResourcePermissions(tx *gorm.DB, resourceType string, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Where("resource_type = ? AND resource_id IN ?", resourceType, ids).
+		Delete(&ResourcePermission{}).Error
+}
+
 // IsExpired 是否已过期
 func (rp *ResourcePermission) IsExpired() bool {
 	if rp.ExpiresAt == nil {
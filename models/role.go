@@ -11,6 +11,7 @@ type Role struct {
 	Name             string            `gorm:"size:100" json:"name"`
 	Description      string            `gorm:"size:500" json:"description,omitempty"`
 	IsSystem         bool              `gorm:"default:false;index" json:"is_system"` // 系统预设不可删除
+	RequireTwoFactor bool              `gorm:"default:false" json:"require_two_factor"` // 拥有该角色的用户必须启用两步验证
 	
 	// 关联
 	Permissions      []Permission      `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// ShareLink 分享链接（文档/文件夹、资产、模型），通过随机 token 公开访问
+type ShareLink struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Token         string     `gorm:"size:64;uniqueIndex" json:"token"`
+	ResourceType  string     `gorm:"size:20;index:idx_share_resource" json:"resource_type"` // document, asset, model
+	ResourceID    uint       `gorm:"index:idx_share_resource" json:"resource_id"`
+	Name          string     `gorm:"size:200" json:"name"`           // 创建时的资源名称
+	PasswordHash  string     `gorm:"size:100" json:"-"`              // bcrypt，为空表示无需密码
+	AccessSecret  string     `gorm:"size:64" json:"-"`               // 签名访问凭证的随机密钥，只保存在服务端
+	ExpiresAt     *time.Time `gorm:"index" json:"expires_at"`        // 为空表示永不过期
+	MaxDownloads  int        `gorm:"default:0" json:"max_downloads"` // 0 表示不限制
+	DownloadCount int        `gorm:"default:0" json:"download_count"`
+	ViewCount     int        `gorm:"default:0" json:"view_count"`
+	LastAccessAt  *time.Time `json:"last_access_at,omitempty"`
+	RevokedAt     *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	RevokedBy     string     `gorm:"size:100" json:"revoked_by,omitempty"`
+	CreatedBy     string     `gorm:"size:100;index" json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// 虚拟字段
+	HasPassword bool `gorm:"-" json:"has_password"`
+}
+
+// ShareAccessLog 分享链接访问日志（包括密码错误、过期等被拒绝的访问）
+type ShareAccessLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ShareID   uint      `gorm:"index" json:"share_id"`
+	Action    string    `gorm:"size:20;index" json:"action"` // view, list, unlock, download
+	ItemID    uint      `json:"item_id,omitempty"`           // 文件夹分享中实际访问的文档ID
+	Success   bool      `gorm:"index" json:"success"`
+	Reason    string    `gorm:"size:200" json:"reason,omitempty"` // 被拒绝的原因
+	UserIP    string    `gorm:"size:50" json:"user_ip"`
+	UserAgent string    `gorm:"size:512" json:"user_agent,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -21,6 +21,8 @@ type Texture struct {
 	DownloadCompleted bool       `gorm:"default:false;index" json:"download_completed"` // 是否已完成下载
 	Source            string     `gorm:"size:20;index;default:'polyhaven'" json:"source"` // 数据来源: polyhaven, ambientcg
 	TextureTypes      string     `gorm:"type:text" json:"texture_types"`                // 包含的贴图类型，逗号分隔，如: "Diffuse,Rough,Normal"
+	PerceptualHash    string     `gorm:"size:16;index" json:"perceptual_hash"`          // 预览图 dHash，用于相似检测
+	DominantColors    string     `gorm:"size:100" json:"dominant_colors"`               // 预览图主色，逗号分隔，如: "#8a3b2c,#c9a27e"
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 }
@@ -86,3 +88,33 @@ type TextureMetrics struct {
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
+
+// TextureSourceMetrics 按数据源统计的每日同步/下载指标
+type TextureSourceMetrics struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	Date            string    `gorm:"uniqueIndex:idx_texture_source_date;size:10" json:"date"`
+	Source          string    `gorm:"uniqueIndex:idx_texture_source_date;size:20" json:"source"`
+	SyncCount       int       `json:"sync_count"`
+	SyncFailed      int       `json:"sync_failed"`
+	DownloadCount   int       `json:"download_count"`
+	DownloadFailed  int       `json:"download_failed"`
+	DownloadSize    int64     `json:"download_size"`
+	AvgSyncTime     float64   `json:"avg_sync_time"`     // 毫秒
+	AvgDownloadTime float64   `json:"avg_download_time"` // 毫秒
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TextureSyncEvent 同步/下载事件明细（由汇总任务聚合到指标表）
+type TextureSyncEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Date       string    `gorm:"index;size:10" json:"date"`
+	Source     string    `gorm:"size:20" json:"source"`
+	Kind       string    `gorm:"size:10" json:"kind"` // sync, download
+	AssetID    string    `gorm:"size:100" json:"asset_id"`
+	Success    bool      `json:"success"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs int64     `json:"duration_ms"`
+	ErrorMsg   string    `gorm:"type:text" json:"error_msg"`
+	CreatedAt  time.Time `json:"created_at"`
+}
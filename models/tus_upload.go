@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// TusUpload 断点续传上传记录（tus 协议），完成后可挂载到文档/模型/资产/项目版本
+type TusUpload struct {
+	ID        string    `gorm:"primaryKey;size:36" json:"id"`
+	UserID    uint      `gorm:"index" json:"-"`            // 创建者，只有创建者能续传、终止和挂载
+	Length    int64     `json:"length"`                    // 总字节数（Upload-Length）
+	Offset    int64     `json:"offset"`                    // 已接收字节数（Upload-Offset）
+	Filename  string    `gorm:"size:255" json:"filename"`  // 原始文件名（Upload-Metadata filename）
+	Metadata  string    `gorm:"type:text" json:"metadata"` // 原始 Upload-Metadata 头，HEAD 时原样返回
+	FilePath  string    `gorm:"size:500" json:"-"`         // 暂存文件路径
+	Completed bool      `gorm:"default:false;index" json:"completed"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"` // 未完成上传的过期时间，每次 PATCH 顺延
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
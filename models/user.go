@@ -13,9 +13,11 @@ type User struct {
 	Phone            string            `gorm:"size:20" json:"phone,omitempty"`
 	RealName         string            `gorm:"size:50" json:"real_name,omitempty"`
 	Avatar           string            `gorm:"size:512" json:"avatar,omitempty"`
+	Department       string            `gorm:"size:50;index" json:"department,omitempty"` // 所属部门，用于文件库部门可见性
 	
 	// 状态
 	Status           string            `gorm:"default:'active';index" json:"status"` // active, disabled, locked
+	AccountType      string            `gorm:"size:20;default:'user';index" json:"account_type"` // user, service（服务账号只能使用访问令牌）
 	
 	// 关联
 	Roles            []Role            `gorm:"many2many:user_roles;" json:"roles,omitempty"`
@@ -44,6 +46,17 @@ const (
 	UserStatusLocked   = "locked"   // 锁定（登录失败过多）
 )
 
+// 账号类型常量
+const (
+	AccountTypeUser    = "user"    // 普通用户
+	AccountTypeService = "service" // 服务账号（流水线工具使用，不能通过密码登录）
+)
+
+// IsServiceAccount 是否为服务账号
+func (u *User) IsServiceAccount() bool {
+	return u.AccountType == AccountTypeService
+}
+
 // IsActive 是否激活状态
 func (u *User) IsActive() bool {
 	return u.Status == UserStatusActive
@@ -65,7 +78,9 @@ type UserResponse struct {
 	Phone       string     `json:"phone,omitempty"`
 	RealName    string     `json:"real_name,omitempty"`
 	Avatar      string     `json:"avatar,omitempty"`
+	Department  string     `json:"department,omitempty"`
 	Status      string     `json:"status"`
+	AccountType string     `json:"account_type"`
 	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 }
@@ -79,7 +94,9 @@ func (u *User) ToResponse() *UserResponse {
 		Phone:       u.Phone,
 		RealName:    u.RealName,
 		Avatar:      u.Avatar,
+		Department:  u.Department,
 		Status:      u.Status,
+		AccountType: u.AccountType,
 		LastLoginAt: u.LastLoginAt,
 		CreatedAt:   u.CreatedAt,
 	}
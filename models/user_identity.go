@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity 外部身份（OIDC 身份提供方的用户与本地用户的关联）
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	Issuer      string     `gorm:"size:255;uniqueIndex:idx_identity_subject" json:"issuer"`  // 身份提供方
+	Subject     string     `gorm:"size:255;uniqueIndex:idx_identity_subject" json:"subject"` // 身份提供方中的用户标识（sub）
+	Email       string     `gorm:"size:100" json:"email"`
+	Name        string     `gorm:"size:100" json:"name"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
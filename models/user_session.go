@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// UserSession 登录会话（每次登录创建一个，访问令牌和刷新令牌都绑定会话ID，撤销后立即失效）
+type UserSession struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	SessionID     string     `gorm:"size:64;uniqueIndex" json:"session_id"`
+	UserID        uint       `gorm:"index" json:"user_id"`
+	Username      string     `gorm:"size:50" json:"username"`
+	Device        string     `gorm:"size:100" json:"device"` // 根据 User-Agent 识别的设备描述
+	UserAgent     string     `gorm:"size:512" json:"user_agent"`
+	LoginIP       string     `gorm:"size:50" json:"login_ip"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	LastSeenIP    string     `gorm:"size:50" json:"last_seen_ip"`
+	RefreshHash   string     `gorm:"size:64" json:"-"` // 当前有效刷新令牌的 SHA-256，每次刷新轮换
+	RefreshCount  int        `gorm:"default:0" json:"refresh_count"`
+	ExpiresAt     time.Time  `gorm:"index" json:"expires_at"` // 刷新令牌过期时间，刷新时顺延
+	RevokedAt     *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	RevokedReason string     `gorm:"size:50" json:"revoked_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// 虚拟字段
+	Current bool `gorm:"-" json:"current"` // 是否为发起请求的会话
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// 会话撤销原因
+const (
+	SessionRevokedLogout         = "logout"          // 用户登出
+	SessionRevokedManual         = "revoked"         // 用户或管理员手动撤销
+	SessionRevokedPasswordChange = "password_change" // 修改或重置密码
+	SessionRevokedUserDisabled   = "user_disabled"   // 用户被禁用或删除
+	SessionRevokedRefreshReuse   = "refresh_reuse"   // 检测到已使用的刷新令牌被重放
+)
+
+// IsActive 会话是否有效（未撤销且未过期）
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
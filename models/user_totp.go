@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// UserTOTP 用户的 TOTP 两步验证配置（每个用户一条，确认验证码后才启用）
+type UserTOTP struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"uniqueIndex" json:"user_id"`
+	Secret       string     `gorm:"size:64" json:"-"` // Base32 密钥，只在绑定时返回一次
+	Enabled      bool       `gorm:"default:false" json:"enabled"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `json:"-"` // 最近一次使用的时间窗口，同一窗口的验证码不能重复使用
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserTOTP) TableName() string {
+	return "user_totps"
+}
+
+// UserRecoveryCode 两步验证恢复码（只保存哈希，每个只能使用一次）
+type UserRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
 	"go_wails_project_manager/models/ai3d"
 
 	"gorm.io/gorm"
@@ -84,9 +85,12 @@ func (s *TaskService) GetTaskByProviderID(provider, providerTaskID string) (*ai3
 	return &task, err
 }
 
-// ListTasks 任务列表
-func (s *TaskService) ListTasks(page, pageSize int, filters map[string]string) ([]*ai3d.Task, int64, error) {
+// ListTasks 任务列表，ids 非 nil 时只返回这些任务（仅凭单独授权访问的用户）
+func (s *TaskService) ListTasks(page, pageSize int, filters map[string]string, ids []uint) ([]*ai3d.Task, int64, error) {
 	query := s.db.Model(&ai3d.Task{})
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
 
 	// 应用过滤器
 	if provider := filters["provider"]; provider != "" {
@@ -252,9 +256,14 @@ func (s *TaskService) pollTaskInternal(ctx context.Context, task *ai3d.Task) err
 	return s.db.Model(task).Updates(updates).Error
 }
 
-// DeleteTask 删除任务
+// DeleteTask 删除任务（同时删除该任务的单独授权）
 func (s *TaskService) DeleteTask(id uint) error {
-	return s.db.Delete(&ai3d.Task{}, id).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&ai3d.Task{}, id).Error; err != nil {
+			return err
+		}
+		return models.DeleteResourcePermissions(tx, models.ResourceAI3D, id)
+	})
 }
 
 // GetPendingTasks 获取待处理的任务
@@ -2,7 +2,7 @@ package asset
 
 import (
 	"go_wails_project_manager/models"
-	"mime/multipart"
+	"go_wails_project_manager/services/upload"
 )
 
 // AssetProcessor 资产处理器接口
@@ -14,7 +14,7 @@ type AssetProcessor interface {
 	ExtractMetadata(filePath string) (*models.AssetMetadata, error)
 	
 	// Validate 验证文件
-	Validate(file *multipart.FileHeader) error
+	Validate(file *upload.File) error
 	
 	// SupportedFormats 获取支持的格式
 	SupportedFormats() []string
@@ -5,12 +5,12 @@ import (
 	"fmt"
 	"go_wails_project_manager/config"
 	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/upload"
 	"image"
 	"image/color"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
-	"mime/multipart"
 	"os"
 	"path/filepath"
 	"strings"
@@ -127,7 +127,7 @@ func (p *ImageProcessor) ExtractMetadata(filePath string) (*models.AssetMetadata
 }
 
 // Validate 验证文件
-func (p *ImageProcessor) Validate(file *multipart.FileHeader) error {
+func (p *ImageProcessor) Validate(file *upload.File) error {
 	// 检查文件扩展名
 	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
 	
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"go_wails_project_manager/config"
 	"go_wails_project_manager/models"
-	"mime/multipart"
+	"go_wails_project_manager/services/upload"
 	"os/exec"
 	"path/filepath"
 	"strconv"
@@ -104,7 +104,7 @@ func (p *VideoProcessor) ExtractMetadata(filePath string) (*models.AssetMetadata
 }
 
 // Validate 验证文件
-func (p *VideoProcessor) Validate(file *multipart.FileHeader) error {
+func (p *VideoProcessor) Validate(file *upload.File) error {
 	// 检查文件扩展名
 	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
 	
@@ -3,6 +3,7 @@ package asset
 import (
 	"fmt"
 	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/palette"
 	"os"
 	"time"
 	
@@ -30,6 +31,8 @@ type QueryFilters struct {
 	Keyword   string
 	SortBy    string // name, created_at, use_count, file_size
 	SortOrder string // asc, desc
+	Color     string  // 主色 #aabbcc
+	Tolerance float64 // 颜色容差（ΔE），0 使用默认值
 }
 
 // List 分页查询
@@ -60,6 +63,15 @@ func (q *QueryService) List(page, pageSize int, filters QueryFilters) ([]*models
 		}
 	}
 	
+	// 主色过滤（Lab 空间 ΔE 距离）
+	if filters.Color != "" {
+		owners, err := palette.MatchingOwners(q.db, palette.OwnerAsset, filters.Color, filters.Tolerance)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("id IN (?)", owners)
+	}
+	
 	// 排序
 	sortBy := "created_at"
 	if filters.SortBy != "" {
@@ -149,6 +161,9 @@ func (q *QueryService) Delete(id uint) error {
 	// 删除标签关联
 	q.db.Where("asset_id = ?", id).Delete(&models.AssetTag{})
 	
+	// 删除主色调色板
+	q.db.Where("owner_type = ? AND owner_id = ?", palette.OwnerAsset, id).Delete(&models.ColorPalette{})
+	
 	// 删除资产记录
 	return q.db.Delete(&asset).Error
 }
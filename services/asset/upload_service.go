@@ -10,9 +10,11 @@ import (
 	"go_wails_project_manager/logger"
 	"go_wails_project_manager/models"
 	"go_wails_project_manager/services/asset/processors"
+	"go_wails_project_manager/services/palette"
 	"go_wails_project_manager/services/storage"
+	"go_wails_project_manager/services/upload"
+	"go_wails_project_manager/utils"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -65,7 +67,7 @@ type UploadMetadata struct {
 }
 
 // Upload 上传资产
-func (s *UploadService) Upload(file *multipart.FileHeader, metadata UploadMetadata) (*models.Asset, error) {
+func (s *UploadService) Upload(file *upload.File, metadata UploadMetadata) (*models.Asset, error) {
 	// 1. 检测真实文件类型
 	realFormat, err := s.detectFileFormat(file)
 	if err != nil {
@@ -165,6 +167,17 @@ func (s *UploadService) Upload(file *multipart.FileHeader, metadata UploadMetada
 	// 保存相对路径到数据库
 	asset.ThumbnailPath = s.getThumbnailPathWithExt(asset.ID, thumbnailExt)
 	
+	// 计算感知哈希（图片使用原图，视频使用缩略图），用于相似资产检测
+	hashSource := actualFilePath
+	if asset.Type != "image" {
+		hashSource = actualThumbnailPath
+	}
+	if phash, err := utils.PerceptualHashFile(hashSource); err != nil {
+		logger.Log.Warnf("计算感知哈希失败: assetID=%d, error=%v", asset.ID, err)
+	} else {
+		asset.PerceptualHash = phash
+	}
+	
 	// 10. 提取元数据
 	assetMetadata, err := processor.ExtractMetadata(actualFilePath)
 	if err == nil {
@@ -179,14 +192,27 @@ func (s *UploadService) Upload(file *multipart.FileHeader, metadata UploadMetada
 		return nil, err
 	}
 	
-	// 12. 更新统计信息
+	// 12. 提取主色（与感知哈希使用同一图片），用于按颜色检索
+	if colors, err := palette.ExtractFile(hashSource, palette.DefaultColorCount); err != nil {
+		logger.Log.Warnf("提取主色失败: assetID=%d, error=%v", asset.ID, err)
+	} else if err := palette.Save(s.db, palette.OwnerAsset, asset.ID, colors); err != nil {
+		logger.Log.Warnf("保存主色失败: assetID=%d, error=%v", asset.ID, err)
+	} else {
+		hexes := make([]string, len(colors))
+		for i, c := range colors {
+			hexes[i] = c.Hex
+		}
+		asset.DominantColors = strings.Join(hexes, ",")
+	}
+	
+	// 13. 更新统计信息
 	s.updateMetrics(asset)
 	
 	return asset, nil
 }
 
 // saveFile 保存文件到磁盘
-func (s *UploadService) saveFile(file *multipart.FileHeader, assetID uint) (string, error) {
+func (s *UploadService) saveFile(file *upload.File, assetID uint) (string, error) {
 	// 读取文件数据
 	src, err := file.Open()
 	if err != nil {
@@ -194,19 +220,14 @@ func (s *UploadService) saveFile(file *multipart.FileHeader, assetID uint) (stri
 	}
 	defer src.Close()
 	
-	data, err := io.ReadAll(src)
-	if err != nil {
-		return "", fmt.Errorf("读取文件数据失败: %w", err)
-	}
-	
 	// 确定文件名
 	ext := filepath.Ext(file.Filename)
 	fileName := "file" + ext
 	
-	// 使用通用存储服务保存
+	// 使用通用存储服务流式保存，大文件（断点续传上传）不整体读入内存
 	subPath := fmt.Sprintf("%d", assetID)
 	
-	return s.storageService.SaveFile(subPath, fileName, data)
+	return s.storageService.SaveFileStream(subPath, fileName, src, file.Size)
 }
 
 // getThumbnailPath 获取缩略图路径
@@ -238,7 +259,7 @@ func (s *UploadService) getActualThumbnailPath(assetID uint, ext string) string
 }
 
 // calculateHash 计算文件哈希
-func (s *UploadService) calculateHash(file *multipart.FileHeader) (string, error) {
+func (s *UploadService) calculateHash(file *upload.File) (string, error) {
 	src, err := file.Open()
 	if err != nil {
 		return "", err
@@ -302,7 +323,7 @@ func (s *UploadService) updateMetrics(asset *models.Asset) {
 }
 
 // detectFileFormat 检测文件真实格式
-func (s *UploadService) detectFileFormat(file *multipart.FileHeader) (string, error) {
+func (s *UploadService) detectFileFormat(file *upload.File) (string, error) {
 	// 打开文件
 	src, err := file.Open()
 	if err != nil {
@@ -0,0 +1,204 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+)
+
+// ArchiveIndexSuffix 归档索引文件后缀（与归档文件同目录同名）
+const ArchiveIndexSuffix = ".idx"
+
+// ArchiveIndex 归档文件的索引：时间范围和各过滤字段的取值计数
+// 查询时先读索引判断归档中是否可能有匹配记录、能否直接得到匹配数，避免解压每个归档文件
+type ArchiveIndex struct {
+	ArchiveDate string         `json:"archive_date"`
+	RecordCount int            `json:"record_count"`
+	Start       time.Time      `json:"start"` // 最早记录时间
+	End         time.Time      `json:"end"`   // 最晚记录时间
+	UserIDs     map[string]int `json:"user_ids"`
+	Usernames   map[string]int `json:"usernames"`
+	UserIPs     map[string]int `json:"user_ips"`
+	Actions     map[string]int `json:"actions"`
+	Resources   map[string]int `json:"resources"`
+	ResourceIDs map[string]int `json:"resource_ids"` // 资源类型:资源ID
+	StatusCodes map[string]int `json:"status_codes"`
+}
+
+// buildArchiveIndex 根据归档内容生成索引
+func buildArchiveIndex(archive *ArchiveFile) *ArchiveIndex {
+	index := &ArchiveIndex{
+		ArchiveDate: archive.ArchiveDate,
+		RecordCount: len(archive.Logs),
+		UserIDs:     make(map[string]int),
+		Usernames:   make(map[string]int),
+		UserIPs:     make(map[string]int),
+		Actions:     make(map[string]int),
+		Resources:   make(map[string]int),
+		ResourceIDs: make(map[string]int),
+		StatusCodes: make(map[string]int),
+	}
+	for i := range archive.Logs {
+		log := &archive.Logs[i]
+		if index.Start.IsZero() || log.CreatedAt.Before(index.Start) {
+			index.Start = log.CreatedAt
+		}
+		if log.CreatedAt.After(index.End) {
+			index.End = log.CreatedAt
+		}
+		if log.UserID != nil {
+			index.UserIDs[strconv.FormatUint(uint64(*log.UserID), 10)]++
+		}
+		index.Usernames[log.Username]++
+		index.UserIPs[log.UserIP]++
+		index.Actions[log.Action]++
+		index.Resources[log.Resource]++
+		if log.ResourceID != nil {
+			index.ResourceIDs[resourceKey(log.Resource, *log.ResourceID)]++
+		}
+		index.StatusCodes[strconv.Itoa(log.StatusCode)]++
+	}
+	return index
+}
+
+// resourceKey 索引中资源ID的键
+func resourceKey(resource string, resourceID uint) string {
+	return fmt.Sprintf("%s:%d", resource, resourceID)
+}
+
+// Count 根据索引估算归档中满足过滤条件的记录数
+// exact 为 true 时计数准确（时间范围覆盖整个归档且最多一个过滤维度）；
+// 为 false 时只是上限，需要解压归档逐条过滤。计数为 0 时归档中一定没有匹配记录
+func (idx *ArchiveIndex) Count(filter AuditFilter) (count int, exact bool) {
+	if idx.RecordCount == 0 {
+		return 0, true
+	}
+	if filter.StartTime != nil && idx.End.Before(*filter.StartTime) {
+		return 0, true
+	}
+	if filter.EndTime != nil && idx.Start.After(*filter.EndTime) {
+		return 0, true
+	}
+
+	count = idx.RecordCount
+	dimensions := 0
+	narrow := func(n int) {
+		dimensions++
+		if n < count {
+			count = n
+		}
+	}
+
+	if filter.UserID != nil {
+		narrow(idx.UserIDs[strconv.FormatUint(uint64(*filter.UserID), 10)])
+	}
+	if filter.Username != "" {
+		narrow(sumMatching(idx.Usernames, func(name string) bool { return containsFold(name, filter.Username) }))
+	}
+	if filter.UserIP != "" {
+		narrow(idx.UserIPs[filter.UserIP])
+	}
+	if filter.Action != "" {
+		narrow(idx.Actions[filter.Action])
+	}
+	// 资源类型和资源ID同时指定时合并为一个维度
+	switch {
+	case filter.Resource != "" && filter.ResourceID != nil:
+		narrow(idx.ResourceIDs[resourceKey(filter.Resource, *filter.ResourceID)])
+	case filter.Resource != "":
+		narrow(idx.Resources[filter.Resource])
+	case filter.ResourceID != nil:
+		suffix := ":" + strconv.FormatUint(uint64(*filter.ResourceID), 10)
+		narrow(sumMatching(idx.ResourceIDs, func(key string) bool { return strings.HasSuffix(key, suffix) }))
+	}
+	if filter.StatusCode != nil {
+		narrow(idx.StatusCodes[strconv.Itoa(*filter.StatusCode)])
+	}
+
+	covered := (filter.StartTime == nil || !idx.Start.Before(*filter.StartTime)) &&
+		(filter.EndTime == nil || !idx.End.After(*filter.EndTime))
+	return count, count == 0 || (covered && dimensions <= 1)
+}
+
+// sumMatching 累加满足条件的取值计数
+func sumMatching(values map[string]int, match func(string) bool) int {
+	total := 0
+	for value, n := range values {
+		if match(value) {
+			total += n
+		}
+	}
+	return total
+}
+
+// containsFold 不区分大小写的包含判断（与数据库 LIKE 的行为一致）
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// matchesFilter 归档记录是否满足过滤条件（与 QueryService.applyFilters 一致）
+func matchesFilter(log *models.AuditLog, filter AuditFilter) bool {
+	switch {
+	case filter.UserID != nil && (log.UserID == nil || *log.UserID != *filter.UserID):
+		return false
+	case filter.Username != "" && !containsFold(log.Username, filter.Username):
+		return false
+	case filter.UserIP != "" && log.UserIP != filter.UserIP:
+		return false
+	case filter.Action != "" && log.Action != filter.Action:
+		return false
+	case filter.Resource != "" && log.Resource != filter.Resource:
+		return false
+	case filter.ResourceID != nil && (log.ResourceID == nil || *log.ResourceID != *filter.ResourceID):
+		return false
+	case filter.StartTime != nil && log.CreatedAt.Before(*filter.StartTime):
+		return false
+	case filter.EndTime != nil && log.CreatedAt.After(*filter.EndTime):
+		return false
+	case filter.StatusCode != nil && log.StatusCode != *filter.StatusCode:
+		return false
+	}
+	return true
+}
+
+// saveArchiveIndex 保存归档索引（与归档文件使用相同的存储）
+func (s *ArchiveService) saveArchiveIndex(archiveFile *ArchiveFile, date time.Time, archiveName string) error {
+	data, err := json.Marshal(buildArchiveIndex(archiveFile))
+	if err != nil {
+		return fmt.Errorf("序列化归档索引失败: %w", err)
+	}
+	subPath := fmt.Sprintf("%d/%02d/%02d", date.Year(), date.Month(), date.Day())
+	if _, err := s.storageService.SaveFile(subPath, archiveName+ArchiveIndexSuffix, data); err != nil {
+		return fmt.Errorf("保存归档索引失败: %w", err)
+	}
+	return nil
+}
+
+// LoadArchiveIndex 读取归档索引；索引不存在或损坏时（如旧归档）根据归档内容重建并写回
+func (s *ArchiveService) LoadArchiveIndex(filePath string) (*ArchiveIndex, error) {
+	indexPath := filePath + ArchiveIndexSuffix
+	if data, err := os.ReadFile(indexPath); err == nil {
+		var index ArchiveIndex
+		if err := json.Unmarshal(data, &index); err == nil {
+			return &index, nil
+		}
+	}
+
+	archive, err := s.LoadFromArchive(filePath)
+	if err != nil {
+		return nil, err
+	}
+	index := buildArchiveIndex(archive)
+	if data, err := json.Marshal(index); err == nil {
+		if err := os.WriteFile(indexPath, data, 0644); err != nil {
+			logger.Log.Warnf("写入归档索引失败: %s, error: %v", indexPath, err)
+		}
+	}
+	return index, nil
+}
@@ -48,6 +48,7 @@ type ArchiveFile struct {
 	RecordCount int                   `json:"record_count"`
 	DateRange   ArchiveDateRange      `json:"date_range"`
 	Logs        []models.AuditLog     `json:"logs"`
+	Manifest    *ArchiveManifest      `json:"manifest,omitempty"` // 签名清单（启用哈希链之前的归档没有）
 }
 
 // ArchiveDateRange 归档日期范围
@@ -61,6 +62,10 @@ func (s *ArchiveService) ArchiveOldLogs() (int64, error) {
 	if !s.config.ArchiveEnabled {
 		return 0, fmt.Errorf("归档功能未启用")
 	}
+	// 没有签名密钥时生成的归档无法防篡改，日志保留在数据库中，等配置密钥后再归档
+	if !s.config.SigningKeyConfigured() {
+		return 0, ErrSigningKeyNotConfigured
+	}
 
 	// 计算归档截止日期（7天前）
 	cutoffDate := time.Now().AddDate(0, 0, -s.config.RetentionDays)
@@ -94,7 +99,7 @@ func (s *ArchiveService) archiveByDate(date time.Time) (int64, error) {
 	// 查询该日期的所有日志
 	var logs []models.AuditLog
 	if err := s.db.Where("created_at >= ? AND created_at < ?", startTime, endTime).
-		Order("created_at ASC").
+		Order("seq ASC, id ASC").
 		Find(&logs).Error; err != nil {
 		return 0, fmt.Errorf("查询日志失败: %w", err)
 	}
@@ -114,7 +119,8 @@ func (s *ArchiveService) archiveByDate(date time.Time) (int64, error) {
 			Start: startTime,
 			End:   endTime,
 		},
-		Logs: logs,
+		Logs:     logs,
+		Manifest: newArchiveManifest(date.Format("2006-01-02"), logs, s.config.IntegritySigningKey),
 	}
 
 	// 保存归档文件
@@ -172,6 +178,11 @@ func (s *ArchiveService) saveArchiveFile(archiveFile *ArchiveFile, date time.Tim
 		return "", fmt.Errorf("保存归档文件失败: %w", err)
 	}
 
+	// 保存索引（失败不影响归档，查询时会根据归档内容重建）
+	if err := s.saveArchiveIndex(archiveFile, date, fileName); err != nil {
+		logger.Log.Warnf("%v", err)
+	}
+
 	return filePath, nil
 }
 
@@ -255,7 +266,8 @@ func (s *ArchiveService) ListArchiveFiles(startDate, endDate time.Time) ([]strin
 				logger.Log.Warnf("读取归档目录失败: %s, error: %v", dirPath, err)
 			} else {
 				for _, entry := range entries {
-					if !entry.IsDir() && strings.HasPrefix(entry.Name(), "audit_") {
+					if !entry.IsDir() && strings.HasPrefix(entry.Name(), "audit_") &&
+						!strings.HasSuffix(entry.Name(), ArchiveIndexSuffix) {
 						files = append(files, filepath.Join(dirPath, entry.Name()))
 					}
 				}
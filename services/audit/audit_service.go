@@ -24,7 +24,9 @@ type AuditService struct {
 	logBuffer   chan *models.AuditLog
 	stopChan    chan struct{}
 	wg          sync.WaitGroup
-	mu          sync.Mutex
+	mu          sync.Mutex // 保护 stopped，保证 Stop 关闭 logBuffer 后不再有写入
+	stopped     bool
+	forwarder   *Forwarder // 外部日志转发（未启用时为 nil）
 }
 
 // NewAuditService 创建审计服务
@@ -36,6 +38,11 @@ func NewAuditService(db *gorm.DB, cfg *config.AuditConfig) *AuditService {
 		stopChan:  make(chan struct{}),
 	}
 
+	// 启动外部日志转发
+	if cfg.ForwardEnabled {
+		service.forwarder = NewForwarder(cfg)
+	}
+
 	// 启动异步写入协程
 	if cfg.AsyncWrite {
 		service.wg.Add(1)
@@ -51,18 +58,37 @@ func (s *AuditService) Log(log *models.AuditLog) {
 		return
 	}
 
-	// 异步写入
+	// 异步写入（服务停止后改为同步写入）
 	if s.config.AsyncWrite {
-		select {
-		case s.logBuffer <- log:
-		default:
-			logger.Log.Warn("审计日志缓冲区已满，丢弃日志")
-		}
-	} else {
-		// 同步写入
-		if err := s.db.Create(log).Error; err != nil {
-			logger.Log.Errorf("写入审计日志失败: %v", err)
+		s.mu.Lock()
+		if !s.stopped {
+			select {
+			case s.logBuffer <- log:
+				s.mu.Unlock()
+				return
+			default:
+				// 缓冲区已满时改为同步写入，不丢弃日志
+				logger.Log.Warn("审计日志缓冲区已满，改为同步写入")
+			}
 		}
+		s.mu.Unlock()
+	}
+	s.writeSync(log)
+}
+
+// writeSync 同步写入（接入哈希链）并转发
+func (s *AuditService) writeSync(log *models.AuditLog) {
+	logs := []*models.AuditLog{log}
+	if err := insertChained(s.db, logs); err != nil {
+		logger.Log.Errorf("写入审计日志失败: %v", err)
+	}
+	s.forward(logs)
+}
+
+// forward 转发到外部接收端；写库失败的日志也照常转发，外部系统中至少保留一份
+func (s *AuditService) forward(logs []*models.AuditLog) {
+	if s.forwarder != nil {
+		s.forwarder.Forward(logs)
 	}
 }
 
@@ -80,11 +106,12 @@ func (s *AuditService) asyncWriter() {
 			return
 		}
 
-		if err := s.db.Create(&batch).Error; err != nil {
+		if err := insertChained(s.db, batch); err != nil {
 			logger.Log.Errorf("批量写入审计日志失败: %v", err)
 		} else {
 			logger.Log.Debugf("批量写入审计日志成功: %d 条", len(batch))
 		}
+		s.forward(batch)
 
 		batch = batch[:0]
 	}
@@ -108,11 +135,23 @@ func (s *AuditService) asyncWriter() {
 	}
 }
 
-// Stop 停止审计服务
+// Stop 停止审计服务：写入缓冲区中剩余的日志并关闭转发，重复调用无效果
 func (s *AuditService) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
 	close(s.stopChan)
 	s.wg.Wait()
 	close(s.logBuffer)
+
+	if s.forwarder != nil {
+		s.forwarder.Stop()
+	}
 }
 
 // LogFromContext 从 Gin Context 记录审计日志
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go_wails_project_manager/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// chainHeadID 链头固定使用的行ID
+const chainHeadID = 1
+
+// ManifestAlgorithm 归档清单使用的算法
+const ManifestAlgorithm = "sha256-chain+hmac-sha256"
+
+// chainMu 串行化哈希链写入（同步写入、异步批量写入和直接调用 Log 的地方共用一条链）
+var chainMu sync.Mutex
+
+// auditHashPayload 参与哈希计算的字段
+// 字段和顺序是哈希格式的一部分，修改后历史记录将无法通过校验；ID 由数据库分配，不参与计算
+type auditHashPayload struct {
+	Seq          uint64 `json:"seq"`
+	PrevHash     string `json:"prev_hash"`
+	UserID       *uint  `json:"user_id"`
+	Username     string `json:"username"`
+	UserIP       string `json:"user_ip"`
+	Action       string `json:"action"`
+	Resource     string `json:"resource"`
+	ResourceID   *uint  `json:"resource_id"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	StatusCode   int    `json:"status_code"`
+	Duration     int64  `json:"duration"`
+	RequestBody  string `json:"request_body"`
+	ResponseBody string `json:"response_body"`
+	ErrorMsg     string `json:"error_msg"`
+	UserAgent    string `json:"user_agent"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// ComputeLogHash 计算审计日志的哈希（覆盖内容、序号和上一条记录的哈希）
+func ComputeLogHash(log *models.AuditLog) string {
+	data, _ := json.Marshal(auditHashPayload{
+		Seq:          log.Seq,
+		PrevHash:     log.PrevHash,
+		UserID:       log.UserID,
+		Username:     log.Username,
+		UserIP:       log.UserIP,
+		Action:       log.Action,
+		Resource:     log.Resource,
+		ResourceID:   log.ResourceID,
+		Method:       log.Method,
+		Path:         log.Path,
+		StatusCode:   log.StatusCode,
+		Duration:     log.Duration,
+		RequestBody:  log.RequestBody,
+		ResponseBody: log.ResponseBody,
+		ErrorMsg:     log.ErrorMsg,
+		UserAgent:    log.UserAgent,
+		CreatedAt:    log.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// insertChained 在一个事务中为日志分配链上序号和哈希并写入，同时推进链头
+func insertChained(db *gorm.DB, logs []*models.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	chainMu.Lock()
+	defer chainMu.Unlock()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var head models.AuditChainHead
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			FirstOrCreate(&head, models.AuditChainHead{ID: chainHeadID}).Error; err != nil {
+			return err
+		}
+
+		for _, log := range logs {
+			if log.CreatedAt.IsZero() {
+				log.CreatedAt = time.Now()
+			}
+			// 截断到微秒，保证经过数据库和归档文件往返后时间（以及哈希）不变
+			log.CreatedAt = log.CreatedAt.Truncate(time.Microsecond)
+
+			head.LastSeq++
+			log.Seq = head.LastSeq
+			log.PrevHash = head.LastHash
+			log.Hash = ComputeLogHash(log)
+			head.LastHash = log.Hash
+		}
+
+		if err := tx.Create(&logs).Error; err != nil {
+			return err
+		}
+		return tx.Save(&head).Error
+	})
+}
+
+// GetChainHead 获取链头（尚未写入任何链上记录时返回零值）
+func GetChainHead(db *gorm.DB) (*models.AuditChainHead, error) {
+	var head models.AuditChainHead
+	err := db.Where("id = ?", chainHeadID).Limit(1).Find(&head).Error
+	return &head, err
+}
+
+// ArchiveManifest 归档清单：记录归档内容的根哈希，并用签名密钥签名
+type ArchiveManifest struct {
+	Algorithm   string    `json:"algorithm"`
+	ArchiveDate string    `json:"archive_date"`
+	RecordCount int       `json:"record_count"`
+	FirstSeq    uint64    `json:"first_seq"`
+	LastSeq     uint64    `json:"last_seq"`
+	Unchained   int       `json:"unchained"` // 启用哈希链之前的旧记录数
+	RootHash    string    `json:"root_hash"` // 按顺序覆盖归档中每条记录哈希的根哈希
+	CreatedAt   time.Time `json:"created_at"`
+	Signature   string    `json:"signature"`
+}
+
+// archiveRootHash 计算归档记录的根哈希：依次串联每条记录的哈希后再做 SHA-256
+// 旧记录没有链上哈希，使用其内容哈希，保证归档中的每条记录都被覆盖
+func archiveRootHash(logs []models.AuditLog) string {
+	h := sha256.New()
+	for i := range logs {
+		if logs[i].Seq == 0 {
+			h.Write([]byte(ComputeLogHash(&logs[i])))
+		} else {
+			h.Write([]byte(logs[i].Hash))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newArchiveManifest 为归档记录生成签名清单
+func newArchiveManifest(archiveDate string, logs []models.AuditLog, key string) *ArchiveManifest {
+	manifest := &ArchiveManifest{
+		Algorithm:   ManifestAlgorithm,
+		ArchiveDate: archiveDate,
+		RecordCount: len(logs),
+		RootHash:    archiveRootHash(logs),
+		CreatedAt:   time.Now(),
+	}
+	for _, log := range logs {
+		if log.Seq == 0 {
+			manifest.Unchained++
+			continue
+		}
+		if manifest.FirstSeq == 0 || log.Seq < manifest.FirstSeq {
+			manifest.FirstSeq = log.Seq
+		}
+		if log.Seq > manifest.LastSeq {
+			manifest.LastSeq = log.Seq
+		}
+	}
+	manifest.Signature = manifest.sign(key)
+	return manifest
+}
+
+// signingPayload 参与签名的清单字段
+func (m *ArchiveManifest) signingPayload() string {
+	return fmt.Sprintf("%s|%s|%d|%d|%d|%d|%s|%s",
+		m.Algorithm, m.ArchiveDate, m.RecordCount, m.FirstSeq, m.LastSeq, m.Unchained,
+		m.RootHash, m.CreatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// sign 计算清单签名（HMAC-SHA256）
+func (m *ArchiveManifest) sign(key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(m.signingPayload()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature 校验清单签名
+func (m *ArchiveManifest) VerifySignature(key string) bool {
+	expected, err := hex.DecodeString(m.sign(key))
+	if err != nil {
+		return false
+	}
+	actual, err := hex.DecodeString(m.Signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, actual)
+}
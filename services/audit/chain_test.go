@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go_wails_project_manager/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newHashedLog 生成一条已计算哈希的链上记录
+func newHashedLog(seq uint64, prevHash string, createdAt time.Time) models.AuditLog {
+	userID := uint(7)
+	log := models.AuditLog{
+		Seq:        seq,
+		PrevHash:   prevHash,
+		UserID:     &userID,
+		Username:   "alice",
+		UserIP:     "10.0.0.1",
+		Action:     "update",
+		Resource:   "documents",
+		Method:     "PUT",
+		Path:       "/api/documents/1",
+		StatusCode: 200,
+		Duration:   12,
+		CreatedAt:  createdAt.Truncate(time.Microsecond),
+	}
+	log.Hash = ComputeLogHash(&log)
+	return log
+}
+
+// TestComputeLogHash 测试哈希覆盖内容和链字段，不受 ID 和时区影响
+func TestComputeLogHash(t *testing.T) {
+	base := newHashedLog(3, "prev", time.Date(2026, 3, 1, 8, 30, 0, 123456000, time.UTC))
+
+	t.Run("相同内容哈希相同", func(t *testing.T) {
+		copied := base
+		assert.Equal(t, base.Hash, ComputeLogHash(&copied))
+		assert.Len(t, base.Hash, 64)
+	})
+
+	t.Run("ID 和时区不参与计算", func(t *testing.T) {
+		copied := base
+		copied.ID = 99
+		copied.CreatedAt = base.CreatedAt.In(time.FixedZone("CST", 8*3600))
+		assert.Equal(t, base.Hash, ComputeLogHash(&copied))
+	})
+
+	t.Run("修改任一字段哈希改变", func(t *testing.T) {
+		otherUser := uint(8)
+		mutations := map[string]func(log *models.AuditLog){
+			"seq":         func(log *models.AuditLog) { log.Seq++ },
+			"prev_hash":   func(log *models.AuditLog) { log.PrevHash = "other" },
+			"user_id":     func(log *models.AuditLog) { log.UserID = &otherUser },
+			"user_id nil": func(log *models.AuditLog) { log.UserID = nil },
+			"username":    func(log *models.AuditLog) { log.Username = "mallory" },
+			"user_ip":     func(log *models.AuditLog) { log.UserIP = "10.0.0.2" },
+			"action":      func(log *models.AuditLog) { log.Action = "delete" },
+			"path":        func(log *models.AuditLog) { log.Path = "/api/documents/2" },
+			"status_code": func(log *models.AuditLog) { log.StatusCode = 403 },
+			"error_msg":   func(log *models.AuditLog) { log.ErrorMsg = "denied" },
+			"created_at":  func(log *models.AuditLog) { log.CreatedAt = log.CreatedAt.Add(time.Microsecond) },
+		}
+		for name, mutate := range mutations {
+			copied := base
+			mutate(&copied)
+			assert.NotEqual(t, base.Hash, ComputeLogHash(&copied), name)
+		}
+	})
+}
+
+// TestArchiveManifest 测试归档清单的序号范围、根哈希和签名
+func TestArchiveManifest(t *testing.T) {
+	now := time.Now()
+	first := newHashedLog(5, "h4", now)
+	second := newHashedLog(6, first.Hash, now.Add(time.Second))
+	legacy := models.AuditLog{Username: "legacy", CreatedAt: now.Add(-time.Hour)}
+	logs := []models.AuditLog{legacy, first, second}
+
+	manifest := newArchiveManifest("2026-03-01", logs, "secret")
+	assert.Equal(t, ManifestAlgorithm, manifest.Algorithm)
+	assert.Equal(t, 3, manifest.RecordCount)
+	assert.Equal(t, uint64(5), manifest.FirstSeq)
+	assert.Equal(t, uint64(6), manifest.LastSeq)
+	assert.Equal(t, 1, manifest.Unchained)
+	assert.Equal(t, archiveRootHash(logs), manifest.RootHash)
+
+	t.Run("签名校验", func(t *testing.T) {
+		assert.True(t, manifest.VerifySignature("secret"))
+		assert.False(t, manifest.VerifySignature("other"))
+	})
+
+	t.Run("JSON 往返后签名仍然有效", func(t *testing.T) {
+		data, err := json.Marshal(manifest)
+		assert.NoError(t, err)
+		var decoded ArchiveManifest
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.True(t, decoded.VerifySignature("secret"))
+	})
+
+	t.Run("修改清单字段后签名无效", func(t *testing.T) {
+		mutations := map[string]func(m *ArchiveManifest){
+			"record_count": func(m *ArchiveManifest) { m.RecordCount-- },
+			"last_seq":     func(m *ArchiveManifest) { m.LastSeq++ },
+			"root_hash":    func(m *ArchiveManifest) { m.RootHash = archiveRootHash(logs[1:]) },
+			"created_at":   func(m *ArchiveManifest) { m.CreatedAt = m.CreatedAt.Add(time.Second) },
+			"signature":    func(m *ArchiveManifest) { m.Signature = "not-hex" },
+		}
+		for name, mutate := range mutations {
+			copied := *manifest
+			mutate(&copied)
+			assert.False(t, copied.VerifySignature("secret"), name)
+		}
+	})
+
+	t.Run("根哈希覆盖每条记录和顺序", func(t *testing.T) {
+		assert.NotEqual(t, manifest.RootHash, archiveRootHash([]models.AuditLog{legacy, second, first}))
+
+		tampered := legacy
+		tampered.Username = "changed"
+		assert.NotEqual(t, manifest.RootHash, archiveRootHash([]models.AuditLog{tampered, first, second}))
+	})
+}
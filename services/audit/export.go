@@ -0,0 +1,231 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/utils"
+)
+
+// 导出格式
+const (
+	ExportFormatCSV   = "csv"   // CSV（带表头）
+	ExportFormatJSONL = "jsonl" // JSON Lines，每行一条
+	ExportFormatCEF   = "cef"   // ArcSight Common Event Format，每行一条
+)
+
+var (
+	ErrExportFormatInvalid = errors.New("导出格式无效，可选 csv、jsonl、cef")
+	ErrExportHourInvalid   = errors.New("审计日志定时导出时间 export.hour 无效，应为 0-23")
+)
+
+// CEF 头部中的设备信息
+const (
+	cefVendor  = "go_wails_project_manager"
+	cefProduct = "audit"
+	cefVersion = "1.0"
+)
+
+// exportContentTypes 各导出格式的 Content-Type 和文件扩展名
+var exportContentTypes = map[string][2]string{
+	ExportFormatCSV:   {"text/csv; charset=utf-8", ".csv"},
+	ExportFormatJSONL: {"application/x-ndjson; charset=utf-8", ".jsonl"},
+	ExportFormatCEF:   {"text/plain; charset=utf-8", ".cef"},
+}
+
+// IsExportFormat 是否为支持的导出格式
+func IsExportFormat(format string) bool {
+	_, ok := exportContentTypes[format]
+	return ok
+}
+
+// ExportContentType 导出格式对应的 Content-Type
+func ExportContentType(format string) string {
+	return exportContentTypes[format][0]
+}
+
+// ExportFileExt 导出格式对应的文件扩展名
+func ExportFileExt(format string) string {
+	return exportContentTypes[format][1]
+}
+
+// LogEncoder 按导出格式逐条写出审计日志
+type LogEncoder interface {
+	Encode(log *models.AuditLog) error
+	Flush() error
+}
+
+// NewLogEncoder 创建指定格式的编码器（CSV 会立即写出表头）
+func NewLogEncoder(w io.Writer, format string) (LogEncoder, error) {
+	switch format {
+	case ExportFormatCSV:
+		encoder := &csvEncoder{writer: csv.NewWriter(w)}
+		if err := encoder.writer.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return encoder, nil
+	case ExportFormatJSONL:
+		buffered := bufio.NewWriter(w)
+		return &jsonlEncoder{writer: buffered, encoder: json.NewEncoder(buffered)}, nil
+	case ExportFormatCEF:
+		return &cefEncoder{writer: bufio.NewWriter(w)}, nil
+	default:
+		return nil, ErrExportFormatInvalid
+	}
+}
+
+// csvHeader CSV 导出的列
+var csvHeader = []string{
+	"id", "seq", "created_at", "user_id", "username", "user_ip", "action", "resource", "resource_id",
+	"method", "path", "status_code", "duration", "request_body", "error_msg", "user_agent", "prev_hash", "hash",
+}
+
+// csvEncoder CSV 编码器
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvEncoder) Encode(log *models.AuditLog) error {
+	record := []string{
+		strconv.FormatUint(uint64(log.ID), 10),
+		strconv.FormatUint(log.Seq, 10),
+		log.CreatedAt.Format("2006-01-02T15:04:05.000000Z07:00"),
+		optionalID(log.UserID),
+		log.Username,
+		log.UserIP,
+		log.Action,
+		log.Resource,
+		optionalID(log.ResourceID),
+		log.Method,
+		log.Path,
+		strconv.Itoa(log.StatusCode),
+		strconv.FormatInt(log.Duration, 10),
+		log.RequestBody,
+		log.ErrorMsg,
+		log.UserAgent,
+		log.PrevHash,
+		log.Hash,
+	}
+	for i, cell := range record {
+		record[i] = utils.CSVSafe(cell)
+	}
+	return e.writer.Write(record)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonlEncoder JSON Lines 编码器
+type jsonlEncoder struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (e *jsonlEncoder) Encode(log *models.AuditLog) error {
+	return e.encoder.Encode(log)
+}
+
+func (e *jsonlEncoder) Flush() error {
+	return e.writer.Flush()
+}
+
+// cefEncoder CEF 编码器
+type cefEncoder struct {
+	writer *bufio.Writer
+}
+
+func (e *cefEncoder) Encode(log *models.AuditLog) error {
+	if _, err := e.writer.WriteString(FormatCEF(log)); err != nil {
+		return err
+	}
+	return e.writer.WriteByte('\n')
+}
+
+func (e *cefEncoder) Flush() error {
+	return e.writer.Flush()
+}
+
+// FormatCEF 将审计日志格式化为一行 CEF 事件
+// CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func FormatCEF(log *models.AuditLog) string {
+	name := strings.TrimSpace(log.Action + " " + log.Resource)
+	header := strings.Join([]string{
+		"CEF:0",
+		cefHeaderEscape(cefVendor),
+		cefHeaderEscape(cefProduct),
+		cefHeaderEscape(cefVersion),
+		cefHeaderEscape(log.Action),
+		cefHeaderEscape(name),
+		strconv.Itoa(cefSeverity(log.StatusCode)),
+	}, "|")
+
+	extension := []string{
+		"rt=" + strconv.FormatInt(log.CreatedAt.UnixMilli(), 10),
+		"suser=" + cefExtensionEscape(log.Username),
+		"src=" + cefExtensionEscape(log.UserIP),
+		"requestMethod=" + cefExtensionEscape(log.Method),
+		"request=" + cefExtensionEscape(log.Path),
+		"outcome=" + strconv.Itoa(log.StatusCode),
+		"cn1Label=duration_ms cn1=" + strconv.FormatInt(log.Duration, 10),
+		"cs1Label=resource cs1=" + cefExtensionEscape(log.Resource),
+		"cs2Label=hash cs2=" + log.Hash,
+		"externalId=" + strconv.FormatUint(log.Seq, 10),
+	}
+	if log.UserID != nil {
+		extension = append(extension, "suid="+optionalID(log.UserID))
+	}
+	if log.ResourceID != nil {
+		extension = append(extension, "cs3Label=resource_id cs3="+optionalID(log.ResourceID))
+	}
+	if log.UserAgent != "" {
+		extension = append(extension, "requestClientApplication="+cefExtensionEscape(log.UserAgent))
+	}
+	if log.ErrorMsg != "" {
+		extension = append(extension, "msg="+cefExtensionEscape(log.ErrorMsg))
+	}
+
+	return header + "|" + strings.Join(extension, " ")
+}
+
+// cefSeverity 按状态码映射 CEF 严重级别（0-10）
+func cefSeverity(statusCode int) int {
+	switch {
+	case statusCode >= 500:
+		return 7
+	case statusCode == 401 || statusCode == 403:
+		return 5
+	case statusCode >= 400:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// cefHeaderEscape 转义 CEF 头部字段中的 \ 和 |
+func cefHeaderEscape(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+// cefExtensionEscape 转义 CEF 扩展字段值中的 \、= 和换行
+func cefExtensionEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
+// optionalID 可为空的ID转字符串
+func optionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *id)
+}
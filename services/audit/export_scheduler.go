@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"sync"
+	"time"
+
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+
+	"gorm.io/gorm"
+)
+
+// ExportScheduler 定时导出调度器（每天导出前一天的日志）
+type ExportScheduler struct {
+	mu            sync.Mutex
+	running       bool
+	ticker        *time.Ticker
+	stopChan      chan struct{}
+	exportService *ExportService
+	config        *config.AuditConfig
+}
+
+// NewExportScheduler 创建定时导出调度器
+func NewExportScheduler(db *gorm.DB, cfg *config.AuditConfig) *ExportScheduler {
+	queryService := NewQueryService(db, cfg, NewArchiveService(db, cfg))
+	return &ExportScheduler{
+		exportService: NewExportService(cfg, queryService),
+		config:        cfg,
+		stopChan:      make(chan struct{}),
+	}
+}
+
+// Start 启动调度器
+func (s *ExportScheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.config.ExportEnabled {
+		logger.Log.Info("审计日志定时导出未启用")
+		return nil
+	}
+
+	if s.running {
+		logger.Log.Warn("审计导出调度器已在运行")
+		return nil
+	}
+	if s.config.ExportHour < 0 || s.config.ExportHour > 23 {
+		return ErrExportHourInvalid
+	}
+
+	s.running = true
+	s.ticker = time.NewTicker(1 * time.Hour) // 每小时检查一次
+
+	go s.run()
+
+	logger.Log.Info("审计导出调度器已启动")
+	return nil
+}
+
+// Stop 停止调度器
+func (s *ExportScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	s.running = false
+	close(s.stopChan)
+
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+
+	logger.Log.Info("审计导出调度器已停止")
+}
+
+// run 运行调度器
+func (s *ExportScheduler) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Errorf("审计导出调度器发生panic: %v", r)
+		}
+	}()
+
+	// 启动时立即检查一次
+	s.checkAndExport()
+
+	for {
+		select {
+		case <-s.ticker.C:
+			s.checkAndExport()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// checkAndExport 到达导出时刻后导出前一天的日志
+// 已导出的文件会被跳过，因此错过导出时刻（如服务重启）时当天稍后仍会补上
+func (s *ExportScheduler) checkAndExport() {
+	now := time.Now()
+	if now.Hour() < s.config.ExportHour {
+		return
+	}
+
+	files, err := s.exportService.ExportDay(now.AddDate(0, 0, -1))
+	if err != nil {
+		logger.Log.Errorf("审计日志定时导出失败: %v", err)
+		return
+	}
+	if len(files) > 0 {
+		logger.Log.Infof("审计日志定时导出完成，生成 %d 个文件", len(files))
+	}
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+)
+
+// ExportService 审计日志导出服务（按需导出和定时导出）
+type ExportService struct {
+	config       *config.AuditConfig
+	queryService *QueryService
+}
+
+// NewExportService 创建导出服务
+func NewExportService(cfg *config.AuditConfig, queryService *QueryService) *ExportService {
+	return &ExportService{
+		config:       cfg,
+		queryService: queryService,
+	}
+}
+
+// Export 按过滤条件导出数据库和归档中的日志（按时间正序），返回导出条数
+func (s *ExportService) Export(w io.Writer, format string, filter AuditFilter) (int, error) {
+	encoder, err := NewLogEncoder(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = s.queryService.Each(filter, func(log *models.AuditLog) error {
+		count++
+		return encoder.Encode(log)
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, encoder.Flush()
+}
+
+// ExportDay 将某一天的日志按配置的每种格式导出到导出目录，已存在的文件跳过，返回新生成的文件
+func (s *ExportService) ExportDay(date time.Time) ([]string, error) {
+	start := dayStart(date)
+	end := start.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	filter := AuditFilter{StartTime: &start, EndTime: &end}
+
+	if err := os.MkdirAll(s.config.ExportDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建导出目录失败: %w", err)
+	}
+
+	var files []string
+	for _, format := range s.config.ExportFormats {
+		if !IsExportFormat(format) {
+			logger.Log.Warnf("忽略无效的审计导出格式: %s", format)
+			continue
+		}
+
+		path := filepath.Join(s.config.ExportDir, fmt.Sprintf("audit_%s%s", start.Format("20060102"), ExportFileExt(format)))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+
+		count, err := s.exportToFile(path, format, filter)
+		if err != nil {
+			return files, err
+		}
+		logger.Log.Infof("审计日志已导出: %s（%d 条）", path, count)
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// exportToFile 先写临时文件再重命名，避免留下不完整的导出文件
+func (s *ExportService) exportToFile(path, format string, filter AuditFilter) (int, error) {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("创建导出文件失败: %w", err)
+	}
+
+	count, err := s.Export(file, format, filter)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("导出审计日志失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("保存导出文件失败: %w", err)
+	}
+	return count, nil
+}
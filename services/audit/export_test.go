@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go_wails_project_manager/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cefExtensionKey 扩展字段中未转义的 key=（= 前不是反斜杠）
+var cefExtensionKey = regexp.MustCompile(`(?:^| )([A-Za-z0-9]+)=`)
+
+// splitCEF 按未转义的 | 拆分 CEF 行的 7 个头部字段和扩展字段（扩展字段中的 | 不是分隔符）
+func splitCEF(line string) []string {
+	var fields []string
+	var current strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line):
+			current.WriteByte(line[i])
+			current.WriteByte(line[i+1])
+			i++
+		case line[i] == '|' && len(fields) < 7:
+			fields = append(fields, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(line[i])
+		}
+	}
+	return append(fields, current.String())
+}
+
+// cefExtensionKeys 扩展字段中出现的 key（跳过转义的 \=）
+func cefExtensionKeys(extension string) []string {
+	var keys []string
+	for _, match := range cefExtensionKey.FindAllStringSubmatchIndex(extension, -1) {
+		if match[2] > 0 && extension[match[2]-1] == '\\' {
+			continue
+		}
+		keys = append(keys, extension[match[2]:match[3]])
+	}
+	return keys
+}
+
+// TestCEFHeaderEscape 测试头部字段转义 \ 和 |，换行替换为空格
+func TestCEFHeaderEscape(t *testing.T) {
+	cases := map[string]string{
+		"update":         "update",
+		`a|b`:            `a\|b`,
+		`a\b`:            `a\\b`,
+		`a\|b`:           `a\\\|b`,
+		"a\r\nb":         "a  b",
+		"a=b":            "a=b",
+		"删除 documents":   "删除 documents",
+		`|CEF:0|evil|x|`: `\|CEF:0\|evil\|x\|`,
+	}
+	for value, expected := range cases {
+		assert.Equal(t, expected, cefHeaderEscape(value), value)
+	}
+}
+
+// TestCEFExtensionEscape 测试扩展字段值转义 \、= 和换行，| 不需要转义
+func TestCEFExtensionEscape(t *testing.T) {
+	cases := map[string]string{
+		"alice":             "alice",
+		"a=b":               `a\=b`,
+		`a\b`:               `a\\b`,
+		`a\=b`:              `a\\\=b`,
+		"a\nb":              `a\nb`,
+		"a\r\nb":            `a\nb`,
+		"a\rb":              `a\rb`,
+		"a|b":               "a|b",
+		"Mozilla/5.0 (X11)": "Mozilla/5.0 (X11)",
+	}
+	for value, expected := range cases {
+		assert.Equal(t, expected, cefExtensionEscape(value), value)
+	}
+}
+
+// TestFormatCEF 测试 CEF 行的头部、扩展字段和严重级别
+func TestFormatCEF(t *testing.T) {
+	userID, resourceID := uint(7), uint(42)
+	log := &models.AuditLog{
+		Seq:        12,
+		UserID:     &userID,
+		Username:   "alice",
+		UserIP:     "10.0.0.1",
+		Action:     "delete",
+		Resource:   "documents",
+		ResourceID: &resourceID,
+		Method:     "DELETE",
+		Path:       "/api/documents/42",
+		StatusCode: 200,
+		Duration:   15,
+		Hash:       "abc123",
+		CreatedAt:  time.UnixMilli(1772323200123),
+	}
+
+	assert.Equal(t,
+		"CEF:0|go_wails_project_manager|audit|1.0|delete|delete documents|3|"+
+			"rt=1772323200123 suser=alice src=10.0.0.1 requestMethod=DELETE request=/api/documents/42 outcome=200 "+
+			"cn1Label=duration_ms cn1=15 cs1Label=resource cs1=documents cs2Label=hash cs2=abc123 externalId=12 "+
+			"suid=7 cs3Label=resource_id cs3=42",
+		FormatCEF(log))
+
+	t.Run("严重级别", func(t *testing.T) {
+		cases := map[int]int{200: 3, 302: 3, 400: 4, 401: 5, 403: 5, 404: 4, 500: 7, 503: 7}
+		for status, severity := range cases {
+			assert.Equal(t, severity, cefSeverity(status), status)
+		}
+	})
+
+	t.Run("可选字段为空时省略", func(t *testing.T) {
+		line := FormatCEF(&models.AuditLog{Action: "login", StatusCode: 401})
+		fields := splitCEF(line)
+		assert.Len(t, fields, 8)
+		assert.Equal(t, "login", fields[5])
+		assert.Equal(t, "5", fields[6])
+		assert.NotContains(t, line, "suid=")
+		assert.NotContains(t, line, "cs3=")
+		assert.NotContains(t, line, "msg=")
+		assert.NotContains(t, line, "requestClientApplication=")
+	})
+}
+
+// TestFormatCEFInjection 测试用户可控的字段无法伪造头部、扩展字段或新的事件行
+func TestFormatCEFInjection(t *testing.T) {
+	log := &models.AuditLog{
+		Seq:        1,
+		Username:   "mallory suser=admin",
+		UserIP:     "10.0.0.9",
+		Action:     "update|10|evil",
+		Resource:   `files\|x`,
+		Method:     "POST",
+		Path:       "/api/files?a=1&b=2",
+		StatusCode: 500,
+		UserAgent:  "curl cs1=forged",
+		ErrorMsg:   "失败\nCEF:0|evil|evil|1|evil|evil|10|suser=admin",
+		CreatedAt:  time.Now(),
+	}
+	line := FormatCEF(log)
+
+	assert.NotContains(t, line, "\n")
+	assert.NotContains(t, line, "\r")
+
+	fields := splitCEF(line)
+	assert.Len(t, fields, 8, "头部字段中的 | 已转义，仍然只有 8 段")
+	assert.Equal(t, `update\|10\|evil`, fields[4])
+	assert.Equal(t, `update\|10\|evil files\\\|x`, fields[5])
+	assert.Equal(t, "7", fields[6])
+
+	assert.Equal(t, []string{
+		"rt", "suser", "src", "requestMethod", "request", "outcome", "cn1Label", "cn1",
+		"cs1Label", "cs1", "cs2Label", "cs2", "externalId", "requestClientApplication", "msg",
+	}, cefExtensionKeys(fields[7]), "字段值中的 = 已转义，不会产生新的 key")
+	assert.Contains(t, fields[7], `suser=mallory suser\=admin`)
+	assert.Contains(t, fields[7], `request=/api/files?a\=1&b\=2`)
+	assert.Contains(t, fields[7], `msg=失败\nCEF:0|evil|evil|1|evil|evil|10|suser\=admin`)
+}
+
+// TestCEFEncoder 测试编码器每条日志输出一行
+func TestCEFEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder, err := NewLogEncoder(&buf, ExportFormatCEF)
+	assert.NoError(t, err)
+
+	logs := []*models.AuditLog{
+		{Seq: 1, Action: "create", ErrorMsg: "line1\nline2", CreatedAt: time.Now()},
+		{Seq: 2, Action: "delete", ErrorMsg: "a\r\nb", CreatedAt: time.Now()},
+	}
+	for _, log := range logs {
+		assert.NoError(t, encoder.Encode(log))
+	}
+	assert.NoError(t, encoder.Flush())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	for i, line := range lines {
+		assert.Equal(t, FormatCEF(logs[i]), line)
+		assert.True(t, strings.HasPrefix(line, "CEF:0|"))
+	}
+
+	_, err = NewLogEncoder(&buf, "xml")
+	assert.ErrorIs(t, err, ErrExportFormatInvalid)
+}
@@ -0,0 +1,441 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+)
+
+// maxRetryBackoff 重试间隔上限
+const maxRetryBackoff = time.Minute
+
+// Forwarder 将写入的审计日志实时转发到外部接收端
+// 每个接收端独立排队、批量发送、失败重试；重试仍失败或队列已满的日志写入离线缓存，接收端恢复后按顺序补发
+type Forwarder struct {
+	workers []*sinkWorker
+	stopped atomic.Bool
+}
+
+// NewForwarder 根据配置创建转发器并启动各接收端的发送协程，配置无效的接收端被跳过
+func NewForwarder(cfg *config.AuditConfig) *Forwarder {
+	forwarder := &Forwarder{}
+	for _, sinkConfig := range cfg.ForwardSinks {
+		sink, err := NewSink(sinkConfig)
+		if err != nil {
+			logger.Log.Errorf("审计日志接收端配置无效，已跳过: %v", err)
+			continue
+		}
+
+		worker := &sinkWorker{
+			sink:     sink,
+			config:   cfg,
+			queue:    make(chan *models.AuditLog, cfg.ForwardBufferSize),
+			spool:    &spoolFile{path: filepath.Join(cfg.ForwardSpoolDir, sink.Name()+".jsonl")},
+			stopChan: make(chan struct{}),
+
+			deadLetters: &spoolFile{path: filepath.Join(cfg.ForwardSpoolDir, sink.Name()+".dead.jsonl")},
+		}
+		worker.wg.Add(1)
+		go worker.run()
+
+		forwarder.workers = append(forwarder.workers, worker)
+		logger.Log.Infof("审计日志转发已启用: %s", sink.Name())
+	}
+	return forwarder
+}
+
+// Forward 将日志加入各接收端的发送队列（不阻塞调用方），停止后直接写入离线缓存，下次启动时补发
+func (f *Forwarder) Forward(logs []*models.AuditLog) {
+	for _, worker := range f.workers {
+		if f.stopped.Load() {
+			worker.spoolLogs(logs)
+			continue
+		}
+		worker.enqueue(logs)
+	}
+}
+
+// Stop 发送队列中剩余的日志（失败则写入离线缓存）并关闭接收端
+func (f *Forwarder) Stop() {
+	f.stopped.Store(true)
+	for _, worker := range f.workers {
+		close(worker.stopChan)
+	}
+	for _, worker := range f.workers {
+		worker.wg.Wait()
+		worker.spoolQueued()
+		if err := worker.sink.Close(); err != nil {
+			logger.Log.Warnf("关闭审计日志接收端 %s 失败: %v", worker.sink.Name(), err)
+		}
+	}
+}
+
+// sinkWorker 单个接收端的发送协程
+type sinkWorker struct {
+	sink     Sink
+	config   *config.AuditConfig
+	queue    chan *models.AuditLog
+	spool    *spoolFile
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+
+	deadLetters *spoolFile // 接收端拒绝的日志，不再自动重发
+}
+
+// enqueue 加入发送队列，队列已满时直接写入离线缓存
+func (w *sinkWorker) enqueue(logs []*models.AuditLog) {
+	for i, log := range logs {
+		select {
+		case w.queue <- log:
+		default:
+			logger.Log.Warnf("审计日志接收端 %s 队列已满，%d 条日志写入离线缓存", w.sink.Name(), len(logs)-i)
+			w.spoolLogs(logs[i:])
+			return
+		}
+	}
+}
+
+// spoolQueued 发送协程退出后，把停止过程中并发加入队列的日志写入离线缓存
+func (w *sinkWorker) spoolQueued() {
+	var logs []*models.AuditLog
+	for {
+		select {
+		case log := <-w.queue:
+			logs = append(logs, log)
+		default:
+			if len(logs) > 0 {
+				w.spoolLogs(logs)
+			}
+			return
+		}
+	}
+}
+
+// run 批量发送，逻辑与 AuditService.asyncWriter 一致
+func (w *sinkWorker) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(w.config.ForwardFlushInterval) * time.Second)
+	defer ticker.Stop()
+
+	batch := make([]*models.AuditLog, 0, w.config.ForwardBatchSize)
+	flush := func(retry bool) {
+		// 离线缓存中有更早的日志时先补发，保证顺序；补发失败则本批也进入缓存
+		if len(batch) > 0 && !w.drainSpool(retry) {
+			w.spoolLogs(batch)
+			batch = batch[:0]
+			return
+		}
+		if len(batch) == 0 {
+			w.drainSpool(retry)
+			return
+		}
+		if n, err := w.deliver(batch, retry); err != nil {
+			logger.Log.Errorf("审计日志转发到 %s 失败，%d 条日志写入离线缓存: %v", w.sink.Name(), len(batch)-n, err)
+			w.spoolLogs(batch[n:])
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case log := <-w.queue:
+			batch = append(batch, log)
+			if len(batch) >= w.config.ForwardBatchSize {
+				flush(true)
+			}
+
+		case <-ticker.C:
+			flush(true)
+
+		case <-w.stopChan:
+			// 停止前取出队列中剩余的日志，只尝试一次，失败的写入离线缓存
+		drain:
+			for {
+				select {
+				case log := <-w.queue:
+					batch = append(batch, log)
+				default:
+					break drain
+				}
+			}
+			flush(false)
+			return
+		}
+	}
+}
+
+// send 发送一批日志，失败后按指数退避重试（停止时不再等待），不可重试的错误直接返回
+func (w *sinkWorker) send(logs []*models.AuditLog, retry bool) error {
+	backoff := time.Duration(w.config.ForwardRetryInterval) * time.Second
+	for attempt := 0; ; attempt++ {
+		err := w.sink.Send(logs)
+		if err == nil || isPermanent(err) || !retry || attempt >= w.config.ForwardMaxRetries {
+			return err
+		}
+
+		logger.Log.Warnf("审计日志转发到 %s 失败（第 %d 次），%v 后重试: %v", w.sink.Name(), attempt+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-w.stopChan:
+			retry = false
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// deliver 发送一批日志，返回已处理的条数；接收端拒绝整批时逐条重发，仍被拒绝的日志移入死信文件，
+// 不让单条无法接收的日志阻塞后续日志。返回错误时 logs[n:] 尚未发送，调用方应写入离线缓存
+func (w *sinkWorker) deliver(logs []*models.AuditLog, retry bool) (int, error) {
+	err := w.send(logs, retry)
+	if err == nil || !isPermanent(err) {
+		if err != nil {
+			return 0, err
+		}
+		return len(logs), nil
+	}
+
+	for i, log := range logs {
+		err := w.send(logs[i:i+1], retry)
+		if err == nil {
+			continue
+		}
+		if !isPermanent(err) {
+			return i, err
+		}
+		data, _ := json.Marshal(log)
+		w.deadLetter(data, err)
+	}
+	return len(logs), nil
+}
+
+// deadLetter 把接收端拒绝或无法解析的日志移入死信文件，需要人工处理
+func (w *sinkWorker) deadLetter(line []byte, reason error) {
+	logger.Log.Errorf("审计日志被接收端 %s 拒绝，已移入死信文件 %s: %v", w.sink.Name(), w.deadLetters.path, reason)
+	if err := w.deadLetters.appendLine(line); err != nil {
+		logger.Log.Errorf("审计日志无法写入死信文件（%v）: %s", err, line)
+	}
+}
+
+// drainSpool 按批流式补发离线缓存中的日志，全部发送成功（或没有缓存）时返回 true
+func (w *sinkWorker) drainSpool(retry bool) bool {
+	reader, err := w.spool.open()
+	if err != nil {
+		logger.Log.Errorf("读取审计日志离线缓存失败: %s, error: %v", w.spool.path, err)
+		return false
+	}
+	if reader == nil {
+		return true
+	}
+	defer reader.close()
+
+	size := max(w.config.ForwardBatchSize, 1)
+	sent := 0
+	done := true
+	for {
+		logs, ends, err := reader.next(size, w.deadLetter)
+		if err != nil {
+			logger.Log.Errorf("读取审计日志离线缓存失败: %s, error: %v", w.spool.path, err)
+			done = false
+			break
+		}
+		if len(logs) == 0 {
+			break
+		}
+
+		n, err := w.deliver(logs, retry)
+		sent += n
+		if n > 0 {
+			reader.consumed = ends[n-1]
+		}
+		if err != nil {
+			logger.Log.Errorf("补发审计日志离线缓存到 %s 失败: %v", w.sink.Name(), err)
+			done = false
+			break
+		}
+	}
+
+	// 去掉已处理的部分，读取期间新追加的日志保留在文件中
+	if err := w.spool.discard(reader.consumed); err != nil {
+		logger.Log.Errorf("更新审计日志离线缓存失败: %s, error: %v", w.spool.path, err)
+	}
+	if sent > 0 {
+		logger.Log.Infof("已补发 %d 条离线缓存的审计日志到 %s", sent, w.sink.Name())
+	}
+	return done
+}
+
+// spoolLogs 写入离线缓存，写入失败时把日志内容记录到应用日志，不静默丢弃
+func (w *sinkWorker) spoolLogs(logs []*models.AuditLog) {
+	if err := w.spool.append(logs); err != nil {
+		for _, log := range logs {
+			data, _ := json.Marshal(log)
+			logger.Log.Errorf("审计日志无法转发也无法写入离线缓存（%v）: %s", err, data)
+		}
+	}
+}
+
+// spoolFile 离线缓存文件（JSON Lines），入队协程和发送协程都会访问
+type spoolFile struct {
+	path string
+	mu   sync.Mutex
+}
+
+// append 追加日志
+func (s *spoolFile) append(logs []*models.AuditLog) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, log := range logs {
+		if err := encoder.Encode(log); err != nil {
+			return err
+		}
+	}
+	return s.appendLine(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// appendLine 追加已编码的内容（末尾补换行）
+func (s *spoolFile) appendLine(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// open 打开缓存文件用于补发，文件不存在时返回 nil
+func (s *spoolFile) open() (*spoolReader, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &spoolReader{file: file, reader: bufio.NewReader(file)}, nil
+}
+
+// discard 删除文件开头已处理的 offset 字节（全部处理完时删除文件）
+// 在锁内把剩余内容复制到新文件，补发期间追加的日志不会丢失
+func (s *spoolFile) discard(offset int64) error {
+	if offset <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() <= offset {
+		file.Close()
+		return removeIfExists(s.path)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	file.Close()
+	return os.Rename(tmpPath, s.path)
+}
+
+// spoolReader 逐行读取离线缓存
+type spoolReader struct {
+	file     *os.File
+	reader   *bufio.Reader
+	offset   int64 // 已读取到的位置
+	consumed int64 // 已处理（发送成功或移入死信）的位置
+}
+
+// next 读取最多 n 条日志，ends[i] 为第 i 条日志之后的文件位置；
+// 无法解析的行交给 bad 处理后跳过，末尾不完整的行（正在追加）留到下次读取
+func (r *spoolReader) next(n int, bad func(line []byte, reason error)) ([]*models.AuditLog, []int64, error) {
+	var logs []*models.AuditLog
+	var ends []int64
+	for len(logs) < n {
+		line, err := r.reader.ReadBytes('\n')
+		if err == io.EOF {
+			return logs, ends, nil
+		}
+		if err != nil {
+			return logs, ends, err
+		}
+		r.offset += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var log models.AuditLog
+		if err := json.Unmarshal(line, &log); err != nil {
+			bad(line, fmt.Errorf("离线缓存中的日志无法解析: %w", err))
+			if len(logs) == 0 {
+				r.consumed = r.offset
+			} else {
+				ends[len(ends)-1] = r.offset
+			}
+			continue
+		}
+		logs = append(logs, &log)
+		ends = append(ends, r.offset)
+	}
+	return logs, ends, nil
+}
+
+// close 关闭缓存文件
+func (r *spoolReader) close() {
+	r.file.Close()
+}
+
+// removeIfExists 删除文件，文件不存在时不报错
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidVerifyRange      = errors.New("校验范围无效，开始时间不能晚于结束时间")
+	ErrVerifyRangeTooLarge     = errors.New("校验范围不能超过 31 天，请分段校验")
+	ErrSigningKeyNotConfigured = errors.New("未配置审计归档签名密钥（audit.integrity.signing_key 或 AUDIT_SIGNING_KEY），拒绝生成无法校验的归档")
+)
+
+// SourceDatabase 校验结果中表示记录来自数据库
+const SourceDatabase = "database"
+
+// verifyMargin 校验范围两侧额外加载的时间，用于取得范围内第一条记录的前驱
+// （同一时刻的并发请求写入顺序可能和创建时间略有出入，跨天的记录也可能被归档到相邻日期）
+const verifyMargin = 24 * time.Hour
+
+// maxVerifyRange 单次校验的最大时间范围，避免一次载入过多记录
+const maxVerifyRange = 31 * 24 * time.Hour
+
+// verifyBatchSize 从数据库分批读取记录的批大小
+const verifyBatchSize = 1000
+
+// IntegrityService 审计日志防篡改校验服务
+type IntegrityService struct {
+	db             *gorm.DB
+	config         *config.AuditConfig
+	archiveService *ArchiveService
+}
+
+// NewIntegrityService 创建防篡改校验服务
+func NewIntegrityService(db *gorm.DB, cfg *config.AuditConfig, archiveService *ArchiveService) *IntegrityService {
+	return &IntegrityService{
+		db:             db,
+		config:         cfg,
+		archiveService: archiveService,
+	}
+}
+
+// ChainBreak 哈希链断裂位置
+type ChainBreak struct {
+	Seq       uint64    `json:"seq"`
+	LogID     uint      `json:"log_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Source    string    `json:"source"` // database 或归档文件路径
+	Reason    string    `json:"reason"`
+}
+
+// ArchiveCheck 单个归档文件的校验结果
+type ArchiveCheck struct {
+	File        string `json:"file"`
+	RecordCount int    `json:"record_count"`
+	Signed      bool   `json:"signed"`
+	Valid       bool   `json:"valid"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// VerifyReport 哈希链校验报告
+type VerifyReport struct {
+	From            time.Time      `json:"from"`
+	To              time.Time      `json:"to"`
+	Verified        bool           `json:"verified"`
+	Checked         int            `json:"checked"`          // 范围内校验过的链上记录数
+	DatabaseRecords int            `json:"database_records"` // 其中来自数据库的记录数
+	ArchivedRecords int            `json:"archived_records"` // 其中来自归档文件的记录数
+	Unchained       int            `json:"unchained"`        // 范围内启用哈希链之前的旧记录（无法校验）
+	FirstSeq        uint64         `json:"first_seq"`
+	LastSeq         uint64         `json:"last_seq"`
+	Archives        []ArchiveCheck `json:"archives"`
+	Breaks          int            `json:"breaks"`                // 发现的断裂数
+	FirstBreak      *ChainBreak    `json:"first_break,omitempty"` // 链上位置最靠前的断裂
+}
+
+// chainRecord 参与校验的记录：只保留链字段和内容哈希的校验结果，不在内存中保留请求体等内容
+type chainRecord struct {
+	id        uint
+	seq       uint64
+	createdAt time.Time
+	hash      string
+	prevHash  string
+	contentOK bool // 记录内容与哈希一致
+	source    string
+}
+
+// newChainRecord 计算内容哈希并生成校验记录
+func newChainRecord(log *models.AuditLog, source string) chainRecord {
+	return chainRecord{
+		id:        log.ID,
+		seq:       log.Seq,
+		createdAt: log.CreatedAt,
+		hash:      log.Hash,
+		prevHash:  log.PrevHash,
+		contentOK: ComputeLogHash(log) == log.Hash,
+		source:    source,
+	}
+}
+
+// position 断裂位置信息
+func (r *chainRecord) position() *models.AuditLog {
+	return &models.AuditLog{ID: r.id, Seq: r.seq, CreatedAt: r.createdAt}
+}
+
+// Verify 校验时间范围内数据库和归档文件中的哈希链，报告第一处断裂（范围不超过 31 天）
+func (s *IntegrityService) Verify(from, to time.Time) (*VerifyReport, error) {
+	if from.After(to) {
+		return nil, ErrInvalidVerifyRange
+	}
+	if to.Sub(from) > maxVerifyRange {
+		return nil, ErrVerifyRangeTooLarge
+	}
+
+	report := &VerifyReport{
+		From:     from,
+		To:       to,
+		Archives: []ArchiveCheck{},
+	}
+	inRange := func(log *models.AuditLog) bool {
+		return !log.CreatedAt.Before(from) && !log.CreatedAt.After(to)
+	}
+
+	// 按序号合并两处来源的记录：归档成功但删除失败时同一条记录会同时出现在两处
+	records := make(map[uint64]chainRecord)
+	add := func(log *models.AuditLog, source string) {
+		if log.Seq == 0 {
+			if inRange(log) {
+				report.Unchained++
+			}
+			return
+		}
+		existing, ok := records[log.Seq]
+		if !ok {
+			records[log.Seq] = newChainRecord(log, source)
+			return
+		}
+		if existing.hash != log.Hash && inRange(log) {
+			report.breakAt(log, source, fmt.Sprintf("序号 %d 在 %s 中存在内容不同的记录", log.Seq, existing.source))
+		}
+	}
+
+	// 1. 归档文件：先校验清单签名和根哈希
+	files, err := s.archiveService.ListArchiveFiles(dayStart(from.Add(-verifyMargin)), dayStart(to.Add(verifyMargin)))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		archive, err := s.archiveService.LoadFromArchive(file)
+		if err != nil {
+			report.Archives = append(report.Archives, ArchiveCheck{File: file, Reason: err.Error()})
+			report.breakAt(&models.AuditLog{}, file, "归档文件无法读取: "+err.Error())
+			continue
+		}
+
+		check := s.checkArchive(file, archive)
+		report.Archives = append(report.Archives, check)
+		if !check.Valid {
+			// 断裂位置取归档中第一条落在校验范围内的记录，范围之外的归档只用于取前驱
+			for i := range archive.Logs {
+				if inRange(&archive.Logs[i]) {
+					report.breakAt(&archive.Logs[i], file, check.Reason)
+					break
+				}
+			}
+		}
+		for i := range archive.Logs {
+			add(&archive.Logs[i], file)
+		}
+	}
+
+	// 2. 数据库中的记录（分批读取）
+	var batch []models.AuditLog
+	if err := s.db.Where("created_at >= ? AND created_at <= ?", from.Add(-verifyMargin), to.Add(verifyMargin)).
+		FindInBatches(&batch, verifyBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				add(&batch[i], SourceDatabase)
+			}
+			return nil
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	// 3. 按序号依次校验：内容哈希、序号连续、与上一条记录相连
+	ordered := make([]chainRecord, 0, len(records))
+	for _, record := range records {
+		ordered = append(ordered, record)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].seq < ordered[j].seq })
+
+	for i := range ordered {
+		record := &ordered[i]
+		log := record.position()
+		if !inRange(log) {
+			continue
+		}
+
+		report.Checked++
+		if record.source == SourceDatabase {
+			report.DatabaseRecords++
+		} else {
+			report.ArchivedRecords++
+		}
+		if report.FirstSeq == 0 {
+			report.FirstSeq = log.Seq
+		}
+		report.LastSeq = log.Seq
+
+		if !record.contentOK {
+			report.breakAt(log, record.source, "记录内容与哈希不一致，可能被修改")
+			continue
+		}
+		if i == 0 {
+			// 链的第一条记录没有前驱；否则前驱在校验范围之外，作为校验起点
+			if record.seq == 1 && record.prevHash != "" {
+				report.breakAt(log, record.source, "第一条记录的 prev_hash 应为空")
+			}
+			continue
+		}
+
+		prev := &ordered[i-1]
+		if record.seq != prev.seq+1 {
+			report.breakAt(log, record.source, fmt.Sprintf("缺少序号 %s 的记录，可能被删除", seqRange(prev.seq+1, record.seq-1)))
+		} else if record.prevHash != prev.hash {
+			report.breakAt(log, record.source, fmt.Sprintf("prev_hash 与序号 %d 的记录哈希不一致", prev.seq))
+		}
+	}
+
+	// 4. 范围覆盖到最新记录时，和链头比对，发现链尾记录被删除
+	head, err := GetChainHead(s.db)
+	if err != nil {
+		return nil, err
+	}
+	if head.LastSeq > 0 && !to.Before(head.UpdatedAt) {
+		var last *chainRecord
+		if len(ordered) > 0 {
+			last = &ordered[len(ordered)-1]
+		}
+		switch {
+		case last == nil || last.seq < head.LastSeq:
+			lastSeq := uint64(0)
+			if last != nil {
+				lastSeq = last.seq
+			}
+			report.breakAt(&models.AuditLog{Seq: lastSeq + 1}, SourceDatabase,
+				fmt.Sprintf("链头序号为 %d，缺少序号 %s 的记录，可能被删除", head.LastSeq, seqRange(lastSeq+1, head.LastSeq)))
+		case last.seq == head.LastSeq && last.hash != head.LastHash:
+			report.breakAt(last.position(), last.source, "最后一条记录的哈希与链头不一致")
+		}
+	}
+
+	report.Verified = report.Breaks == 0
+	return report, nil
+}
+
+// checkArchive 校验归档文件的清单签名和根哈希
+func (s *IntegrityService) checkArchive(file string, archive *ArchiveFile) ArchiveCheck {
+	check := ArchiveCheck{
+		File:        file,
+		RecordCount: len(archive.Logs),
+		Signed:      archive.Manifest != nil,
+	}
+
+	manifest := archive.Manifest
+	switch {
+	case manifest == nil:
+		check.Reason = "归档文件缺少签名清单"
+	case !s.config.SigningKeyConfigured():
+		check.Reason = "未配置签名密钥，无法校验归档清单签名"
+	case !manifest.VerifySignature(s.config.IntegritySigningKey):
+		check.Reason = "归档清单签名无效"
+	case manifest.RecordCount != len(archive.Logs):
+		check.Reason = fmt.Sprintf("归档记录数为 %d，清单记录数为 %d", len(archive.Logs), manifest.RecordCount)
+	case manifest.RootHash != archiveRootHash(archive.Logs):
+		check.Reason = "归档记录与清单根哈希不一致，可能被修改"
+	default:
+		check.Valid = true
+		for i := range archive.Logs {
+			if archive.Logs[i].Seq != 0 && ComputeLogHash(&archive.Logs[i]) != archive.Logs[i].Hash {
+				check.Valid = false
+				check.Reason = fmt.Sprintf("归档中序号 %d 的记录内容与哈希不一致，可能被修改", archive.Logs[i].Seq)
+				break
+			}
+		}
+	}
+
+	// 启用哈希链之前生成的归档无法校验，只在其中有链上记录时视为异常
+	if manifest == nil {
+		check.Valid = true
+		for _, log := range archive.Logs {
+			if log.Seq != 0 {
+				check.Valid = false
+				break
+			}
+		}
+		if check.Valid {
+			check.Reason = "启用哈希链之前的归档，无法校验"
+		}
+	}
+	return check
+}
+
+// breakAt 记录一处断裂，只保留链上位置最靠前的一处（无法确定位置的断裂，序号为 0，排在最前）
+func (r *VerifyReport) breakAt(log *models.AuditLog, source, reason string) {
+	r.Breaks++
+	if r.FirstBreak != nil && r.FirstBreak.Seq <= log.Seq {
+		return
+	}
+	r.FirstBreak = &ChainBreak{
+		Seq:       log.Seq,
+		LogID:     log.ID,
+		CreatedAt: log.CreatedAt,
+		Source:    source,
+		Reason:    reason,
+	}
+}
+
+// seqRange 序号区间的描述
+func seqRange(first, last uint64) string {
+	if first == last {
+		return fmt.Sprintf("%d", first)
+	}
+	return fmt.Sprintf("%d 至 %d", first, last)
+}
+
+// dayStart 当天零点
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
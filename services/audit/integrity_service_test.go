@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newIntegrityTestService 创建使用临时数据库和归档目录的校验服务
+func newIntegrityTestService(t *testing.T) (*IntegrityService, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.AuditLog{}, &models.AuditChainHead{}))
+
+	cfg := &config.AuditConfig{
+		ArchiveEnabled:      true,
+		ArchiveLocalEnabled: true,
+		ArchiveStorageDir:   t.TempDir(),
+		ArchiveCompression:  true,
+		IntegritySigningKey: "test-signing-key",
+	}
+	return NewIntegrityService(db, cfg, NewArchiveService(db, cfg)), db
+}
+
+// insertTestLogs 按时间顺序写入链上记录
+func insertTestLogs(t *testing.T, db *gorm.DB, times ...time.Time) []*models.AuditLog {
+	t.Helper()
+	logs := make([]*models.AuditLog, len(times))
+	for i, createdAt := range times {
+		logs[i] = &models.AuditLog{Username: "alice", Action: "update", Resource: "documents", StatusCode: 200, CreatedAt: createdAt}
+	}
+	assert.NoError(t, insertChained(db, logs))
+	return logs
+}
+
+// recentTimes 最近一小时内的 n 个时间点
+func recentTimes(n int) []time.Time {
+	times := make([]time.Time, n)
+	for i := range times {
+		times[i] = time.Now().Add(time.Duration(i-n) * time.Minute)
+	}
+	return times
+}
+
+// TestVerifyIntactChain 测试完整的哈希链通过校验
+func TestVerifyIntactChain(t *testing.T) {
+	service, db := newIntegrityTestService(t)
+	insertTestLogs(t, db, recentTimes(5)...)
+
+	report, err := service.Verify(time.Now().Add(-2*time.Hour), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, report.Verified)
+	assert.Equal(t, 5, report.Checked)
+	assert.Equal(t, 5, report.DatabaseRecords)
+	assert.Equal(t, uint64(1), report.FirstSeq)
+	assert.Equal(t, uint64(5), report.LastSeq)
+	assert.Nil(t, report.FirstBreak)
+}
+
+// TestVerifyDetectsBreaks 测试发现修改、删除中间记录和删除链尾记录
+func TestVerifyDetectsBreaks(t *testing.T) {
+	from, to := time.Now().Add(-2*time.Hour), time.Now().Add(time.Minute)
+
+	t.Run("修改记录内容", func(t *testing.T) {
+		service, db := newIntegrityTestService(t)
+		logs := insertTestLogs(t, db, recentTimes(5)...)
+		db.Model(&models.AuditLog{}).Where("id = ?", logs[2].ID).UpdateColumn("username", "mallory")
+
+		report, err := service.Verify(from, to)
+		assert.NoError(t, err)
+		assert.False(t, report.Verified)
+		assert.Equal(t, uint64(3), report.FirstBreak.Seq)
+		assert.Equal(t, logs[2].ID, report.FirstBreak.LogID)
+		assert.Equal(t, SourceDatabase, report.FirstBreak.Source)
+		assert.Contains(t, report.FirstBreak.Reason, "内容与哈希不一致")
+	})
+
+	t.Run("重新计算哈希的修改仍会断开后一条记录", func(t *testing.T) {
+		service, db := newIntegrityTestService(t)
+		logs := insertTestLogs(t, db, recentTimes(5)...)
+		forged := *logs[1]
+		forged.Username = "mallory"
+		forged.Hash = ComputeLogHash(&forged)
+		db.Model(&models.AuditLog{}).Where("id = ?", forged.ID).
+			UpdateColumns(map[string]interface{}{"username": forged.Username, "hash": forged.Hash})
+
+		report, err := service.Verify(from, to)
+		assert.NoError(t, err)
+		assert.False(t, report.Verified)
+		assert.Equal(t, uint64(3), report.FirstBreak.Seq)
+		assert.Contains(t, report.FirstBreak.Reason, "prev_hash")
+	})
+
+	t.Run("删除中间记录", func(t *testing.T) {
+		service, db := newIntegrityTestService(t)
+		logs := insertTestLogs(t, db, recentTimes(5)...)
+		db.Delete(&models.AuditLog{}, logs[2].ID)
+
+		report, err := service.Verify(from, to)
+		assert.NoError(t, err)
+		assert.False(t, report.Verified)
+		assert.Equal(t, uint64(4), report.FirstBreak.Seq)
+		assert.Contains(t, report.FirstBreak.Reason, "缺少序号 3")
+	})
+
+	t.Run("删除链尾记录", func(t *testing.T) {
+		service, db := newIntegrityTestService(t)
+		logs := insertTestLogs(t, db, recentTimes(5)...)
+		db.Delete(&models.AuditLog{}, logs[4].ID)
+
+		report, err := service.Verify(from, to)
+		assert.NoError(t, err)
+		assert.False(t, report.Verified)
+		assert.Equal(t, uint64(5), report.FirstBreak.Seq)
+		assert.Contains(t, report.FirstBreak.Reason, "链头序号为 5")
+	})
+}
+
+// TestVerifyArchives 测试归档文件的清单签名和归档与数据库之间的链接
+func TestVerifyArchives(t *testing.T) {
+	day := dayStart(time.Now().AddDate(0, 0, -10))
+	archivedTimes := []time.Time{day.Add(time.Hour), day.Add(2 * time.Hour), day.Add(3 * time.Hour)}
+	from, to := day, day.Add(24*time.Hour)
+
+	t.Run("归档后链仍然完整", func(t *testing.T) {
+		service, db := newIntegrityTestService(t)
+		insertTestLogs(t, db, archivedTimes...)
+		insertTestLogs(t, db, recentTimes(2)...)
+		archived, err := service.archiveService.archiveByDate(day)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), archived)
+
+		report, err := service.Verify(from, to)
+		assert.NoError(t, err)
+		assert.True(t, report.Verified)
+		assert.Equal(t, 3, report.ArchivedRecords)
+		assert.Len(t, report.Archives, 1)
+		assert.True(t, report.Archives[0].Signed)
+		assert.True(t, report.Archives[0].Valid)
+
+		// 同时覆盖归档和数据库：数据库中的第一条记录以归档中的最后一条为前驱
+		report, err = service.Verify(from, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, report.Verified)
+		assert.Equal(t, 5, report.Checked)
+		assert.Equal(t, 3, report.ArchivedRecords)
+		assert.Equal(t, 2, report.DatabaseRecords)
+	})
+
+	t.Run("签名密钥不一致", func(t *testing.T) {
+		service, db := newIntegrityTestService(t)
+		insertTestLogs(t, db, archivedTimes...)
+		_, err := service.archiveService.archiveByDate(day)
+		assert.NoError(t, err)
+		service.config.IntegritySigningKey = "another-key"
+
+		report, err := service.Verify(from, to)
+		assert.NoError(t, err)
+		assert.False(t, report.Verified)
+		assert.False(t, report.Archives[0].Valid)
+		assert.Equal(t, "归档清单签名无效", report.Archives[0].Reason)
+		assert.Equal(t, uint64(1), report.FirstBreak.Seq)
+	})
+
+	t.Run("未配置签名密钥时无法校验", func(t *testing.T) {
+		service, db := newIntegrityTestService(t)
+		insertTestLogs(t, db, archivedTimes...)
+		_, err := service.archiveService.archiveByDate(day)
+		assert.NoError(t, err)
+		service.config.IntegritySigningKey = ""
+
+		report, err := service.Verify(from, to)
+		assert.NoError(t, err)
+		assert.False(t, report.Verified)
+		assert.Contains(t, report.Archives[0].Reason, "未配置签名密钥")
+	})
+}
+
+// TestVerifyRange 测试校验范围检查
+func TestVerifyRange(t *testing.T) {
+	service, _ := newIntegrityTestService(t)
+	now := time.Now()
+
+	_, err := service.Verify(now, now.Add(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidVerifyRange)
+
+	_, err = service.Verify(now.Add(-maxVerifyRange-time.Second), now)
+	assert.ErrorIs(t, err, ErrVerifyRangeTooLarge)
+
+	report, err := service.Verify(now.Add(-maxVerifyRange), now)
+	assert.NoError(t, err)
+	assert.True(t, report.Verified)
+	assert.Zero(t, report.Checked)
+}
@@ -1,24 +1,49 @@
 package audit
 
 import (
+	"encoding/json"
 	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
 	"go_wails_project_manager/models"
+	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// QueryService 查询服务
+// defaultArchiveLookback 未指定开始时间时查询的归档范围（与归档统计一致，最近一年）
+const defaultArchiveLookback = 365 * 24 * time.Hour
+
+const (
+	segmentCacheTTL     = 5 * time.Minute // 归档统计结果的缓存时间，翻页时不再重复解压归档
+	maxSegmentCacheSize = 32              // 最多缓存的过滤条件数
+)
+
+// QueryService 查询服务（同时查询数据库和归档文件）
 type QueryService struct {
-	db     *gorm.DB
-	config *config.AuditConfig
+	db             *gorm.DB
+	config         *config.AuditConfig
+	archiveService *ArchiveService
+
+	cacheMu      sync.Mutex
+	segmentCache map[string]*segmentCacheEntry // 过滤条件 -> 归档统计结果
 }
 
-// NewQueryService 创建查询服务
-func NewQueryService(db *gorm.DB, cfg *config.AuditConfig) *QueryService {
+// segmentCacheEntry 缓存的归档统计结果（不含日志内容，只保存文件列表和匹配数量）
+type segmentCacheEntry struct {
+	segments []archiveSegment
+	expires  time.Time
+}
+
+// NewQueryService 创建查询服务，archiveService 为 nil 时只查询数据库
+func NewQueryService(db *gorm.DB, cfg *config.AuditConfig, archiveService *ArchiveService) *QueryService {
 	return &QueryService{
-		db:     db,
-		config: cfg,
+		db:             db,
+		config:         cfg,
+		archiveService: archiveService,
+		segmentCache:   make(map[string]*segmentCacheEntry),
 	}
 }
 
@@ -35,6 +60,7 @@ type AuditFilter struct {
 	StatusCode *int
 	Page       int
 	PageSize   int
+	LiveOnly   bool // 只查询数据库，不查询归档文件
 }
 
 // AuditStatistics 审计统计信息
@@ -58,19 +84,8 @@ type IPActivity struct {
 	Count int64  `json:"count"`
 }
 
-// List 查询审计日志列表
+// List 查询审计日志列表（数据库和归档文件，按时间倒序统一分页）
 func (s *QueryService) List(filter AuditFilter) ([]models.AuditLog, int64, error) {
-	query := s.db.Model(&models.AuditLog{})
-
-	// 应用过滤条件
-	query = s.applyFilters(query, filter)
-
-	// 统计总数
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
 	// 分页
 	if filter.Page <= 0 {
 		filter.Page = 1
@@ -84,18 +99,303 @@ func (s *QueryService) List(filter AuditFilter) ([]models.AuditLog, int64, error
 
 	offset := (filter.Page - 1) * filter.PageSize
 
-	// 查询数据
-	var logs []models.AuditLog
-	if err := query.Order("created_at DESC").
-		Offset(offset).
-		Limit(filter.PageSize).
-		Find(&logs).Error; err != nil {
+	return s.search(filter, offset, filter.PageSize)
+}
+
+// search 按时间倒序查询数据库和归档文件中满足条件的日志
+// 数据库中是保留期内的新数据，归档文件是更早的数据，因此先取数据库，再按日期从新到旧接续归档
+func (s *QueryService) search(filter AuditFilter, offset, limit int) ([]models.AuditLog, int64, error) {
+	query := s.applyFilters(s.db.Model(&models.AuditLog{}), filter)
+
+	var liveTotal int64
+	if err := query.Count(&liveTotal).Error; err != nil {
+		return nil, 0, err
+	}
+
+	segments, err := s.archiveSegments(filter)
+	if err != nil {
 		return nil, 0, err
 	}
+	total := liveTotal
+	for _, segment := range segments {
+		total += int64(segment.count)
+	}
+
+	logs := make([]models.AuditLog, 0, limit)
+	if int64(offset) < liveTotal {
+		if err := query.Order("created_at DESC").
+			Offset(offset).
+			Limit(limit).
+			Find(&logs).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	skip := max(offset-int(liveTotal), 0)
+	for _, segment := range segments {
+		if len(logs) >= limit {
+			break
+		}
+		if skip >= segment.count {
+			skip -= segment.count
+			continue
+		}
+		archived := segment.load(s.archiveService, filter)
+		end := min(skip+limit-len(logs), len(archived))
+		if skip < end {
+			logs = append(logs, archived[skip:end]...)
+		}
+		skip = 0
+	}
 
 	return logs, total, nil
 }
 
+// eachBatchSize 遍历数据库记录时每批读取的数量
+const eachBatchSize = 500
+
+// Each 按时间正序遍历满足条件的全部日志（先归档后数据库），用于导出
+func (s *QueryService) Each(filter AuditFilter, fn func(log *models.AuditLog) error) error {
+	segments, err := s.archiveSegments(filter)
+	if err != nil {
+		return err
+	}
+	for i := len(segments) - 1; i >= 0; i-- {
+		logs := segments[i].load(s.archiveService, filter)
+		for j := len(logs) - 1; j >= 0; j-- {
+			if err := fn(&logs[j]); err != nil {
+				return err
+			}
+		}
+	}
+
+	query := s.applyFilters(s.db.Model(&models.AuditLog{}), filter).
+		Order("created_at ASC, id ASC")
+	for offset := 0; ; offset += eachBatchSize {
+		var batch []models.AuditLog
+		if err := query.Session(&gorm.Session{}).
+			Offset(offset).
+			Limit(eachBatchSize).
+			Find(&batch).Error; err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < eachBatchSize {
+			return nil
+		}
+	}
+}
+
+// archiveSegment 一天的归档（同一天重复归档时可能有多个文件）
+type archiveSegment struct {
+	files      []string
+	start, end time.Time         // 归档记录的时间范围
+	count      int               // 满足过滤条件的记录数
+	live       map[uint]bool     // 归档后删除失败、仍留在数据库中的记录ID，由数据库查询返回
+	logs       []models.AuditLog // 过滤后按时间倒序的记录，nil 表示尚未加载
+}
+
+// load 解压归档文件并过滤（结果缓存在 segment 中），无法读取的归档跳过
+// 重复归档的记录按ID去重，仍在数据库中的记录不再从归档返回
+func (seg *archiveSegment) load(archiveService *ArchiveService, filter AuditFilter) []models.AuditLog {
+	if seg.logs != nil {
+		return seg.logs
+	}
+
+	logs := make([]models.AuditLog, 0, seg.count)
+	seen := make(map[uint]bool)
+	for _, file := range seg.files {
+		archive, err := archiveService.LoadFromArchive(file)
+		if err != nil {
+			logger.Log.Warnf("读取归档文件失败: %s, error: %v", file, err)
+			continue
+		}
+		for i := range archive.Logs {
+			id := archive.Logs[i].ID
+			if seen[id] || seg.live[id] {
+				continue
+			}
+			seen[id] = true
+			if matchesFilter(&archive.Logs[i], filter) {
+				log := archive.Logs[i]
+				log.Archived = true
+				logs = append(logs, log)
+			}
+		}
+	}
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].CreatedAt.After(logs[j].CreatedAt) })
+
+	seg.logs = logs
+	return logs
+}
+
+// archiveSegments 查询范围内可能有匹配记录的归档（按日期从新到旧）
+// 先读每个归档的索引：没有匹配的直接跳过，能准确计数的不解压，其余解压后逐条过滤计数
+// 统计结果按过滤条件缓存，翻页和导出时不再重复解压
+func (s *QueryService) archiveSegments(filter AuditFilter) ([]*archiveSegment, error) {
+	if s.archiveService == nil || filter.LiveOnly {
+		return nil, nil
+	}
+
+	key := segmentCacheKey(filter)
+	if segments, ok := s.cachedSegments(key); ok {
+		return segments, nil
+	}
+	segments, err := s.scanArchiveSegments(filter)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheSegments(key, segments)
+	return segments, nil
+}
+
+// scanArchiveSegments 读取归档索引统计每天的匹配记录数
+func (s *QueryService) scanArchiveSegments(filter AuditFilter) ([]*archiveSegment, error) {
+	end := time.Now()
+	if filter.EndTime != nil {
+		end = *filter.EndTime
+	}
+	start := time.Now().Add(-defaultArchiveLookback)
+	if filter.StartTime != nil {
+		start = *filter.StartTime
+	}
+	if start.After(end) {
+		return nil, nil
+	}
+
+	files, err := s.archiveService.ListArchiveFiles(dayStart(start), dayStart(end))
+	if err != nil {
+		return nil, err
+	}
+
+	// ListArchiveFiles 按日期目录升序返回，同一目录的文件属于同一天
+	var segments []*archiveSegment
+	segmentByDir := make(map[string]*archiveSegment)
+	needsLoad := make(map[*archiveSegment]bool)
+	for _, file := range files {
+		index, err := s.archiveService.LoadArchiveIndex(file)
+		if err != nil {
+			logger.Log.Warnf("读取归档索引失败: %s, error: %v", file, err)
+			continue
+		}
+		count, exact := index.Count(filter)
+		if count == 0 {
+			continue
+		}
+
+		dir := filepath.Dir(file)
+		segment, ok := segmentByDir[dir]
+		if !ok {
+			segment = &archiveSegment{start: index.Start, end: index.End}
+			segmentByDir[dir] = segment
+			segments = append(segments, segment)
+		}
+		segment.files = append(segment.files, file)
+		segment.count += count
+		if index.Start.Before(segment.start) {
+			segment.start = index.Start
+		}
+		if index.End.After(segment.end) {
+			segment.end = index.End
+		}
+		if !exact {
+			needsLoad[segment] = true
+		}
+	}
+
+	result := make([]*archiveSegment, 0, len(segments))
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := segments[i]
+		// 重复归档或归档后删除失败时同一条记录会出现多次，需要解压后去重计数
+		live, err := s.liveIDs(segment.start, segment.end)
+		if err != nil {
+			return nil, err
+		}
+		if len(live) > 0 || len(segment.files) > 1 {
+			segment.live = live
+			needsLoad[segment] = true
+		}
+		if needsLoad[segment] {
+			segment.count = len(segment.load(s.archiveService, filter))
+		}
+		if segment.count > 0 {
+			result = append(result, segment)
+		}
+	}
+	return result, nil
+}
+
+// liveIDs 查询归档时间范围内仍留在数据库中的记录ID（正常归档后为空）
+func (s *QueryService) liveIDs(start, end time.Time) (map[uint]bool, error) {
+	var ids []uint
+	if err := s.db.Model(&models.AuditLog{}).
+		Where("created_at >= ? AND created_at <= ?", start, end).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	live := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		live[id] = true
+	}
+	return live, nil
+}
+
+// segmentCacheKey 归档统计缓存的键（与分页无关）
+func segmentCacheKey(filter AuditFilter) string {
+	filter.Page, filter.PageSize = 0, 0
+	key, _ := json.Marshal(filter)
+	return string(key)
+}
+
+// cachedSegments 返回缓存的归档统计（副本，加载的日志只在本次查询中使用）
+func (s *QueryService) cachedSegments(key string) ([]*archiveSegment, bool) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	entry, ok := s.segmentCache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	segments := make([]*archiveSegment, len(entry.segments))
+	for i := range entry.segments {
+		segment := entry.segments[i]
+		segments[i] = &segment
+	}
+	return segments, true
+}
+
+// cacheSegments 缓存归档统计，不保存已解压的日志以控制内存占用
+func (s *QueryService) cacheSegments(key string, segments []*archiveSegment) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	now := time.Now()
+	if len(s.segmentCache) >= maxSegmentCacheSize {
+		for k, entry := range s.segmentCache {
+			if now.After(entry.expires) {
+				delete(s.segmentCache, k)
+			}
+		}
+		if len(s.segmentCache) >= maxSegmentCacheSize {
+			s.segmentCache = make(map[string]*segmentCacheEntry)
+		}
+	}
+
+	entry := &segmentCacheEntry{
+		segments: make([]archiveSegment, len(segments)),
+		expires:  now.Add(segmentCacheTTL),
+	}
+	for i, segment := range segments {
+		entry.segments[i] = *segment
+		entry.segments[i].logs = nil
+	}
+	s.segmentCache[key] = entry
+}
+
 // GetByID 根据ID查询审计日志
 func (s *QueryService) GetByID(id uint) (*models.AuditLog, error) {
 	var log models.AuditLog
@@ -105,38 +405,24 @@ func (s *QueryService) GetByID(id uint) (*models.AuditLog, error) {
 	return &log, nil
 }
 
-// GetByUser 查询用户的审计日志
+// GetByUser 查询用户的审计日志（包括归档）
 func (s *QueryService) GetByUser(userID uint, limit int) ([]models.AuditLog, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 
-	var logs []models.AuditLog
-	if err := s.db.Where("user_id = ?", userID).
-		Order("created_at DESC").
-		Limit(limit).
-		Find(&logs).Error; err != nil {
-		return nil, err
-	}
-
-	return logs, nil
+	logs, _, err := s.search(AuditFilter{UserID: &userID}, 0, limit)
+	return logs, err
 }
 
-// GetByResource 查询资源的审计日志
+// GetByResource 查询资源的审计日志（包括归档）
 func (s *QueryService) GetByResource(resource string, resourceID uint, limit int) ([]models.AuditLog, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 
-	var logs []models.AuditLog
-	if err := s.db.Where("resource = ? AND resource_id = ?", resource, resourceID).
-		Order("created_at DESC").
-		Limit(limit).
-		Find(&logs).Error; err != nil {
-		return nil, err
-	}
-
-	return logs, nil
+	logs, _, err := s.search(AuditFilter{Resource: resource, ResourceID: &resourceID}, 0, limit)
+	return logs, err
 }
 
 // GetByIP 查询IP的审计日志
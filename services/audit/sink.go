@@ -0,0 +1,394 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+)
+
+// 接收端类型
+const (
+	SinkTypeSyslog = "syslog" // RFC 5424 syslog（TCP/UDP）
+	SinkTypeHTTP   = "http"   // HTTP POST JSON
+	SinkTypeFile   = "file"   // 按大小轮转的本地文件
+)
+
+// 接收端消息格式（syslog 和 file）
+const (
+	SinkFormatCEF  = "cef"
+	SinkFormatJSON = "json"
+)
+
+var (
+	ErrSinkTypeInvalid   = errors.New("审计日志接收端类型无效，可选 syslog、http、file")
+	ErrSinkFormatInvalid = errors.New("审计日志接收端消息格式无效，可选 cef、json")
+)
+
+// 接收端默认值
+const (
+	defaultSinkTimeout    = 10 * time.Second
+	defaultSyslogFacility = 13 // log audit
+	defaultFileMaxSizeMB  = 100
+	defaultFileMaxBackups = 10
+)
+
+// Sink 审计日志外部接收端；Send 失败时由转发器重试和离线缓存，实现只需在出错时返回错误
+// 接收端明确拒绝、重试也不会成功的日志返回 *PermanentError，转发器会把它移入死信文件
+type Sink interface {
+	Name() string
+	Send(logs []*models.AuditLog) error
+	Close() error
+}
+
+// PermanentError 不可重试的发送错误（如 HTTP 4xx、UDP 消息超过数据报上限、日志无法编码）
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// isPermanent 是否为不可重试的发送错误
+func isPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// wsaEMSGSIZE Windows 下数据报过长的错误码（syscall.EMSGSIZE 在 Windows 上只是占位值）
+const wsaEMSGSIZE = syscall.Errno(10040)
+
+// isMessageTooLong UDP 消息超过数据报上限
+func isMessageTooLong(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && (errno == syscall.EMSGSIZE || errno == wsaEMSGSIZE)
+}
+
+// NewSink 根据配置创建接收端
+func NewSink(cfg config.AuditSinkConfig) (Sink, error) {
+	if cfg.Name == "" {
+		cfg.Name = cfg.Type
+	}
+	if cfg.Format == "" {
+		cfg.Format = SinkFormatJSON
+	}
+	if cfg.Format != SinkFormatCEF && cfg.Format != SinkFormatJSON {
+		return nil, ErrSinkFormatInvalid
+	}
+	timeout := defaultSinkTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	switch cfg.Type {
+	case SinkTypeSyslog:
+		if cfg.Network == "" {
+			cfg.Network = "udp"
+		}
+		if cfg.Network != "tcp" && cfg.Network != "udp" {
+			return nil, fmt.Errorf("syslog 接收端 %s 的 network 无效，可选 tcp、udp", cfg.Name)
+		}
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("syslog 接收端 %s 缺少 address", cfg.Name)
+		}
+		if cfg.AppName == "" {
+			cfg.AppName = cefVendor
+		}
+		if cfg.Facility <= 0 || cfg.Facility > 23 {
+			cfg.Facility = defaultSyslogFacility
+		}
+		hostname, _ := os.Hostname()
+		if hostname == "" {
+			hostname = "-"
+		}
+		return &syslogSink{cfg: cfg, hostname: hostname, timeout: timeout}, nil
+
+	case SinkTypeHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("http 接收端 %s 缺少 url", cfg.Name)
+		}
+		return &httpSink{cfg: cfg, client: &http.Client{Timeout: timeout}}, nil
+
+	case SinkTypeFile:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("file 接收端 %s 缺少 path", cfg.Name)
+		}
+		if cfg.MaxSizeMB <= 0 {
+			cfg.MaxSizeMB = defaultFileMaxSizeMB
+		}
+		if cfg.MaxBackups <= 0 {
+			cfg.MaxBackups = defaultFileMaxBackups
+		}
+		return &fileSink{cfg: cfg}, nil
+
+	default:
+		return nil, ErrSinkTypeInvalid
+	}
+}
+
+// formatMessage 按接收端格式生成单条消息
+func formatMessage(format string, log *models.AuditLog) ([]byte, error) {
+	if format == SinkFormatCEF {
+		return []byte(FormatCEF(log)), nil
+	}
+	return json.Marshal(log)
+}
+
+// syslogSink RFC 5424 syslog 接收端，TCP 使用八位组计数分帧（RFC 6587）
+type syslogSink struct {
+	cfg      config.AuditSinkConfig
+	hostname string
+	timeout  time.Duration
+	conn     net.Conn
+}
+
+func (s *syslogSink) Name() string {
+	return s.cfg.Name
+}
+
+func (s *syslogSink) Send(logs []*models.AuditLog) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.cfg.Network, s.cfg.Address, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	for _, log := range logs {
+		msg, err := s.message(log)
+		if err != nil {
+			return &PermanentError{Err: err}
+		}
+		if s.cfg.Network == "tcp" {
+			msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+		if _, err := s.conn.Write(msg); err != nil {
+			if isMessageTooLong(err) {
+				return &PermanentError{Err: fmt.Errorf("日志 %d 超过 UDP 数据报上限（%d 字节）: %w", log.ID, len(msg), err)}
+			}
+			// 连接可能已断开，下次发送时重新建立
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// message 生成 RFC 5424 消息：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (s *syslogSink) message(log *models.AuditLog) ([]byte, error) {
+	body, err := formatMessage(s.cfg.Format, log)
+	if err != nil {
+		return nil, err
+	}
+
+	msgID := syslogField(log.Action, 32)
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s - ",
+		s.cfg.Facility*8+syslogSeverity(log.StatusCode),
+		log.CreatedAt.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogField(s.hostname, 255),
+		syslogField(s.cfg.AppName, 48),
+		os.Getpid(),
+		msgID,
+	)
+	return append([]byte(header), body...), nil
+}
+
+// syslogSeverity 按状态码映射 syslog 严重级别：5xx 为 error，4xx 为 warning，其余为 informational
+func syslogSeverity(statusCode int) int {
+	switch {
+	case statusCode >= 500:
+		return 3
+	case statusCode >= 400:
+		return 4
+	default:
+		return 6
+	}
+}
+
+// syslogField 头部字段只能是可打印 ASCII 且不含空格，为空时使用 NILVALUE（-）
+func syslogField(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// httpSink HTTP 接收端：每批 POST 一个 JSON 数组，非 2xx 视为失败
+type httpSink struct {
+	cfg    config.AuditSinkConfig
+	client *http.Client
+}
+
+func (s *httpSink) Name() string {
+	return s.cfg.Name
+}
+
+func (s *httpSink) Send(logs []*models.AuditLog) error {
+	body, err := json.Marshal(logs)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("接收端返回状态码 %d", resp.StatusCode)
+		// 4xx 表示接收端拒绝这批日志，超时（408）和限流（429）除外
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return &PermanentError{Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// fileSink 本地文件接收端：每行一条，超过大小后轮转为 path.1、path.2 ……
+type fileSink struct {
+	cfg  config.AuditSinkConfig
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (s *fileSink) Name() string {
+	return s.cfg.Name
+}
+
+func (s *fileSink) Send(logs []*models.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	maxSize := int64(s.cfg.MaxSizeMB) * 1024 * 1024
+	for _, log := range logs {
+		line, err := formatMessage(s.cfg.Format, log)
+		if err != nil {
+			return &PermanentError{Err: err}
+		}
+		line = append(line, '\n')
+
+		if s.size > 0 && s.size+int64(len(line)) > maxSize {
+			if err := s.rotate(); err != nil {
+				return err
+			}
+		}
+		n, err := s.file.Write(line)
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// open 打开（或创建）当前日志文件
+func (s *fileSink) open() error {
+	if s.file != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.cfg.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate 轮转：path.(n-1) -> path.n …… path -> path.1，超出保留数量的最旧文件被删除
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", s.cfg.Path, s.cfg.MaxBackups))
+	for i := s.cfg.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", s.cfg.Path, i), fmt.Sprintf("%s.%d", s.cfg.Path, i+1))
+	}
+	if err := os.Rename(s.cfg.Path, s.cfg.Path+".1"); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/utils"
+)
+
+var (
+	ErrInvalidPermissionCode = errors.New("权限代码格式错误，应为 资源:操作，如 projects:delete")
+)
+
+// 授予路径命中所查权限的方式（对应 middleware.MatchPermission 的匹配规则）
+const (
+	MatchExact            = "exact"             // 精确匹配
+	MatchResourceWildcard = "resource_wildcard" // resource:*
+	MatchActionWildcard   = "action_wildcard"   // *:action
+	MatchAll              = "all"               // *:*
+)
+
+// PermissionGrantPath 命中所查权限的授予路径
+type PermissionGrantPath struct {
+	PermissionSource
+	Match string `json:"match"`
+	Path  string `json:"path"` // 简写，如 role:editor > group:editor_group
+}
+
+// PermissionExplanation 用户为什么拥有（或没有）某个权限
+type PermissionExplanation struct {
+	UserID   uint                  `json:"user_id"`
+	Username string                `json:"username"`
+	Status   string                `json:"status"`
+	Code     string                `json:"code"`
+	Granted  bool                  `json:"granted"` // 有任一授予路径或对象授权
+	Active   bool                  `json:"active"`  // 禁用或锁定的账号即使有权限也无法使用
+	Paths    []PermissionGrantPath `json:"paths"`
+
+	// 指定 resource_id 时，该对象上生效的单独授权
+	ResourceID     *uint                       `json:"resource_id,omitempty"`
+	ResourceGrants []models.ResourcePermission `json:"resource_grants,omitempty"`
+}
+
+// ExplainPermission 列出用户获得指定权限的全部授予路径，resourceID 不为 0 时同时列出该对象的单独授权
+func (pcs *PermissionCalculatorService) ExplainPermission(userID uint, code string, resourceID uint) (*PermissionExplanation, error) {
+	parts := strings.Split(code, ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, ErrInvalidPermissionCode
+	}
+
+	user, err := pcs.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &PermissionExplanation{
+		UserID:   user.ID,
+		Username: user.Username,
+		Status:   user.Status,
+		Code:     code,
+		Active:   user.IsActive(),
+		Paths:    []PermissionGrantPath{},
+	}
+	for _, source := range permissionSources(user) {
+		if !middleware.MatchPermission([]string{source.Permission}, code) {
+			continue
+		}
+		explanation.Paths = append(explanation.Paths, PermissionGrantPath{
+			PermissionSource: source,
+			Match:            matchType(source.Permission, code),
+			Path:             source.Label(),
+		})
+	}
+
+	if resourceID > 0 {
+		grants, err := pcs.ResourceGrants(userID, parts[0], resourceID, parts[1])
+		if err != nil {
+			return nil, err
+		}
+		explanation.ResourceID = &resourceID
+		explanation.ResourceGrants = grants
+	}
+
+	explanation.Granted = len(explanation.Paths) > 0 || len(explanation.ResourceGrants) > 0
+	return explanation, nil
+}
+
+// matchType 授予的权限代码以哪种方式命中所查权限
+func matchType(granted, required string) string {
+	switch {
+	case granted == required:
+		return MatchExact
+	case granted == "*:*":
+		return MatchAll
+	case strings.HasSuffix(granted, ":*"):
+		return MatchResourceWildcard
+	default:
+		return MatchActionWildcard
+	}
+}
+
+// UserAccess 权限矩阵中的一行
+type UserAccess struct {
+	UserID      uint                `json:"user_id"`
+	Username    string              `json:"username"`
+	RealName    string              `json:"real_name"`
+	Department  string              `json:"department"`
+	Status      string              `json:"status"`
+	AccountType string              `json:"account_type"`
+	Roles       []string            `json:"roles"`
+	Permissions map[string][]string `json:"permissions"` // 权限代码 -> 授予路径，通配符授予时附带实际授予的代码
+}
+
+// AccessMatrix 全部用户的角色与权限矩阵，用于定期权限审查
+type AccessMatrix struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Permissions []string     `json:"permissions"` // 权限目录中的具体权限代码（矩阵的列）
+	Users       []UserAccess `json:"users"`
+}
+
+// AccessMatrix 生成权限矩阵
+func (pcs *PermissionCalculatorService) AccessMatrix() (*AccessMatrix, error) {
+	// 通配符权限（*:*、*:read 等）不单独成列，授予时展开到其覆盖的每个权限中
+	var codes []string
+	if err := pcs.db.Model(&models.Permission{}).Where("code NOT LIKE ?", "%*%").Order("code").Pluck("code", &codes).Error; err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := pcs.usersWithPermissions().Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	matrix := &AccessMatrix{
+		GeneratedAt: time.Now(),
+		Permissions: codes,
+		Users:       make([]UserAccess, 0, len(users)),
+	}
+	for i := range users {
+		user := &users[i]
+		row := UserAccess{
+			UserID:      user.ID,
+			Username:    user.Username,
+			RealName:    user.RealName,
+			Department:  user.Department,
+			Status:      user.Status,
+			AccountType: user.AccountType,
+			Roles:       make([]string, 0, len(user.Roles)),
+			Permissions: make(map[string][]string),
+		}
+		for _, role := range user.Roles {
+			row.Roles = append(row.Roles, role.Code)
+		}
+		sort.Strings(row.Roles)
+
+		sources := permissionSources(user)
+		for _, code := range codes {
+			seen := make(map[string]bool)
+			for _, source := range sources {
+				if !middleware.MatchPermission([]string{source.Permission}, code) {
+					continue
+				}
+				label := source.Label()
+				if source.Permission != code {
+					label += " (" + source.Permission + ")"
+				}
+				if !seen[label] {
+					seen[label] = true
+					row.Permissions[code] = append(row.Permissions[code], label)
+				}
+			}
+		}
+		matrix.Users = append(matrix.Users, row)
+	}
+
+	return matrix, nil
+}
+
+// WriteCSV 以 CSV 输出权限矩阵：每个用户一行，每个权限一列，单元格为授予路径（没有该权限时为空）
+// 用户名、姓名、部门等单元格按 utils.CSVSafe 转义，避免在 Excel 中被当作公式执行
+func (m *AccessMatrix) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"user_id", "username", "real_name", "department", "status", "account_type", "roles"}
+	if err := writeCSVRecord(writer, append(header, m.Permissions...)); err != nil {
+		return err
+	}
+	for _, user := range m.Users {
+		record := []string{
+			strconv.FormatUint(uint64(user.UserID), 10),
+			user.Username,
+			user.RealName,
+			user.Department,
+			user.Status,
+			user.AccountType,
+			strings.Join(user.Roles, ","),
+		}
+		for _, code := range m.Permissions {
+			record = append(record, strings.Join(user.Permissions[code], "; "))
+		}
+		if err := writeCSVRecord(writer, record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeCSVRecord 转义公式字符后写入一行
+func writeCSVRecord(writer *csv.Writer, record []string) error {
+	for i, cell := range record {
+		record[i] = utils.CSVSafe(cell)
+	}
+	return writer.Write(record)
+}
@@ -0,0 +1,683 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcLoginTTL 发起登录到回调之间允许的最长时间
+	oidcLoginTTL = 10 * time.Minute
+	// oidcTicketTTL 回调后前端换取 Token 的一次性票据有效期
+	oidcTicketTTL = time.Minute
+	// oidcDiscoveryTTL 身份提供方配置和签名公钥的缓存时间
+	oidcDiscoveryTTL = time.Hour
+)
+
+var (
+	ErrOIDCDisabled        = errors.New("未启用单点登录")
+	ErrOIDCDiscovery       = errors.New("无法读取身份提供方配置")
+	ErrOIDCState           = errors.New("登录请求无效或已过期，请重新登录")
+	ErrOIDCExchange        = errors.New("授权码换取令牌失败")
+	ErrOIDCIDToken         = errors.New("身份令牌校验失败")
+	ErrOIDCNoAccount       = errors.New("没有关联的账号，请联系管理员开通")
+	ErrOIDCEmailUnverified = errors.New("邮箱未经身份提供方验证，无法关联已有账号")
+	ErrOIDCServiceAccount  = errors.New("服务账号不能通过单点登录")
+	ErrOIDCTicket          = errors.New("登录票据无效或已过期，请重新登录")
+)
+
+// usernamePattern 自动创建用户时用户名允许的字符
+var usernamePattern = regexp.MustCompile(`[^A-Za-z0-9._@-]+`)
+
+// oidcProvider 身份提供方配置（/.well-known/openid-configuration）
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcLogin 进行中的登录请求
+type oidcLogin struct {
+	verifier  string // PKCE code_verifier
+	nonce     string
+	redirect  string // 登录完成后前端要打开的页面
+	expiresAt time.Time
+}
+
+// oidcTicket 回调完成后签发给前端的一次性票据
+type oidcTicket struct {
+	userID    uint
+	expiresAt time.Time
+}
+
+// OIDCService OIDC 单点登录服务（授权码 + PKCE），负责校验身份令牌、按需创建用户和映射角色
+type OIDCService struct {
+	db     *gorm.DB
+	config *config.OIDCConfig
+	client *http.Client
+
+	mu         sync.Mutex
+	provider   *oidcProvider
+	providerAt time.Time
+	keys       map[string]interface{} // kid -> 公钥
+	keysAt     time.Time
+	logins     map[string]*oidcLogin  // state -> 登录请求
+	tickets    map[string]*oidcTicket // 票据 -> 用户
+}
+
+// NewOIDCService 创建 OIDC 单点登录服务
+func NewOIDCService(db *gorm.DB, cfg *config.OIDCConfig) *OIDCService {
+	return &OIDCService{
+		db:      db,
+		config:  cfg,
+		client:  &http.Client{Timeout: time.Duration(cfg.HTTPTimeout) * time.Second},
+		logins:  make(map[string]*oidcLogin),
+		tickets: make(map[string]*oidcTicket),
+	}
+}
+
+// Enabled 是否启用单点登录
+func (s *OIDCService) Enabled() bool {
+	return s.config.Enabled && s.config.Issuer != "" && s.config.ClientID != ""
+}
+
+// ProviderName 登录页显示的身份提供方名称
+func (s *OIDCService) ProviderName() string {
+	return s.config.ProviderName
+}
+
+// AuthURL 生成跳转到身份提供方的授权地址，返回授权地址和 state
+// redirect 为登录完成后前端要打开的站内路径
+func (s *OIDCService) AuthURL(redirect string) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+	provider, err := s.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state := randomID()
+	login := &oidcLogin{
+		verifier:  randomID() + randomID(),
+		nonce:     randomID(),
+		redirect:  safeRedirect(redirect),
+		expiresAt: time.Now().Add(oidcLoginTTL),
+	}
+
+	s.mu.Lock()
+	s.pruneLocked()
+	s.logins[state] = login
+	s.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(login.verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", s.config.ClientID)
+	query.Set("redirect_uri", s.config.RedirectURL)
+	query.Set("scope", strings.Join(s.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", login.nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// HandleCallback 处理身份提供方回调：校验 state，用授权码换取令牌，校验身份令牌并找到或创建本地用户
+// 返回本地用户和登录完成后前端要打开的路径
+func (s *OIDCService) HandleCallback(state, code, ip string) (*models.User, string, error) {
+	if !s.Enabled() {
+		return nil, "", ErrOIDCDisabled
+	}
+
+	s.mu.Lock()
+	login, ok := s.logins[state]
+	delete(s.logins, state)
+	s.mu.Unlock()
+	if !ok || time.Now().After(login.expiresAt) || code == "" {
+		return nil, "", ErrOIDCState
+	}
+
+	provider, err := s.discover()
+	if err != nil {
+		return nil, "", err
+	}
+
+	idToken, accessToken, err := s.exchange(provider, code, login.verifier)
+	if err != nil {
+		return nil, "", err
+	}
+
+	claims, err := s.verifyIDToken(provider, idToken, login.nonce)
+	if err != nil {
+		logger.Log.Warnf("OIDC 身份令牌校验失败: %v", err)
+		return nil, "", ErrOIDCIDToken
+	}
+
+	// 身份令牌中没有的声明（如 groups）从 userinfo 补充，sub 必须一致
+	if provider.UserinfoEndpoint != "" && accessToken != "" {
+		if info, err := s.userinfo(provider, accessToken); err == nil && info["sub"] == claims["sub"] {
+			for key, value := range info {
+				if _, exists := claims[key]; !exists {
+					claims[key] = value
+				}
+			}
+		} else if err != nil {
+			logger.Log.Warnf("OIDC 获取用户信息失败: %v", err)
+		}
+	}
+
+	user, err := s.provision(provider.Issuer, claims, ip)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, login.redirect, nil
+}
+
+// IssueTicket 为完成单点登录的用户签发一次性票据，前端用票据换取 Token（避免 Token 出现在跳转地址中）
+func (s *OIDCService) IssueTicket(userID uint) string {
+	ticket := randomID()
+	s.mu.Lock()
+	s.tickets[ticket] = &oidcTicket{userID: userID, expiresAt: time.Now().Add(oidcTicketTTL)}
+	s.mu.Unlock()
+	return ticket
+}
+
+// RedeemTicket 使用一次性票据，返回用户ID
+func (s *OIDCService) RedeemTicket(ticket string) (uint, error) {
+	s.mu.Lock()
+	entry, ok := s.tickets[ticket]
+	delete(s.tickets, ticket)
+	s.mu.Unlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return 0, ErrOIDCTicket
+	}
+	return entry.userID, nil
+}
+
+// FrontendURL 登录完成或失败后跳转的前端地址
+func (s *OIDCService) FrontendURL(params url.Values) string {
+	base := s.config.FrontendURL
+	if base == "" {
+		base = "/"
+	}
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+	return base + separator + params.Encode()
+}
+
+// discover 读取身份提供方配置（缓存一小时）
+func (s *OIDCService) discover() (*oidcProvider, error) {
+	s.mu.Lock()
+	if s.provider != nil && time.Since(s.providerAt) < oidcDiscoveryTTL {
+		provider := s.provider
+		s.mu.Unlock()
+		return provider, nil
+	}
+	s.mu.Unlock()
+
+	issuer := strings.TrimSuffix(s.config.Issuer, "/")
+	var provider oidcProvider
+	if err := s.getJSON(issuer+"/.well-known/openid-configuration", "", &provider); err != nil {
+		logger.Log.Errorf("OIDC 读取身份提供方配置失败: issuer=%s, error=%v", issuer, err)
+		return nil, ErrOIDCDiscovery
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer || provider.AuthorizationEndpoint == "" ||
+		provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		logger.Log.Errorf("OIDC 身份提供方配置不完整或 issuer 不匹配: %s", provider.Issuer)
+		return nil, ErrOIDCDiscovery
+	}
+
+	s.mu.Lock()
+	s.provider = &provider
+	s.providerAt = time.Now()
+	s.mu.Unlock()
+	return &provider, nil
+}
+
+// exchange 用授权码和 code_verifier 换取令牌
+func (s *OIDCService) exchange(provider *oidcProvider, code, verifier string) (string, string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.config.RedirectURL)
+	form.Set("client_id", s.config.ClientID)
+	form.Set("code_verifier", verifier)
+	if s.config.ClientSecret != "" {
+		form.Set("client_secret", s.config.ClientSecret)
+	}
+
+	resp, err := s.client.PostForm(provider.TokenEndpoint, form)
+	if err != nil {
+		logger.Log.Warnf("OIDC 换取令牌失败: %v", err)
+		return "", "", ErrOIDCExchange
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		IDToken     string `json:"id_token"`
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(body, &result); err != nil || resp.StatusCode != http.StatusOK || result.IDToken == "" {
+		logger.Log.Warnf("OIDC 换取令牌失败: status=%d, error=%s %s", resp.StatusCode, result.Error, result.Description)
+		return "", "", ErrOIDCExchange
+	}
+	return result.IDToken, result.AccessToken, nil
+}
+
+// verifyIDToken 校验身份令牌的签名、issuer、audience、有效期和 nonce
+func (s *OIDCService) verifyIDToken(provider *oidcProvider, idToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.signingKey(provider, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(s.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if value, _ := claims["nonce"].(string); value != nonce {
+		return nil, errors.New("nonce 不匹配")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("缺少 sub 声明")
+	}
+	return claims, nil
+}
+
+// signingKey 按 kid 查找签名公钥，找不到时重新读取一次 JWKS（身份提供方轮换密钥）
+func (s *OIDCService) signingKey(provider *oidcProvider, kid string) (interface{}, error) {
+	lookup := func() (interface{}, bool) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if time.Since(s.keysAt) >= oidcDiscoveryTTL {
+			return nil, false
+		}
+		if key, ok := s.keys[kid]; ok {
+			return key, true
+		}
+		// 未指定 kid 且只有一个公钥时直接使用
+		if kid == "" && len(s.keys) == 1 {
+			for _, key := range s.keys {
+				return key, true
+			}
+		}
+		return nil, false
+	}
+
+	if key, ok := lookup(); ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := s.getJSON(provider.JWKSURI, "", &jwks); err != nil {
+		return nil, fmt.Errorf("读取签名公钥失败: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.keysAt = time.Now()
+	s.mu.Unlock()
+
+	if key, ok := lookup(); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未找到签名公钥: kid=%s", kid)
+}
+
+// userinfo 读取用户信息
+func (s *OIDCService) userinfo(provider *oidcProvider, accessToken string) (map[string]interface{}, error) {
+	info := make(map[string]interface{})
+	if err := s.getJSON(provider.UserinfoEndpoint, accessToken, &info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// getJSON 请求 JSON 接口
+func (s *OIDCService) getJSON(endpoint, bearer string, target interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回 %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+// provision 根据身份令牌声明找到本地用户：已关联的外部身份 → 按邮箱关联已有账号 → 自动创建
+func (s *OIDCService) provision(issuer string, claims jwt.MapClaims, ip string) (*models.User, error) {
+	subject, _ := claims["sub"].(string)
+	email := strings.TrimSpace(claimString(claims, "email"))
+	name := claimString(claims, "name")
+	mappedRoles := s.mapRoles(claims)
+
+	var user models.User
+	found := false
+
+	var identity models.UserIdentity
+	if err := s.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error; err == nil {
+		if err := s.db.First(&user, identity.UserID).Error; err == nil {
+			found = true
+		} else {
+			// 本地用户已删除，外部身份失效
+			s.db.Delete(&identity)
+			identity = models.UserIdentity{}
+		}
+	}
+
+	if !found && s.config.LinkByEmail && email != "" {
+		if err := s.db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error; err == nil {
+			if s.config.RequireVerified && !claimBool(claims, "email_verified") {
+				return nil, ErrOIDCEmailUnverified
+			}
+			found = true
+			logger.Log.Infof("OIDC 按邮箱关联已有账号: user=%s, subject=%s", user.Username, subject)
+		}
+	}
+
+	if found {
+		if user.IsServiceAccount() {
+			return nil, ErrOIDCServiceAccount
+		}
+		if s.config.SyncRoles && len(mappedRoles) > 0 {
+			s.assignRoles(&user, mappedRoles, true)
+		}
+	} else {
+		if !s.config.AutoCreateUsers {
+			return nil, ErrOIDCNoAccount
+		}
+		created, err := s.createUser(claims, subject, email, name, mappedRoles)
+		if err != nil {
+			return nil, err
+		}
+		user = *created
+	}
+
+	now := time.Now()
+	if identity.ID == 0 {
+		identity = models.UserIdentity{UserID: user.ID, Issuer: issuer, Subject: subject}
+	}
+	identity.Email = email
+	identity.Name = name
+	identity.LastLoginAt = &now
+	if err := s.db.Save(&identity).Error; err != nil {
+		return nil, err
+	}
+
+	s.db.Model(&user).Updates(map[string]interface{}{"last_login_at": now, "last_login_ip": ip})
+	if err := s.db.Preload("Roles").First(&user, user.ID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// createUser 自动创建单点登录用户（随机密码，只能通过单点登录或管理员重置密码后登录）
+func (s *OIDCService) createUser(claims jwt.MapClaims, subject, email, name string, mappedRoles []string) (*models.User, error) {
+	username := s.uniqueUsername(claims, subject, email)
+
+	// 邮箱唯一，没有邮箱或邮箱已被占用时使用占位邮箱
+	var count int64
+	if email != "" {
+		s.db.Model(&models.User{}).Where("LOWER(email) = ?", strings.ToLower(email)).Count(&count)
+	}
+	if email == "" || count > 0 {
+		email = username + "@oidc.local"
+	}
+
+	password, err := middleware.HashPassword(randomID())
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:    username,
+		Password:    password,
+		Email:       email,
+		RealName:    name,
+		Status:      models.UserStatusActive,
+		AccountType: models.AccountTypeUser,
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, err
+	}
+
+	roles := mappedRoles
+	if len(roles) == 0 {
+		roles = s.config.DefaultRoles
+	}
+	s.assignRoles(user, roles, false)
+
+	logger.Log.Infof("OIDC 自动创建用户: user=%s, subject=%s, roles=%v", user.Username, subject, roles)
+	return user, nil
+}
+
+// uniqueUsername 生成不重复的用户名
+func (s *OIDCService) uniqueUsername(claims jwt.MapClaims, subject, email string) string {
+	base := claimString(claims, s.config.UsernameClaim)
+	if base == "" && email != "" {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	base = usernamePattern.ReplaceAllString(base, "_")
+	if len(base) < 3 {
+		base = "sso_" + sha256Hex(subject)[:8]
+	}
+	base = truncate(base, 40)
+
+	username := base
+	for i := 2; ; i++ {
+		var count int64
+		s.db.Model(&models.User{}).Where("username = ?", username).Count(&count)
+		if count == 0 {
+			return username
+		}
+		username = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+// assignRoles 按角色编码分配角色，replace 为 true 时替换现有角色
+func (s *OIDCService) assignRoles(user *models.User, codes []string, replace bool) {
+	var roles []models.Role
+	s.db.Where("code IN ?", codes).Find(&roles)
+	if len(roles) == 0 {
+		logger.Log.Warnf("OIDC 映射的角色不存在: %v", codes)
+		return
+	}
+
+	association := s.db.Model(user).Association("Roles")
+	var err error
+	if replace {
+		err = association.Replace(roles)
+	} else {
+		err = association.Append(roles)
+	}
+	if err != nil {
+		logger.Log.Warnf("OIDC 分配角色失败: user=%s, error=%v", user.Username, err)
+		return
+	}
+	middleware.InvalidatePermissionCache(user.ID)
+}
+
+// mapRoles 按映射规则计算声明对应的本地角色编码
+func (s *OIDCService) mapRoles(claims jwt.MapClaims) []string {
+	seen := make(map[string]bool)
+	var roles []string
+	for _, mapping := range s.config.RoleMappings {
+		for _, value := range claimValues(claims, mapping.Claim) {
+			if value != mapping.Value {
+				continue
+			}
+			for _, role := range mapping.Roles {
+				if !seen[role] {
+					seen[role] = true
+					roles = append(roles, role)
+				}
+			}
+		}
+	}
+	return roles
+}
+
+// pruneLocked 清理过期的登录请求和票据（调用方持有锁）
+func (s *OIDCService) pruneLocked() {
+	now := time.Now()
+	for state, login := range s.logins {
+		if now.After(login.expiresAt) {
+			delete(s.logins, state)
+		}
+	}
+	for ticket, entry := range s.tickets {
+		if now.After(entry.expiresAt) {
+			delete(s.tickets, ticket)
+		}
+	}
+}
+
+// claimValues 读取声明的值，支持字符串、字符串数组和以点分隔的嵌套声明（如 realm_access.roles）
+func claimValues(claims map[string]interface{}, name string) []string {
+	var value interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	case bool:
+		return []string{fmt.Sprint(v)}
+	}
+	return nil
+}
+
+// claimString 读取字符串声明
+func claimString(claims map[string]interface{}, name string) string {
+	if values := claimValues(claims, name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// claimBool 读取布尔声明（部分身份提供方以字符串 "true" 返回）
+func claimBool(claims map[string]interface{}, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// safeRedirect 只允许站内路径，防止登录后跳转到外部地址
+func safeRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.Contains(redirect, "\\") {
+		return ""
+	}
+	return redirect
+}
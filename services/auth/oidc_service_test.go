@@ -0,0 +1,424 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testClientID    = "pm"
+	testRedirectURL = "http://pm.local/api/auth/oidc/callback"
+)
+
+// testAuthCode 测试身份提供方签发的授权码
+type testAuthCode struct {
+	challenge string
+	nonce     string
+}
+
+// testIdP 本地测试身份提供方，提供发现、令牌、JWKS 和 userinfo 接口
+type testIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu       sync.Mutex
+	codes    map[string]testAuthCode
+	claims   func(claims jwt.MapClaims)        // 签发前修改身份令牌声明
+	sign     func(claims jwt.MapClaims) string // 替换默认的 RS256 签名
+	userinfo map[string]interface{}
+}
+
+// newTestIdP 启动本地测试身份提供方
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	idp := &testIdP{key: key, kid: "key-1", codes: make(map[string]testAuthCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.handleDiscovery)
+	mux.HandleFunc("/token", idp.handleToken)
+	mux.HandleFunc("/jwks", idp.handleJWKS)
+	mux.HandleFunc("/userinfo", idp.handleUserinfo)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 idp.server.URL,
+		"authorization_endpoint": idp.server.URL + "/authorize",
+		"token_endpoint":         idp.server.URL + "/token",
+		"userinfo_endpoint":      idp.server.URL + "/userinfo",
+		"jwks_uri":               idp.server.URL + "/jwks",
+	})
+}
+
+// handleToken 校验授权码、client_id、redirect_uri 和 PKCE code_verifier 后签发身份令牌
+func (idp *testIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	idp.mu.Lock()
+	code, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != testClientID || r.PostForm.Get("redirect_uri") != testRedirectURL ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != code.challenge {
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"aud":                testClientID,
+		"sub":                "user-1",
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              code.nonce,
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"name":               "Alice",
+	}
+	if idp.claims != nil {
+		idp.claims(claims)
+	}
+	var idToken string
+	if idp.sign != nil {
+		idToken = idp.sign(claims)
+	} else {
+		idToken = signTestToken(jwt.SigningMethodRS256, idp.kid, idp.key, claims)
+	}
+	writeTestJSON(w, http.StatusOK, map[string]string{"id_token": idToken, "access_token": "access-token", "token_type": "Bearer"})
+}
+
+func (idp *testIdP) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": idp.kid,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (idp *testIdP) handleUserinfo(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer access-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	idp.mu.Lock()
+	info := idp.userinfo
+	idp.mu.Unlock()
+	if info == nil {
+		info = map[string]interface{}{"sub": "user-1", "groups": []string{"pm-admins"}}
+	}
+	writeTestJSON(w, http.StatusOK, info)
+}
+
+// authorize 模拟浏览器跳转到身份提供方并完成登录，返回 state 和授权码
+func (idp *testIdP) authorize(t *testing.T, service *OIDCService, redirect string) (string, string) {
+	t.Helper()
+	authURL, state, err := service.AuthURL(redirect)
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, idp.server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, testClientID, query.Get("client_id"))
+	assert.Equal(t, testRedirectURL, query.Get("redirect_uri"))
+	assert.Equal(t, state, query.Get("state"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotEmpty(t, query.Get("nonce"))
+	assert.NotEmpty(t, query.Get("code_challenge"))
+
+	code := randomID()
+	idp.mu.Lock()
+	idp.codes[code] = testAuthCode{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	idp.mu.Unlock()
+	return state, code
+}
+
+// signTestToken 签发测试用的身份令牌
+func signTestToken(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// newOIDCTestService 创建连接到本地测试身份提供方的单点登录服务
+func newOIDCTestService(t *testing.T) (*OIDCService, *testIdP, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "oidc.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.Permission{}, &models.PermissionGroup{}, &models.Role{}, &models.User{}, &models.UserIdentity{}))
+	assert.NoError(t, db.Create(&[]models.Role{{Code: "admin", Name: "管理员"}, {Code: "viewer", Name: "访客"}}).Error)
+
+	idp := newTestIdP(t)
+	cfg := &config.OIDCConfig{
+		Enabled:         true,
+		Issuer:          idp.server.URL,
+		ClientID:        testClientID,
+		RedirectURL:     testRedirectURL,
+		Scopes:          []string{"openid", "profile", "email"},
+		UsernameClaim:   "preferred_username",
+		AutoCreateUsers: true,
+		DefaultRoles:    []string{"viewer"},
+		RoleMappings:    []config.OIDCRoleMapping{{Claim: "groups", Value: "pm-admins", Roles: []string{"admin"}}},
+		HTTPTimeout:     5,
+	}
+	return NewOIDCService(db, cfg), idp, db
+}
+
+// roleCodes 用户的角色编码
+func roleCodes(user *models.User) []string {
+	codes := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		codes = append(codes, role.Code)
+	}
+	return codes
+}
+
+// TestOIDCLoginFlow 测试完整的授权码登录：创建用户、映射角色、再次登录使用同一账号
+func TestOIDCLoginFlow(t *testing.T) {
+	service, idp, db := newOIDCTestService(t)
+
+	state, code := idp.authorize(t, service, "/projects/1")
+	user, redirect, err := service.HandleCallback(state, code, "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "/projects/1", redirect)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.Equal(t, []string{"admin"}, roleCodes(user), "userinfo 中的 groups 映射为角色")
+
+	var identity models.UserIdentity
+	assert.NoError(t, db.Where("issuer = ? AND subject = ?", idp.server.URL, "user-1").First(&identity).Error)
+	assert.Equal(t, user.ID, identity.UserID)
+
+	t.Run("再次登录使用已关联的账号", func(t *testing.T) {
+		state, code := idp.authorize(t, service, "")
+		again, redirect, err := service.HandleCallback(state, code, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.Empty(t, redirect)
+		assert.Equal(t, user.ID, again.ID)
+
+		var count int64
+		db.Model(&models.User{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("userinfo 的 sub 不一致时不合并声明", func(t *testing.T) {
+		idp.userinfo = map[string]interface{}{"sub": "someone-else", "groups": []string{"pm-admins"}}
+		defer func() { idp.userinfo = nil }()
+		idp.claims = func(claims jwt.MapClaims) {
+			claims["sub"] = "user-2"
+			claims["preferred_username"] = "bob"
+			claims["email"] = "bob@example.com"
+		}
+		defer func() { idp.claims = nil }()
+
+		state, code := idp.authorize(t, service, "")
+		bob, _, err := service.HandleCallback(state, code, "10.0.0.2")
+		assert.NoError(t, err)
+		assert.Equal(t, "bob", bob.Username)
+		assert.Equal(t, []string{"viewer"}, roleCodes(bob))
+	})
+}
+
+// TestOIDCState 测试 state 只能使用一次且在有效期内
+func TestOIDCState(t *testing.T) {
+	service, idp, _ := newOIDCTestService(t)
+
+	t.Run("未知 state", func(t *testing.T) {
+		_, code := idp.authorize(t, service, "")
+		_, _, err := service.HandleCallback("unknown", code, "")
+		assert.ErrorIs(t, err, ErrOIDCState)
+	})
+
+	t.Run("重复使用 state", func(t *testing.T) {
+		state, code := idp.authorize(t, service, "")
+		_, _, err := service.HandleCallback(state, code, "")
+		assert.NoError(t, err)
+		_, _, err = service.HandleCallback(state, code, "")
+		assert.ErrorIs(t, err, ErrOIDCState)
+	})
+
+	t.Run("state 已过期", func(t *testing.T) {
+		state, code := idp.authorize(t, service, "")
+		service.mu.Lock()
+		service.logins[state].expiresAt = time.Now().Add(-time.Second)
+		service.mu.Unlock()
+		_, _, err := service.HandleCallback(state, code, "")
+		assert.ErrorIs(t, err, ErrOIDCState)
+	})
+
+	t.Run("缺少授权码", func(t *testing.T) {
+		state, _ := idp.authorize(t, service, "")
+		_, _, err := service.HandleCallback(state, "", "")
+		assert.ErrorIs(t, err, ErrOIDCState)
+	})
+
+	t.Run("失败后 state 也不能再次使用", func(t *testing.T) {
+		state, code := idp.authorize(t, service, "")
+		_, _, err := service.HandleCallback(state, "", "")
+		assert.ErrorIs(t, err, ErrOIDCState)
+		_, _, err = service.HandleCallback(state, code, "")
+		assert.ErrorIs(t, err, ErrOIDCState)
+	})
+}
+
+// TestOIDCPKCE 测试 code_challenge 与 code_verifier 对应，授权码被截获后无法单独换取令牌
+func TestOIDCPKCE(t *testing.T) {
+	service, idp, _ := newOIDCTestService(t)
+
+	t.Run("code_challenge 为 code_verifier 的 S256", func(t *testing.T) {
+		authURL, state, err := service.AuthURL("")
+		assert.NoError(t, err)
+		parsed, _ := url.Parse(authURL)
+
+		service.mu.Lock()
+		verifier := service.logins[state].verifier
+		service.mu.Unlock()
+		sum := sha256.Sum256([]byte(verifier))
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:]), parsed.Query().Get("code_challenge"))
+		assert.GreaterOrEqual(t, len(verifier), 43)
+	})
+
+	t.Run("code_verifier 不匹配", func(t *testing.T) {
+		state, code := idp.authorize(t, service, "")
+		service.mu.Lock()
+		service.logins[state].verifier = randomID() + randomID()
+		service.mu.Unlock()
+		_, _, err := service.HandleCallback(state, code, "")
+		assert.ErrorIs(t, err, ErrOIDCExchange)
+	})
+
+	t.Run("授权码用于另一个登录请求", func(t *testing.T) {
+		_, code := idp.authorize(t, service, "")
+		other, _ := idp.authorize(t, service, "")
+		_, _, err := service.HandleCallback(other, code, "")
+		assert.ErrorIs(t, err, ErrOIDCExchange)
+	})
+}
+
+// TestOIDCIDTokenValidation 测试身份令牌的签名、算法、issuer、audience、有效期、nonce 和 sub 校验
+func TestOIDCIDTokenValidation(t *testing.T) {
+	service, idp, db := newOIDCTestService(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		claims func(claims jwt.MapClaims)
+		sign   func(claims jwt.MapClaims) string
+	}{
+		{name: "nonce 不匹配", claims: func(c jwt.MapClaims) { c["nonce"] = "other-nonce" }},
+		{name: "缺少 nonce", claims: func(c jwt.MapClaims) { delete(c, "nonce") }},
+		{name: "audience 不匹配", claims: func(c jwt.MapClaims) { c["aud"] = "another-client" }},
+		{name: "issuer 不匹配", claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "已过期", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() }},
+		{name: "缺少 exp", claims: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "缺少 sub", claims: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "其他密钥签名", sign: func(c jwt.MapClaims) string {
+			return signTestToken(jwt.SigningMethodRS256, idp.kid, otherKey, c)
+		}},
+		{name: "未知 kid", sign: func(c jwt.MapClaims) string {
+			return signTestToken(jwt.SigningMethodRS256, "rotated", otherKey, c)
+		}},
+		{name: "HS256 对称签名", sign: func(c jwt.MapClaims) string {
+			return signTestToken(jwt.SigningMethodHS256, idp.kid, []byte(testClientID), c)
+		}},
+		{name: "alg none", sign: func(c jwt.MapClaims) string {
+			return signTestToken(jwt.SigningMethodNone, idp.kid, jwt.UnsafeAllowNoneSignatureType, c)
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			idp.claims, idp.sign = tc.claims, tc.sign
+			defer func() { idp.claims, idp.sign = nil, nil }()
+
+			state, code := idp.authorize(t, service, "")
+			user, _, err := service.HandleCallback(state, code, "")
+			assert.ErrorIs(t, err, ErrOIDCIDToken)
+			assert.Nil(t, user)
+		})
+	}
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	assert.Zero(t, count, "校验失败时不创建用户")
+
+	t.Run("exp 在允许的时钟偏差内", func(t *testing.T) {
+		idp.claims = func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-30 * time.Second).Unix() }
+		defer func() { idp.claims = nil }()
+
+		state, code := idp.authorize(t, service, "")
+		_, _, err := service.HandleCallback(state, code, "")
+		assert.NoError(t, err)
+	})
+}
+
+// TestSafeRedirect 测试登录后只允许跳转到站内路径
+func TestSafeRedirect(t *testing.T) {
+	cases := map[string]string{
+		"/projects/1?tab=files":    "/projects/1?tab=files",
+		"/":                        "/",
+		"":                         "",
+		"projects":                 "",
+		"//evil.example.com":       "",
+		"/\\evil.example.com":      "",
+		"https://evil.example.com": "",
+		"javascript:alert(1)":      "",
+	}
+	for redirect, expected := range cases {
+		assert.Equal(t, expected, safeRedirect(redirect), redirect)
+	}
+}
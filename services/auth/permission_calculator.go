@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"strings"
+	"time"
+
+	"go_wails_project_manager/middleware"
 	"go_wails_project_manager/models"
 
 	"gorm.io/gorm"
@@ -16,49 +20,171 @@ func NewPermissionCalculatorService(db *gorm.DB) *PermissionCalculatorService {
 	return &PermissionCalculatorService{db: db}
 }
 
+// 权限来源类型
+const (
+	SourceRole      = "role"       // 角色直接授予
+	SourceRoleGroup = "role_group" // 角色的权限组
+	SourceUser      = "user"       // 直接授予用户
+	SourceUserGroup = "user_group" // 直接授予用户的权限组
+)
+
+// SourceRef 权限来源中的角色或权限组
+type SourceRef struct {
+	ID   uint   `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// PermissionSource 一条权限的授予路径
+type PermissionSource struct {
+	Type       string     `json:"type"`
+	Role       *SourceRef `json:"role,omitempty"`
+	Group      *SourceRef `json:"group,omitempty"`
+	Permission string     `json:"permission"` // 授予的权限代码（可能是通配符）
+}
+
+// Label 授予路径的简写，如 role:editor > group:editor_group
+func (ps PermissionSource) Label() string {
+	parts := make([]string, 0, 2)
+	if ps.Role != nil {
+		parts = append(parts, "role:"+ps.Role.Code)
+	}
+	if ps.Group != nil {
+		parts = append(parts, "group:"+ps.Group.Code)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "user")
+	}
+	return strings.Join(parts, " > ")
+}
+
 // CalculateUserPermissions 计算用户最终权限
 func (pcs *PermissionCalculatorService) CalculateUserPermissions(userID uint) ([]string, error) {
-	var user models.User
-	if err := pcs.db.Preload("Roles.Permissions").
-		Preload("Roles.PermissionGroups.Permissions").
-		Preload("Permissions").
-		Preload("PermissionGroups.Permissions").
-		First(&user, userID).Error; err != nil {
+	user, err := pcs.loadUser(userID)
+	if err != nil {
 		return nil, err
 	}
 
 	permMap := make(map[string]bool)
+	for _, source := range permissionSources(user) {
+		permMap[source.Permission] = true
+	}
+
+	// 转换为数组
+	perms := make([]string, 0, len(permMap))
+	for perm := range permMap {
+		perms = append(perms, perm)
+	}
+
+	return perms, nil
+}
+
+// loadUser 加载用户及其角色、权限组和直接授予的权限
+func (pcs *PermissionCalculatorService) loadUser(userID uint) (*models.User, error) {
+	var user models.User
+	if err := pcs.usersWithPermissions().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// usersWithPermissions 预加载计算权限所需的关联
+func (pcs *PermissionCalculatorService) usersWithPermissions() *gorm.DB {
+	return pcs.db.Preload("Roles.Permissions").
+		Preload("Roles.PermissionGroups.Permissions").
+		Preload("Permissions").
+		Preload("PermissionGroups.Permissions")
+}
+
+// permissionSources 展开用户全部权限的授予路径
+func permissionSources(user *models.User) []PermissionSource {
+	var sources []PermissionSource
 
 	// 1. 从角色获取权限
 	for _, role := range user.Roles {
+		roleRef := &SourceRef{ID: role.ID, Code: role.Code, Name: role.Name}
 		for _, perm := range role.Permissions {
-			permMap[perm.Code] = true
+			sources = append(sources, PermissionSource{Type: SourceRole, Role: roleRef, Permission: perm.Code})
 		}
 		// 从角色的权限组获取权限
 		for _, group := range role.PermissionGroups {
+			groupRef := &SourceRef{ID: group.ID, Code: group.Code, Name: group.Name}
 			for _, perm := range group.Permissions {
-				permMap[perm.Code] = true
+				sources = append(sources, PermissionSource{Type: SourceRoleGroup, Role: roleRef, Group: groupRef, Permission: perm.Code})
 			}
 		}
 	}
 
 	// 2. 从用户直接授权获取权限
 	for _, perm := range user.Permissions {
-		permMap[perm.Code] = true
+		sources = append(sources, PermissionSource{Type: SourceUser, Permission: perm.Code})
 	}
 
 	// 3. 从用户直接授权的权限组获取权限
 	for _, group := range user.PermissionGroups {
+		groupRef := &SourceRef{ID: group.ID, Code: group.Code, Name: group.Name}
 		for _, perm := range group.Permissions {
-			permMap[perm.Code] = true
+			sources = append(sources, PermissionSource{Type: SourceUserGroup, Group: groupRef, Permission: perm.Code})
 		}
 	}
 
-	// 转换为数组
-	perms := make([]string, 0, len(permMap))
-	for perm := range permMap {
-		perms = append(perms, perm)
+	return sources
+}
+
+// HasResourcePermission 用户能否对指定对象执行操作：角色（及直接授予）的权限，或该对象的单独授权
+func (pcs *PermissionCalculatorService) HasResourcePermission(userID uint, resourceType string, resourceID uint, permission string) (bool, error) {
+	perms, err := pcs.CalculateUserPermissions(userID)
+	if err != nil {
+		return false, err
 	}
+	if middleware.MatchPermission(perms, resourceType+":"+permission) {
+		return true, nil
+	}
+	return pcs.HasResourceGrant(userID, resourceType, resourceID, permission)
+}
 
-	return perms, nil
+// HasResourceGrant 用户是否被单独授予了对象的权限（忽略已过期的授权）
+func (pcs *PermissionCalculatorService) HasResourceGrant(userID uint, resourceType string, resourceID uint, permission string) (bool, error) {
+	grants, err := pcs.ResourceGrants(userID, resourceType, resourceID, permission)
+	return len(grants) > 0, err
+}
+
+// ResourceGrants 用户对象上生效的单独授权（忽略已过期的授权）
+// 文件库的文件夹授权向下继承，授予文件夹的权限对其中全部子项生效
+func (pcs *PermissionCalculatorService) ResourceGrants(userID uint, resourceType string, resourceID uint, permission string) ([]models.ResourcePermission, error) {
+	resourceIDs := []uint{resourceID}
+	if resourceType == models.ResourceDocuments {
+		if err := pcs.db.Raw("WITH RECURSIVE ancestor(id, parent_id) AS ("+
+			"SELECT id, parent_id FROM document WHERE id = ?"+
+			" UNION SELECT document.id, document.parent_id FROM document JOIN ancestor ON document.id = ancestor.parent_id"+
+			") SELECT id FROM ancestor", resourceID).Scan(&resourceIDs).Error; err != nil {
+			return nil, err
+		}
+		if len(resourceIDs) == 0 {
+			return nil, nil
+		}
+	}
+
+	var grants []models.ResourcePermission
+	err := pcs.activeGrants(userID, resourceType, permission).
+		Where("resource_id IN ?", resourceIDs).
+		Find(&grants).Error
+	return grants, err
+}
+
+// GrantedResourceIDs 用户被单独授予了指定权限的对象ID（忽略已过期的授权）
+func (pcs *PermissionCalculatorService) GrantedResourceIDs(userID uint, resourceType, permission string) ([]uint, error) {
+	var ids []uint
+	err := pcs.activeGrants(userID, resourceType, permission).
+		Distinct("resource_id").
+		Pluck("resource_id", &ids).Error
+	return ids, err
+}
+
+// activeGrants 用户未过期的对象授权，授权为 * 时包含全部操作
+func (pcs *PermissionCalculatorService) activeGrants(userID uint, resourceType, permission string) *gorm.DB {
+	return pcs.db.Model(&models.ResourcePermission{}).
+		Where("user_id = ? AND resource_type = ?", userID, resourceType).
+		Where("permission IN ?", []string{permission, models.ActionAll}).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
 }
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/models/ai3d"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrResourcePermissionNotFound = errors.New("资源授权不存在")
+	ErrResourceTypeNotGrantable   = errors.New("该资源类型不支持单独授权，可选 documents、models、projects、ai3d")
+	ErrResourceActionInvalid      = errors.New("授权操作无效，可选 read、download、upload、update、delete、share、*")
+	ErrResourceNotFound           = errors.New("资源不存在")
+	ErrGranteeNotFound            = errors.New("被授权用户不存在")
+)
+
+// resourceGrantCleanupInterval 过期授权清理间隔
+const resourceGrantCleanupInterval = time.Hour
+
+// grantableResources 支持单独授权的资源类型及其对象表
+var grantableResources = map[string]interface{}{
+	models.ResourceDocuments: &models.Document{},
+	models.ResourceModels:    &models.Model{},
+	models.ResourceProjects:  &models.Project{},
+	models.ResourceAI3D:      &ai3d.Task{},
+}
+
+// grantableActions 可单独授予的操作，* 表示对象的全部操作
+var grantableActions = map[string]bool{
+	models.ActionRead:     true,
+	models.ActionDownload: true,
+	models.ActionUpload:   true,
+	models.ActionUpdate:   true,
+	models.ActionDelete:   true,
+	models.ActionShare:    true,
+	models.ActionAll:      true,
+}
+
+// GrantResourcePermissionInput 授予对象权限的参数
+type GrantResourcePermissionInput struct {
+	UserID       uint
+	ResourceType string
+	ResourceID   uint
+	Permission   string
+	ExpiresAt    *time.Time
+	GrantedBy    uint
+}
+
+// ResourcePermissionService 对象级授权服务（为单个文档、模型、项目或 AI3D 任务授权）
+type ResourcePermissionService struct {
+	db       *gorm.DB
+	ticker   *time.Ticker
+	stopChan chan bool
+}
+
+// NewResourcePermissionService 创建对象级授权服务
+func NewResourcePermissionService(db *gorm.DB) *ResourcePermissionService {
+	return &ResourcePermissionService{db: db}
+}
+
+// IsGrantableResource 资源类型是否支持单独授权
+func IsGrantableResource(resourceType string) bool {
+	_, ok := grantableResources[resourceType]
+	return ok
+}
+
+// Grant 授予用户对象权限，同一用户、对象和操作重复授权时更新过期时间和授权人
+func (s *ResourcePermissionService) Grant(input GrantResourcePermissionInput) (*models.ResourcePermission, error) {
+	table, ok := grantableResources[input.ResourceType]
+	if !ok {
+		return nil, ErrResourceTypeNotGrantable
+	}
+	if !grantableActions[input.Permission] {
+		return nil, ErrResourceActionInvalid
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, ErrExpiresInPast
+	}
+
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("id = ?", input.UserID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrGranteeNotFound
+	}
+	if err := s.db.Model(table).Where("id = ?", input.ResourceID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	var grant models.ResourcePermission
+	err := s.db.Where("user_id = ? AND resource_type = ? AND resource_id = ? AND permission = ?",
+		input.UserID, input.ResourceType, input.ResourceID, input.Permission).
+		First(&grant).Error
+	switch err {
+	case nil:
+		grant.ExpiresAt = input.ExpiresAt
+		grant.GrantedBy = input.GrantedBy
+		if err := s.db.Model(&grant).Select("expires_at", "granted_by").Updates(&grant).Error; err != nil {
+			return nil, err
+		}
+	case gorm.ErrRecordNotFound:
+		grant = models.ResourcePermission{
+			UserID:       input.UserID,
+			ResourceType: input.ResourceType,
+			ResourceID:   input.ResourceID,
+			Permission:   input.Permission,
+			GrantedBy:    input.GrantedBy,
+			ExpiresAt:    input.ExpiresAt,
+		}
+		if err := s.db.Create(&grant).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Get 获取授权记录
+func (s *ResourcePermissionService) Get(id uint) (*models.ResourcePermission, error) {
+	var grant models.ResourcePermission
+	if err := s.db.First(&grant, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrResourcePermissionNotFound
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Revoke 撤销授权
+func (s *ResourcePermissionService) Revoke(id uint) error {
+	result := s.db.Delete(&models.ResourcePermission{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrResourcePermissionNotFound
+	}
+	return nil
+}
+
+// GetByUser 用户未过期的对象授权
+func (s *ResourcePermissionService) GetByUser(userID uint) ([]models.ResourcePermission, error) {
+	var grants []models.ResourcePermission
+	err := s.active().Where("user_id = ?", userID).
+		Order("resource_type, resource_id, permission").
+		Find(&grants).Error
+	return grants, err
+}
+
+// GetByResource 对象未过期的授权（附带被授权用户）
+func (s *ResourcePermissionService) GetByResource(resourceType string, resourceID uint) ([]models.ResourcePermission, error) {
+	var grants []models.ResourcePermission
+	err := s.active().Preload("User").
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Order("user_id, permission").
+		Find(&grants).Error
+	return grants, err
+}
+
+// CleanExpired 删除已过期的授权，返回删除数量
+func (s *ResourcePermissionService) CleanExpired() (int64, error) {
+	result := s.db.Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).
+		Delete(&models.ResourcePermission{})
+	return result.RowsAffected, result.Error
+}
+
+// StartCleanupJob 启动过期授权清理任务（过期授权在检查时已被忽略，这里只负责清理数据）
+func (s *ResourcePermissionService) StartCleanupJob() {
+	if s.ticker != nil {
+		return
+	}
+
+	s.ticker = time.NewTicker(resourceGrantCleanupInterval)
+	s.stopChan = make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				if count, err := s.CleanExpired(); err != nil {
+					logger.Log.Errorf("清理过期资源授权失败: %v", err)
+				} else if count > 0 {
+					logger.Log.Infof("已清理 %d 条过期资源授权", count)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopCleanupJob 停止过期授权清理任务
+func (s *ResourcePermissionService) StopCleanupJob() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+		close(s.stopChan)
+		s.ticker = nil
+	}
+}
+
+// active 未过期的授权
+func (s *ResourcePermissionService) active() *gorm.DB {
+	return s.db.Model(&models.ResourcePermission{}).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// touchInterval 会话最后活动时间的更新间隔，避免每个请求都写数据库
+const touchInterval = time.Minute
+
+var (
+	ErrSessionNotFound    = errors.New("会话不存在")
+	ErrSessionRevoked     = errors.New("会话已失效，请重新登录")
+	ErrSessionExpired     = errors.New("会话已过期，请重新登录")
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，会话已撤销，请重新登录")
+)
+
+// SessionService 登录会话服务：创建会话、轮换刷新令牌、校验和撤销会话
+type SessionService struct {
+	db *gorm.DB
+}
+
+// userRevokedHooks 撤销用户全部会话后的回调（修改密码、重置密码、禁用、全部下线都会触发）
+var userRevokedHooks []func(userID uint)
+
+// OnUserRevoked 注册撤销用户全部会话后的回调，用于清除会话之外缓存的登录凭据（如 WebDAV）
+func OnUserRevoked(hook func(userID uint)) {
+	userRevokedHooks = append(userRevokedHooks, hook)
+}
+
+// NewSessionService 创建会话服务
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{db: db}
+}
+
+// Create 登录时创建会话，返回会话和首个刷新令牌ID（jti）
+func (s *SessionService) Create(userID uint, username, userAgent, ip string, ttl time.Duration) (*models.UserSession, string, error) {
+	refreshID := randomID()
+	now := time.Now()
+	session := &models.UserSession{
+		SessionID:   randomID(),
+		UserID:      userID,
+		Username:    username,
+		Device:      DeviceName(userAgent),
+		UserAgent:   truncate(userAgent, 512),
+		LoginIP:     ip,
+		LastSeenAt:  now,
+		LastSeenIP:  ip,
+		RefreshHash: sha256Hex(refreshID),
+		ExpiresAt:   now.Add(ttl),
+	}
+	if err := s.db.Create(session).Error; err != nil {
+		return nil, "", err
+	}
+	return session, refreshID, nil
+}
+
+// Rotate 使用刷新令牌换取新的刷新令牌ID（单次有效）
+// 出示的刷新令牌不是当前有效的那个，说明旧令牌被重放（可能已泄露），立即撤销整个会话
+func (s *SessionService) Rotate(sessionID, refreshID, ip string, ttl time.Duration) (*models.UserSession, string, error) {
+	var session models.UserSession
+	if err := s.db.Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", ErrSessionNotFound
+		}
+		return nil, "", err
+	}
+	if session.RevokedAt != nil {
+		return nil, "", ErrSessionRevoked
+	}
+	if !session.IsActive() {
+		return nil, "", ErrSessionExpired
+	}
+
+	presented := sha256Hex(refreshID)
+	newRefreshID := randomID()
+	now := time.Now()
+	result := s.db.Model(&models.UserSession{}).
+		Where("id = ? AND refresh_hash = ? AND revoked_at IS NULL", session.ID, presented).
+		Updates(map[string]interface{}{
+			"refresh_hash":  sha256Hex(newRefreshID),
+			"refresh_count": gorm.Expr("refresh_count + 1"),
+			"expires_at":    now.Add(ttl),
+			"last_seen_at":  now,
+			"last_seen_ip":  ip,
+		})
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 摘要不匹配（或并发刷新中落后的一方）：按令牌重放处理
+		logger.Log.Warnf("检测到刷新令牌重放，撤销会话: sessionID=%s, userID=%d, ip=%s", sessionID, session.UserID, ip)
+		s.Revoke(sessionID, models.SessionRevokedRefreshReuse)
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	if err := s.db.First(&session, session.ID).Error; err != nil {
+		return nil, "", err
+	}
+	return &session, newRefreshID, nil
+}
+
+// ValidateSession 校验访问令牌绑定的会话（实现 middleware.SessionValidator），顺带更新最后活动时间
+func (s *SessionService) ValidateSession(sessionID string, userID uint, ip string) error {
+	var session models.UserSession
+	err := s.db.Select("id", "user_id", "expires_at", "revoked_at", "last_seen_at", "last_seen_ip").
+		Where("session_id = ?", sessionID).First(&session).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionRevoked
+	}
+	if !session.IsActive() {
+		return ErrSessionExpired
+	}
+
+	if time.Since(session.LastSeenAt) > touchInterval || session.LastSeenIP != ip {
+		s.db.Model(&models.UserSession{}).Where("id = ?", session.ID).
+			UpdateColumns(map[string]interface{}{"last_seen_at": time.Now(), "last_seen_ip": ip})
+	}
+	return nil
+}
+
+// List 列出用户的有效会话（userID 为 0 时列出全部用户的有效会话），currentSessionID 对应的会话标记为当前会话
+func (s *SessionService) List(userID uint, currentSessionID string) ([]*models.UserSession, error) {
+	query := s.db.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	if userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var sessions []*models.UserSession
+	if err := query.Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.SessionID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// Get 按主键获取会话
+func (s *SessionService) Get(id uint) (*models.UserSession, error) {
+	var session models.UserSession
+	if err := s.db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Revoke 撤销单个会话
+func (s *SessionService) Revoke(sessionID, reason string) error {
+	return s.db.Model(&models.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// RevokeUser 撤销用户的全部会话，exceptSessionID 不为空时保留该会话（如修改密码时保留当前会话）
+func (s *SessionService) RevokeUser(userID uint, reason, exceptSessionID string) (int64, error) {
+	query := s.db.Model(&models.UserSession{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptSessionID != "" {
+		query = query.Where("session_id <> ?", exceptSessionID)
+	}
+	result := query.Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason})
+	for _, hook := range userRevokedHooks {
+		hook(userID)
+	}
+	return result.RowsAffected, result.Error
+}
+
+// DeviceName 根据 User-Agent 识别设备描述，如“Chrome / Windows”
+func DeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "未知设备"
+	}
+
+	browser := "其他客户端"
+	for _, candidate := range []struct{ token, name string }{
+		{"Electron/", "桌面客户端"},
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"okhttp", "okhttp"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	system := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			system = candidate.name
+			break
+		}
+	}
+
+	if system == "" {
+		return browser
+	}
+	return browser + " / " + system
+}
+
+// randomID 生成随机ID（会话ID和刷新令牌ID）
+func randomID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// sha256Hex 计算摘要（刷新令牌ID和个人访问令牌都只保存摘要）
+func sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate 截断超长字符串
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
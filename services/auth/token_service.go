@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"errors"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAPITokenInvalid       = errors.New("无效的访问令牌")
+	ErrAPITokenRevoked       = errors.New("访问令牌已撤销")
+	ErrAPITokenExpired       = errors.New("访问令牌已过期")
+	ErrAPITokenIPDenied      = errors.New("访问令牌不允许从当前地址使用")
+	ErrAPITokenOwnerDisabled = errors.New("令牌所属账号已被禁用")
+	ErrAPITokenNotFound      = errors.New("访问令牌不存在")
+	ErrScopesRequired        = errors.New("至少需要指定一个权限范围")
+	ErrInvalidScope          = errors.New("权限范围格式错误，应为 资源:操作，如 models:create")
+	ErrScopeNotGranted       = errors.New("权限范围超出了账号本身拥有的权限")
+	ErrInvalidAllowedIP      = errors.New("IP 白名单格式错误，应为 IP 地址或 CIDR")
+	ErrExpiresInPast         = errors.New("过期时间必须晚于当前时间")
+	ErrServiceAccountExists  = errors.New("用户名已存在")
+)
+
+// CreateTokenInput 创建访问令牌的参数
+type CreateTokenInput struct {
+	Name        string
+	Description string
+	Scopes      []string
+	AllowedIPs  []string
+	ExpiresAt   *time.Time
+	CreatedBy   string
+}
+
+// CreateServiceAccountInput 创建服务账号的参数
+type CreateServiceAccountInput struct {
+	Username   string
+	RealName   string
+	Department string
+	RoleIDs    []uint
+}
+
+// TokenService 个人访问令牌和服务账号服务
+type TokenService struct {
+	db *gorm.DB
+}
+
+// NewTokenService 创建访问令牌服务
+func NewTokenService(db *gorm.DB) *TokenService {
+	return &TokenService{db: db}
+}
+
+// Create 为用户创建访问令牌，返回令牌记录和明文令牌（明文只在创建时返回一次）
+// 权限范围必须是所属账号已拥有的权限，令牌不能获得比账号更大的权限
+func (s *TokenService) Create(owner *models.User, ownerPerms []string, input CreateTokenInput) (*models.APIToken, string, error) {
+	scopes, err := normalizeScopes(input.Scopes, ownerPerms)
+	if err != nil {
+		return nil, "", err
+	}
+	allowedIPs, err := normalizeAllowedIPs(input.AllowedIPs)
+	if err != nil {
+		return nil, "", err
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, "", ErrExpiresInPast
+	}
+
+	// 令牌格式：pmt_<8位标识>_<32位随机串>，前缀部分保存明文用于识别
+	prefix := middleware.APITokenPrefix + randomID()[:8]
+	plain := prefix + "_" + randomID()
+
+	token := &models.APIToken{
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+		UserID:      owner.ID,
+		Username:    owner.Username,
+		Prefix:      prefix,
+		TokenHash:   sha256Hex(plain),
+		Scopes:      strings.Join(scopes, ","),
+		AllowedIPs:  strings.Join(allowedIPs, ","),
+		ExpiresAt:   input.ExpiresAt,
+		CreatedBy:   input.CreatedBy,
+	}
+	if err := s.db.Create(token).Error; err != nil {
+		return nil, "", err
+	}
+	return token, plain, nil
+}
+
+// AuthenticateAPIToken 校验访问令牌（实现 middleware.APITokenAuthenticator），顺带更新最后使用时间
+func (s *TokenService) AuthenticateAPIToken(plain, ip string) (*middleware.Claims, error) {
+	var token models.APIToken
+	if err := s.db.Where("token_hash = ?", sha256Hex(plain)).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrAPITokenInvalid
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, ErrAPITokenRevoked
+	}
+	if token.IsExpired() {
+		return nil, ErrAPITokenExpired
+	}
+	if !ipAllowed(token.AllowedIPList(), ip) {
+		return nil, ErrAPITokenIPDenied
+	}
+
+	var owner models.User
+	if err := s.db.Select("id", "username", "status").First(&owner, token.UserID).Error; err != nil {
+		return nil, ErrAPITokenOwnerDisabled
+	}
+	if owner.Status == models.UserStatusDisabled {
+		return nil, ErrAPITokenOwnerDisabled
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > touchInterval || token.LastUsedIP != ip {
+		s.db.Model(&models.APIToken{}).Where("id = ?", token.ID).
+			UpdateColumns(map[string]interface{}{"last_used_at": time.Now(), "last_used_ip": ip})
+	}
+
+	return &middleware.Claims{
+		UserID:    owner.ID,
+		Username:  owner.Username,
+		TokenType: middleware.TokenTypeAPI,
+		Scopes:    token.ScopeList(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID: token.Prefix,
+		},
+	}, nil
+}
+
+// List 列出用户未撤销的访问令牌（userID 为 0 时列出全部）
+func (s *TokenService) List(userID uint) ([]*models.APIToken, error) {
+	query := s.db.Where("revoked_at IS NULL")
+	if userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var tokens []*models.APIToken
+	if err := query.Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Get 按主键获取未撤销的访问令牌
+func (s *TokenService) Get(id uint) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := s.db.Where("revoked_at IS NULL").First(&token, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrAPITokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Revoke 撤销访问令牌，撤销后立即失效
+func (s *TokenService) Revoke(id uint) error {
+	return s.db.Model(&models.APIToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// CreateServiceAccount 创建服务账号
+// 服务账号使用随机密码且不能通过密码登录，只能通过访问令牌调用接口，权限来自分配的角色
+func (s *TokenService) CreateServiceAccount(input CreateServiceAccountInput) (*models.User, error) {
+	var count int64
+	s.db.Model(&models.User{}).Where("username = ?", input.Username).Count(&count)
+	if count > 0 {
+		return nil, ErrServiceAccountExists
+	}
+
+	password, err := middleware.HashPassword(randomID())
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:    input.Username,
+		Password:    password,
+		Email:       input.Username + "@service-account.local", // 邮箱唯一，服务账号使用占位邮箱
+		RealName:    input.RealName,
+		Department:  input.Department,
+		Status:      models.UserStatusActive,
+		AccountType: models.AccountTypeService,
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, err
+	}
+
+	if len(input.RoleIDs) > 0 {
+		var roles []models.Role
+		s.db.Where("id IN ?", input.RoleIDs).Find(&roles)
+		if len(roles) > 0 {
+			s.db.Model(user).Association("Roles").Append(roles)
+		}
+	}
+	return user, nil
+}
+
+// ListServiceAccounts 列出服务账号
+func (s *TokenService) ListServiceAccounts() ([]models.User, error) {
+	var users []models.User
+	err := s.db.Preload("Roles").Where("account_type = ?", models.AccountTypeService).
+		Order("created_at DESC").Find(&users).Error
+	return users, err
+}
+
+// normalizeScopes 校验并去重权限范围
+func normalizeScopes(scopes, ownerPerms []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		parts := strings.Split(scope, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.Contains(scope, ",") {
+			return nil, ErrInvalidScope
+		}
+		if !middleware.MatchPermission(ownerPerms, scope) {
+			return nil, ErrScopeNotGranted
+		}
+		seen[scope] = true
+		result = append(result, scope)
+	}
+	if len(result) == 0 {
+		return nil, ErrScopesRequired
+	}
+	return result, nil
+}
+
+// normalizeAllowedIPs 校验 IP 白名单
+func normalizeAllowedIPs(entries []string) ([]string, error) {
+	var result []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return nil, ErrInvalidAllowedIP
+			}
+		} else if net.ParseIP(entry) == nil {
+			return nil, ErrInvalidAllowedIP
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// ipAllowed 来源 IP 是否在白名单中（白名单为空不限制）
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(addr) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,396 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// twoFactorChallengeTTL 密码验证通过后完成两步验证（或绑定验证器）的时限
+	twoFactorChallengeTTL = 5 * time.Minute
+	// twoFactorMaxAttempts 每个挑战允许输错验证码的次数，超过后需重新输入密码
+	twoFactorMaxAttempts = 5
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+)
+
+// 挑战用途
+const (
+	ChallengeVerify = "verify" // 已启用两步验证，需要输入验证码
+	ChallengeEnroll = "enroll" // 角色要求两步验证但尚未绑定，需要先绑定验证器
+)
+
+var (
+	ErrTwoFactorChallenge       = errors.New("两步验证已过期，请重新登录")
+	ErrTwoFactorCodeInvalid     = errors.New("验证码错误")
+	ErrTwoFactorNotEnabled      = errors.New("未启用两步验证")
+	ErrTwoFactorAlreadyEnabled  = errors.New("已启用两步验证")
+	ErrTwoFactorNotSetup        = errors.New("请先获取绑定密钥")
+	ErrTwoFactorRequiredByRole  = errors.New("所属角色要求启用两步验证，不能关闭")
+	ErrTwoFactorTooManyAttempts = errors.New("验证码错误次数过多，请重新登录")
+)
+
+// twoFactorChallenge 密码验证通过后等待两步验证的登录
+type twoFactorChallenge struct {
+	userID    uint
+	purpose   string
+	attempts  int
+	expiresAt time.Time
+}
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"` // 所属角色要求启用
+	ConfirmedAt            *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedAt             *time.Time `json:"last_used_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetup 绑定验证器所需的信息
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`           // 无法扫码时手动输入
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// 地址，前端渲染为二维码
+}
+
+// TwoFactorService TOTP 两步验证服务
+type TwoFactorService struct {
+	db     *gorm.DB
+	issuer string // 验证器应用中显示的发行方
+
+	mu         sync.Mutex
+	challenges map[string]*twoFactorChallenge // 挑战令牌 -> 登录
+}
+
+// NewTwoFactorService 创建两步验证服务
+func NewTwoFactorService(db *gorm.DB, issuer string) *TwoFactorService {
+	if issuer == "" {
+		issuer = "ProjectManager"
+	}
+	return &TwoFactorService{
+		db:         db,
+		issuer:     issuer,
+		challenges: make(map[string]*twoFactorChallenge),
+	}
+}
+
+// Status 获取用户的两步验证状态
+func (s *TwoFactorService) Status(userID uint) (*TwoFactorStatus, error) {
+	required, err := s.Required(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{Required: required}
+	totp, err := s.enabledTOTP(userID)
+	if err == ErrTwoFactorNotEnabled {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.Enabled = true
+	status.ConfirmedAt = totp.ConfirmedAt
+	status.LastUsedAt = totp.LastUsedAt
+	s.db.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&status.RecoveryCodesRemaining)
+	return status, nil
+}
+
+// Enabled 用户是否已启用两步验证
+func (s *TwoFactorService) Enabled(userID uint) (bool, error) {
+	_, err := s.enabledTOTP(userID)
+	if err == ErrTwoFactorNotEnabled {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Required 用户的任一角色是否要求启用两步验证
+func (s *TwoFactorService) Required(userID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ? AND roles.require_two_factor = ?", userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// NewChallenge 为通过密码验证的用户创建挑战，前端凭挑战令牌完成两步验证或绑定验证器
+func (s *TwoFactorService) NewChallenge(userID uint, purpose string) string {
+	token := randomID()
+	s.mu.Lock()
+	s.pruneLocked()
+	s.challenges[token] = &twoFactorChallenge{
+		userID:    userID,
+		purpose:   purpose,
+		expiresAt: time.Now().Add(twoFactorChallengeTTL),
+	}
+	s.mu.Unlock()
+	return token
+}
+
+// ChallengeTTL 挑战有效期（秒）
+func (s *TwoFactorService) ChallengeTTL() int {
+	return int(twoFactorChallengeTTL.Seconds())
+}
+
+// ChallengeUser 返回挑战对应的用户（不消耗挑战）
+func (s *TwoFactorService) ChallengeUser(token, purpose string) (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	challenge, ok := s.challenges[token]
+	if !ok || challenge.purpose != purpose || time.Now().After(challenge.expiresAt) {
+		return 0, ErrTwoFactorChallenge
+	}
+	return challenge.userID, nil
+}
+
+// Setup 生成新的密钥（尚未启用，需要用 Enable 提交验证码确认）
+func (s *TwoFactorService) Setup(user *models.User) (*TwoFactorSetup, error) {
+	var totp models.UserTOTP
+	err := s.db.Where("user_id = ?", user.ID).First(&totp).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	totp.UserID = user.ID
+	totp.Secret = secret
+	totp.LastUsedStep = 0
+	if err := s.db.Save(&totp).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+// Enable 校验验证器生成的验证码并启用两步验证，返回恢复码明文（只返回这一次）
+func (s *TwoFactorService) Enable(userID uint, code string) ([]string, error) {
+	var totp models.UserTOTP
+	if err := s.db.Where("user_id = ?", userID).First(&totp).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTwoFactorNotSetup
+		}
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err := s.useCode(&totp, code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&totp).Updates(map[string]interface{}{
+		"enabled":      true,
+		"confirmed_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return s.regenerateRecoveryCodes(userID)
+}
+
+// Verify 使用挑战令牌和验证码（或恢复码）完成两步验证，成功后挑战失效
+// 返回用户ID和是否使用了恢复码
+func (s *TwoFactorService) Verify(token, code, recoveryCode string) (uint, bool, error) {
+	userID, err := s.ChallengeUser(token, ChallengeVerify)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if recoveryCode != "" {
+		err = s.useRecoveryCode(userID, recoveryCode)
+	} else {
+		var totp *models.UserTOTP
+		totp, err = s.enabledTOTP(userID)
+		if err == nil {
+			err = s.useCode(totp, code)
+		}
+	}
+
+	if err == ErrTwoFactorCodeInvalid {
+		return userID, false, s.recordAttempt(token)
+	}
+	if err != nil {
+		return userID, false, err
+	}
+
+	s.ConsumeChallenge(token)
+	return userID, recoveryCode != "", nil
+}
+
+// ConsumeChallenge 使挑战失效
+func (s *TwoFactorService) ConsumeChallenge(token string) {
+	s.mu.Lock()
+	delete(s.challenges, token)
+	s.mu.Unlock()
+}
+
+// CheckCode 校验已启用用户的验证码（关闭两步验证、重新生成恢复码等敏感操作前确认）
+func (s *TwoFactorService) CheckCode(userID uint, code string) error {
+	totp, err := s.enabledTOTP(userID)
+	if err != nil {
+		return err
+	}
+	return s.useCode(totp, code)
+}
+
+// RegenerateRecoveryCodes 作废现有恢复码并生成新的一组
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	if _, err := s.enabledTOTP(userID); err != nil {
+		return nil, err
+	}
+	return s.regenerateRecoveryCodes(userID)
+}
+
+// Disable 关闭两步验证并删除密钥和恢复码（管理员重置也使用该方法）
+func (s *TwoFactorService) Disable(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserTOTP{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error
+	})
+}
+
+// enabledTOTP 获取已启用的两步验证配置
+func (s *TwoFactorService) enabledTOTP(userID uint) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+	err := s.db.Where("user_id = ? AND enabled = ?", userID, true).First(&totp).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// useCode 校验验证码并记录使用的时间窗口，同一窗口内的验证码只能使用一次
+func (s *TwoFactorService) useCode(totp *models.UserTOTP, code string) error {
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok || step <= totp.LastUsedStep {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	// 条件更新，并发提交同一个验证码时只有一个成功
+	now := time.Now()
+	result := s.db.Model(&models.UserTOTP{}).
+		Where("id = ? AND last_used_step < ?", totp.ID, step).
+		Updates(map[string]interface{}{"last_used_step": step, "last_used_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+	totp.LastUsedStep = step
+	totp.LastUsedAt = &now
+	return nil
+}
+
+// useRecoveryCode 使用恢复码（每个只能使用一次）
+func (s *TwoFactorService) useRecoveryCode(userID uint, code string) error {
+	hash := sha256Hex(normalizeRecoveryCode(code))
+	result := s.db.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+// regenerateRecoveryCodes 删除旧的恢复码并生成新的一组，只保存摘要
+func (s *TwoFactorService) regenerateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.UserRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.UserRecoveryCode{
+			UserID:   userID,
+			CodeHash: sha256Hex(normalizeRecoveryCode(code)),
+		})
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// recordAttempt 记录一次验证失败，超过次数后挑战失效
+func (s *TwoFactorService) recordAttempt(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	challenge, ok := s.challenges[token]
+	if !ok {
+		return ErrTwoFactorChallenge
+	}
+	challenge.attempts++
+	if challenge.attempts >= twoFactorMaxAttempts {
+		delete(s.challenges, token)
+		return ErrTwoFactorTooManyAttempts
+	}
+	return ErrTwoFactorCodeInvalid
+}
+
+// pruneLocked 清理过期的挑战（调用方持有锁）
+func (s *TwoFactorService) pruneLocked() {
+	now := time.Now()
+	for token, challenge := range s.challenges {
+		if now.After(challenge.expiresAt) {
+			delete(s.challenges, token)
+		}
+	}
+}
+
+// newRecoveryCode 生成恢复码，格式为 xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode 忽略大小写、空格和连字符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,247 @@
+package document
+
+import (
+	"errors"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/models/requirement"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDocumentForbidden = errors.New("无权访问该文档")
+	ErrDocumentReadOnly  = errors.New("无权修改该文档")
+)
+
+// Viewer 当前访问文件库的用户
+// 可见范围：公开文档、自己上传的文档、本部门文档、所在项目的文档、单独授权的文档，
+// 以及上述文件夹下的全部子项（文件夹权限向下继承）
+type Viewer struct {
+	UserID     uint
+	Username   string
+	Department string   // 未启用部门权限时为空
+	Projects   []string // 所在项目的名称和标识，未启用项目权限时为空
+	Admin      bool     // 拥有 documents:admin 权限，可见全部文档
+	GrantsOnly bool     // 没有 documents:read 权限，只能看到单独授权的文档（及其子项）
+}
+
+// AccessService 文档可见性服务
+type AccessService struct {
+	db     *gorm.DB
+	config *config.DocumentConfig
+	logger *logrus.Logger
+}
+
+// NewAccessService 创建文档可见性服务
+func NewAccessService(db *gorm.DB, cfg *config.DocumentConfig, logger *logrus.Logger) *AccessService {
+	return &AccessService{
+		db:     db,
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// LoadViewer 根据登录用户加载部门和项目成员关系，userID 为 0 表示匿名访问（只能看到公开文档）
+func (s *AccessService) LoadViewer(userID uint, username string, admin bool) (*Viewer, error) {
+	viewer := &Viewer{UserID: userID, Username: username, Admin: admin}
+	if userID == 0 || admin {
+		return viewer, nil
+	}
+
+	if s.config.EnableDepartment {
+		var user models.User
+		if err := s.db.Select("id", "department").First(&user, userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return viewer, nil
+			}
+			return nil, err
+		}
+		viewer.Department = user.Department
+	}
+
+	if s.config.EnableProject {
+		var projects []struct {
+			Name string
+			Key  string
+		}
+		err := s.db.Model(&requirement.ProjectMember{}).
+			Select("requirement_projects.name, requirement_projects.key").
+			Joins("JOIN requirement_projects ON requirement_projects.id = requirement_project_members.project_id").
+			Where("requirement_project_members.user_id = ?", userID).
+			Scan(&projects).Error
+		if err != nil {
+			// 项目表不可用时按无项目处理，不影响其他可见范围
+			s.logger.Warnf("加载用户项目失败: userID=%d, error=%v", userID, err)
+		}
+		for _, project := range projects {
+			viewer.Projects = append(viewer.Projects, project.Name)
+			if project.Key != "" {
+				viewer.Projects = append(viewer.Projects, project.Key)
+			}
+		}
+	}
+
+	return viewer, nil
+}
+
+// CanView 检查文档（包括回收站中的文档）是否对用户可见
+func (s *AccessService) CanView(viewer *Viewer, id uint) error {
+	return s.CanViewAll(viewer, []uint{id})
+}
+
+// CanViewAll 检查一组文档是否全部对用户可见
+func (s *AccessService) CanViewAll(viewer *Viewer, ids []uint) error {
+	var total int64
+	if err := s.db.Unscoped().Model(&models.Document{}).Where("id IN ?", ids).Count(&total).Error; err != nil {
+		return err
+	}
+	if int(total) < len(uniqueIDs(ids)) {
+		return gorm.ErrRecordNotFound
+	}
+
+	visible, err := s.VisibleIDs(viewer, ids)
+	if err != nil {
+		return err
+	}
+	if len(visible) < len(uniqueIDs(ids)) {
+		return ErrDocumentForbidden
+	}
+	return nil
+}
+
+// CanEditAll 检查用户能否对一组文档执行修改类操作（action 为 update、delete、upload 等）
+// 可修改范围：自己上传的文档、本部门文档、单独授予了该操作的文档，以及上述文件夹下的全部子项；
+// 公开文档和项目文档只可查看，调用方应先用 CanViewAll 检查可见性
+func (s *AccessService) CanEditAll(viewer *Viewer, ids []uint, action string) error {
+	if viewer != nil && viewer.Admin {
+		return nil
+	}
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	condition, args := editableCondition("id", viewer, action)
+	var total int64
+	if err := s.db.Unscoped().Model(&models.Document{}).Where(condition, args...).
+		Where("id IN ?", ids).Count(&total).Error; err != nil {
+		return err
+	}
+	if int(total) < len(ids) {
+		return ErrDocumentReadOnly
+	}
+	return nil
+}
+
+// VisibleIDs 返回 ids 中对用户可见的文档ID
+func (s *AccessService) VisibleIDs(viewer *Viewer, ids []uint) (map[uint]bool, error) {
+	var visible []uint
+	if len(ids) > 0 {
+		if err := s.db.Unscoped().Model(&models.Document{}).Scopes(VisibleScope(viewer)).
+			Where("id IN ?", ids).Pluck("id", &visible).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	result := make(map[uint]bool, len(visible))
+	for _, id := range visible {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// VisibleScope 将查询限制在用户可见的文档范围内，viewer 为 nil 表示不限制（内部调用）
+func VisibleScope(viewer *Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		condition, args := visibleCondition("id", viewer)
+		if condition == "" {
+			return db
+		}
+		return db.Where(condition, args...)
+	}
+}
+
+// visibleCondition 生成可见性过滤条件，column 为文档ID列名（原生 SQL 中可带表别名）
+// 先找出直接可见的文档，再沿 parent_id 递归展开其子孙，实现文件夹权限继承
+func visibleCondition(column string, viewer *Viewer) (string, []interface{}) {
+	if viewer == nil || viewer.Admin {
+		return "", nil
+	}
+
+	var direct []string
+	var args []interface{}
+	// 仅凭单独授权访问的用户只能看到授权的文档
+	if !viewer.GrantsOnly {
+		direct = append(direct, "is_public = ?")
+		args = append(args, true)
+		if viewer.Username != "" {
+			direct = append(direct, "uploaded_by = ?")
+			args = append(args, viewer.Username)
+		}
+		if viewer.Department != "" {
+			direct = append(direct, "department = ?")
+			args = append(args, viewer.Department)
+		}
+		if len(viewer.Projects) > 0 {
+			direct = append(direct, "project IN ?")
+			args = append(args, viewer.Projects)
+		}
+	}
+	if viewer.UserID > 0 {
+		direct = append(direct, "id IN (SELECT resource_id FROM resource_permissions"+
+			" WHERE user_id = ? AND resource_type = ? AND (expires_at IS NULL OR expires_at > ?))")
+		args = append(args, viewer.UserID, models.ResourceDocuments, time.Now())
+	}
+
+	return inheritedCondition(column, direct), args
+}
+
+// editableCondition 生成可修改范围的过滤条件，viewer 为 nil 或匿名用户时不匹配任何文档
+func editableCondition(column string, viewer *Viewer, action string) (string, []interface{}) {
+	if viewer == nil || viewer.UserID == 0 {
+		return "1 = 0", nil
+	}
+
+	var direct []string
+	var args []interface{}
+	if !viewer.GrantsOnly {
+		if viewer.Username != "" {
+			direct = append(direct, "uploaded_by = ?")
+			args = append(args, viewer.Username)
+		}
+		if viewer.Department != "" {
+			direct = append(direct, "department = ?")
+			args = append(args, viewer.Department)
+		}
+	}
+	direct = append(direct, "id IN (SELECT resource_id FROM resource_permissions"+
+		" WHERE user_id = ? AND resource_type = ? AND permission IN ? AND (expires_at IS NULL OR expires_at > ?))")
+	args = append(args, viewer.UserID, models.ResourceDocuments, []string{action, models.ActionAll}, time.Now())
+
+	return inheritedCondition(column, direct), args
+}
+
+// inheritedCondition 从满足 direct 任一条件的文档出发，沿 parent_id 递归展开其子孙
+func inheritedCondition(column string, direct []string) string {
+	return column + " IN (WITH RECURSIVE matched_document(id) AS (" +
+		"SELECT id FROM document WHERE " + strings.Join(direct, " OR ") +
+		" UNION SELECT child.id FROM document child JOIN matched_document ON child.parent_id = matched_document.id" +
+		") SELECT id FROM matched_document)"
+}
+
+// uniqueIDs 去重
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,265 @@
+package document
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrArchiveEmpty          = errors.New("没有可打包的文件")
+	ErrArchiveTooLarge       = errors.New("打包文件总大小超过限制")
+	ErrArchiveTooManyFiles   = errors.New("打包文件数量超过限制")
+	ErrArchiveHistoryVersion = errors.New("历史版本不能打包下载，请使用单文件下载")
+)
+
+// storedFormats 本身已压缩的格式，打包时不再压缩
+var storedFormats = map[string]bool{
+	"zip": true, "rar": true, "7z": true, "gz": true,
+	"jpg": true, "jpeg": true, "png": true, "gif": true, "webp": true,
+	"mp4": true, "webm": true, "avi": true, "mov": true, "mp3": true,
+	"docx": true, "xlsx": true, "pptx": true,
+}
+
+// ArchiveEntry 压缩包中的一项（文件或空文件夹）
+type ArchiveEntry struct {
+	Path     string // 压缩包内路径，文件夹以 / 结尾
+	Document *models.Document
+}
+
+// ArchivePlan 打包计划：在开始输出前完成遍历和限制检查，避免写出一半才失败
+type ArchivePlan struct {
+	Name      string // 下载文件名
+	RootID    uint   // 访问日志关联的文档（文件夹打包为该文件夹，多选为共同父文件夹，0 表示根目录）
+	Entries   []*ArchiveEntry
+	FileCount int
+	TotalSize int64
+}
+
+// ArchiveResult 打包结果
+type ArchiveResult struct {
+	Written []uint   // 成功写入的文件ID
+	Missing []string // 物理文件缺失而跳过的文件路径
+}
+
+// ArchiveService 文件夹和多选文件打包下载服务
+type ArchiveService struct {
+	db     *gorm.DB
+	config *config.DocumentConfig
+}
+
+// NewArchiveService 创建打包下载服务
+func NewArchiveService(db *gorm.DB, cfg *config.DocumentConfig) *ArchiveService {
+	return &ArchiveService{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// Plan 展开选中的文件和文件夹，生成保持目录结构的打包计划
+func (s *ArchiveService) Plan(ids []uint) (*ArchivePlan, error) {
+	ids, err := normalizeBatchIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []*models.Document
+	if err := s.db.Where("id IN ?", ids).Order("is_folder DESC, name").Find(&roots).Error; err != nil {
+		return nil, err
+	}
+	if len(roots) != len(ids) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	plan := &ArchivePlan{}
+	used := make(map[string]bool)
+	for _, root := range roots {
+		if !root.IsLatest {
+			return nil, ErrArchiveHistoryVersion
+		}
+		if err := s.addEntry(plan, root, "", used); err != nil {
+			return nil, err
+		}
+	}
+	if plan.FileCount == 0 && len(plan.Entries) == 0 {
+		return nil, ErrArchiveEmpty
+	}
+
+	if len(roots) == 1 {
+		plan.Name = strings.TrimSuffix(roots[0].Name, path.Ext(roots[0].Name)) + ".zip"
+		if roots[0].IsFolder {
+			plan.Name = roots[0].Name + ".zip"
+			plan.RootID = roots[0].ID
+		}
+	} else {
+		plan.Name = fmt.Sprintf("documents_%s.zip", time.Now().Format("20060102_150405"))
+	}
+	if plan.RootID == 0 {
+		plan.RootID = commonParentID(roots)
+	}
+	return plan, nil
+}
+
+// addEntry 递归添加文件或文件夹，dir 为压缩包内的父目录
+func (s *ArchiveService) addEntry(plan *ArchivePlan, document *models.Document, dir string, used map[string]bool) error {
+	entryPath := uniqueEntryPath(dir, sanitizeEntryName(document.Name), document.IsFolder, used)
+
+	if !document.IsFolder {
+		plan.FileCount++
+		plan.TotalSize += document.FileSize
+		if s.config.ArchiveMaxFiles > 0 && plan.FileCount > s.config.ArchiveMaxFiles {
+			return ErrArchiveTooManyFiles
+		}
+		if s.config.ArchiveMaxSize > 0 && plan.TotalSize > s.config.ArchiveMaxSize {
+			return ErrArchiveTooLarge
+		}
+		plan.Entries = append(plan.Entries, &ArchiveEntry{Path: entryPath, Document: document})
+		return nil
+	}
+
+	var children []*models.Document
+	if err := s.db.Where("parent_id = ? AND is_latest = ?", document.ID, true).
+		Order("is_folder DESC, name").Find(&children).Error; err != nil {
+		return err
+	}
+	if len(children) == 0 {
+		// 保留空文件夹
+		plan.Entries = append(plan.Entries, &ArchiveEntry{Path: entryPath + "/", Document: document})
+		return nil
+	}
+	for _, child := range children {
+		if err := s.addEntry(plan, child, entryPath, used); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteZip 按打包计划将文件流式写入 zip（不生成临时文件）
+// 物理文件缺失时跳过并在压缩包末尾附加说明文件
+func (s *ArchiveService) WriteZip(w io.Writer, plan *ArchivePlan) (*ArchiveResult, error) {
+	result := &ArchiveResult{}
+	zipWriter := zip.NewWriter(w)
+
+	for _, entry := range plan.Entries {
+		header := &zip.FileHeader{
+			Name:     entry.Path,
+			Modified: entry.Document.UpdatedAt,
+		}
+		if entry.Document.IsFolder {
+			if _, err := zipWriter.CreateHeader(header); err != nil {
+				return result, err
+			}
+			continue
+		}
+
+		file, err := os.Open(actualFilePath(s.config, entry.Document.FilePath))
+		if err != nil {
+			logger.Log.Warnf("打包时文件不存在: documentID=%d, error=%v", entry.Document.ID, err)
+			result.Missing = append(result.Missing, entry.Path)
+			continue
+		}
+
+		header.Method = zip.Deflate
+		if storedFormats[strings.ToLower(entry.Document.Format)] {
+			header.Method = zip.Store
+		}
+
+		writer, err := zipWriter.CreateHeader(header)
+		if err == nil {
+			_, err = io.Copy(writer, file)
+		}
+		file.Close()
+		if err != nil {
+			return result, fmt.Errorf("写入 %s 失败: %w", entry.Path, err)
+		}
+		result.Written = append(result.Written, entry.Document.ID)
+	}
+
+	if len(result.Missing) > 0 {
+		writer, err := zipWriter.Create("缺失文件.txt")
+		if err != nil {
+			return result, err
+		}
+		fmt.Fprintf(writer, "以下 %d 个文件在存储中不存在，未能打包：\r\n%s\r\n",
+			len(result.Missing), strings.Join(result.Missing, "\r\n"))
+	}
+
+	return result, zipWriter.Close()
+}
+
+// RecordDownload 为成功写入的每个文件增加下载次数，并为本次打包记录一条访问日志
+func (s *ArchiveService) RecordDownload(plan *ArchivePlan, result *ArchiveResult, userName, userIP string) {
+	if len(result.Written) > 0 {
+		if err := s.db.Model(&models.Document{}).Where("id IN ?", result.Written).
+			UpdateColumn("download_count", gorm.Expr("download_count + 1")).Error; err != nil {
+			logger.Log.Warnf("更新下载次数失败: %v", err)
+		}
+	}
+
+	if !s.config.LogAccess {
+		return
+	}
+	s.db.Create(&models.DocumentAccessLog{
+		DocumentID: plan.RootID,
+		Action:     "archive",
+		UserName:   userName,
+		UserIP:     userIP,
+	})
+}
+
+// uniqueEntryPath 同一目录下重名时追加序号，如“报告 (2).pdf”
+func uniqueEntryPath(dir, name string, isFolder bool, used map[string]bool) string {
+	if name == "" {
+		name = "未命名"
+	}
+	ext := ""
+	if !isFolder {
+		ext = path.Ext(name)
+	}
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := path.Join(dir, name)
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = path.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// sanitizeEntryName 去除名称中的路径分隔符和非法字符，避免生成跨目录的条目
+func sanitizeEntryName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_", ":", "_", "\x00", "").Replace(strings.TrimSpace(name))
+	if name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// commonParentID 多选项目的共同父文件夹，不同或位于根目录时返回 0
+func commonParentID(documents []*models.Document) uint {
+	var parentID *uint
+	for i, document := range documents {
+		if i == 0 {
+			parentID = document.ParentID
+			continue
+		}
+		if !sameParent(parentID, document.ParentID) {
+			return 0
+		}
+	}
+	if parentID == nil {
+		return 0
+	}
+	return *parentID
+}
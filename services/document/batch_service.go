@@ -0,0 +1,549 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/upload"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxBatchItems 单次批量操作的最大项目数
+const maxBatchItems = 500
+
+// 批量操作类型
+const (
+	BulkActionDelete = "delete" // 移入回收站
+	BulkActionTag    = "tag"    // 追加标签
+	BulkActionUntag  = "untag"  // 移除标签
+	BulkActionUpdate = "update" // 设置部门、项目、是否公开
+)
+
+var (
+	ErrEmptyBatch          = errors.New("未指定要操作的文档")
+	ErrTooManyBatchItems   = fmt.Errorf("单次最多操作 %d 个文档", maxBatchItems)
+	ErrInvalidTargetFolder = errors.New("目标文件夹不存在")
+	ErrMoveIntoSelf        = errors.New("不能移动或复制到自身或其子文件夹中")
+	ErrHistoryVersion      = errors.New("历史版本不能单独操作，请操作最新版本")
+	ErrInvalidBulkAction   = errors.New("不支持的批量操作")
+	ErrEmptyBulkUpdate     = errors.New("未指定要修改的字段")
+)
+
+// BatchItemResult 单个项目的操作结果
+type BatchItemResult struct {
+	ID      uint   `json:"id"`
+	Success bool   `json:"success"`
+	NewID   uint   `json:"new_id,omitempty"` // 复制时新建的文档ID
+	Name    string `json:"name,omitempty"`   // 复制时新建的文档名称，或移动时重名被自动重命名后的名称
+	Error   string `json:"error,omitempty"`
+}
+
+// BatchResult 批量操作结果
+type BatchResult struct {
+	Items     []*BatchItemResult `json:"items"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+}
+
+// BulkRequest 批量修改参数
+type BulkRequest struct {
+	Action     string   // delete, tag, untag, update
+	Tags       []string // tag/untag 使用
+	Department *string  // update 使用，nil 表示不修改
+	Project    *string
+	IsPublic   *bool
+	Operator   string
+	OperatorIP string
+}
+
+// BatchService 文档移动、复制和批量操作服务
+// 每个项目在独立的保存点中执行，单个项目失败只回滚该项目，其余项目在同一事务中提交
+type BatchService struct {
+	db            *gorm.DB
+	uploadService *UploadService
+	trashService  *TrashService
+}
+
+// NewBatchService 创建批量操作服务
+func NewBatchService(db *gorm.DB, uploadService *UploadService, trashService *TrashService) *BatchService {
+	return &BatchService{
+		db:            db,
+		uploadService: uploadService,
+		trashService:  trashService,
+	}
+}
+
+// Move 将文档和文件夹移动到目标文件夹（targetID 为空表示根目录），文件的历史版本随之移动
+// 与目标文件夹中已有项目重名时自动追加“(副本)”后缀
+func (s *BatchService) Move(ids []uint, targetID *uint, operator, operatorIP string) (*BatchResult, error) {
+	ids, err := normalizeBatchIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTargetFolder(targetID); err != nil {
+		return nil, err
+	}
+
+	result := &BatchResult{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			item := &BatchItemResult{ID: id}
+			item.Error = errorText(tx.Transaction(func(tx *gorm.DB) error {
+				renamed, err := moveDocument(tx, id, targetID)
+				item.Name = renamed
+				return err
+			}))
+			if item.Error != "" {
+				item.Name = ""
+			}
+			result.add(item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range result.Items {
+		if item.Success {
+			s.uploadService.logAccess(item.ID, "move", operator, operatorIP)
+		}
+	}
+	return result, nil
+}
+
+// Copy 将文档和文件夹复制到目标文件夹（文件夹深度复制，仅复制文件的最新版本）
+// 与目标文件夹中已有项目重名时自动追加“(副本)”后缀
+// 物理文件在事务开始前复制，事务中只创建文档记录，失败时再清理已复制的文件
+func (s *BatchService) Copy(ids []uint, targetID *uint, operator, operatorIP string) (*BatchResult, error) {
+	ids, err := normalizeBatchIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTargetFolder(targetID); err != nil {
+		return nil, err
+	}
+
+	trees := make([]*copyNode, len(ids))
+	prepareErrs := make([]error, len(ids))
+	for i, id := range ids {
+		trees[i], prepareErrs[i] = s.prepareCopy(id, targetID)
+	}
+
+	result := &BatchResult{}
+	var copied []*models.Document
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			item := &BatchItemResult{ID: id}
+			err := prepareErrs[i]
+			if err == nil {
+				var created []*models.Document
+				err = tx.Transaction(func(tx *gorm.DB) error {
+					name := uniqueName(tx, targetID, trees[i].source.Name, "副本")
+					root, err := copyTree(tx, trees[i], targetID, name, operator, operatorIP, &created)
+					if err != nil {
+						return err
+					}
+					item.NewID = root.ID
+					item.Name = root.Name
+					return nil
+				})
+				if err != nil {
+					// 该项目已回滚，清理已复制的物理文件
+					s.removeCopiedFiles(trees[i])
+					trees[i] = nil
+					item.NewID, item.Name = 0, ""
+				} else {
+					copied = append(copied, created...)
+				}
+			}
+			item.Error = errorText(err)
+			result.add(item)
+		}
+		return nil
+	})
+	if err != nil {
+		for _, tree := range trees {
+			s.removeCopiedFiles(tree)
+		}
+		return nil, err
+	}
+
+	for _, document := range copied {
+		if document.IsFolder {
+			continue
+		}
+		if s.uploadService.fileProcessorService != nil {
+			go s.uploadService.generatePreview(document)
+		}
+		go s.uploadService.indexText(document)
+	}
+	for _, item := range result.Items {
+		if item.Success {
+			s.uploadService.logAccess(item.NewID, "copy", operator, operatorIP)
+		}
+	}
+	return result, nil
+}
+
+// Bulk 批量删除、打标签或修改部门/项目/公开属性
+func (s *BatchService) Bulk(ids []uint, req BulkRequest) (*BatchResult, error) {
+	ids, err := normalizeBatchIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var apply func(tx *gorm.DB, document *models.Document) error
+	switch req.Action {
+	case BulkActionDelete:
+		apply = func(tx *gorm.DB, document *models.Document) error {
+			return s.trashService.MoveToTrashTx(tx, document.ID, req.Operator)
+		}
+	case BulkActionTag, BulkActionUntag:
+		tags := normalizeTags(req.Tags)
+		if len(tags) == 0 {
+			return nil, errors.New("未指定标签")
+		}
+		apply = func(tx *gorm.DB, document *models.Document) error {
+			merged := mergeTags(document.Tags, tags, req.Action == BulkActionTag)
+			return tx.Model(document).Update("tags", merged).Error
+		}
+	case BulkActionUpdate:
+		updates := map[string]interface{}{}
+		if req.Department != nil {
+			updates["department"] = *req.Department
+		}
+		if req.Project != nil {
+			updates["project"] = *req.Project
+		}
+		if req.IsPublic != nil {
+			updates["is_public"] = *req.IsPublic
+		}
+		if len(updates) == 0 {
+			return nil, ErrEmptyBulkUpdate
+		}
+		apply = func(tx *gorm.DB, document *models.Document) error {
+			return tx.Model(document).Updates(updates).Error
+		}
+	default:
+		return nil, ErrInvalidBulkAction
+	}
+
+	result := &BatchResult{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			item := &BatchItemResult{ID: id}
+			item.Error = errorText(tx.Transaction(func(tx *gorm.DB) error {
+				var document models.Document
+				if err := tx.First(&document, id).Error; err != nil {
+					return err
+				}
+				if !document.IsLatest {
+					return ErrHistoryVersion
+				}
+				return apply(tx, &document)
+			}))
+			result.add(item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range result.Items {
+		if item.Success && req.Action != BulkActionDelete {
+			s.uploadService.logAccess(item.ID, "bulk_"+req.Action, req.Operator, req.OperatorIP)
+		}
+	}
+	return result, nil
+}
+
+// checkTargetFolder 验证目标文件夹存在（nil 表示根目录）
+func (s *BatchService) checkTargetFolder(targetID *uint) error {
+	if targetID == nil {
+		return nil
+	}
+	var count int64
+	s.db.Model(&models.Document{}).Where("id = ? AND is_folder = ?", *targetID, true).Count(&count)
+	if count == 0 {
+		return ErrInvalidTargetFolder
+	}
+	return nil
+}
+
+// moveDocument 移动单个文档，与目标文件夹中的项目重名时自动重命名并返回新名称
+func moveDocument(tx *gorm.DB, id uint, targetID *uint) (string, error) {
+	var document models.Document
+	if err := tx.First(&document, id).Error; err != nil {
+		return "", err
+	}
+	if !document.IsLatest {
+		return "", ErrHistoryVersion
+	}
+	if sameParent(document.ParentID, targetID) {
+		return "", nil
+	}
+	if document.IsFolder {
+		if err := checkNotDescendant(tx, document.ID, targetID); err != nil {
+			return "", err
+		}
+	}
+
+	renamed := ""
+	if name := uniqueName(tx, targetID, document.Name, "副本"); name != document.Name {
+		if err := tx.Model(&document).Update("name", name).Error; err != nil {
+			return "", err
+		}
+		renamed = name
+	}
+
+	if err := models.MoveToParent(tx, &document, targetID); err != nil {
+		return "", err
+	}
+
+	if document.IsFolder {
+		return renamed, nil
+	}
+	// 历史版本与最新版本保持在同一文件夹
+	history, err := versionHistory(tx, &document)
+	if err != nil {
+		return "", err
+	}
+	if len(history) <= 1 {
+		return renamed, nil
+	}
+	versionIDs := make([]uint, 0, len(history)-1)
+	for _, version := range history[1:] {
+		versionIDs = append(versionIDs, version.ID)
+	}
+	return renamed, tx.Model(&models.Document{}).Where("id IN ?", versionIDs).UpdateColumn("parent_id", targetID).Error
+}
+
+// copyNode 待复制的文档树，文件已复制到新的存储位置
+type copyNode struct {
+	source   models.Document
+	subPath  string // 复制后文件所在的存储子路径，文件夹为空
+	filePath string
+	children []*copyNode
+}
+
+// prepareCopy 读取要复制的文档树并复制物理文件（在事务之外执行），失败时清理已复制的文件
+func (s *BatchService) prepareCopy(id uint, targetID *uint) (*copyNode, error) {
+	var source models.Document
+	if err := s.db.First(&source, id).Error; err != nil {
+		return nil, err
+	}
+	if !source.IsLatest {
+		return nil, ErrHistoryVersion
+	}
+	if source.IsFolder {
+		if err := checkNotDescendant(s.db, source.ID, targetID); err != nil {
+			return nil, err
+		}
+	}
+
+	node := &copyNode{source: source}
+	if err := s.copyFiles(node); err != nil {
+		s.removeCopiedFiles(node)
+		return nil, err
+	}
+	return node, nil
+}
+
+// copyFiles 递归复制文件并读取文件夹的子项
+func (s *BatchService) copyFiles(node *copyNode) error {
+	if !node.source.IsFolder {
+		actualPath := actualFilePath(s.uploadService.config, node.source.FilePath)
+		file, err := upload.FromPath(actualPath, filepath.Base(actualPath))
+		if err != nil {
+			return fmt.Errorf("读取源文件失败: %w", err)
+		}
+		subPath := storageSubPath(uuid.NewString())
+		filePath, err := s.uploadService.saveFileTo(file, subPath, node.source.Format)
+		if err != nil {
+			return err
+		}
+		node.subPath, node.filePath = subPath, filePath
+		return nil
+	}
+
+	var children []models.Document
+	if err := s.db.Where("parent_id = ? AND is_latest = ?", node.source.ID, true).Order("id").Find(&children).Error; err != nil {
+		return err
+	}
+	for i := range children {
+		child := &copyNode{source: children[i]}
+		node.children = append(node.children, child)
+		if err := s.copyFiles(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeCopiedFiles 删除复制失败或回滚后残留的物理文件
+func (s *BatchService) removeCopiedFiles(node *copyNode) {
+	if node == nil {
+		return
+	}
+	if node.subPath != "" {
+		if err := s.uploadService.storageService.DeleteFile(node.subPath); err != nil {
+			logger.Log.Warnf("清理复制失败的文件失败: %s - %v", node.subPath, err)
+		} else {
+			logger.Log.Infof("已清理复制失败的文件: sourceID=%d", node.source.ID)
+		}
+	}
+	for _, child := range node.children {
+		s.removeCopiedFiles(child)
+	}
+}
+
+// copyTree 递归创建复制的文档记录，文件夹统计由 AfterCreate 钩子在复制子文件时累加
+func copyTree(tx *gorm.DB, node *copyNode, parentID *uint, name, operator, operatorIP string, created *[]*models.Document) (*models.Document, error) {
+	source := &node.source
+	document := &models.Document{
+		Name:           name,
+		Description:    source.Description,
+		Category:       source.Category,
+		Tags:           source.Tags,
+		Type:           source.Type,
+		ParentID:       parentID,
+		IsFolder:       source.IsFolder,
+		FilePath:       node.filePath,
+		FileSize:       source.FileSize,
+		FileHash:       source.FileHash,
+		PerceptualHash: source.PerceptualHash,
+		Format:         source.Format,
+		Version:        source.Version,
+		Department:     source.Department,
+		Project:        source.Project,
+		IsPublic:       source.IsPublic,
+		IsLatest:       true,
+		UploadedBy:     operator,
+		UploadIP:       operatorIP,
+	}
+	if err := tx.Create(document).Error; err != nil {
+		return nil, fmt.Errorf("创建文档记录失败: %w", err)
+	}
+	*created = append(*created, document)
+
+	for _, child := range node.children {
+		if _, err := copyTree(tx, child, &document.ID, child.source.Name, operator, operatorIP, created); err != nil {
+			return nil, err
+		}
+	}
+	return document, nil
+}
+
+// checkNotDescendant 检查目标文件夹不是 folderID 自身或其子文件夹，避免形成循环
+func checkNotDescendant(tx *gorm.DB, folderID uint, targetID *uint) error {
+	seen := make(map[uint]bool)
+	for current := targetID; current != nil; {
+		if *current == folderID {
+			return ErrMoveIntoSelf
+		}
+		if seen[*current] {
+			return ErrMoveIntoSelf
+		}
+		seen[*current] = true
+
+		var folder models.Document
+		if err := tx.Select("id", "parent_id").First(&folder, *current).Error; err != nil {
+			return err
+		}
+		current = folder.ParentID
+	}
+	return nil
+}
+
+// add 记录单个项目结果
+func (r *BatchResult) add(item *BatchItemResult) {
+	item.Success = item.Error == ""
+	if item.Success {
+		r.Succeeded++
+	} else {
+		r.Failed++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// normalizeBatchIDs 去重并检查数量
+func normalizeBatchIDs(ids []uint) ([]uint, error) {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(unique) > maxBatchItems {
+		return nil, ErrTooManyBatchItems
+	}
+	return unique, nil
+}
+
+// errorText 将单个项目的错误转换为提示文本
+func errorText(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "文档不存在"
+	}
+	return err.Error()
+}
+
+// sameParent 判断两个父文件夹ID是否相同
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// normalizeTags 去除空白和重复标签
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		for _, t := range strings.Split(tag, ",") {
+			t = strings.TrimSpace(t)
+			if t != "" && !seen[t] {
+				seen[t] = true
+				result = append(result, t)
+			}
+		}
+	}
+	return result
+}
+
+// mergeTags 在逗号分隔的标签中追加或移除标签
+func mergeTags(current string, tags []string, add bool) string {
+	existing := normalizeTags([]string{current})
+	if add {
+		return strings.Join(normalizeTags(append(existing, tags...)), ",")
+	}
+
+	remove := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		remove[tag] = true
+	}
+	kept := make([]string, 0, len(existing))
+	for _, tag := range existing {
+		if !remove[tag] {
+			kept = append(kept, tag)
+		}
+	}
+	return strings.Join(kept, ",")
+}
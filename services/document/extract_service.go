@@ -0,0 +1,325 @@
+package document
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/upload"
+	"go_wails_project_manager/utils"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotArchive           = errors.New("该文档不是压缩包")
+	ErrArchiveUnsupported   = errors.New("暂只支持浏览和解压 zip 格式的压缩包")
+	ErrArchiveCorrupt       = errors.New("压缩包已损坏或无法读取")
+	ErrArchiveFileMissing   = errors.New("压缩包文件不存在")
+	ErrArchiveEntryNotFound = errors.New("压缩包中不存在该文件")
+	ErrArchiveNoFiles       = errors.New("压缩包中没有可解压的文件")
+)
+
+// ArchiveEntryNode 压缩包目录树中的一项
+type ArchiveEntryNode struct {
+	Name           string              `json:"name"`
+	Path           string              `json:"path"` // 压缩包内路径（不以 / 开头）
+	IsDir          bool                `json:"is_dir"`
+	Size           int64               `json:"size"`
+	CompressedSize int64               `json:"compressed_size"`
+	ModifiedAt     *time.Time          `json:"modified_at,omitempty"`
+	Children       []*ArchiveEntryNode `json:"children,omitempty"`
+}
+
+// ArchiveListing 压缩包目录树
+type ArchiveListing struct {
+	DocumentID     uint                `json:"document_id"`
+	Entries        []*ArchiveEntryNode `json:"entries"`
+	FileCount      int                 `json:"file_count"`
+	FolderCount    int                 `json:"folder_count"`
+	TotalSize      int64               `json:"total_size"`
+	CompressedSize int64               `json:"compressed_size"`
+	Skipped        []string            `json:"skipped,omitempty"` // 路径非法而忽略的条目
+}
+
+// ExtractOptions 解压到文件库的参数
+type ExtractOptions struct {
+	ParentID   *uint  // 目标文件夹，nil 表示根目录
+	Name       string // 新建文件夹名称，为空时使用压缩包名称（去掉扩展名）
+	UploadedBy string
+	UploadIP   string
+}
+
+// ExtractService 压缩包浏览和解压服务（目前只支持 zip）
+type ExtractService struct {
+	db            *gorm.DB
+	config        *config.DocumentConfig
+	uploadService *UploadService
+}
+
+// NewExtractService 创建压缩包浏览和解压服务
+func NewExtractService(db *gorm.DB, cfg *config.DocumentConfig, uploadService *UploadService) *ExtractService {
+	return &ExtractService{
+		db:            db,
+		config:        cfg,
+		uploadService: uploadService,
+	}
+}
+
+// ListEntries 列出压缩包的目录树
+func (s *ExtractService) ListEntries(id uint) (*ArchiveListing, error) {
+	document, reader, err := s.open(id)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	listing := &ArchiveListing{DocumentID: document.ID, Entries: []*ArchiveEntryNode{}}
+	nodes := make(map[string]*ArchiveEntryNode)
+
+	// dir 查找或创建目录节点（压缩包中不一定包含目录条目）
+	var dir func(dirPath string) *ArchiveEntryNode
+	dir = func(dirPath string) *ArchiveEntryNode {
+		if node, ok := nodes[dirPath]; ok {
+			return node
+		}
+		node := &ArchiveEntryNode{Name: path.Base(dirPath), Path: dirPath, IsDir: true}
+		nodes[dirPath] = node
+		listing.FolderCount++
+		if parent := path.Dir(dirPath); parent != "." {
+			dir(parent).Children = append(dir(parent).Children, node)
+		} else {
+			listing.Entries = append(listing.Entries, node)
+		}
+		return node
+	}
+
+	for _, file := range reader.File {
+		entryPath, err := utils.SafeArchivePath(utils.ArchiveEntryName(file))
+		if err != nil || entryPath == "" || file.Mode()&os.ModeSymlink != 0 {
+			listing.Skipped = append(listing.Skipped, utils.ArchiveEntryName(file))
+			continue
+		}
+
+		var modified *time.Time
+		if !file.Modified.IsZero() {
+			t := file.Modified
+			modified = &t
+		}
+
+		if file.FileInfo().IsDir() {
+			dir(entryPath).ModifiedAt = modified
+			continue
+		}
+		if _, exists := nodes[entryPath]; exists {
+			continue
+		}
+
+		node := &ArchiveEntryNode{
+			Name:           path.Base(entryPath),
+			Path:           entryPath,
+			Size:           int64(file.UncompressedSize64),
+			CompressedSize: int64(file.CompressedSize64),
+			ModifiedAt:     modified,
+		}
+		nodes[entryPath] = node
+		if parent := path.Dir(entryPath); parent != "." {
+			dir(parent).Children = append(dir(parent).Children, node)
+		} else {
+			listing.Entries = append(listing.Entries, node)
+		}
+		listing.FileCount++
+		listing.TotalSize += node.Size
+		listing.CompressedSize += node.CompressedSize
+	}
+
+	sortEntryNodes(listing.Entries)
+	return listing, nil
+}
+
+// OpenEntry 打开压缩包中的单个文件，调用方负责关闭返回的 ReadCloser
+func (s *ExtractService) OpenEntry(id uint, entryPath string) (io.ReadCloser, *ArchiveEntryNode, error) {
+	entryPath, err := utils.SafeArchivePath(strings.Trim(entryPath, "/"))
+	if err != nil || entryPath == "" {
+		return nil, nil, ErrArchiveEntryNotFound
+	}
+
+	_, reader, err := s.open(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || file.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		name, err := utils.SafeArchivePath(utils.ArchiveEntryName(file))
+		if err != nil || name != entryPath {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			reader.Close()
+			return nil, nil, ErrArchiveCorrupt
+		}
+		node := &ArchiveEntryNode{
+			Name:           path.Base(name),
+			Path:           name,
+			Size:           int64(file.UncompressedSize64),
+			CompressedSize: int64(file.CompressedSize64),
+		}
+		if !file.Modified.IsZero() {
+			modified := file.Modified
+			node.ModifiedAt = &modified
+		}
+		return &entryReader{ReadCloser: rc, archive: reader}, node, nil
+	}
+
+	reader.Close()
+	return nil, nil, ErrArchiveEntryNotFound
+}
+
+// RecordEntryDownload 记录压缩包内文件的下载
+func (s *ExtractService) RecordEntryDownload(id uint, userName, userIP string) {
+	s.uploadService.logAccess(id, "download_entry", userName, userIP)
+}
+
+// Extract 将压缩包解压为文件库中的新文件夹（保持目录结构）
+// 先解压到临时目录并检查路径和大小限制，再通过文件夹上传逻辑导入，保证哈希、统计和预览图一致
+func (s *ExtractService) Extract(id uint, options ExtractOptions) (map[string]interface{}, error) {
+	document, reader, err := s.open(id)
+	if err != nil {
+		return nil, err
+	}
+	reader.Close()
+
+	if options.ParentID != nil {
+		var count int64
+		s.db.Model(&models.Document{}).Where("id = ? AND is_folder = ?", *options.ParentID, true).Count(&count)
+		if count == 0 {
+			return nil, ErrInvalidTargetFolder
+		}
+	}
+
+	name := sanitizeEntryName(options.Name)
+	if name == "" {
+		name = sanitizeEntryName(strings.TrimSuffix(document.Name, path.Ext(document.Name)))
+	}
+	if name == "" || name == "_" {
+		name = fmt.Sprintf("解压_%d", document.ID)
+	}
+
+	tempDir, err := os.MkdirTemp("", "document-extract-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	limits := utils.ArchiveLimits{
+		MaxFiles:     s.config.ExtractMaxFiles,
+		MaxTotalSize: s.config.ExtractMaxSize,
+		MaxRatio:     s.config.ExtractMaxRatio,
+	}
+	if err := utils.ExtractArchiveWithLimits(actualFilePath(s.config, document.FilePath), tempDir, limits); err != nil {
+		return nil, err
+	}
+
+	var files []*upload.File
+	var filePaths []string
+	err = filepath.Walk(tempDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		relative, err := filepath.Rel(tempDir, filePath)
+		if err != nil {
+			return err
+		}
+		file, err := upload.FromPath(filePath, info.Name())
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		filePaths = append(filePaths, name+"/"+filepath.ToSlash(relative))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrArchiveNoFiles
+	}
+
+	result, err := s.uploadService.UploadFolder(files, filePaths, FolderUploadMetadata{
+		Description: fmt.Sprintf("由压缩包 %s 解压", document.Name),
+		Department:  document.Department,
+		Project:     document.Project,
+		UploadedBy:  options.UploadedBy,
+		UploadIP:    options.UploadIP,
+		ParentID:    options.ParentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.uploadService.logAccess(document.ID, "extract", options.UploadedBy, options.UploadIP)
+	logger.Log.Infof("压缩包已解压: documentID=%d, folder=%v, files=%d", document.ID, result["root_folder_id"], len(files))
+	return result, nil
+}
+
+// open 加载压缩包文档并打开 zip 文件
+func (s *ExtractService) open(id uint) (*models.Document, *zip.ReadCloser, error) {
+	var document models.Document
+	if err := s.db.First(&document, id).Error; err != nil {
+		return nil, nil, err
+	}
+	if document.IsFolder || document.Type != models.TypeArchive && !strings.EqualFold(document.Format, "zip") {
+		return nil, nil, ErrNotArchive
+	}
+	if !strings.EqualFold(document.Format, "zip") {
+		return nil, nil, ErrArchiveUnsupported
+	}
+
+	reader, err := zip.OpenReader(actualFilePath(s.config, document.FilePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrArchiveFileMissing
+		}
+		logger.Log.Warnf("打开压缩包失败: documentID=%d, error=%v", id, err)
+		return nil, nil, ErrArchiveCorrupt
+	}
+	return &document, reader, nil
+}
+
+// entryReader 关闭条目时同时关闭压缩包
+type entryReader struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (r *entryReader) Close() error {
+	r.ReadCloser.Close()
+	return r.archive.Close()
+}
+
+// sortEntryNodes 文件夹在前，同类按名称排序
+func sortEntryNodes(nodes []*ArchiveEntryNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].IsDir != nodes[j].IsDir {
+			return nodes[i].IsDir
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	for _, node := range nodes {
+		sortEntryNodes(node.Children)
+	}
+}
@@ -1,16 +1,20 @@
 package document
 
 import (
+	"errors"
 	"fmt"
 	"go_wails_project_manager/models"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrEmptyDocumentUpdate = errors.New("未指定要修改的字段")
+	ErrEmptyDocumentName   = errors.New("文档名称不能为空")
+)
+
 // QueryService 查询服务
 type QueryService struct {
 	db *gorm.DB
@@ -33,14 +37,15 @@ type QueryFilters struct {
 	Project    string
 	IsPublic   *bool
 	Keyword    string
-	SortBy     string // name, created_at, download_count, file_size
-	SortOrder  string // asc, desc
-	ParentID   *uint  // 父文件夹ID，nil表示查询所有，0表示根目录
+	SortBy     string  // name, created_at, download_count, file_size
+	SortOrder  string  // asc, desc
+	ParentID   *uint   // 父文件夹ID，nil表示查询所有，0表示根目录
+	Viewer     *Viewer // 当前用户，限制为其可见的文档（nil 表示不限制）
 }
 
 // List 分页查询
 func (q *QueryService) List(page, pageSize int, filters QueryFilters) ([]*models.Document, int64, error) {
-	query := q.db.Model(&models.Document{}).Where("is_latest = ?", true)
+	query := q.db.Model(&models.Document{}).Where("is_latest = ?", true).Scopes(VisibleScope(filters.Viewer))
 
 	// 父文件夹过滤
 	if filters.ParentID != nil {
@@ -147,7 +152,7 @@ func (q *QueryService) loadFolderThumbnails(documents []*models.Document) {
 	var thumbnails []FolderThumbnail
 	q.db.Model(&models.Document{}).
 		Select("parent_id, thumbnail_path").
-		Where("parent_id IN ? AND is_folder = ? AND thumbnail_path IS NOT NULL AND thumbnail_path != ''", folderIDs, false).
+		Where("parent_id IN ? AND is_folder = ? AND is_latest = ? AND thumbnail_path IS NOT NULL AND thumbnail_path != ''", folderIDs, false, true).
 		Order("created_at DESC").
 		Limit(len(folderIDs) * 4). // 每个文件夹最多4个
 		Find(&thumbnails)
@@ -185,104 +190,66 @@ func (q *QueryService) GetDetail(id uint) (*models.Document, *models.DocumentMet
 	return &document, &metadata, nil
 }
 
-// GetVersions 获取版本列表
+// GetVersions 获取版本列表（按新到旧排列，documentID 可以是任意版本）
 func (q *QueryService) GetVersions(documentID uint) ([]*models.Document, error) {
-	var document models.Document
-	if err := q.db.First(&document, documentID).Error; err != nil {
+	latest, err := latestVersion(q.db, documentID)
+	if err != nil {
 		return nil, err
 	}
-
-	var versions []*models.Document
-
-	// 如果是最新版本，查找所有相关版本
-	if document.IsLatest {
-		// 查找所有父版本
-		q.db.Where("id = ? OR parent_id = ?", documentID, documentID).
-			Order("created_at DESC").
-			Find(&versions)
-	} else {
-		// 如果不是最新版本，查找最新版本和所有相关版本
-		if document.ParentID != nil {
-			q.db.Where("id = ? OR parent_id = ?", *document.ParentID, *document.ParentID).
-				Order("created_at DESC").
-				Find(&versions)
-		} else {
-			versions = append(versions, &document)
-		}
-	}
-
-	return versions, nil
+	return versionHistory(q.db, latest)
 }
 
-// Delete 删除文档（硬删除，包括物理文件）
-func (q *QueryService) Delete(id uint) error {
-	var document models.Document
-	if err := q.db.First(&document, id).Error; err != nil {
-		return err
-	}
-
-	// 如果是文件夹，递归删除所有子项
-	if document.IsFolder {
-		var children []*models.Document
-		if err := q.db.Where("parent_id = ?", id).Find(&children).Error; err != nil {
-			return fmt.Errorf("查询子项失败: %w", err)
-		}
+// UpdateInput 可修改的文档信息，nil 表示不修改
+type UpdateInput struct {
+	Name        *string
+	Description *string
+	Category    *string
+	Tags        *string
+	Department  *string
+	Project     *string
+	IsPublic    *bool
+}
 
-		// 递归删除所有子项
-		for _, child := range children {
-			if err := q.Delete(child.ID); err != nil {
-				return fmt.Errorf("删除子项失败 (ID: %d): %w", child.ID, err)
-			}
+// Update 更新文档信息
+func (q *QueryService) Update(id uint, input UpdateInput) error {
+	updates := make(map[string]interface{})
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return ErrEmptyDocumentName
 		}
+		updates["name"] = name
 	}
-
-	// 删除物理文件
-	if document.FilePath != "" && !document.IsFolder {
-		// FilePath 格式: static/documents/2026/02/09/123/file.zip
-		// 提取目录路径: static/documents/2026/02/09/123
-		// 使用正斜杠分割（数据库中存储的是正斜杠）
-		parts := strings.Split(document.FilePath, "/")
-		if len(parts) >= 2 {
-			// 重新组合为目录路径（去掉最后的文件名）
-			dirPath := filepath.Join(parts[:len(parts)-1]...)
-			
-			// 删除整个目录（包含文件和可能的其他资源）
-			if err := os.RemoveAll(dirPath); err != nil && !os.IsNotExist(err) {
-				// 文件删除失败只记录警告，不阻止数据库删除
-				fmt.Printf("警告: 删除文件目录失败 %s: %v\n", dirPath, err)
-			} else {
-				fmt.Printf("已删除文件目录: %s\n", dirPath)
-			}
-		}
+	if input.Description != nil {
+		updates["description"] = *input.Description
 	}
-
-	// 删除缩略图
-	if document.ThumbnailPath != "" {
-		if err := os.Remove(document.ThumbnailPath); err != nil && !os.IsNotExist(err) {
-			fmt.Printf("警告: 删除缩略图失败 %s: %v\n", document.ThumbnailPath, err)
-		}
+	if input.Category != nil {
+		updates["category"] = *input.Category
 	}
-
-	// 删除预览
-	if document.PreviewPath != "" {
-		if err := os.RemoveAll(document.PreviewPath); err != nil && !os.IsNotExist(err) {
-			fmt.Printf("警告: 删除预览失败 %s: %v\n", document.PreviewPath, err)
-		}
+	if input.Tags != nil {
+		updates["tags"] = *input.Tags
+	}
+	if input.Department != nil {
+		updates["department"] = *input.Department
+	}
+	if input.Project != nil {
+		updates["project"] = *input.Project
+	}
+	if input.IsPublic != nil {
+		updates["is_public"] = *input.IsPublic
+	}
+	if len(updates) == 0 {
+		return ErrEmptyDocumentUpdate
 	}
 
-	// 删除元数据
-	q.db.Unscoped().Where("document_id = ?", id).Delete(&models.DocumentMetadata{})
-
-	// 删除访问日志
-	q.db.Unscoped().Where("document_id = ?", id).Delete(&models.DocumentAccessLog{})
-
-	// 硬删除文档记录（使用 Unscoped 跳过软删除）
-	return q.db.Unscoped().Delete(&document).Error
-}
-
-// Update 更新文档信息
-func (q *QueryService) Update(id uint, updates map[string]interface{}) error {
-	return q.db.Model(&models.Document{}).Where("id = ?", id).Updates(updates).Error
+	result := q.db.Model(&models.Document{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 // IncrementDownloadCount 增加下载次数
@@ -308,8 +275,9 @@ func (q *QueryService) IncrementViewCount(id uint) error {
 // GetStatistics 获取统计信息
 func (q *QueryService) GetStatistics(filters QueryFilters) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
+	visible := VisibleScope(filters.Viewer)
 
-	query := q.db.Model(&models.Document{}).Where("is_latest = ?", true)
+	query := q.db.Model(&models.Document{}).Where("is_latest = ?", true).Scopes(visible)
 
 	// 应用过滤条件
 	if filters.Type != "" {
@@ -338,6 +306,7 @@ func (q *QueryService) GetStatistics(filters QueryFilters) (map[string]interface
 	}
 	q.db.Model(&models.Document{}).
 		Where("is_latest = ?", true).
+		Scopes(visible).
 		Select("type, COUNT(*) as count").
 		Group("type").
 		Scan(&typeStats)
@@ -355,6 +324,7 @@ func (q *QueryService) GetStatistics(filters QueryFilters) (map[string]interface
 	}
 	q.db.Model(&models.Document{}).
 		Where("is_latest = ?", true).
+		Scopes(visible).
 		Select("format, COUNT(*) as count").
 		Group("format").
 		Scan(&formatStats)
@@ -370,6 +340,7 @@ func (q *QueryService) GetStatistics(filters QueryFilters) (map[string]interface
 	var recentUploads int64
 	q.db.Model(&models.Document{}).
 		Where("created_at >= ?", sevenDaysAgo).
+		Scopes(visible).
 		Count(&recentUploads)
 	stats["recent_uploads"] = recentUploads
 
@@ -380,6 +351,7 @@ func (q *QueryService) GetStatistics(filters QueryFilters) (map[string]interface
 func (q *QueryService) GetPopular(limit int, filters QueryFilters) ([]*models.Document, error) {
 	query := q.db.Model(&models.Document{}).
 		Where("is_latest = ?", true).
+		Scopes(VisibleScope(filters.Viewer)).
 		Order("download_count DESC")
 
 	if filters.Type != "" {
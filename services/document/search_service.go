@@ -0,0 +1,477 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/fileprocessor"
+	docutils "go_wails_project_manager/utils/document"
+	"html"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	maxIndexedChars   = 2 * 1024 * 1024 // 单个文档最多索引的字符数
+	maxPlainTextBytes = 8 * 1024 * 1024 // TXT/MD 最多读取的字节数
+	maxMatchesPerDoc  = 3               // 每个文档返回的命中片段数
+	backfillBatchSize = 50
+	snippetTokens     = 32 // trigram 分词下约等于片段字符数
+	minTrigramRunes   = 3  // trigram 分词能匹配的最短关键词
+
+	// FTS5 片段的高亮占位符（Unicode 私有区字符），转义 HTML 后再替换为 <mark>
+	markOpen  = "\uE000"
+	markClose = "\uE001"
+)
+
+var (
+	ErrEmptySearchQuery = errors.New("搜索关键词不能为空")
+	ErrBackfillRunning  = errors.New("全文索引补建任务正在运行")
+)
+
+// textFormats 支持全文索引的格式
+var textFormats = map[string]bool{
+	"pdf": true, "docx": true, "xlsx": true, "pptx": true, "txt": true, "md": true,
+	"doc": true, "xls": true, "ppt": true, // 旧版 Office 格式需要 LibreOffice 转换为 PDF
+}
+
+// SearchMatch 命中片段
+type SearchMatch struct {
+	Page    int    `json:"page"`    // 页码（PDF/Word 为页，PPT 为幻灯片，Excel 为工作表，从 1 开始）
+	Snippet string `json:"snippet"` // 命中片段，关键词以 <mark></mark> 标记
+}
+
+// SearchHit 搜索结果
+type SearchHit struct {
+	Document *models.Document `json:"document"`
+	Matches  []SearchMatch    `json:"matches"`
+}
+
+// SearchService 文档全文检索服务
+type SearchService struct {
+	db          *gorm.DB
+	config      *config.DocumentConfig
+	logger      *logrus.Logger
+	pdfTool     *docutils.PDFTool
+	libreOffice *docutils.LibreOffice
+	backfilling atomic.Bool
+}
+
+// NewSearchService 创建全文检索服务，fpConfig 为空时使用默认工具路径且不启用 LibreOffice
+func NewSearchService(db *gorm.DB, cfg *config.DocumentConfig, logger *logrus.Logger) *SearchService {
+	service := &SearchService{
+		db:     db,
+		config: cfg,
+		logger: logger,
+	}
+	service.SetFileProcessorConfig(nil)
+	return service
+}
+
+// SetFileProcessorConfig 设置文本提取使用的 PDF 工具和 LibreOffice
+func (s *SearchService) SetFileProcessorConfig(fpConfig *fileprocessor.Config) {
+	if fpConfig == nil {
+		s.pdfTool = docutils.NewPDFTool("", 0)
+		s.libreOffice = nil
+		return
+	}
+
+	s.pdfTool = docutils.NewPDFTool(fpConfig.PDF.BinPath, fpConfig.PDF.Timeout)
+	s.libreOffice = nil
+	if fpConfig.LibreOffice.BinPath != "" {
+		timeout := time.Duration(fpConfig.LibreOffice.Timeout) * time.Second
+		s.libreOffice = docutils.NewLibreOffice(fpConfig.LibreOffice.BinPath, timeout)
+	}
+}
+
+// IndexDocument 提取文档文本并写入全文索引（已有索引会被替换）
+func (s *SearchService) IndexDocument(document *models.Document) error {
+	format := strings.ToLower(document.Format)
+	if document.IsFolder || !textFormats[format] {
+		return s.saveStatus(document.ID, models.TextIndexUnsupported, 0, 0, "")
+	}
+
+	pages, err := s.extractText(actualFilePath(s.config, document.FilePath), format)
+	if err != nil {
+		s.logger.Warnf("[全文索引] 提取文本失败: documentID=%d, format=%s, error=%v", document.ID, format, err)
+		s.removeIndex(document.ID)
+		return s.saveStatus(document.ID, models.TextIndexFailed, 0, 0, err.Error())
+	}
+
+	charCount := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM document_fts WHERE document_id = ?", document.ID).Error; err != nil {
+			return err
+		}
+		for i, page := range pages {
+			page = strings.TrimSpace(page)
+			if page == "" {
+				continue
+			}
+			if remaining := maxIndexedChars - charCount; utf8.RuneCountInString(page) > remaining {
+				page = truncateRunes(page, remaining)
+			}
+			if page == "" {
+				break
+			}
+			charCount += utf8.RuneCountInString(page)
+			if err := tx.Exec("INSERT INTO document_fts (content, document_id, page) VALUES (?, ?, ?)",
+				page, document.ID, i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	status := models.TextIndexIndexed
+	if charCount == 0 {
+		status = models.TextIndexEmpty
+	}
+	s.logger.Infof("[全文索引] 已索引文档 %d: %d 页, %d 字", document.ID, len(pages), charCount)
+	return s.saveStatus(document.ID, status, len(pages), charCount, "")
+}
+
+// RemoveDocuments 删除文档的全文索引（文档被彻底删除或转为历史版本时调用）
+func (s *SearchService) RemoveDocuments(ids ...uint) {
+	removeTextIndex(s.db, ids...)
+}
+
+// removeIndex 删除 FTS 中的文档内容
+func (s *SearchService) removeIndex(id uint) {
+	if err := s.db.Exec("DELETE FROM document_fts WHERE document_id = ?", id).Error; err != nil {
+		s.logger.Warnf("[全文索引] 删除索引失败: documentID=%d, error=%v", id, err)
+	}
+}
+
+// removeTextIndex 删除文档的全文索引内容和索引状态（索引表不存在时忽略）
+func removeTextIndex(db *gorm.DB, ids ...uint) {
+	if len(ids) == 0 {
+		return
+	}
+	db.Exec("DELETE FROM document_fts WHERE document_id IN ?", ids)
+	db.Where("document_id IN ?", ids).Delete(&models.DocumentTextIndex{})
+}
+
+// saveStatus 记录索引状态
+func (s *SearchService) saveStatus(documentID uint, status string, pageCount, charCount int, errMsg string) error {
+	record := models.DocumentTextIndex{DocumentID: documentID}
+	return s.db.Where("document_id = ?", documentID).
+		Assign(models.DocumentTextIndex{
+			Status:    status,
+			PageCount: pageCount,
+			CharCount: charCount,
+			ErrorMsg:  errMsg,
+			IndexedAt: time.Now(),
+		}).
+		FirstOrCreate(&record).Error
+}
+
+// extractText 按格式提取文本，返回按页分组的内容
+func (s *SearchService) extractText(filePath, format string) ([]string, error) {
+	switch format {
+	case "pdf":
+		return s.pdfTool.ExtractText(filePath)
+	case "docx", "xlsx", "pptx":
+		return docutils.ExtractOfficeText(filePath, format, maxIndexedChars)
+	case "txt", "md":
+		return readPlainText(filePath)
+	case "doc", "xls", "ppt":
+		if s.libreOffice == nil {
+			return nil, fmt.Errorf("未配置 LibreOffice，无法提取 %s 文本", format)
+		}
+		tempDir, err := os.MkdirTemp("", "document-text-*")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tempDir)
+
+		pdfPath, err := s.libreOffice.ConvertToPDF(nil, filePath, tempDir)
+		if err != nil {
+			return nil, err
+		}
+		return s.pdfTool.ExtractText(pdfPath)
+	}
+	return nil, fmt.Errorf("不支持的格式: %s", format)
+}
+
+// readPlainText 读取纯文本文件（整体作为第 1 页）
+func readPlainText(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxPlainTextBytes))
+	if err != nil {
+		return nil, err
+	}
+	return []string{strings.ToValidUTF8(string(data), "")}, nil
+}
+
+// Search 全文检索最新版本的文档内容，按相关度排序
+// 关键词以空格分隔（同时满足），不足 3 个字符的关键词使用 LIKE 匹配；只返回 viewer 可见的文档
+func (s *SearchService) Search(query string, page, pageSize int, viewer *Viewer) ([]*SearchHit, int64, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, 0, ErrEmptySearchQuery
+	}
+
+	var phrases []string
+	var shortTerms []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) >= minTrigramRunes {
+			phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		} else {
+			shortTerms = append(shortTerms, term)
+		}
+	}
+
+	// 命中条件（FTS5 辅助函数要求直接引用虚拟表名，不能使用别名）
+	where := "d.deleted_at IS NULL AND d.is_latest = ?"
+	args := []interface{}{true}
+	if len(phrases) > 0 {
+		where += " AND document_fts MATCH ?"
+		args = append(args, strings.Join(phrases, " "))
+	}
+	for _, term := range shortTerms {
+		where += ` AND document_fts.content LIKE ? ESCAPE '\'`
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	if condition, visibleArgs := visibleCondition("d.id", viewer); condition != "" {
+		where += " AND " + condition
+		args = append(args, visibleArgs...)
+	}
+	from := "FROM document_fts JOIN document d ON d.id = document_fts.document_id WHERE " + where
+
+	var total int64
+	if err := s.db.Raw("SELECT COUNT(DISTINCT d.id) "+from, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*SearchHit{}, 0, nil
+	}
+
+	// 按文档分页，每个文档取最相关的页
+	score := "0"
+	if len(phrases) > 0 {
+		score = "document_fts.rank"
+	}
+	type rankedDoc struct {
+		DocumentID uint
+		Score      float64
+	}
+	var ranked []rankedDoc
+	offset := (page - 1) * pageSize
+	pageArgs := append(append([]interface{}{}, args...), pageSize, offset)
+	if err := s.db.Raw(
+		"SELECT document_id, MIN(score) AS score FROM (SELECT d.id AS document_id, "+score+" AS score "+from+
+			") GROUP BY document_id ORDER BY score, document_id DESC LIMIT ? OFFSET ?", pageArgs...).
+		Scan(&ranked).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(ranked) == 0 {
+		return []*SearchHit{}, total, nil
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.DocumentID
+	}
+
+	// 命中片段
+	type matchRow struct {
+		DocumentID uint
+		Page       int
+		Snippet    string
+		Content    string
+	}
+	var rows []matchRow
+	matchArgs := append(append([]interface{}{}, args...), ids)
+	if len(phrases) > 0 {
+		err := s.db.Raw(fmt.Sprintf(
+			"SELECT document_fts.document_id, document_fts.page, snippet(document_fts, 0, '%s', '%s', '…', %d) AS snippet %s AND d.id IN ? ORDER BY document_fts.rank",
+			markOpen, markClose, snippetTokens, from), matchArgs...).Scan(&rows).Error
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		if err := s.db.Raw("SELECT document_fts.document_id, document_fts.page, document_fts.content "+from+" AND d.id IN ? ORDER BY document_fts.page", matchArgs...).
+			Scan(&rows).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	matches := make(map[uint][]SearchMatch)
+	for _, row := range rows {
+		if len(matches[row.DocumentID]) >= maxMatchesPerDoc {
+			continue
+		}
+		snippet := markSnippet(row.Snippet)
+		if row.Snippet == "" {
+			// 只有短关键词时没有 FTS 片段；FTS 片段为空时没有可高亮的短关键词，截取开头
+			term := ""
+			if len(shortTerms) > 0 {
+				term = shortTerms[0]
+			}
+			snippet = highlightSnippet(row.Content, term)
+		}
+		matches[row.DocumentID] = append(matches[row.DocumentID], SearchMatch{Page: row.Page, Snippet: snippet})
+	}
+
+	var documents []*models.Document
+	if err := s.db.Where("id IN ?", ids).Find(&documents).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uint]*models.Document, len(documents))
+	for _, doc := range documents {
+		byID[doc.ID] = doc
+	}
+
+	hits := make([]*SearchHit, 0, len(ranked))
+	for _, r := range ranked {
+		if doc, ok := byID[r.DocumentID]; ok {
+			hits = append(hits, &SearchHit{Document: doc, Matches: matches[r.DocumentID]})
+		}
+	}
+	return hits, total, nil
+}
+
+// Backfill 为尚未建立全文索引的最新版本文档补建索引，返回处理的文档数
+func (s *SearchService) Backfill() (int, error) {
+	if !s.backfilling.CompareAndSwap(false, true) {
+		return 0, ErrBackfillRunning
+	}
+	defer s.backfilling.Store(false)
+
+	formats := make([]string, 0, len(textFormats))
+	for format := range textFormats {
+		formats = append(formats, format)
+	}
+
+	processed := 0
+	lastID := uint(0)
+	for {
+		var documents []*models.Document
+		err := s.db.Where("id > ? AND is_folder = ? AND is_latest = ? AND LOWER(format) IN ?", lastID, false, true, formats).
+			Where("id NOT IN (?)", s.db.Model(&models.DocumentTextIndex{}).Select("document_id")).
+			Order("id").Limit(backfillBatchSize).Find(&documents).Error
+		if err != nil {
+			return processed, err
+		}
+		if len(documents) == 0 {
+			break
+		}
+
+		for _, document := range documents {
+			if err := s.IndexDocument(document); err != nil {
+				s.logger.Warnf("[全文索引] 补建索引失败: documentID=%d, error=%v", document.ID, err)
+			}
+			processed++
+			lastID = document.ID
+		}
+	}
+
+	if processed > 0 {
+		s.logger.Infof("[全文索引] 补建完成，共处理 %d 个文档", processed)
+	}
+	return processed, nil
+}
+
+// IsBackfilling 补建任务是否正在运行
+func (s *SearchService) IsBackfilling() bool {
+	return s.backfilling.Load()
+}
+
+// IndexStats 各索引状态的文档数
+func (s *SearchService) IndexStats() (map[string]int64, error) {
+	type statusCount struct {
+		Status string
+		Count  int64
+	}
+	var counts []statusCount
+	if err := s.db.Model(&models.DocumentTextIndex{}).Select("status, COUNT(*) AS count").
+		Group("status").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		stats[c.Status] = c.Count
+	}
+	return stats, nil
+}
+
+// markSnippet 转义 FTS5 片段中的 HTML，再把高亮占位符替换为 <mark>
+func markSnippet(snippet string) string {
+	return strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// highlightSnippet 为 LIKE 匹配的结果截取关键词附近的片段并标记（片段内容已转义 HTML）
+// 在原文字符上逐个按大小写折叠比较，避免 ToLower 改变字符数（如 İ）导致下标错位
+func highlightSnippet(content, term string) string {
+	runes := []rune(content)
+	termRunes := []rune(term)
+
+	index := -1
+	for i := 0; len(termRunes) > 0 && i+len(termRunes) <= len(runes); i++ {
+		if strings.EqualFold(string(runes[i:i+len(termRunes)]), term) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return html.EscapeString(truncateRunes(content, snippetTokens))
+	}
+
+	start := index - snippetTokens/2
+	if start < 0 {
+		start = 0
+	}
+	end := index + len(termRunes) + snippetTokens/2
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	sb.WriteString(html.EscapeString(string(runes[start:index])))
+	sb.WriteString("<mark>")
+	sb.WriteString(html.EscapeString(string(runes[index : index+len(termRunes)])))
+	sb.WriteString("</mark>")
+	sb.WriteString(html.EscapeString(string(runes[index+len(termRunes) : end])))
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+// truncateRunes 按字符数截断
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,489 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// purgeInterval 回收站过期清理间隔
+const purgeInterval = 6 * time.Hour
+
+var (
+	ErrTrashItemNotFound    = errors.New("回收站中不存在该项目")
+	ErrNotTrashRoot         = errors.New("该项目随上级文件夹一起删除，请还原上级文件夹")
+	ErrInvalidRestoreTarget = errors.New("还原目标文件夹不存在")
+)
+
+// TrashItem 回收站项目（被直接删除的顶层文件或文件夹）
+type TrashItem struct {
+	*models.Document
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // 自动彻底删除时间（未配置保留天数时为空）
+	ParentExists bool       `json:"parent_exists"`        // 原父文件夹是否仍存在（不存在时还原到根目录或指定目录）
+}
+
+// RestoreResult 还原结果
+type RestoreResult struct {
+	Document      *models.Document `json:"document"`
+	ParentID      *uint            `json:"parent_id"`      // 实际还原到的父文件夹，nil 表示根目录
+	ParentMissing bool             `json:"parent_missing"` // 原父文件夹已删除，已还原到根目录或指定目录
+	Renamed       bool             `json:"renamed"`        // 与同目录项目重名，已自动重命名
+}
+
+// TrashService 回收站服务
+// 删除时只将顶层项目和其所有子项标记为软删除（子项记录 trash_root_id），
+// 保留目录结构和文件夹统计，彻底删除时才清理物理文件
+type TrashService struct {
+	db            *gorm.DB
+	logger        *logrus.Logger
+	config        *config.DocumentConfig
+	retentionDays int
+	ticker        *time.Ticker
+	stopChan      chan bool
+}
+
+// NewTrashService 创建回收站服务，cfg.TrashRetentionDays <= 0 表示不自动清理
+func NewTrashService(db *gorm.DB, logger *logrus.Logger, cfg *config.DocumentConfig) *TrashService {
+	return &TrashService{
+		db:            db,
+		logger:        logger,
+		config:        cfg,
+		retentionDays: cfg.TrashRetentionDays,
+	}
+}
+
+// MoveToTrash 将文档或文件夹（连同所有子项）移入回收站
+func (s *TrashService) MoveToTrash(id uint, deletedBy string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.MoveToTrashTx(tx, id, deletedBy)
+	})
+}
+
+// MoveToTrashTx 在调用方的事务中将文档或文件夹（连同所有子项）移入回收站
+func (s *TrashService) MoveToTrashTx(tx *gorm.DB, id uint, deletedBy string) error {
+	var document models.Document
+	if err := tx.First(&document, id).Error; err != nil {
+		return err
+	}
+
+	var descendantIDs []uint
+	if document.IsFolder {
+		if err := collectDescendants(tx, id, &descendantIDs); err != nil {
+			return fmt.Errorf("查询子项失败: %w", err)
+		}
+	} else if document.IsLatest {
+		// 删除最新版本时历史版本一起移入回收站
+		versions, err := versionHistory(tx, &document)
+		if err != nil {
+			return fmt.Errorf("查询历史版本失败: %w", err)
+		}
+		for _, version := range versions[1:] {
+			descendantIDs = append(descendantIDs, version.ID)
+		}
+	}
+
+	// 子项仅打标记，不触发钩子，保留文件夹结构和统计
+	now := time.Now()
+	if len(descendantIDs) > 0 {
+		if err := tx.Model(&models.Document{}).Where("id IN ?", descendantIDs).
+			UpdateColumns(map[string]interface{}{
+				"deleted_at":    now,
+				"trash_root_id": id,
+				"deleted_by":    deletedBy,
+			}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&document).UpdateColumns(map[string]interface{}{
+		"trash_root_id": id,
+		"deleted_by":    deletedBy,
+	}).Error; err != nil {
+		return err
+	}
+
+	// 顶层项目软删除，AfterDelete 钩子从父文件夹统计中扣除
+	return tx.Delete(&document).Error
+}
+
+// collectDescendants 递归收集文件夹下所有未删除的子项ID
+func collectDescendants(db *gorm.DB, folderID uint, ids *[]uint) error {
+	var children []models.Document
+	if err := db.Select("id", "is_folder").Where("parent_id = ?", folderID).Find(&children).Error; err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		*ids = append(*ids, child.ID)
+		if child.IsFolder {
+			if err := collectDescendants(db, child.ID, ids); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// List 分页查询回收站中 viewer 可见的顶层项目（按删除时间倒序）
+func (s *TrashService) List(page, pageSize int, keyword string, viewer *Viewer) ([]*TrashItem, int64, error) {
+	query := s.trashRoots().Scopes(VisibleScope(viewer))
+	if keyword != "" {
+		query = query.Where("name LIKE ?", "%"+keyword+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var documents []*models.Document
+	offset := (page - 1) * pageSize
+	if err := query.Order("deleted_at DESC").Offset(offset).Limit(pageSize).Find(&documents).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 查询仍存在的原父文件夹
+	var parentIDs []uint
+	for _, doc := range documents {
+		if doc.ParentID != nil {
+			parentIDs = append(parentIDs, *doc.ParentID)
+		}
+	}
+	liveParents := make(map[uint]bool)
+	if len(parentIDs) > 0 {
+		var ids []uint
+		s.db.Model(&models.Document{}).Where("id IN ? AND is_folder = ?", parentIDs, true).Pluck("id", &ids)
+		for _, id := range ids {
+			liveParents[id] = true
+		}
+	}
+
+	items := make([]*TrashItem, len(documents))
+	for i, doc := range documents {
+		items[i] = &TrashItem{
+			Document:     doc,
+			ExpiresAt:    s.expiresAt(doc),
+			ParentExists: doc.ParentID == nil || liveParents[*doc.ParentID],
+		}
+	}
+	return items, total, nil
+}
+
+// Restore 还原回收站项目（连同删除时一起移入回收站的子项）
+// targetParentID 非空时还原到指定文件夹（0 表示根目录）；
+// 否则还原到原父文件夹，原父文件夹已删除时还原到根目录
+func (s *TrashService) Restore(id uint, targetParentID *uint) (*RestoreResult, error) {
+	document, err := s.getTrashRoot(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{}
+	var parentID *uint
+	if targetParentID != nil {
+		if *targetParentID != 0 {
+			if !s.folderExists(*targetParentID) {
+				return nil, ErrInvalidRestoreTarget
+			}
+			parentID = targetParentID
+		}
+		result.ParentMissing = document.ParentID != nil && !s.folderExists(*document.ParentID)
+	} else if document.ParentID != nil {
+		if s.folderExists(*document.ParentID) {
+			parentID = document.ParentID
+		} else {
+			result.ParentMissing = true
+		}
+	}
+
+	// 单独删除的历史版本与最新版本同名，不需要重命名
+	name := document.Name
+	if document.IsFolder || document.IsLatest {
+		name = uniqueName(s.db, parentID, document.Name, "已恢复")
+	}
+	result.Renamed = name != document.Name
+
+	// 文件的子项是其历史版本，随最新版本一起移动到还原目录
+	descendantUpdates := map[string]interface{}{
+		"deleted_at":    nil,
+		"trash_root_id": nil,
+		"deleted_by":    "",
+	}
+	if !document.IsFolder {
+		descendantUpdates["parent_id"] = parentID
+		descendantUpdates["name"] = name
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Document{}).
+			Where("trash_root_id = ? AND id <> ?", id, id).
+			UpdateColumns(descendantUpdates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&models.Document{}).Where("id = ?", id).
+			UpdateColumns(map[string]interface{}{
+				"deleted_at":    nil,
+				"trash_root_id": nil,
+				"deleted_by":    "",
+				"parent_id":     parentID,
+				"name":          name,
+			}).Error; err != nil {
+			return err
+		}
+
+		document.ParentID = parentID
+		return models.AttachToParent(tx, document)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var restored models.Document
+	if err := s.db.First(&restored, id).Error; err != nil {
+		return nil, err
+	}
+	result.Document = &restored
+	result.ParentID = parentID
+
+	s.logger.Infof("[回收站] 已还原 %d: %s", id, restored.Name)
+	return result, nil
+}
+
+// Purge 彻底删除回收站项目（连同子项、物理文件、元数据和访问日志）
+// 物理文件在事务提交后才删除，事务失败时项目仍可从回收站还原；PurgeExpired 和 Empty 同样经过这里
+func (s *TrashService) Purge(id uint) error {
+	if _, err := s.getTrashRoot(id); err != nil {
+		return err
+	}
+
+	var documents []models.Document
+	if err := s.db.Unscoped().Where("trash_root_id = ?", id).Find(&documents).Error; err != nil {
+		return err
+	}
+
+	ids := make([]uint, len(documents))
+	for i := range documents {
+		ids[i] = documents[i].ID
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 保持版本链连续（单独删除的历史版本）
+		for i := range documents {
+			if err := unlinkVersion(tx, &documents[i]); err != nil {
+				return err
+			}
+		}
+
+		// 删除元数据
+		if err := tx.Unscoped().Where("document_id IN ?", ids).Delete(&models.DocumentMetadata{}).Error; err != nil {
+			return err
+		}
+
+		// 删除访问日志
+		if err := tx.Unscoped().Where("document_id IN ?", ids).Delete(&models.DocumentAccessLog{}).Error; err != nil {
+			return err
+		}
+
+		// 删除单独授权
+		if err := models.DeleteResourcePermissions(tx, models.ResourceDocuments, ids...); err != nil {
+			return err
+		}
+
+		// 硬删除文档记录（统计已在移入回收站时扣除，跳过钩子）
+		return tx.Unscoped().Session(&gorm.Session{SkipHooks: true}).
+			Where("id IN ?", ids).Delete(&models.Document{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	for i := range documents {
+		removeDocumentFiles(s.config, &documents[i])
+	}
+	removeTextIndex(s.db, ids...)
+
+	s.logger.Infof("[回收站] 已彻底删除 %d（共 %d 项）", id, len(ids))
+	return nil
+}
+
+// Empty 清空回收站，返回彻底删除的顶层项目数
+func (s *TrashService) Empty() (int, error) {
+	var ids []uint
+	if err := s.trashRoots().Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return s.purgeAll(ids)
+}
+
+// PurgeExpired 彻底删除超过保留期的回收站项目
+func (s *TrashService) PurgeExpired() (int, error) {
+	if s.retentionDays <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -s.retentionDays)
+	var ids []uint
+	if err := s.trashRoots().Where("deleted_at < ?", cutoff).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	count, err := s.purgeAll(ids)
+	if count > 0 {
+		s.logger.Infof("[回收站] 已自动清理 %d 个超过 %d 天的项目", count, s.retentionDays)
+	}
+	return count, err
+}
+
+// purgeAll 逐个彻底删除，单个失败不影响其余项目
+func (s *TrashService) purgeAll(ids []uint) (int, error) {
+	count := 0
+	var lastErr error
+	for _, id := range ids {
+		if err := s.Purge(id); err != nil {
+			s.logger.Warnf("[回收站] 彻底删除 %d 失败: %v", id, err)
+			lastErr = err
+			continue
+		}
+		count++
+	}
+	return count, lastErr
+}
+
+// StartPurgeJob 启动回收站过期清理任务
+func (s *TrashService) StartPurgeJob() {
+	if s.ticker != nil || s.retentionDays <= 0 {
+		return
+	}
+
+	s.ticker = time.NewTicker(purgeInterval)
+	s.stopChan = make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				if _, err := s.PurgeExpired(); err != nil {
+					s.logger.Errorf("[回收站] 自动清理失败: %v", err)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopPurgeJob 停止回收站过期清理任务
+func (s *TrashService) StopPurgeJob() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+		close(s.stopChan)
+		s.ticker = nil
+	}
+}
+
+// trashRoots 回收站顶层项目查询
+func (s *TrashService) trashRoots() *gorm.DB {
+	return s.db.Unscoped().Model(&models.Document{}).
+		Where("deleted_at IS NOT NULL AND trash_root_id = id")
+}
+
+// getTrashRoot 获取回收站顶层项目
+func (s *TrashService) getTrashRoot(id uint) (*models.Document, error) {
+	var document models.Document
+	if err := s.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&document).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, err
+	}
+	if document.TrashRootID == nil || *document.TrashRootID != id {
+		return nil, ErrNotTrashRoot
+	}
+	return &document, nil
+}
+
+// folderExists 检查文件夹是否存在（未删除）
+func (s *TrashService) folderExists(id uint) bool {
+	var count int64
+	s.db.Model(&models.Document{}).Where("id = ? AND is_folder = ?", id, true).Count(&count)
+	return count > 0
+}
+
+// uniqueName 与目标目录下的项目重名时追加后缀，如“名称 (已恢复).pdf”、“名称 (副本 2).pdf”
+func uniqueName(db *gorm.DB, parentID *uint, name, suffix string) string {
+	query := db.Model(&models.Document{}).Where("is_latest = ?", true)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var names []string
+	query.Pluck("name", &names)
+	taken := make(map[string]bool, len(names))
+	for _, n := range names {
+		taken[n] = true
+	}
+	if !taken[name] {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := fmt.Sprintf("%s (%s)%s", base, suffix, ext)
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%s %d)%s", base, suffix, i, ext)
+	}
+	return candidate
+}
+
+// expiresAt 计算自动彻底删除时间
+func (s *TrashService) expiresAt(document *models.Document) *time.Time {
+	if s.retentionDays <= 0 || !document.DeletedAt.Valid {
+		return nil
+	}
+	expires := document.DeletedAt.Time.AddDate(0, 0, s.retentionDays)
+	return &expires
+}
+
+// removeDocumentFiles 删除文档的物理文件、缩略图和预览（启用 NAS 时删除 NAS 上的文件）
+func removeDocumentFiles(cfg *config.DocumentConfig, document *models.Document) {
+	if document.FilePath != "" && !document.IsFolder {
+		// FilePath 格式: static/documents/2026/02/09/123/file.zip
+		// 删除文件所在目录: static/documents/2026/02/09/123（包含文件和可能的其他资源）
+		// 使用正斜杠判断（数据库中存储的是正斜杠），至少要有一级目录，避免删除存储根目录
+		if strings.Contains(document.FilePath, "/") {
+			dirPath := filepath.Dir(actualFilePath(cfg, document.FilePath))
+			if err := os.RemoveAll(dirPath); err != nil && !os.IsNotExist(err) {
+				// 文件删除失败只记录警告，不阻止数据库删除
+				logger.Log.Warnf("删除文件目录失败 %s: %v", dirPath, err)
+			} else {
+				logger.Log.Infof("已删除文件目录: %s", dirPath)
+			}
+		}
+	}
+
+	// 删除缩略图
+	if document.ThumbnailPath != "" {
+		thumbnailPath := actualFilePath(cfg, document.ThumbnailPath)
+		if err := os.Remove(thumbnailPath); err != nil && !os.IsNotExist(err) {
+			logger.Log.Warnf("删除缩略图失败 %s: %v", thumbnailPath, err)
+		}
+	}
+
+	// 删除预览
+	if document.PreviewPath != "" {
+		previewPath := actualFilePath(cfg, document.PreviewPath)
+		if err := os.RemoveAll(previewPath); err != nil && !os.IsNotExist(err) {
+			logger.Log.Warnf("删除预览失败 %s: %v", previewPath, err)
+		}
+	}
+}
@@ -10,8 +10,9 @@ import (
 	"go_wails_project_manager/services/fileprocessor"
 	"go_wails_project_manager/services/fileprocessor/processors"
 	"go_wails_project_manager/services/storage"
+	"go_wails_project_manager/services/upload"
+	"go_wails_project_manager/utils"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -28,6 +29,7 @@ type UploadService struct {
 	storageService       *storage.FileStorageService
 	fileProcessorService fileprocessor.IFileProcessorService // 文件处理器服务接口
 	fpConfig             *fileprocessor.Config                // 文件处理器配置
+	searchService        *SearchService                       // 全文检索服务
 }
 
 // NewUploadService 创建上传服务
@@ -44,6 +46,7 @@ func NewUploadService(db *gorm.DB, cfg *config.DocumentConfig) *UploadService {
 		db:             db,
 		config:         cfg,
 		storageService: storage.NewFileStorageService(storageConfig, logger.Log),
+		searchService:  NewSearchService(db, cfg, logger.Log),
 	}
 }
 
@@ -55,6 +58,7 @@ func (s *UploadService) SetFileProcessorService(fpService fileprocessor.IFilePro
 // SetFileProcessorConfig 设置文件处理器配置
 func (s *UploadService) SetFileProcessorConfig(fpConfig *fileprocessor.Config) {
 	s.fpConfig = fpConfig
+	s.searchService.SetFileProcessorConfig(fpConfig)
 }
 // RegenerateThumbnail 重新生成文档缩略图（公开方法）
 func (s *UploadService) RegenerateThumbnail(documentID uint) error {
@@ -113,28 +117,11 @@ type FolderUploadMetadata struct {
 }
 
 // Upload 上传文档
-func (s *UploadService) Upload(file *multipart.FileHeader, metadata UploadMetadata) (*models.Document, error) {
-	// 1. 检测文件格式
-	format := s.detectFileFormat(file)
-	if format == "" {
-		return nil, fmt.Errorf("无法识别的文件格式")
-	}
-
-	// 2. 根据格式判断文档类型
-	docType := models.GetDocumentType(format)
-
-	// 3. 验证文件格式（如果启用了格式限制）
-	if !s.config.AllowAllFormats {
-		if !s.isFormatAllowed(docType, format) {
-			return nil, fmt.Errorf("不支持的文件格式: %s", format)
-		}
-	}
-
-	// 4. 验证文件大小
-	if file.Size > s.config.MaxFileSize {
-		return nil, fmt.Errorf("文件大小超过限制: %.2f GB (最大 %.2f GB)", 
-			float64(file.Size)/1024/1024/1024,
-			float64(s.config.MaxFileSize)/1024/1024/1024)
+func (s *UploadService) Upload(file *upload.File, metadata UploadMetadata) (*models.Document, error) {
+	// 1-4. 检测并验证文件格式和大小
+	format, docType, err := s.validateFile(file)
+	if err != nil {
+		return nil, err
 	}
 
 	// 5. 计算文件哈希
@@ -196,6 +183,11 @@ func (s *UploadService) Upload(file *multipart.FileHeader, metadata UploadMetada
 	}
 	document.FilePath = filePath
 
+	// 图片文件直接计算感知哈希，其他格式在生成预览图后计算
+	if isImageFormat(format) {
+		document.PerceptualHash = s.perceptualHash(filePath)
+	}
+
 	// 11. 更新文档路径
 	if err := s.db.Save(document).Error; err != nil {
 		s.storageService.DeleteFile(fmt.Sprintf("%d", document.ID))
@@ -208,15 +200,53 @@ func (s *UploadService) Upload(file *multipart.FileHeader, metadata UploadMetada
 		go s.generatePreview(document)
 	}
 
-	// 13. 更新统计信息
+	// 13. 建立全文索引
+	go s.indexText(document)
+
+	// 14. 更新统计信息
 	s.updateMetrics(document)
 
-	// 14. 记录访问日志
+	// 15. 记录访问日志
 	s.logAccess(document.ID, "upload", metadata.UploadedBy, metadata.UploadIP)
 
 	return document, nil
 }
 
+// indexText 提取文档文本并写入全文索引
+func (s *UploadService) indexText(document *models.Document) {
+	if err := s.searchService.IndexDocument(document); err != nil {
+		logger.Log.Warnf("建立全文索引失败: documentID=%d, error=%v", document.ID, err)
+	}
+}
+
+// validateFile 检测文件格式并验证格式和大小限制，返回格式和文档类型
+func (s *UploadService) validateFile(file *upload.File) (string, string, error) {
+	// 1. 检测文件格式
+	format := s.detectFileFormat(file)
+	if format == "" {
+		return "", "", fmt.Errorf("无法识别的文件格式")
+	}
+
+	// 2. 根据格式判断文档类型
+	docType := models.GetDocumentType(format)
+
+	// 3. 验证文件格式（如果启用了格式限制）
+	if !s.config.AllowAllFormats {
+		if !s.isFormatAllowed(docType, format) {
+			return "", "", fmt.Errorf("不支持的文件格式: %s", format)
+		}
+	}
+
+	// 4. 验证文件大小
+	if file.Size > s.config.MaxFileSize {
+		return "", "", fmt.Errorf("文件大小超过限制: %.2f GB (最大 %.2f GB)",
+			float64(file.Size)/1024/1024/1024,
+			float64(s.config.MaxFileSize)/1024/1024/1024)
+	}
+
+	return format, docType, nil
+}
+
 // generatePreview 生成预览图（异步）
 func (s *UploadService) generatePreview(document *models.Document) {
 	logger.Log.Infof("开始生成预览图: documentID=%d, format=%s, filePath=%s", 
@@ -311,15 +341,46 @@ func (s *UploadService) generatePreview(document *models.Document) {
 	}
 
 	logger.Log.Infof("预览图生成成功: documentID=%d, path=%s", document.ID, thumbnailRelativePath)
+
+	// 非图片文件使用预览图计算感知哈希
+	if document.PerceptualHash == "" {
+		if phash := s.perceptualHash(thumbnailRelativePath); phash != "" {
+			s.db.Model(document).Update("perceptual_hash", phash)
+		}
+	}
+}
+
+// perceptualHash 计算存储文件的感知哈希，失败时返回空字符串（不影响上传）
+func (s *UploadService) perceptualHash(relativePath string) string {
+	phash, err := utils.PerceptualHashFile(s.getActualFilePath(relativePath))
+	if err != nil {
+		logger.Log.Warnf("计算感知哈希失败: path=%s, error=%v", relativePath, err)
+		return ""
+	}
+	return phash
+}
+
+// isImageFormat 判断是否为可计算感知哈希的图片格式
+func isImageFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case "jpg", "jpeg", "png", "gif", "webp":
+		return true
+	}
+	return false
 }
 
 // getActualFilePath 获取文件的实际物理路径
 func (s *UploadService) getActualFilePath(relativePath string) string {
+	return actualFilePath(s.config, relativePath)
+}
+
+// actualFilePath 根据存储配置获取文件的实际物理路径
+func actualFilePath(cfg *config.DocumentConfig, relativePath string) string {
 	// 如果启用了 NAS，使用 NAS 路径
-	if s.config.NASEnabled && s.config.NASPath != "" {
+	if cfg.NASEnabled && cfg.NASPath != "" {
 		// 标准化路径分隔符
 		cleanPath := strings.ReplaceAll(relativePath, "\\", "/")
-		storageDir := strings.ReplaceAll(s.config.StorageDir, "\\", "/")
+		storageDir := strings.ReplaceAll(cfg.StorageDir, "\\", "/")
 		
 		// 移除 storage_dir 前缀（如果存在）
 		if strings.HasPrefix(cleanPath, storageDir) {
@@ -331,7 +392,7 @@ func (s *UploadService) getActualFilePath(relativePath string) string {
 		cleanPath = strings.TrimPrefix(cleanPath, "\\")
 		
 		// 拼接 NAS 路径
-		actualPath := filepath.Join(s.config.NASPath, cleanPath)
+		actualPath := filepath.Join(cfg.NASPath, cleanPath)
 		return actualPath
 	}
 	
@@ -340,9 +401,21 @@ func (s *UploadService) getActualFilePath(relativePath string) string {
 }
 
 // saveFile 保存文件到存储（流式处理，避免大文件占用内存）
-func (s *UploadService) saveFile(file *multipart.FileHeader, documentID uint, format string) (string, error) {
-	logger.Log.Infof("开始保存文件: documentID=%d, format=%s, size=%.2fMB", 
-		documentID, format, float64(file.Size)/1024/1024)
+func (s *UploadService) saveFile(file *upload.File, documentID uint, format string) (string, error) {
+	return s.saveFileTo(file, storageSubPath(fmt.Sprintf("%d", documentID)), format)
+}
+
+// storageSubPath 使用年月日分组，避免单个文件夹文件过多
+// 格式: 2026/02/09/123
+func storageSubPath(dirName string) string {
+	now := time.Now()
+	return fmt.Sprintf("%d/%02d/%02d/%s", now.Year(), now.Month(), now.Day(), dirName)
+}
+
+// saveFileTo 将文件流式保存到指定子路径
+func (s *UploadService) saveFileTo(file *upload.File, subPath string, format string) (string, error) {
+	logger.Log.Infof("开始保存文件: subPath=%s, format=%s, size=%.2fMB", 
+		subPath, format, float64(file.Size)/1024/1024)
 	
 	// 打开文件
 	src, err := file.Open()
@@ -355,23 +428,18 @@ func (s *UploadService) saveFile(file *multipart.FileHeader, documentID uint, fo
 	// 使用检测到的格式作为扩展名
 	fileName := "file." + format
 
-	// 使用年月日分组，避免单个文件夹文件过多
-	// 格式: 2026/02/09/123
-	now := time.Now()
-	subPath := fmt.Sprintf("%d/%02d/%02d/%d", now.Year(), now.Month(), now.Day(), documentID)
-
 	filePath, err := s.storageService.SaveFileStream(subPath, fileName, src, file.Size)
 	if err != nil {
-		logger.Log.Errorf("保存文件失败: documentID=%d, error=%v", documentID, err)
+		logger.Log.Errorf("保存文件失败: subPath=%s, error=%v", subPath, err)
 		return "", err
 	}
 	
-	logger.Log.Infof("文件保存成功: documentID=%d, path=%s", documentID, filePath)
+	logger.Log.Infof("文件保存成功: path=%s", filePath)
 	return filePath, nil
 }
 
 // detectFileFormat 检测文件格式
-func (s *UploadService) detectFileFormat(file *multipart.FileHeader) string {
+func (s *UploadService) detectFileFormat(file *upload.File) string {
 	// 1. 优先从文件名获取扩展名
 	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
 	if ext != "" {
@@ -461,7 +529,7 @@ func (s *UploadService) isFormatAllowed(docType, format string) bool {
 }
 
 // calculateHash 计算文件哈希
-func (s *UploadService) calculateHash(file *multipart.FileHeader) (string, error) {
+func (s *UploadService) calculateHash(file *upload.File) (string, error) {
 	src, err := file.Open()
 	if err != nil {
 		return "", err
@@ -628,7 +696,7 @@ func (s *UploadService) CreateFolder(name, description string, parentID *uint, d
 
 
 // UploadFolder 上传文件夹（保持结构）
-func (s *UploadService) UploadFolder(files []*multipart.FileHeader, filePaths []string, metadata FolderUploadMetadata) (map[string]interface{}, error) {
+func (s *UploadService) UploadFolder(files []*upload.File, filePaths []string, metadata FolderUploadMetadata) (map[string]interface{}, error) {
 	if len(files) != len(filePaths) {
 		return nil, fmt.Errorf("文件数量与路径数量不匹配")
 	}
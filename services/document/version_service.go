@@ -0,0 +1,288 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/upload"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrVersionControlDisabled = errors.New("版本控制未启用")
+	ErrFolderHasNoVersion     = errors.New("文件夹不支持版本管理")
+	ErrSameAsLatestVersion    = errors.New("文件内容与当前版本相同")
+	ErrVersionNotInHistory    = errors.New("该版本不属于此文档")
+	ErrAlreadyLatestVersion   = errors.New("该版本已是最新版本")
+	ErrVersionConflict        = errors.New("文档已有更新的版本，请刷新后重试")
+)
+
+// versionPattern 可自动递增的版本号格式，如 v1.0、1.2
+var versionPattern = regexp.MustCompile(`^(v?)(\d+)\.(\d+)$`)
+
+// VersionMetadata 新版本上传元数据
+type VersionMetadata struct {
+	Version    string // 版本号，为空时自动递增
+	UploadedBy string
+	UploadIP   string
+}
+
+// UploadVersion 为文档上传新版本
+// documentID 可以是任意版本，新版本总是基于当前最新版本创建；
+// 旧版本转为历史版本，不再计入文件夹统计
+func (s *UploadService) UploadVersion(documentID uint, file *upload.File, metadata VersionMetadata) (*models.Document, error) {
+	if !s.config.VersionControlEnabled {
+		return nil, ErrVersionControlDisabled
+	}
+
+	latest, err := latestVersion(s.db, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	format, docType, err := s.validateFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	fileHash, err := s.calculateHash(file)
+	if err != nil {
+		return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+	if fileHash == latest.FileHash {
+		return nil, ErrSameAsLatestVersion
+	}
+
+	if metadata.Version == "" {
+		metadata.Version = nextVersion(latest.Version)
+	}
+
+	// 文件先落盘再写数据库，避免在事务中执行耗时的文件 I/O；
+	// 新版本 ID 尚未分配，目录使用随机名称
+	subPath := storageSubPath(uuid.NewString())
+	filePath, err := s.saveFileTo(file, subPath, format)
+	if err != nil {
+		return nil, err
+	}
+	perceptualHash := ""
+	if isImageFormat(format) {
+		perceptualHash = s.perceptualHash(filePath)
+	}
+
+	document := &models.Document{
+		Name:            latest.Name,
+		Description:     latest.Description,
+		Category:        latest.Category,
+		Tags:            latest.Tags,
+		Type:            docType,
+		ParentID:        latest.ParentID,
+		FilePath:        filePath,
+		FileSize:        file.Size,
+		FileHash:        fileHash,
+		PerceptualHash:  perceptualHash,
+		Format:          format,
+		Version:         metadata.Version,
+		ParentVersionID: &latest.ID,
+		Department:      latest.Department,
+		Project:         latest.Project,
+		IsPublic:        latest.IsPublic,
+		IsLatest:        true,
+		UploadedBy:      metadata.UploadedBy,
+		UploadIP:        metadata.UploadIP,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 旧版本转为历史版本并从文件夹统计中扣除；
+		// 只有仍是最新版本时才更新，并发上传时后提交的一方得到冲突错误
+		result := tx.Model(&models.Document{}).
+			Where("id = ? AND is_latest = ?", latest.ID, true).
+			UpdateColumn("is_latest", false)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrVersionConflict
+		}
+		latest.IsLatest = false
+		if err := models.DetachFromParent(tx, latest); err != nil {
+			return err
+		}
+
+		// 新版本创建时由 AfterCreate 钩子计入文件夹统计
+		if err := tx.Create(document).Error; err != nil {
+			return fmt.Errorf("创建版本记录失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// 事务失败时清理已保存的文件
+		s.storageService.DeleteFile(subPath)
+		return nil, err
+	}
+
+	s.pruneVersions(document)
+
+	// 重新生成预览图
+	if s.fileProcessorService != nil {
+		go s.generatePreview(document)
+	}
+
+	// 只检索最新版本的内容
+	s.searchService.RemoveDocuments(latest.ID)
+	go s.indexText(document)
+
+	s.updateMetrics(document)
+	s.logAccess(document.ID, "upload_version", metadata.UploadedBy, metadata.UploadIP)
+
+	logger.Log.Infof("文档 %s 上传新版本 %s (ID: %d)", document.Name, document.Version, document.ID)
+	return document, nil
+}
+
+// RestoreVersion 将历史版本恢复为最新版本（复制该版本的文件创建一个新版本，保留完整历史）
+func (s *UploadService) RestoreVersion(documentID, versionID uint, uploadedBy, uploadIP string) (*models.Document, error) {
+	var version models.Document
+	if err := s.db.First(&version, versionID).Error; err != nil {
+		return nil, err
+	}
+	if version.IsLatest {
+		return nil, ErrAlreadyLatestVersion
+	}
+
+	latest, err := latestVersion(s.db, documentID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := versionHistory(s.db, latest)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, v := range history {
+		if v.ID == version.ID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrVersionNotInHistory
+	}
+
+	actualPath := s.getActualFilePath(version.FilePath)
+	file, err := upload.FromPath(actualPath, filepath.Base(actualPath))
+	if err != nil {
+		return nil, fmt.Errorf("读取版本文件失败: %w", err)
+	}
+
+	return s.UploadVersion(latest.ID, file, VersionMetadata{
+		UploadedBy: uploadedBy,
+		UploadIP:   uploadIP,
+	})
+}
+
+// pruneVersions 超过最大版本数时彻底删除最旧的历史版本
+func (s *UploadService) pruneVersions(latest *models.Document) {
+	if s.config.MaxVersions <= 0 {
+		return
+	}
+
+	history, err := versionHistory(s.db, latest)
+	if err != nil || len(history) <= s.config.MaxVersions {
+		return
+	}
+
+	for _, version := range history[s.config.MaxVersions:] {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := unlinkVersion(tx, version); err != nil {
+				return err
+			}
+			tx.Unscoped().Where("document_id = ?", version.ID).Delete(&models.DocumentMetadata{})
+			tx.Unscoped().Where("document_id = ?", version.ID).Delete(&models.DocumentAccessLog{})
+			if err := models.DeleteResourcePermissions(tx, models.ResourceDocuments, version.ID); err != nil {
+				return err
+			}
+			return tx.Unscoped().Session(&gorm.Session{SkipHooks: true}).Delete(version).Error
+		})
+		if err != nil {
+			logger.Log.Warnf("清理历史版本失败: documentID=%d, error=%v", version.ID, err)
+			continue
+		}
+		// 记录删除成功后再删除文件，事务失败时版本仍可访问
+		removeDocumentFiles(s.config, version)
+		logger.Log.Infof("已清理超出保留数量的历史版本: %s %s (ID: %d)", version.Name, version.Version, version.ID)
+	}
+}
+
+// latestVersion 获取文档的最新版本（documentID 可以是任意版本）
+func latestVersion(db *gorm.DB, documentID uint) (*models.Document, error) {
+	var document models.Document
+	if err := db.First(&document, documentID).Error; err != nil {
+		return nil, err
+	}
+	if document.IsFolder {
+		return nil, ErrFolderHasNoVersion
+	}
+
+	// 沿版本链向后查找，跳过已移入回收站的中间版本
+	for !document.IsLatest {
+		var next models.Document
+		if err := db.Unscoped().Where("parent_version_id = ?", document.ID).First(&next).Error; err != nil {
+			return nil, err
+		}
+		document = next
+	}
+	if document.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &document, nil
+}
+
+// versionHistory 从最新版本沿 ParentVersionID 向前追溯，返回按新到旧排列的版本列表
+// 已移入回收站的版本会被跳过，但不会中断追溯
+func versionHistory(db *gorm.DB, latest *models.Document) ([]*models.Document, error) {
+	versions := []*models.Document{latest}
+	seen := map[uint]bool{latest.ID: true}
+
+	current := latest
+	for current.ParentVersionID != nil && !seen[*current.ParentVersionID] {
+		var previous models.Document
+		if err := db.Unscoped().First(&previous, *current.ParentVersionID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				break
+			}
+			return nil, err
+		}
+		seen[previous.ID] = true
+		if !previous.DeletedAt.Valid {
+			versions = append(versions, &previous)
+		}
+		current = &previous
+	}
+	return versions, nil
+}
+
+// unlinkVersion 彻底删除版本前，将其后续版本接到它的上一版本，保持版本链连续
+func unlinkVersion(tx *gorm.DB, version *models.Document) error {
+	return tx.Unscoped().Model(&models.Document{}).
+		Where("parent_version_id = ?", version.ID).
+		UpdateColumn("parent_version_id", version.ParentVersionID).Error
+}
+
+// nextVersion 自动递增版本号（v1.0 -> v1.1），无法识别的格式追加 .1
+func nextVersion(current string) string {
+	if current == "" {
+		return "v1.1"
+	}
+
+	matches := versionPattern.FindStringSubmatch(current)
+	if matches == nil {
+		return current + ".1"
+	}
+	minor, _ := strconv.Atoi(matches[3])
+	return fmt.Sprintf("%s%s.%d", matches[1], matches[2], minor+1)
+}
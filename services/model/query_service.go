@@ -23,6 +23,7 @@ type QueryFilters struct {
 	Type      string
 	SortBy    string // name, created_at, use_count
 	SortOrder string // asc, desc
+	IDs       []uint // 非 nil 时只返回这些模型（仅凭单独授权访问的用户）
 }
 
 type ModelStatistics struct {
@@ -56,6 +57,10 @@ func (q *QueryService) List(page, pageSize int, filters QueryFilters) ([]*models
 	query := q.db.Model(&models.Model{})
 
 	// 应用过滤条件
+	if filters.IDs != nil {
+		query = query.Where("id IN ?", filters.IDs)
+	}
+
 	if filters.Category != "" {
 		query = query.Where("category = ?", filters.Category)
 	}
@@ -116,8 +121,8 @@ func (q *QueryService) ListByTags(tagNames []string, page, pageSize int) ([]*mod
 	return q.List(page, pageSize, filters)
 }
 
-// Search 搜索（名称、标签）
-func (q *QueryService) Search(keyword string, page, pageSize int) ([]*models.Model, int64, error) {
+// Search 搜索（名称、标签），ids 非 nil 时只在这些模型中搜索
+func (q *QueryService) Search(keyword string, page, pageSize int, ids []uint) ([]*models.Model, int64, error) {
 	var modelList []*models.Model
 	var total int64
 
@@ -127,6 +132,9 @@ func (q *QueryService) Search(keyword string, page, pageSize int) ([]*models.Mod
 		"%"+keyword+"%",
 		"%"+keyword+"%",
 	)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
 
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
@@ -233,12 +241,17 @@ func (q *QueryService) Delete(id uint) error {
 	// 4. 删除标签关联
 	q.db.Where("model_id = ?", id).Delete(&models.ModelTag{})
 
-	return nil
+	// 5. 删除单独授权
+	return models.DeleteResourcePermissions(q.db, models.ResourceModels, id)
 }
 
-// GetPopular 获取热门模型
-func (q *QueryService) GetPopular(limit int) ([]*models.Model, error) {
+// GetPopular 获取热门模型，ids 非 nil 时只在这些模型中统计
+func (q *QueryService) GetPopular(limit int, ids []uint) ([]*models.Model, error) {
 	var models []*models.Model
-	err := q.db.Order("use_count DESC").Limit(limit).Find(&models).Error
+	query := q.db.Order("use_count DESC")
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+	err := query.Limit(limit).Find(&models).Error
 	return models, err
 }
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"path/filepath"
 	"strings"
 
@@ -15,6 +14,8 @@ import (
 	"go_wails_project_manager/logger"
 	"go_wails_project_manager/models"
 	"go_wails_project_manager/services/storage"
+	"go_wails_project_manager/services/upload"
+	"go_wails_project_manager/utils"
 )
 
 var (
@@ -59,7 +60,7 @@ func NewUploadService(db *gorm.DB, cfg *config.ModelConfig) *UploadService {
 
 // UploadSingle 单文件上传（模型 + 预览图）
 func (s *UploadService) UploadSingle(
-	modelFile, thumbnailFile *multipart.FileHeader,
+	modelFile, thumbnailFile *upload.File,
 	metadata UploadMetadata,
 ) (*models.Model, error) {
 	// 1. 验证模型文件
@@ -120,6 +121,7 @@ func (s *UploadService) UploadSingle(
 	// 8. 更新路径
 	model.FilePath = filePath
 	model.ThumbnailPath = thumbnailPath
+	model.PerceptualHash = s.thumbnailHash(thumbnailFile)
 	if err := s.db.Save(model).Error; err != nil {
 		s.storageService.DeleteFile(fmt.Sprintf("%d", model.ID)) // 清理文件
 		return nil, fmt.Errorf("更新模型记录失败: %w", err)
@@ -129,7 +131,7 @@ func (s *UploadService) UploadSingle(
 }
 
 // validateFile 验证文件
-func (s *UploadService) validateFile(file *multipart.FileHeader, allowedTypes []string, maxSize int64) error {
+func (s *UploadService) validateFile(file *upload.File, allowedTypes []string, maxSize int64) error {
 	// 检查文件大小
 	if file.Size > maxSize {
 		return ErrFileTooLarge
@@ -155,7 +157,7 @@ func (s *UploadService) validateFile(file *multipart.FileHeader, allowedTypes []
 }
 
 // calculateHash 计算文件哈希
-func (s *UploadService) calculateHash(file *multipart.FileHeader) (string, error) {
+func (s *UploadService) calculateHash(file *upload.File) (string, error) {
 	f, err := file.Open()
 	if err != nil {
 		return "", err
@@ -183,29 +185,40 @@ func (s *UploadService) checkDuplicate(fileHash string) (*models.Model, bool, er
 	return &model, true, nil
 }
 
-// saveModelFile 保存模型文件
-func (s *UploadService) saveModelFile(file *multipart.FileHeader, modelID uint, fileType string) (string, error) {
-	// 读取文件数据
+// thumbnailHash 计算预览图感知哈希，失败时返回空字符串（不影响上传）
+func (s *UploadService) thumbnailHash(file *upload.File) string {
 	src, err := file.Open()
 	if err != nil {
-		return "", err
+		return ""
 	}
 	defer src.Close()
 
-	data, err := io.ReadAll(src)
+	phash, err := utils.PerceptualHash(src)
+	if err != nil {
+		logger.Log.Warnf("计算预览图感知哈希失败: %v", err)
+		return ""
+	}
+	return phash
+}
+
+// saveModelFile 保存模型文件
+func (s *UploadService) saveModelFile(file *upload.File, modelID uint, fileType string) (string, error) {
+	// 读取文件数据
+	src, err := file.Open()
 	if err != nil {
 		return "", err
 	}
+	defer src.Close()
 
-	// 使用通用存储服务保存
+	// 使用通用存储服务流式保存，大文件（断点续传上传）不整体读入内存
 	subPath := fmt.Sprintf("%d", modelID)
 	fileName := fmt.Sprintf("model.%s", fileType)
 	
-	return s.storageService.SaveFile(subPath, fileName, data)
+	return s.storageService.SaveFileStream(subPath, fileName, src, file.Size)
 }
 
 // saveThumbnail 保存预览图
-func (s *UploadService) saveThumbnail(file *multipart.FileHeader, modelID uint) (string, error) {
+func (s *UploadService) saveThumbnail(file *upload.File, modelID uint) (string, error) {
 	// 读取文件数据
 	src, err := file.Open()
 	if err != nil {
@@ -213,17 +226,12 @@ func (s *UploadService) saveThumbnail(file *multipart.FileHeader, modelID uint)
 	}
 	defer src.Close()
 
-	data, err := io.ReadAll(src)
-	if err != nil {
-		return "", err
-	}
-
 	// 获取原始扩展名
 	ext := strings.ToLower(filepath.Ext(file.Filename))
 	fileName := fmt.Sprintf("thumbnail%s", ext)
 	
-	// 使用通用存储服务保存
+	// 使用通用存储服务流式保存
 	subPath := fmt.Sprintf("%d", modelID)
 	
-	return s.storageService.SaveFile(subPath, fileName, data)
+	return s.storageService.SaveFileStream(subPath, fileName, src, file.Size)
 }
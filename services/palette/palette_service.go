@@ -0,0 +1,339 @@
+// Package palette 提供图片主色提取（Lab 空间 k-means）和按颜色检索
+package palette
+
+import (
+	"errors"
+	"fmt"
+	"go_wails_project_manager/models"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+)
+
+// 调色板归属类型（与表名一致）
+const (
+	OwnerTexture = "texture"
+	OwnerAsset   = "asset"
+)
+
+const (
+	// DefaultColorCount 每张图片提取的主色数量
+	DefaultColorCount = 5
+	// DefaultTolerance 默认颜色容差（CIE76 ΔE，约 2.3 为人眼可分辨差异）
+	DefaultTolerance = 20.0
+	// MaxTolerance 颜色容差上限
+	MaxTolerance = 100.0
+	// MinMatchWeight 参与颜色检索的最小占比，避免少量点缀色命中
+	MinMatchWeight = 0.1
+
+	sampleSize    = 64 // 采样缩放尺寸
+	maxIterations = 12
+)
+
+var (
+	ErrInvalidColor = errors.New("无效的颜色值，格式应为 #aabbcc")
+	ErrEmptyImage   = errors.New("图片没有可用像素")
+)
+
+// Color 主色
+type Color struct {
+	Hex    string  `json:"hex"`
+	L      float64 `json:"l"`
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+	Weight float64 `json:"weight"`
+}
+
+// labPoint Lab 空间中的像素
+type labPoint struct {
+	l, a, b float64
+}
+
+// ExtractFile 从图片文件提取主色
+func ExtractFile(path string, k int) ([]Color, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ExtractReader(file, k)
+}
+
+// ExtractReader 从图片数据流提取主色
+func ExtractReader(r io.Reader, k int) ([]Color, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %w", err)
+	}
+	return Extract(img, k)
+}
+
+// Extract 使用 k-means 在 Lab 空间聚类提取主色，结果按占比降序
+func Extract(img image.Image, k int) ([]Color, error) {
+	if k <= 0 {
+		k = DefaultColorCount
+	}
+
+	small := imaging.Resize(img, sampleSize, 0, imaging.Box)
+	points := make([]labPoint, 0, len(small.Pix)/4)
+	for i := 0; i+3 < len(small.Pix); i += 4 {
+		if small.Pix[i+3] < 128 {
+			continue // 跳过透明像素
+		}
+		l, a, b := RGBToLab(small.Pix[i], small.Pix[i+1], small.Pix[i+2])
+		points = append(points, labPoint{l, a, b})
+	}
+	if len(points) == 0 {
+		return nil, ErrEmptyImage
+	}
+	if k > len(points) {
+		k = len(points)
+	}
+
+	centers := initCenters(points, k)
+	assignments := make([]int, len(points))
+	for iter := 0; iter < maxIterations; iter++ {
+		changed := false
+		for i, p := range points {
+			nearest := nearestCenter(p, centers)
+			if nearest != assignments[i] || iter == 0 {
+				assignments[i] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([]labPoint, len(centers))
+		counts := make([]int, len(centers))
+		for i, p := range points {
+			c := assignments[i]
+			sums[c].l += p.l
+			sums[c].a += p.a
+			sums[c].b += p.b
+			counts[c]++
+		}
+		for c := range centers {
+			if counts[c] > 0 {
+				n := float64(counts[c])
+				centers[c] = labPoint{sums[c].l / n, sums[c].a / n, sums[c].b / n}
+			}
+		}
+	}
+
+	counts := make([]int, len(centers))
+	for _, c := range assignments {
+		counts[c]++
+	}
+
+	colors := make([]Color, 0, len(centers))
+	for c, center := range centers {
+		if counts[c] == 0 {
+			continue
+		}
+		colors = append(colors, Color{
+			Hex:    LabToHex(center.l, center.a, center.b),
+			L:      center.l,
+			A:      center.a,
+			B:      center.b,
+			Weight: float64(counts[c]) / float64(len(points)),
+		})
+	}
+	sort.SliceStable(colors, func(i, j int) bool {
+		return colors[i].Weight > colors[j].Weight
+	})
+	return colors, nil
+}
+
+// initCenters k-means++ 初始化聚类中心（固定随机种子，保证结果可复现）
+func initCenters(points []labPoint, k int) []labPoint {
+	rng := rand.New(rand.NewSource(1))
+	centers := []labPoint{points[rng.Intn(len(points))]}
+	distances := make([]float64, len(points))
+
+	for len(centers) < k {
+		total := 0.0
+		for i, p := range points {
+			distances[i] = labDistanceSq(p, centers[nearestCenter(p, centers)])
+			total += distances[i]
+		}
+		if total == 0 {
+			break // 剩余像素颜色完全相同
+		}
+
+		target := rng.Float64() * total
+		for i, d := range distances {
+			target -= d
+			if target <= 0 {
+				centers = append(centers, points[i])
+				break
+			}
+		}
+	}
+	return centers
+}
+
+// nearestCenter 查找最近的聚类中心
+func nearestCenter(p labPoint, centers []labPoint) int {
+	best, bestDist := 0, math.MaxFloat64
+	for i, c := range centers {
+		if d := labDistanceSq(p, c); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func labDistanceSq(p, q labPoint) float64 {
+	dl, da, db := p.l-q.l, p.a-q.a, p.b-q.b
+	return dl*dl + da*da + db*db
+}
+
+// Save 保存调色板，并更新归属记录的 dominant_colors 字段
+func Save(db *gorm.DB, ownerType string, ownerID uint, colors []Color) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+			Delete(&models.ColorPalette{}).Error; err != nil {
+			return err
+		}
+
+		hexes := make([]string, 0, len(colors))
+		for i, c := range colors {
+			row := models.ColorPalette{
+				OwnerType: ownerType,
+				OwnerID:   ownerID,
+				Rank:      i,
+				Hex:       c.Hex,
+				L:         c.L,
+				A:         c.A,
+				B:         c.B,
+				Weight:    c.Weight,
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			hexes = append(hexes, c.Hex)
+		}
+
+		return tx.Table(ownerType).Where("id = ?", ownerID).
+			Update("dominant_colors", strings.Join(hexes, ",")).Error
+	})
+}
+
+// MatchingOwners 构造按颜色筛选的子查询，返回主色与目标颜色 ΔE 不超过容差的归属 ID
+func MatchingOwners(db *gorm.DB, ownerType, hex string, tolerance float64) (*gorm.DB, error) {
+	r, g, b, err := ParseHex(hex)
+	if err != nil {
+		return nil, err
+	}
+	tolerance = NormalizeTolerance(tolerance)
+	l, a, bb := RGBToLab(r, g, b)
+
+	// 比较距离平方，避免依赖数据库的 SQRT 函数
+	return db.Model(&models.ColorPalette{}).
+		Select("owner_id").
+		Where("owner_type = ? AND weight >= ?", ownerType, MinMatchWeight).
+		Where("(lab_l - ?) * (lab_l - ?) + (lab_a - ?) * (lab_a - ?) + (lab_b - ?) * (lab_b - ?) <= ?",
+			l, l, a, a, bb, bb, tolerance*tolerance), nil
+}
+
+// NormalizeTolerance 规范化颜色容差，非法值使用默认容差
+func NormalizeTolerance(tolerance float64) float64 {
+	if tolerance <= 0 || math.IsNaN(tolerance) {
+		return DefaultTolerance
+	}
+	if tolerance > MaxTolerance {
+		return MaxTolerance
+	}
+	return tolerance
+}
+
+// ParseHex 解析 #aabbcc / aabbcc / #abc 格式的颜色
+func ParseHex(hex string) (uint8, uint8, uint8, error) {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, ErrInvalidColor
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, ErrInvalidColor
+	}
+	return uint8(value >> 16), uint8(value >> 8), uint8(value), nil
+}
+
+// RGBToLab sRGB 转 CIE Lab（D65 白点）
+func RGBToLab(r, g, b uint8) (float64, float64, float64) {
+	lr, lg, lb := srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)
+
+	x := (lr*0.4124 + lg*0.3576 + lb*0.1805) / 0.95047
+	y := (lr*0.2126 + lg*0.7152 + lb*0.0722) / 1.00000
+	z := (lr*0.0193 + lg*0.1192 + lb*0.9505) / 1.08883
+
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// LabToHex CIE Lab 转 #aabbcc
+func LabToHex(l, a, b float64) string {
+	fy := (l + 16) / 116
+	fx := fy + a/500
+	fz := fy - b/200
+
+	x := labFInv(fx) * 0.95047
+	y := labFInv(fy) * 1.00000
+	z := labFInv(fz) * 1.08883
+
+	lr := x*3.2406 + y*-1.5372 + z*-0.4986
+	lg := x*-0.9689 + y*1.8758 + z*0.0415
+	lb := x*0.0557 + y*-0.2040 + z*1.0570
+
+	return fmt.Sprintf("#%02x%02x%02x", linearToSRGB(lr), linearToSRGB(lg), linearToSRGB(lb))
+}
+
+func srgbToLinear(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) uint8 {
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+func labF(t float64) float64 {
+	if t > 216.0/24389.0 {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27.0*t + 16) / 116
+}
+
+func labFInv(t float64) float64 {
+	if t3 := t * t * t; t3 > 216.0/24389.0 {
+		return t3
+	}
+	return (116*t - 16) * 27.0 / 24389.0
+}
@@ -22,12 +22,15 @@ func NewProjectService(db *gorm.DB) *ProjectService {
 	return &ProjectService{db: db}
 }
 
-// GetProjects 获取项目列表
-func (ps *ProjectService) GetProjects(page, pageSize int, keyword string) ([]models.Project, int64, error) {
+// GetProjects 获取项目列表，ids 非 nil 时只返回这些项目（仅凭单独授权访问的用户）
+func (ps *ProjectService) GetProjects(page, pageSize int, keyword string, ids []uint) ([]models.Project, int64, error) {
 	var projects []models.Project
 	var total int64
 
 	query := ps.db.Model(&models.Project{})
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
 	
 	if keyword != "" {
 		query = query.Where("name LIKE ? OR description LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
@@ -106,7 +109,12 @@ func (ps *ProjectService) DeleteProject(id uint) error {
 	}
 
 	// 删除项目
-	return ps.db.Delete(&models.Project{}, id).Error
+	if err := ps.db.Delete(&models.Project{}, id).Error; err != nil {
+		return err
+	}
+
+	// 删除单独授权
+	return models.DeleteResourcePermissions(ps.db, models.ResourceProjects, id)
 }
 
 // GetVersionHistory 获取版本历史
@@ -118,6 +126,15 @@ func (ps *ProjectService) GetVersionHistory(projectID uint) ([]models.ProjectVer
 	return versions, nil
 }
 
+// GetVersion 获取版本信息
+func (ps *ProjectService) GetVersion(versionID uint) (*models.ProjectVersion, error) {
+	var version models.ProjectVersion
+	if err := ps.db.First(&version, versionID).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
 // RollbackVersion 回滚版本
 func (ps *ProjectService) RollbackVersion(versionID uint) error {
 	// 1. 获取版本信息
@@ -0,0 +1,684 @@
+// Package share 分享链接服务：为文档/文件夹、资产和模型生成可公开访问的链接
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/storage"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// accessKeyTTL 输入密码后访问凭证的有效期
+const accessKeyTTL = 12 * time.Hour
+
+// 访问日志动作
+const (
+	AccessView     = "view"
+	AccessList     = "list"
+	AccessUnlock   = "unlock"
+	AccessDownload = "download"
+)
+
+var (
+	ErrShareNotFound        = errors.New("分享链接不存在")
+	ErrShareRevoked         = errors.New("分享链接已被取消")
+	ErrShareExpired         = errors.New("分享链接已过期")
+	ErrDownloadLimitReached = errors.New("分享链接下载次数已用完")
+	ErrPasswordRequired     = errors.New("需要输入访问密码")
+	ErrWrongPassword        = errors.New("访问密码错误")
+	ErrInvalidResourceType  = errors.New("不支持分享的资源类型")
+	ErrResourceNotFound     = errors.New("要分享的资源不存在")
+	ErrInvalidExpiry        = errors.New("过期时间必须晚于当前时间")
+	ErrItemOutsideShare     = errors.New("该文件不在分享范围内")
+	ErrNotFolderShare       = errors.New("该分享不是文件夹")
+	ErrCannotDownloadFolder = errors.New("文件夹不能直接下载")
+)
+
+// CreateRequest 创建分享参数
+type CreateRequest struct {
+	ResourceType string // document, asset, model
+	ResourceID   uint
+	Password     string     // 为空表示无需密码
+	ExpiresAt    *time.Time // 为空表示永不过期
+	MaxDownloads int        // 0 表示不限制
+	CreatedBy    string
+}
+
+// ListFilters 分享列表过滤条件
+type ListFilters struct {
+	ResourceType   string
+	ResourceID     uint
+	CreatedBy      string
+	IncludeRevoked bool
+	// Owner 非空时只返回该用户创建的分享，以及 ManagedTypes 中资源类型的全部分享（对应资源的管理员）
+	Owner        string
+	ManagedTypes []string
+}
+
+// permissionResources 分享的资源类型对应的权限资源
+var permissionResources = map[string]string{
+	models.ResourceDocument: models.ResourceDocuments,
+	models.ResourceAsset:    models.ResourceAssets,
+	models.ResourceModel:    models.ResourceModels,
+}
+
+// PermissionResource 分享的资源类型对应的权限资源（如 document -> documents），不支持分享的类型返回空
+func PermissionResource(resourceType string) string {
+	return permissionResources[resourceType]
+}
+
+// ResourceTypes 支持分享的资源类型
+func ResourceTypes() []string {
+	return []string{models.ResourceDocument, models.ResourceAsset, models.ResourceModel}
+}
+
+// SharedItem 公开访问时返回的资源信息（不包含存储路径和上传者信息）
+type SharedItem struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	IsFolder   bool      `json:"is_folder"`
+	Size       int64     `json:"size"`
+	Format     string    `json:"format,omitempty"`
+	ChildCount int       `json:"child_count,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ShareInfo 公开访问的分享信息
+type ShareInfo struct {
+	Token        string      `json:"token"`
+	ResourceType string      `json:"resource_type"`
+	ExpiresAt    *time.Time  `json:"expires_at"`
+	MaxDownloads int         `json:"max_downloads"`
+	Downloads    int         `json:"download_count"`
+	HasPassword  bool        `json:"has_password"`
+	Item         *SharedItem `json:"item"`
+}
+
+// DownloadFile 分享下载的文件
+type DownloadFile struct {
+	Path   string   // 实际文件路径
+	Name   string   // 下载文件名
+	ItemID uint     // 实际下载的资源ID
+	File   *os.File // 已打开的文件（Download 返回时），由调用方关闭
+}
+
+// AccessContext 访问者信息，用于访问日志
+type AccessContext struct {
+	IP        string
+	UserAgent string
+}
+
+// Service 分享链接服务
+type Service struct {
+	db       *gorm.DB
+	logger   *logrus.Logger
+	storages map[string]*storage.FileStorageService // 资源类型 -> 存储服务
+	logDocs  bool                                   // 是否同时写入文档访问日志
+}
+
+// NewService 创建分享链接服务
+func NewService(db *gorm.DB, docConfig *config.DocumentConfig, logger *logrus.Logger) *Service {
+	storages := map[string]*storage.FileStorageService{
+		models.ResourceDocument: storage.NewFileStorageService(&storage.StorageConfig{
+			LocalStorageEnabled: docConfig.LocalStorageEnabled,
+			StorageDir:          docConfig.StorageDir,
+			NASEnabled:          docConfig.NASEnabled,
+			NASPath:             docConfig.NASPath,
+		}, logger),
+	}
+	if config.AppConfig != nil {
+		storages[models.ResourceAsset] = storage.NewFileStorageService(&storage.StorageConfig{
+			LocalStorageEnabled: config.AppConfig.Asset.LocalStorageEnabled,
+			StorageDir:          config.AppConfig.Asset.StorageDir,
+			NASEnabled:          config.AppConfig.Asset.NASEnabled,
+			NASPath:             config.AppConfig.Asset.NASPath,
+		}, logger)
+		storages[models.ResourceModel] = storage.NewFileStorageService(&storage.StorageConfig{
+			LocalStorageEnabled: config.AppConfig.Model.LocalStorageEnabled,
+			StorageDir:          config.AppConfig.Model.StorageDir,
+			NASEnabled:          config.AppConfig.Model.NASEnabled,
+			NASPath:             config.AppConfig.Model.NASPath,
+		}, logger)
+	}
+
+	return &Service{
+		db:       db,
+		logger:   logger,
+		storages: storages,
+		logDocs:  docConfig.LogAccess,
+	}
+}
+
+// ==================== 管理 ====================
+
+// Create 创建分享链接
+func (s *Service) Create(req CreateRequest) (*models.ShareLink, error) {
+	if _, ok := s.storages[req.ResourceType]; !ok {
+		return nil, ErrInvalidResourceType
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+	if req.MaxDownloads < 0 {
+		req.MaxDownloads = 0
+	}
+
+	name, err := s.resourceName(req.ResourceType, req.ResourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("生成分享 token 失败: %w", err)
+	}
+
+	link := &models.ShareLink{
+		Token:        token,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Name:         name,
+		ExpiresAt:    req.ExpiresAt,
+		MaxDownloads: req.MaxDownloads,
+		CreatedBy:    req.CreatedBy,
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		link.PasswordHash = string(hash)
+		link.HasPassword = true
+		if link.AccessSecret, err = generateToken(); err != nil {
+			return nil, fmt.Errorf("生成访问凭证密钥失败: %w", err)
+		}
+	}
+
+	if err := s.db.Create(link).Error; err != nil {
+		return nil, fmt.Errorf("创建分享链接失败: %w", err)
+	}
+
+	s.logger.Infof("[分享] %s 创建分享 %s/%d (ID: %d)", req.CreatedBy, req.ResourceType, req.ResourceID, link.ID)
+	return link, nil
+}
+
+// List 分页查询分享链接
+func (s *Service) List(page, pageSize int, filters ListFilters) ([]*models.ShareLink, int64, error) {
+	query := s.db.Model(&models.ShareLink{})
+	if filters.ResourceType != "" {
+		query = query.Where("resource_type = ?", filters.ResourceType)
+	}
+	if filters.ResourceID > 0 {
+		query = query.Where("resource_id = ?", filters.ResourceID)
+	}
+	if filters.CreatedBy != "" {
+		query = query.Where("created_by = ?", filters.CreatedBy)
+	}
+	if filters.Owner != "" {
+		if len(filters.ManagedTypes) > 0 {
+			query = query.Where("created_by = ? OR resource_type IN ?", filters.Owner, filters.ManagedTypes)
+		} else {
+			query = query.Where("created_by = ?", filters.Owner)
+		}
+	}
+	if !filters.IncludeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var links []*models.ShareLink
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&links).Error; err != nil {
+		return nil, 0, err
+	}
+	for _, link := range links {
+		link.HasPassword = link.PasswordHash != ""
+	}
+	return links, total, nil
+}
+
+// Get 按ID获取分享链接
+func (s *Service) Get(id uint) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := s.db.First(&link, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+	link.HasPassword = link.PasswordHash != ""
+	return &link, nil
+}
+
+// Revoke 取消分享链接
+func (s *Service) Revoke(id uint, revokedBy string) error {
+	link, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if link.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	return s.db.Model(link).Updates(map[string]interface{}{
+		"revoked_at": now,
+		"revoked_by": revokedBy,
+	}).Error
+}
+
+// AccessLogs 分页查询分享链接的访问日志
+func (s *Service) AccessLogs(shareID uint, page, pageSize int) ([]*models.ShareAccessLog, int64, error) {
+	query := s.db.Model(&models.ShareAccessLog{}).Where("share_id = ?", shareID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*models.ShareAccessLog
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// ==================== 公开访问 ====================
+
+// Open 根据 token 获取有效的分享链接（已取消或已过期返回错误，链接本身仍会返回用于记录日志）
+func (s *Service) Open(token string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := s.db.Where("token = ?", token).First(&link).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+	link.HasPassword = link.PasswordHash != ""
+
+	if link.RevokedAt != nil {
+		return &link, ErrShareRevoked
+	}
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		return &link, ErrShareExpired
+	}
+	return &link, nil
+}
+
+// Unlock 校验访问密码，返回后续请求使用的访问凭证
+func (s *Service) Unlock(link *models.ShareLink, password string) (string, time.Time, error) {
+	if link.PasswordHash == "" {
+		return "", time.Time{}, nil
+	}
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		return "", time.Time{}, ErrWrongPassword
+	}
+	if link.AccessSecret == "" {
+		// 早期创建的分享没有签名密钥，首次解锁时补充
+		secret, err := generateToken()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if err := s.db.Model(link).UpdateColumn("access_secret", secret).Error; err != nil {
+			return "", time.Time{}, err
+		}
+		link.AccessSecret = secret
+	}
+
+	expires := time.Now().Add(accessKeyTTL)
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(expires) {
+		expires = *link.ExpiresAt
+	}
+	return signAccessKey(link, expires), expires, nil
+}
+
+// Authorize 检查访问凭证（无密码的分享直接通过）
+func (s *Service) Authorize(link *models.ShareLink, accessKey string) error {
+	if link.PasswordHash == "" {
+		return nil
+	}
+	if accessKey == "" {
+		return ErrPasswordRequired
+	}
+
+	parts := strings.SplitN(accessKey, ".", 2)
+	if len(parts) != 2 {
+		return ErrPasswordRequired
+	}
+	unix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ErrPasswordRequired
+	}
+	expires := time.Unix(unix, 0)
+	if time.Now().After(expires) || link.AccessSecret == "" {
+		return ErrPasswordRequired
+	}
+	if !hmac.Equal([]byte(signAccessKey(link, expires)), []byte(accessKey)) {
+		return ErrPasswordRequired
+	}
+	return nil
+}
+
+// Info 获取分享的资源信息
+func (s *Service) Info(link *models.ShareLink) (*ShareInfo, error) {
+	item, err := s.rootItem(link)
+	if err != nil {
+		return nil, err
+	}
+	return &ShareInfo{
+		Token:        link.Token,
+		ResourceType: link.ResourceType,
+		ExpiresAt:    link.ExpiresAt,
+		MaxDownloads: link.MaxDownloads,
+		Downloads:    link.DownloadCount,
+		HasPassword:  link.HasPassword,
+		Item:         item,
+	}, nil
+}
+
+// ListFolder 列出分享文件夹（或其子文件夹）的内容，folderID 为 0 表示分享的根文件夹
+func (s *Service) ListFolder(link *models.ShareLink, folderID uint) (*SharedItem, []*SharedItem, error) {
+	if link.ResourceType != models.ResourceDocument {
+		return nil, nil, ErrNotFolderShare
+	}
+	if folderID == 0 {
+		folderID = link.ResourceID
+	}
+
+	folder, err := s.sharedDocument(link, folderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !folder.IsFolder {
+		return nil, nil, ErrNotFolderShare
+	}
+
+	var children []*models.Document
+	if err := s.db.Where("parent_id = ? AND is_latest = ?", folder.ID, true).
+		Order("is_folder DESC, name").Find(&children).Error; err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]*SharedItem, len(children))
+	for i, child := range children {
+		items[i] = documentItem(child)
+	}
+	return documentItem(folder), items, nil
+}
+
+// Download 解析并打开要下载的文件，打开成功后才占用一次下载次数；itemID 为 0 表示分享的资源本身
+// count 为 false 时（HEAD 和 Range 续传请求）只检查次数是否用完，不占用次数
+// 返回的 File 由调用方关闭
+func (s *Service) Download(link *models.ShareLink, itemID uint, count bool) (*DownloadFile, error) {
+	file, err := s.resolveFile(link, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if link.MaxDownloads > 0 && link.DownloadCount >= link.MaxDownloads {
+		return nil, ErrDownloadLimitReached
+	}
+
+	file.File, err = os.Open(file.Path)
+	if err != nil {
+		s.logger.Warnf("[分享] 打开文件失败: %s, error: %v", file.Path, err)
+		return nil, ErrResourceNotFound
+	}
+	if !count {
+		return file, nil
+	}
+
+	// 原子递增，避免并发下载超出次数限制
+	result := s.db.Model(&models.ShareLink{}).
+		Where("id = ? AND (max_downloads = 0 OR download_count < max_downloads)", link.ID).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+	if result.Error != nil || result.RowsAffected == 0 {
+		file.File.Close()
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		return nil, ErrDownloadLimitReached
+	}
+
+	if link.ResourceType == models.ResourceDocument {
+		s.db.Model(&models.Document{}).Where("id = ?", file.ItemID).
+			UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+	}
+	return file, nil
+}
+
+// RecordAccess 记录访问日志（包括被拒绝的访问）
+func (s *Service) RecordAccess(link *models.ShareLink, action string, itemID uint, accessErr error, access AccessContext) {
+	if link == nil {
+		return
+	}
+
+	log := &models.ShareAccessLog{
+		ShareID:   link.ID,
+		Action:    action,
+		ItemID:    itemID,
+		Success:   accessErr == nil,
+		UserIP:    access.IP,
+		UserAgent: truncate(access.UserAgent, 512),
+	}
+	if accessErr != nil {
+		log.Reason = truncate(accessErr.Error(), 200)
+	}
+	if err := s.db.Create(log).Error; err != nil {
+		s.logger.Warnf("[分享] 记录访问日志失败: %v", err)
+	}
+	if accessErr != nil {
+		return
+	}
+
+	updates := map[string]interface{}{"last_access_at": time.Now()}
+	if action == AccessView {
+		updates["view_count"] = gorm.Expr("view_count + 1")
+	}
+	s.db.Model(&models.ShareLink{}).Where("id = ?", link.ID).UpdateColumns(updates)
+
+	// 文档下载同时写入文档访问日志
+	if action == AccessDownload && link.ResourceType == models.ResourceDocument && s.logDocs {
+		s.db.Create(&models.DocumentAccessLog{
+			DocumentID: itemID,
+			Action:     "share_download",
+			UserName:   "share:" + strconv.FormatUint(uint64(link.ID), 10),
+			UserIP:     access.IP,
+		})
+	}
+}
+
+// ==================== 内部方法 ====================
+
+// resourceName 校验资源存在并返回名称
+func (s *Service) resourceName(resourceType string, resourceID uint) (string, error) {
+	var err error
+	var name string
+	switch resourceType {
+	case models.ResourceDocument:
+		var document models.Document
+		err = s.db.Where("is_latest = ?", true).First(&document, resourceID).Error
+		name = document.Name
+	case models.ResourceAsset:
+		var asset models.Asset
+		err = s.db.First(&asset, resourceID).Error
+		name = asset.Name
+	case models.ResourceModel:
+		var model models.Model
+		err = s.db.First(&model, resourceID).Error
+		name = model.Name
+	default:
+		return "", ErrInvalidResourceType
+	}
+	if err == gorm.ErrRecordNotFound {
+		return "", ErrResourceNotFound
+	}
+	return name, err
+}
+
+// rootItem 分享的资源本身
+func (s *Service) rootItem(link *models.ShareLink) (*SharedItem, error) {
+	switch link.ResourceType {
+	case models.ResourceDocument:
+		document, err := s.sharedDocument(link, link.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		return documentItem(document), nil
+	case models.ResourceAsset:
+		var asset models.Asset
+		if err := s.db.First(&asset, link.ResourceID).Error; err != nil {
+			return nil, notFound(err)
+		}
+		return &SharedItem{ID: asset.ID, Name: asset.Name, Size: asset.FileSize, Format: asset.Format, UpdatedAt: asset.UpdatedAt}, nil
+	case models.ResourceModel:
+		var model models.Model
+		if err := s.db.First(&model, link.ResourceID).Error; err != nil {
+			return nil, notFound(err)
+		}
+		return &SharedItem{ID: model.ID, Name: model.Name, Size: model.FileSize, Format: model.Type, UpdatedAt: model.UpdatedAt}, nil
+	}
+	return nil, ErrInvalidResourceType
+}
+
+// resolveFile 解析要下载的文件路径
+func (s *Service) resolveFile(link *models.ShareLink, itemID uint) (*DownloadFile, error) {
+	var relativePath, name, format string
+	switch link.ResourceType {
+	case models.ResourceDocument:
+		if itemID == 0 {
+			itemID = link.ResourceID
+		}
+		document, err := s.sharedDocument(link, itemID)
+		if err != nil {
+			return nil, err
+		}
+		if document.IsFolder {
+			return nil, ErrCannotDownloadFolder
+		}
+		relativePath, name, format = document.FilePath, document.Name, document.Format
+	case models.ResourceAsset:
+		var asset models.Asset
+		if err := s.db.First(&asset, link.ResourceID).Error; err != nil {
+			return nil, notFound(err)
+		}
+		itemID = asset.ID
+		relativePath, name, format = asset.FilePath, asset.Name, asset.Format
+	case models.ResourceModel:
+		var model models.Model
+		if err := s.db.First(&model, link.ResourceID).Error; err != nil {
+			return nil, notFound(err)
+		}
+		itemID = model.ID
+		relativePath, name, format = model.FilePath, model.Name, model.Type
+	default:
+		return nil, ErrInvalidResourceType
+	}
+
+	path, err := s.storages[link.ResourceType].ResolvePath(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	if format != "" && !strings.EqualFold(filepath.Ext(name), "."+format) {
+		name += "." + format
+	}
+	return &DownloadFile{Path: path, Name: name, ItemID: itemID}, nil
+}
+
+// sharedDocument 获取分享范围内的文档（分享的文档本身或分享文件夹的子孙）
+func (s *Service) sharedDocument(link *models.ShareLink, documentID uint) (*models.Document, error) {
+	var document models.Document
+	if err := s.db.Where("is_latest = ?", true).First(&document, documentID).Error; err != nil {
+		return nil, notFound(err)
+	}
+	if document.ID == link.ResourceID {
+		return &document, nil
+	}
+
+	// 沿父文件夹向上查找，必须位于分享的文件夹内
+	seen := map[uint]bool{document.ID: true}
+	for parentID := document.ParentID; parentID != nil && !seen[*parentID]; {
+		if *parentID == link.ResourceID {
+			return &document, nil
+		}
+		seen[*parentID] = true
+
+		var parent models.Document
+		if err := s.db.Select("id", "parent_id").First(&parent, *parentID).Error; err != nil {
+			break
+		}
+		parentID = parent.ParentID
+	}
+	return nil, ErrItemOutsideShare
+}
+
+// documentItem 转换为公开的资源信息
+func documentItem(document *models.Document) *SharedItem {
+	item := &SharedItem{
+		ID:        document.ID,
+		Name:      document.Name,
+		IsFolder:  document.IsFolder,
+		Size:      document.FileSize,
+		Format:    document.Format,
+		UpdatedAt: document.UpdatedAt,
+	}
+	if document.IsFolder {
+		item.Size = document.TotalSize
+		item.ChildCount = document.ChildCount
+	}
+	return item
+}
+
+// signAccessKey 用分享的服务端密钥签名访问凭证
+func signAccessKey(link *models.ShareLink, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(link.AccessSecret))
+	mac.Write([]byte(link.Token + "|" + exp))
+	return exp + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateToken 生成 URL 安全的随机 token
+func generateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// notFound 资源被删除时统一返回 ErrResourceNotFound
+func notFound(err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return ErrResourceNotFound
+	}
+	return err
+}
+
+// truncate 按字节截断字符串
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,339 @@
+package similarity
+
+import (
+	"errors"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/palette"
+	"go_wails_project_manager/services/texture"
+	"go_wails_project_manager/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 资源库类型
+const (
+	LibraryAsset    = "asset"
+	LibraryTexture  = "texture"
+	LibraryModel    = "model"
+	LibraryDocument = "document"
+)
+
+// maxMatches 单次查询返回的最大相似结果数
+const maxMatches = 50
+
+var (
+	ErrUnknownLibrary   = errors.New("未知的资源库")
+	ErrNoPerceptualHash = errors.New("资源尚未计算感知哈希")
+)
+
+// Match 相似结果
+type Match struct {
+	ID       uint        `json:"id"`
+	Distance int         `json:"distance"` // 汉明距离，越小越相似
+	Item     interface{} `json:"item"`
+}
+
+// Filter 过滤相似结果（如去掉当前用户不可见的资源），在截取前 maxMatches 个之前执行，nil 表示不过滤
+type Filter func(matches []Match) []Match
+
+// hashRow 感知哈希查询行
+type hashRow struct {
+	ID             uint
+	PerceptualHash string
+}
+
+// Service 感知哈希相似检索服务
+type Service struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+}
+
+// NewService 创建相似检索服务
+func NewService(db *gorm.DB, logger *logrus.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// tableOf 获取资源库对应的模型
+func tableOf(library string) (interface{}, error) {
+	switch library {
+	case LibraryAsset:
+		return &models.Asset{}, nil
+	case LibraryTexture:
+		return &models.Texture{}, nil
+	case LibraryModel:
+		return &models.Model{}, nil
+	case LibraryDocument:
+		return &models.Document{}, nil
+	}
+	return nil, ErrUnknownLibrary
+}
+
+// FindSimilar 查找与指定资源相似的资源
+func (s *Service) FindSimilar(library string, id uint, threshold int, filter Filter) ([]Match, error) {
+	table, err := tableOf(library)
+	if err != nil {
+		return nil, err
+	}
+
+	var row hashRow
+	if err := s.db.Model(table).Select("id, perceptual_hash").Where("id = ?", id).Take(&row).Error; err != nil {
+		return nil, err
+	}
+	if row.PerceptualHash == "" {
+		return nil, ErrNoPerceptualHash
+	}
+
+	return s.FindByHash(library, row.PerceptualHash, threshold, id, filter)
+}
+
+// FindByHash 按感知哈希查找相似资源，excludeID 为需要排除的资源（如自身）
+// filter 在截取结果之前执行，保证过滤后仍返回最相似的可见资源
+func (s *Service) FindByHash(library, hash string, threshold int, excludeID uint, filter Filter) ([]Match, error) {
+	table, err := tableOf(library)
+	if err != nil {
+		return nil, err
+	}
+	if hash == "" {
+		return []Match{}, nil
+	}
+	threshold = utils.NormalizeSimilarityThreshold(threshold)
+
+	var rows []hashRow
+	query := s.db.Model(table).Select("id, perceptual_hash").Where("perceptual_hash <> ''")
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, 0)
+	for _, row := range rows {
+		distance := utils.HashDistance(hash, row.PerceptualHash)
+		if distance < 0 || distance > threshold {
+			continue
+		}
+		matches = append(matches, Match{ID: row.ID, Distance: distance})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if filter != nil && len(matches) > 0 {
+		matches = filter(matches)
+	}
+	if len(matches) > maxMatches {
+		matches = matches[:maxMatches]
+	}
+
+	if err := s.loadItems(library, matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// loadItems 加载相似结果对应的资源记录
+func (s *Service) loadItems(library string, matches []Match) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+
+	items := make(map[uint]interface{}, len(ids))
+	switch library {
+	case LibraryAsset:
+		var records []models.Asset
+		if err := s.db.Where("id IN ?", ids).Find(&records).Error; err != nil {
+			return err
+		}
+		for i := range records {
+			items[records[i].ID] = records[i]
+		}
+	case LibraryTexture:
+		var records []models.Texture
+		if err := s.db.Where("id IN ?", ids).Find(&records).Error; err != nil {
+			return err
+		}
+		for i := range records {
+			items[records[i].ID] = records[i]
+		}
+	case LibraryModel:
+		var records []models.Model
+		if err := s.db.Where("id IN ?", ids).Find(&records).Error; err != nil {
+			return err
+		}
+		for i := range records {
+			items[records[i].ID] = records[i]
+		}
+	case LibraryDocument:
+		var records []models.Document
+		if err := s.db.Where("id IN ?", ids).Find(&records).Error; err != nil {
+			return err
+		}
+		for i := range records {
+			items[records[i].ID] = records[i]
+		}
+	}
+
+	for i := range matches {
+		matches[i].Item = items[matches[i].ID]
+	}
+	return nil
+}
+
+// Backfill 为资源库中尚未计算感知哈希的资源补算哈希
+func (s *Service) Backfill(library string) (updated int, failed int, err error) {
+	table, err := tableOf(library)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var ids []uint
+	if err := s.db.Model(table).Where("perceptual_hash = '' OR perceptual_hash IS NULL").Pluck("id", &ids).Error; err != nil {
+		return 0, 0, err
+	}
+
+	for _, id := range ids {
+		path, err := s.imagePath(library, id)
+		if err != nil || path == "" {
+			continue // 没有可用图片（如无预览图的文档）
+		}
+
+		phash, err := utils.PerceptualHashFile(path)
+		if err != nil {
+			s.logger.Warnf("[Similarity] 计算感知哈希失败 %s#%d: %v", library, id, err)
+			failed++
+			continue
+		}
+
+		if err := s.db.Model(table).Where("id = ?", id).Update("perceptual_hash", phash).Error; err != nil {
+			return updated, failed, err
+		}
+		updated++
+	}
+
+	s.logger.Infof("[Similarity] %s 感知哈希补算完成: 成功 %d, 失败 %d, 共 %d", library, updated, failed, len(ids))
+	return updated, failed, nil
+}
+
+// BackfillPalettes 为材质/资产中尚未提取主色的资源补算调色板
+func (s *Service) BackfillPalettes(library string) (updated int, failed int, err error) {
+	var ownerType string
+	switch library {
+	case LibraryTexture:
+		ownerType = palette.OwnerTexture
+	case LibraryAsset:
+		ownerType = palette.OwnerAsset
+	default:
+		return 0, 0, nil // 其他资源库不支持按颜色检索
+	}
+
+	table, _ := tableOf(library)
+	var ids []uint
+	if err := s.db.Model(table).Where("dominant_colors = '' OR dominant_colors IS NULL").Pluck("id", &ids).Error; err != nil {
+		return 0, 0, err
+	}
+
+	for _, id := range ids {
+		path, err := s.imagePath(library, id)
+		if err != nil || path == "" {
+			continue
+		}
+
+		colors, err := palette.ExtractFile(path, palette.DefaultColorCount)
+		if err != nil {
+			s.logger.Warnf("[Similarity] 提取主色失败 %s#%d: %v", library, id, err)
+			failed++
+			continue
+		}
+		if err := palette.Save(s.db, ownerType, id, colors); err != nil {
+			return updated, failed, err
+		}
+		updated++
+	}
+
+	s.logger.Infof("[Similarity] %s 主色补算完成: 成功 %d, 失败 %d, 共 %d", library, updated, failed, len(ids))
+	return updated, failed, nil
+}
+
+// imagePath 获取资源用于计算感知哈希的图片物理路径
+func (s *Service) imagePath(library string, id uint) (string, error) {
+	switch library {
+	case LibraryAsset:
+		var asset models.Asset
+		if err := s.db.First(&asset, id).Error; err != nil {
+			return "", err
+		}
+		cfg := config.AppConfig.Asset
+		path := asset.ThumbnailPath
+		if asset.Type == "image" {
+			path = asset.FilePath
+		}
+		return resolveStoredPath(path, cfg.StorageDir, cfg.NASEnabled, cfg.NASPath), nil
+
+	case LibraryModel:
+		var model models.Model
+		if err := s.db.First(&model, id).Error; err != nil {
+			return "", err
+		}
+		cfg := config.AppConfig.Model
+		return resolveStoredPath(model.ThumbnailPath, cfg.StorageDir, cfg.NASEnabled, cfg.NASPath), nil
+
+	case LibraryDocument:
+		var document models.Document
+		if err := s.db.First(&document, id).Error; err != nil {
+			return "", err
+		}
+		cfg, err := config.LoadDocumentConfig()
+		if err != nil {
+			return "", err
+		}
+		path := document.ThumbnailPath
+		switch strings.ToLower(document.Format) {
+		case "jpg", "jpeg", "png", "gif", "webp":
+			path = document.FilePath
+		}
+		return resolveStoredPath(path, cfg.StorageDir, cfg.NASEnabled, cfg.NASPath), nil
+
+	case LibraryTexture:
+		var file models.File
+		if err := s.db.Where("related_id = ? AND related_type = ? AND file_type = ?",
+			id, "Texture", "thumbnail").First(&file).Error; err != nil {
+			return "", err
+		}
+		return texture.ResolveFilePath(&file)
+	}
+	return "", ErrUnknownLibrary
+}
+
+// resolveStoredPath 将数据库中的相对路径解析为物理路径（优先本地，其次 NAS）
+func resolveStoredPath(storedPath, storageDir string, nasEnabled bool, nasPath string) string {
+	if storedPath == "" {
+		return ""
+	}
+	if _, err := os.Stat(storedPath); err == nil {
+		return storedPath
+	}
+	if nasEnabled && nasPath != "" {
+		cleanPath := strings.ReplaceAll(storedPath, "\\", "/")
+		cleanPath = strings.TrimPrefix(cleanPath, strings.ReplaceAll(storageDir, "\\", "/"))
+		return filepath.Join(nasPath, strings.TrimPrefix(cleanPath, "/"))
+	}
+	return storedPath
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -262,3 +263,26 @@ func (s *FileStorageService) GetFilePath(subPath, fileName string) (string, erro
 
 	return "", fmt.Errorf("文件不存在: %s/%s", subPath, fileName)
 }
+
+// ResolvePath 将数据库记录的相对路径（StorageDir/子路径/文件名）解析为实际文件路径（优先本地，然后NAS）
+func (s *FileStorageService) ResolvePath(relativePath string) (string, error) {
+	cleanPath := filepath.ToSlash(relativePath)
+	storageDir := strings.TrimSuffix(filepath.ToSlash(s.config.StorageDir), "/")
+	subPath := strings.TrimPrefix(strings.TrimPrefix(cleanPath, storageDir), "/")
+
+	if s.config.LocalStorageEnabled || !s.config.NASEnabled {
+		localPath := filepath.Join(s.config.StorageDir, subPath)
+		if _, err := os.Stat(localPath); err == nil {
+			return localPath, nil
+		}
+	}
+
+	if s.config.NASEnabled && s.config.NASPath != "" {
+		nasPath := filepath.Join(s.config.NASPath, subPath)
+		if _, err := os.Stat(nasPath); err == nil {
+			return nasPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("文件不存在: %s", relativePath)
+}
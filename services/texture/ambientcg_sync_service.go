@@ -20,6 +20,7 @@ type AmbientCGSyncService struct {
 	adapter         *AmbientCGAdapter
 	httpClient      *http.Client
 	downloadService *DownloadService // 添加下载服务
+	metricsService  *MetricsService
 }
 
 // NewAmbientCGSyncService 创建 AmbientCG 同步服务
@@ -32,6 +33,7 @@ func NewAmbientCGSyncService(db *gorm.DB, logger *logrus.Logger) *AmbientCGSyncS
 		logger:          logger,
 		adapter:         adapter,
 		downloadService: downloadService, // 使用下载服务
+		metricsService:  NewMetricsService(db, logger),
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second, // 增加超时到 60 秒
 		},
@@ -124,7 +126,10 @@ func (s *AmbientCGSyncService) SyncMetadata() error {
 				}
 
 				// 保存元数据
-				if err := s.saveMetadata(&mat); err != nil {
+				startedAt := time.Now()
+				err = s.saveMetadata(&mat)
+				s.metricsService.RecordSync("ambientcg", mat.AssetID, startedAt, err)
+				if err != nil {
 					s.logError("保存元数据失败 %s: %v", err, mat.AssetID)
 					mu.Lock()
 					failCount++
@@ -467,7 +472,10 @@ func (s *AmbientCGSyncService) IncrementalSync() error {
 				s.updateProgress(syncLog.ID, currentProcessed, currentProcessed, mat.AssetID)
 
 				// 保存或更新元数据
-				if err := s.saveOrUpdateMetadata(&mat); err != nil {
+				startedAt := time.Now()
+				err := s.saveOrUpdateMetadata(&mat)
+				s.metricsService.RecordSync("ambientcg", mat.AssetID, startedAt, err)
+				if err != nil {
 					s.logError("保存元数据失败 %s: %v", err, mat.AssetID)
 					mu.Lock()
 					failCount++
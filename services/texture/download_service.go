@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"go_wails_project_manager/config"
 	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/palette"
+	"go_wails_project_manager/utils"
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
@@ -142,6 +144,20 @@ func (s *DownloadService) DownloadThumbnail(textureID uint, assetID string, thum
 		return nil, fmt.Errorf("保存缩略图失败: %w", err)
 	}
 
+	// 记录预览图感知哈希，用于相似材质检索
+	if phash, err := utils.PerceptualHash(bytes.NewReader(imageData)); err != nil {
+		s.logger.Warnf("计算缩略图感知哈希失败 %s: %v", assetID, err)
+	} else {
+		s.db.Model(&models.Texture{}).Where("id = ?", textureID).Update("perceptual_hash", phash)
+	}
+
+	// 提取预览图主色，用于按颜色检索
+	if colors, err := palette.ExtractReader(bytes.NewReader(imageData), palette.DefaultColorCount); err != nil {
+		s.logger.Warnf("提取缩略图主色失败 %s: %v", assetID, err)
+	} else if err := palette.Save(s.db, palette.OwnerTexture, textureID, colors); err != nil {
+		s.logger.Warnf("保存缩略图主色失败 %s: %v", assetID, err)
+	}
+
 	totalDuration := time.Since(startTime)
 	s.logger.Infof("缩略图保存成功: %s, 总耗时: %v", file.LocalPath, totalDuration)
 	return file, nil
@@ -0,0 +1,756 @@
+package texture
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnsupportedExportFormat = errors.New("不支持的导出格式")
+	ErrTextureNotDownloaded    = errors.New("材质尚未下载")
+	ErrNoExportableMaps        = errors.New("材质没有可导出的贴图")
+	ErrExportNotPrepared       = errors.New("导出所需的贴图尚未生成")
+)
+
+// textureLocks 每个材质的贴图生成锁（所有服务实例共享），避免并发请求重复写入同一文件
+var textureLocks sync.Map
+
+// lockTexture 锁定材质的贴图生成，返回解锁函数
+func lockTexture(textureID uint) func() {
+	lock, _ := textureLocks.LoadOrStore(textureID, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// 导出格式
+const (
+	ExportFormatGLTF    = "gltf"
+	ExportFormatThreeJS = "threejs"
+	ExportFormatMtlx    = "mtlx"
+)
+
+// ORMTextureType 打包后的 ORM 贴图类型（R=AO, G=Roughness, B=Metalness）
+const ORMTextureType = "ORM"
+
+// ExportMap 导出时引用的贴图文件
+type ExportMap struct {
+	Slot         string `json:"slot"`          // Three.js 贴图类型，如 map / normalMap
+	OriginalType string `json:"original_type"` // 原始贴图类型，如 Diffuse / NormalGL
+	FileID       uint   `json:"file_id"`
+	FileName     string `json:"file_name"`
+	URL          string `json:"url"`
+	ZipPath      string `json:"zip_path"` // 压缩包内路径
+	diskPath     string
+}
+
+// MaterialExport 材质导出结果
+type MaterialExport struct {
+	AssetID    string      `json:"asset_id"`
+	Format     string      `json:"format"`
+	FileName   string      `json:"file_name"` // 材质定义文件名
+	Definition []byte      `json:"-"`         // 材质定义内容
+	Maps       []ExportMap `json:"maps"`
+	Unmapped   []string    `json:"unmapped"` // 目标格式无法表达的贴图类型
+}
+
+// ExportService 材质导出服务
+type ExportService struct {
+	db              *gorm.DB
+	logger          *logrus.Logger
+	downloadService *DownloadService
+	normalService   *NormalService
+}
+
+// NewExportService 创建材质导出服务
+func NewExportService(db *gorm.DB, logger *logrus.Logger) *ExportService {
+	return &ExportService{
+		db:              db,
+		logger:          logger,
+		downloadService: NewDownloadService(db, logger),
+		normalService:   NewNormalService(db, logger),
+	}
+}
+
+// ExportPreparation 导出前生成的贴图
+type ExportPreparation struct {
+	AssetID string       `json:"asset_id"`
+	Format  string       `json:"format"`
+	Normal  *models.File `json:"normal,omitempty"` // 指定约定的法线贴图
+	ORM     *ExportMap   `json:"orm,omitempty"`    // glTF 使用的 ORM 贴图
+}
+
+// exportOptions 校验导出格式并确定法线约定：为空时使用 OpenGL，glTF 和 Three.js 规定使用 OpenGL
+func exportOptions(format, normalConvention string) (string, string, error) {
+	format = strings.ToLower(format)
+	if format != ExportFormatGLTF && format != ExportFormatThreeJS && format != ExportFormatMtlx {
+		return "", "", ErrUnsupportedExportFormat
+	}
+	if normalConvention == "" || format == ExportFormatGLTF || format == ExportFormatThreeJS {
+		normalConvention = NormalConventionGL
+	}
+	return format, normalConvention, nil
+}
+
+// downloadedTexture 查询已下载的材质
+func (s *ExportService) downloadedTexture(assetID string) (*models.Texture, error) {
+	var texture models.Texture
+	if err := s.db.Where("asset_id = ?", assetID).First(&texture).Error; err != nil {
+		return nil, err
+	}
+	if !texture.DownloadCompleted {
+		return nil, ErrTextureNotDownloaded
+	}
+	return &texture, nil
+}
+
+// textureFiles 查询材质的贴图文件
+func (s *ExportService) textureFiles(textureID uint) ([]models.File, error) {
+	var files []models.File
+	if err := s.db.Where("related_id = ? AND related_type = ? AND file_type = ?",
+		textureID, "Texture", "texture").Order("file_name ASC").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Prepare 生成导出所需的贴图：缺失约定的法线贴图和 glTF 的 ORM 贴图，已存在时直接返回
+func (s *ExportService) Prepare(assetID, format, normalConvention string) (*ExportPreparation, error) {
+	format, normalConvention, err := exportOptions(format, normalConvention)
+	if err != nil {
+		return nil, err
+	}
+	texture, err := s.downloadedTexture(assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	preparation := &ExportPreparation{AssetID: texture.AssetID, Format: format}
+	preparation.Normal, err = s.normalService.EnsureConvention(texture, normalConvention)
+	if err != nil && err != ErrNoNormalMap {
+		return nil, fmt.Errorf("转换法线贴图失败: %w", err)
+	}
+
+	if format == ExportFormatGLTF {
+		unlock := lockTexture(texture.ID)
+		defer unlock()
+
+		files, err := s.textureFiles(texture.ID)
+		if err != nil {
+			return nil, err
+		}
+		slots, _ := s.collectMaps(files, normalConvention)
+		if preparation.ORM, err = s.ensureORM(texture, slots); err != nil {
+			return nil, fmt.Errorf("生成 ORM 贴图失败: %w", err)
+		}
+	}
+	return preparation, nil
+}
+
+// Export 生成材质定义，只读取已有的贴图，缺少导出所需的贴图时返回 ErrExportNotPrepared（先调用 Prepare 生成）
+// packaged 为 true 时贴图引用为压缩包内的相对路径，否则为可访问的完整 URL
+// normalConvention 指定法线贴图约定（gl / dx），为空时使用 OpenGL；
+// glTF 和 Three.js 规定使用 OpenGL 约定，始终导出 OpenGL 法线
+func (s *ExportService) Export(assetID, format string, packaged bool, normalConvention string) (*MaterialExport, error) {
+	format, normalConvention, err := exportOptions(format, normalConvention)
+	if err != nil {
+		return nil, err
+	}
+	texture, err := s.downloadedTexture(assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.textureFiles(texture.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots, unmapped := s.collectMaps(files, normalConvention)
+	if len(slots) == 0 {
+		return nil, ErrNoExportableMaps
+	}
+	if normal, ok := slots["normalMap"]; ok && NormalConvention(normal.OriginalType) != "" &&
+		NormalConvention(normal.OriginalType) != normalConvention {
+		return nil, ErrExportNotPrepared
+	}
+
+	// glTF 需要将 AO / 粗糙度 / 金属度合并为一张 ORM 贴图
+	if format == ExportFormatGLTF && slots[ORMTextureType] == nil &&
+		(slots["aoMap"] != nil || slots["roughnessMap"] != nil || slots["metalnessMap"] != nil) {
+		return nil, ErrExportNotPrepared
+	}
+
+	// 记录材质定义实际引用的贴图，压缩包只包含这些文件
+	referenced := make(map[string]*ExportMap)
+	uriOf := func(m *ExportMap) string {
+		referenced[m.Slot] = m
+		if packaged {
+			return m.ZipPath
+		}
+		return m.URL
+	}
+
+	export := &MaterialExport{
+		AssetID:  texture.AssetID,
+		Format:   format,
+		Unmapped: unmapped,
+	}
+
+	switch format {
+	case ExportFormatGLTF:
+		export.FileName = texture.AssetID + ".gltf"
+		export.Definition, export.Unmapped, err = buildGLTFMaterial(texture, slots, uriOf, unmapped)
+	case ExportFormatThreeJS:
+		export.FileName = texture.AssetID + ".json"
+		export.Definition, err = buildThreeJSMaterial(texture, slots, uriOf)
+	case ExportFormatMtlx:
+		export.FileName = texture.AssetID + ".mtlx"
+		export.Definition, export.Unmapped, err = buildMaterialX(texture, slots, uriOf, unmapped)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(referenced))
+	for slot := range referenced {
+		keys = append(keys, slot)
+	}
+	sort.Strings(keys)
+	for _, slot := range keys {
+		export.Maps = append(export.Maps, *referenced[slot])
+	}
+	return export, nil
+}
+
+// WriteZip 将材质定义和引用的贴图写入 zip
+func (s *ExportService) WriteZip(w io.Writer, export *MaterialExport) error {
+	archive := zip.NewWriter(w)
+
+	writer, err := archive.Create(export.FileName)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(export.Definition); err != nil {
+		return err
+	}
+
+	for _, m := range export.Maps {
+		if err := copyIntoZip(archive, m.ZipPath, m.diskPath); err != nil {
+			return fmt.Errorf("写入贴图失败 %s: %w", m.FileName, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+// collectMaps 按 Three.js 贴图类型归类文件，同一类型只保留一个
+func (s *ExportService) collectMaps(files []models.File, normalConvention string) (map[string]*ExportMap, []string) {
+	slots := make(map[string]*ExportMap)
+	unmappedSet := make(map[string]bool)
+
+	for i := range files {
+		file := &files[i]
+		originalType := file.TextureType
+		if originalType == "" {
+			originalType = models.ExtractTextureType(file.FileName)
+		}
+
+		ext := strings.ToLower(filepath.Ext(file.FileName))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			continue
+		}
+
+		slot := ""
+		switch {
+		case originalType == ORMTextureType || strings.EqualFold(originalType, "arm"):
+			slot = ORMTextureType
+		default:
+			slot = config.TextureMapping.MapToThreeJS(originalType)
+		}
+		if slot == "" || slot == "other" {
+			unmappedSet[originalType] = true
+			continue
+		}
+
+		diskPath, err := ResolveFilePath(file)
+		if err != nil {
+			s.logger.Warnf("[Texture Export] %v", err)
+			continue
+		}
+
+		candidate := &ExportMap{
+			Slot:         slot,
+			OriginalType: originalType,
+			FileID:       file.ID,
+			FileName:     file.FileName,
+			URL:          file.FullURL,
+			ZipPath:      "textures/" + file.FileName,
+			diskPath:     diskPath,
+		}
+
+		if existing, ok := slots[slot]; ok && !preferMap(candidate, existing, normalConvention) {
+			continue
+		}
+		slots[slot] = candidate
+	}
+
+	unmapped := make([]string, 0, len(unmappedSet))
+	for t := range unmappedSet {
+		unmapped = append(unmapped, t)
+	}
+	sort.Strings(unmapped)
+	return slots, unmapped
+}
+
+// preferMap 判断候选贴图是否优于已选贴图（法线贴图优先指定约定）
+func preferMap(candidate, existing *ExportMap, normalConvention string) bool {
+	if candidate.Slot == "normalMap" {
+		return NormalConvention(candidate.OriginalType) == normalConvention &&
+			NormalConvention(existing.OriginalType) != normalConvention
+	}
+	return false
+}
+
+// ensureORM 获取或生成 ORM 贴图，生成结果作为材质文件保存以便复用（调用方持有材质的生成锁）
+func (s *ExportService) ensureORM(texture *models.Texture, slots map[string]*ExportMap) (*ExportMap, error) {
+	if orm, ok := slots[ORMTextureType]; ok {
+		return orm, nil
+	}
+
+	ao, rough, metal := slots["aoMap"], slots["roughnessMap"], slots["metalnessMap"]
+	if ao == nil && rough == nil && metal == nil {
+		return nil, nil
+	}
+
+	data, err := packORM(ao, rough, metal)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := fmt.Sprintf("%s_%s.png", texture.AssetID, ORMTextureType)
+	file, err := s.downloadService.saveFile(texture.ID, "Texture", "texture", data, fileName, texture.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	if file.TextureType != ORMTextureType {
+		// 文件类型和贴图关联一起提交，失败时下次导出准备会重新登记
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(file).Update("texture_type", ORMTextureType).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.TextureFile{
+				TextureID:  texture.ID,
+				FileID:     file.ID,
+				MapType:    ORMTextureType,
+				Resolution: fmt.Sprintf("%dx%d", file.Width, file.Height),
+			}).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.logger.Infof("[Texture Export] 已生成 ORM 贴图: %s", file.CDNPath)
+	}
+
+	// 重新查询以获得完整 URL
+	if err := s.db.First(file, file.ID).Error; err != nil {
+		return nil, err
+	}
+
+	diskPath, err := ResolveFilePath(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportMap{
+		Slot:         ORMTextureType,
+		OriginalType: ORMTextureType,
+		FileID:       file.ID,
+		FileName:     file.FileName,
+		URL:          file.FullURL,
+		ZipPath:      "textures/" + file.FileName,
+		diskPath:     diskPath,
+	}, nil
+}
+
+// packORM 合并 AO(R) / Roughness(G) / Metalness(B)，缺失通道分别填充 1 / 1 / 0
+func packORM(ao, rough, metal *ExportMap) ([]byte, error) {
+	channels := make([]*image.Gray, 3)
+	width, height := 0, 0
+
+	for i, m := range []*ExportMap{ao, rough, metal} {
+		if m == nil {
+			continue
+		}
+		img, err := imaging.Open(m.diskPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取贴图失败 %s: %w", m.FileName, err)
+		}
+		bounds := img.Bounds()
+		if bounds.Dx() > width {
+			width, height = bounds.Dx(), bounds.Dy()
+		}
+		channels[i] = toGray(img)
+	}
+
+	defaults := []uint8{255, 255, 0}
+	packed := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i, ch := range channels {
+		if ch != nil && (ch.Bounds().Dx() != width || ch.Bounds().Dy() != height) {
+			channels[i] = toGray(imaging.Resize(ch, width, height, imaging.Lanczos))
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var px [3]uint8
+			for i, ch := range channels {
+				if ch == nil {
+					px[i] = defaults[i]
+				} else {
+					px[i] = ch.GrayAt(x, y).Y
+				}
+			}
+			packed.SetNRGBA(x, y, color.NRGBA{R: px[0], G: px[1], B: px[2], A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, packed); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toGray 转换为灰度图（从零点开始的坐标）
+func toGray(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray.Set(x, y, color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)))
+		}
+	}
+	return gray
+}
+
+// ResolveFilePath 解析贴图文件在磁盘上的路径（本地优先，其次 NAS）
+func ResolveFilePath(file *models.File) (string, error) {
+	if file.LocalPath != "" {
+		if _, err := os.Stat(file.LocalPath); err == nil {
+			return file.LocalPath, nil
+		}
+	}
+
+	if config.AppConfig.Texture.NASEnabled && config.AppConfig.Texture.NASPath != "" && file.CDNPath != "" {
+		nasPath := filepath.Join(config.AppConfig.Texture.NASPath, filepath.FromSlash(file.CDNPath))
+		if _, err := os.Stat(nasPath); err == nil {
+			return nasPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("贴图文件不存在: %s", file.FileName)
+}
+
+// copyIntoZip 将磁盘文件写入 zip
+func copyIntoZip(archive *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// ==================== glTF ====================
+
+type gltfTextureInfo struct {
+	Index    int      `json:"index"`
+	Scale    *float64 `json:"scale,omitempty"`
+	Strength *float64 `json:"strength,omitempty"`
+}
+
+type gltfPBR struct {
+	BaseColorTexture         *gltfTextureInfo `json:"baseColorTexture,omitempty"`
+	MetallicRoughnessTexture *gltfTextureInfo `json:"metallicRoughnessTexture,omitempty"`
+	MetallicFactor           float64          `json:"metallicFactor"`
+	RoughnessFactor          float64          `json:"roughnessFactor"`
+}
+
+type gltfMaterial struct {
+	Name                 string                 `json:"name"`
+	PBRMetallicRoughness gltfPBR                `json:"pbrMetallicRoughness"`
+	NormalTexture        *gltfTextureInfo       `json:"normalTexture,omitempty"`
+	OcclusionTexture     *gltfTextureInfo       `json:"occlusionTexture,omitempty"`
+	Extras               map[string]interface{} `json:"extras,omitempty"`
+}
+
+type gltfDocument struct {
+	Asset     map[string]string   `json:"asset"`
+	Materials []gltfMaterial      `json:"materials"`
+	Textures  []map[string]int    `json:"textures"`
+	Images    []map[string]string `json:"images"`
+	Samplers  []map[string]int    `json:"samplers"`
+}
+
+// buildGLTFMaterial 生成 glTF 2.0 材质文档
+func buildGLTFMaterial(texture *models.Texture, slots map[string]*ExportMap, uriOf func(*ExportMap) string, unmapped []string) ([]byte, []string, error) {
+	doc := gltfDocument{
+		Asset:    map[string]string{"version": "2.0", "generator": "media-manager-server"},
+		Textures: []map[string]int{},
+		Images:   []map[string]string{},
+		// LINEAR / LINEAR_MIPMAP_LINEAR / REPEAT
+		Samplers: []map[string]int{{"magFilter": 9729, "minFilter": 9987, "wrapS": 10497, "wrapT": 10497}},
+	}
+
+	addTexture := func(m *ExportMap) *gltfTextureInfo {
+		doc.Images = append(doc.Images, map[string]string{"name": m.OriginalType, "uri": uriOf(m)})
+		index := len(doc.Textures)
+		doc.Textures = append(doc.Textures, map[string]int{"sampler": 0, "source": len(doc.Images) - 1})
+		return &gltfTextureInfo{Index: index}
+	}
+
+	material := gltfMaterial{
+		Name: texture.Name,
+		PBRMetallicRoughness: gltfPBR{
+			MetallicFactor:  1,
+			RoughnessFactor: 1,
+		},
+	}
+
+	if m, ok := slots["map"]; ok {
+		material.PBRMetallicRoughness.BaseColorTexture = addTexture(m)
+	}
+	if m, ok := slots[ORMTextureType]; ok {
+		info := addTexture(m)
+		if slots["roughnessMap"] != nil || slots["metalnessMap"] != nil || strings.EqualFold(m.OriginalType, "arm") {
+			material.PBRMetallicRoughness.MetallicRoughnessTexture = info
+		} else {
+			// 没有粗糙度和金属度时使用非金属材质
+			material.PBRMetallicRoughness.MetallicFactor = 0
+		}
+		if slots["aoMap"] != nil || strings.EqualFold(m.OriginalType, "arm") {
+			material.OcclusionTexture = &gltfTextureInfo{Index: info.Index}
+		}
+	} else {
+		material.PBRMetallicRoughness.MetallicFactor = 0
+	}
+	if m, ok := slots["normalMap"]; ok {
+		material.NormalTexture = addTexture(m)
+	}
+
+	// glTF 核心规范无法表达的贴图
+	handled := map[string]bool{"map": true, "normalMap": true, "aoMap": true, "roughnessMap": true, "metalnessMap": true, ORMTextureType: true}
+	for slot, m := range slots {
+		if !handled[slot] {
+			unmapped = append(unmapped, m.OriginalType)
+		}
+	}
+	sort.Strings(unmapped)
+
+	doc.Materials = []gltfMaterial{material}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	return data, unmapped, err
+}
+
+// ==================== Three.js ====================
+
+// buildThreeJSMaterial 生成 Three.js MeshStandardMaterial 参数
+func buildThreeJSMaterial(texture *models.Texture, slots map[string]*ExportMap, uriOf func(*ExportMap) string) ([]byte, error) {
+	maps := make(map[string]string)
+	originalTypes := make(map[string]string)
+	for slot, m := range slots {
+		if slot == ORMTextureType {
+			continue
+		}
+		maps[slot] = uriOf(m)
+		originalTypes[slot] = m.OriginalType
+	}
+
+	// 已打包的 ARM 贴图同时作为 AO / 粗糙度 / 金属度贴图
+	if m, ok := slots[ORMTextureType]; ok && strings.EqualFold(m.OriginalType, "arm") {
+		for _, slot := range []string{"aoMap", "roughnessMap", "metalnessMap"} {
+			if _, exists := maps[slot]; !exists {
+				maps[slot] = uriOf(m)
+				originalTypes[slot] = m.OriginalType
+			}
+		}
+	}
+
+	material := map[string]interface{}{
+		"type":     "MeshStandardMaterial",
+		"name":     texture.Name,
+		"maps":     maps,
+		"userData": map[string]interface{}{"asset_id": texture.AssetID, "source": texture.Source, "original_types": originalTypes},
+	}
+
+	if _, ok := maps["metalnessMap"]; ok {
+		material["metalness"] = 1
+	} else {
+		material["metalness"] = 0
+	}
+	material["roughness"] = 1
+
+	if _, ok := slots["normalMap"]; ok {
+		material["normalScale"] = []float64{1, 1}
+	}
+	if _, ok := maps["alphaMap"]; ok {
+		material["transparent"] = true
+	}
+	if _, ok := maps["displacementMap"]; ok {
+		material["displacementScale"] = 0.1
+	}
+
+	return json.MarshalIndent(material, "", "  ")
+}
+
+// ==================== MaterialX ====================
+
+type mtlxInput struct {
+	XMLName    xml.Name `xml:"input"`
+	Name       string   `xml:"name,attr"`
+	Type       string   `xml:"type,attr"`
+	Value      string   `xml:"value,attr,omitempty"`
+	NodeName   string   `xml:"nodename,attr,omitempty"`
+	ColorSpace string   `xml:"colorspace,attr,omitempty"`
+}
+
+type mtlxNode struct {
+	XMLName xml.Name
+	Name    string      `xml:"name,attr"`
+	Type    string      `xml:"type,attr"`
+	Inputs  []mtlxInput `xml:"input"`
+}
+
+type mtlxDocument struct {
+	XMLName xml.Name   `xml:"materialx"`
+	Version string     `xml:"version,attr"`
+	Nodes   []mtlxNode `xml:",any"`
+}
+
+// buildMaterialX 生成 MaterialX standard_surface 材质
+func buildMaterialX(texture *models.Texture, slots map[string]*ExportMap, uriOf func(*ExportMap) string, unmapped []string) ([]byte, []string, error) {
+	doc := mtlxDocument{Version: "1.38"}
+	name := mtlxName(texture.AssetID)
+	shader := mtlxNode{XMLName: xml.Name{Local: "standard_surface"}, Name: "SR_" + name, Type: "surfaceshader"}
+	material := mtlxNode{
+		XMLName: xml.Name{Local: "surfacematerial"},
+		Name:    "M_" + name,
+		Type:    "material",
+		Inputs:  []mtlxInput{{Name: "surfaceshader", Type: "surfaceshader", NodeName: shader.Name}},
+	}
+
+	addImage := func(nodeName, valueType, colorSpace string, m *ExportMap) {
+		doc.Nodes = append(doc.Nodes, mtlxNode{
+			XMLName: xml.Name{Local: "image"},
+			Name:    nodeName,
+			Type:    valueType,
+			Inputs:  []mtlxInput{{Name: "file", Type: "filename", Value: uriOf(m), ColorSpace: colorSpace}},
+		})
+	}
+
+	// 贴图类型 -> standard_surface 输入
+	inputs := []struct {
+		slot, input, valueType, colorSpace string
+	}{
+		{"map", "base_color", "color3", "srgb_texture"},
+		{"roughnessMap", "specular_roughness", "float", ""},
+		{"metalnessMap", "metalness", "float", ""},
+		{"specularMap", "specular", "float", ""},
+		{"alphaMap", "opacity", "color3", ""},
+	}
+	for _, in := range inputs {
+		m, ok := slots[in.slot]
+		if !ok {
+			continue
+		}
+		nodeName := in.input + "_image"
+		addImage(nodeName, in.valueType, in.colorSpace, m)
+		shader.Inputs = append(shader.Inputs, mtlxInput{Name: in.input, Type: in.valueType, NodeName: nodeName})
+	}
+
+	if m, ok := slots["normalMap"]; ok {
+		addImage("normal_image", "vector3", "", m)
+		normalInputs := []mtlxInput{{Name: "in", Type: "vector3", NodeName: "normal_image"}}
+		if NormalConvention(m.OriginalType) == NormalConventionDX {
+			// MaterialX 使用 OpenGL 约定，DirectX 法线翻转 Y 分量
+			normalInputs = []mtlxInput{{Name: "in", Type: "vector3", NodeName: "normal_flip_y"}}
+			doc.Nodes = append(doc.Nodes, mtlxNode{
+				XMLName: xml.Name{Local: "multiply"},
+				Name:    "normal_flip_y",
+				Type:    "vector3",
+				Inputs: []mtlxInput{
+					{Name: "in1", Type: "vector3", NodeName: "normal_image"},
+					{Name: "in2", Type: "vector3", Value: "1, -1, 1"},
+				},
+			})
+		}
+		doc.Nodes = append(doc.Nodes, mtlxNode{XMLName: xml.Name{Local: "normalmap"}, Name: "normalmap", Type: "vector3", Inputs: normalInputs})
+		shader.Inputs = append(shader.Inputs, mtlxInput{Name: "normal", Type: "vector3", NodeName: "normalmap"})
+	}
+
+	if m, ok := slots["displacementMap"]; ok {
+		addImage("displacement_image", "float", "", m)
+		doc.Nodes = append(doc.Nodes, mtlxNode{
+			XMLName: xml.Name{Local: "displacement"},
+			Name:    "displacement",
+			Type:    "displacementshader",
+			Inputs:  []mtlxInput{{Name: "displacement", Type: "float", NodeName: "displacement_image"}},
+		})
+		material.Inputs = append(material.Inputs, mtlxInput{Name: "displacementshader", Type: "displacementshader", NodeName: "displacement"})
+	}
+
+	// standard_surface 没有对应输入的贴图
+	for _, slot := range []string{"aoMap", "anisotropyMap", "reflectionMap"} {
+		if m, ok := slots[slot]; ok {
+			unmapped = append(unmapped, m.OriginalType)
+		}
+	}
+	sort.Strings(unmapped)
+
+	doc.Nodes = append(doc.Nodes, shader, material)
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]byte(xml.Header), data...), unmapped, nil
+}
+
+// mtlxName 生成合法的 MaterialX 节点名
+func mtlxName(assetID string) string {
+	var b strings.Builder
+	for _, r := range assetID {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
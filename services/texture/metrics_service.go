@@ -0,0 +1,413 @@
+package texture
+
+import (
+	"errors"
+	"go_wails_project_manager/models"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 指标事件类型
+const (
+	MetricsKindSync     = "sync"
+	MetricsKindDownload = "download"
+)
+
+const (
+	metricsDateLayout     = "2006-01-02"
+	metricsRollupInterval = time.Hour
+	metricsDefaultDays    = 30  // 默认查询最近 30 天
+	metricsMaxDays        = 366 // 单次查询最大天数
+	metricsEventRetention = 90  // 事件明细保留天数
+)
+
+var (
+	ErrInvalidMetricsDate  = errors.New("无效的日期，格式应为 YYYY-MM-DD")
+	ErrInvalidMetricsRange = errors.New("无效的时间范围")
+)
+
+// SourceMetricsPoint 数据源单日指标
+type SourceMetricsPoint struct {
+	Date                string  `json:"date"`
+	SyncCount           int     `json:"sync_count"`
+	SyncFailed          int     `json:"sync_failed"`
+	DownloadCount       int     `json:"download_count"`
+	DownloadFailed      int     `json:"download_failed"`
+	DownloadSize        int64   `json:"download_size"`
+	AvgSyncTime         float64 `json:"avg_sync_time"`
+	AvgDownloadTime     float64 `json:"avg_download_time"`
+	SyncFailureRate     float64 `json:"sync_failure_rate"`
+	DownloadFailureRate float64 `json:"download_failure_rate"`
+	FailureRate         float64 `json:"failure_rate"`
+}
+
+// SourceMetricsSummary 数据源区间汇总
+type SourceMetricsSummary struct {
+	Source         string               `json:"source"`
+	SyncCount      int                  `json:"sync_count"`
+	SyncFailed     int                  `json:"sync_failed"`
+	DownloadCount  int                  `json:"download_count"`
+	DownloadFailed int                  `json:"download_failed"`
+	DownloadSize   int64                `json:"download_size"`
+	FailureRate    float64              `json:"failure_rate"`
+	Series         []SourceMetricsPoint `json:"series"`
+}
+
+// MetricsSeries 指标时间序列
+type MetricsSeries struct {
+	From    string                  `json:"from"`
+	To      string                  `json:"to"`
+	Daily   []models.TextureMetrics `json:"daily"`
+	Sources []SourceMetricsSummary  `json:"sources"`
+}
+
+// eventAggregate 事件聚合行
+type eventAggregate struct {
+	Source    string
+	Kind      string
+	Total     int
+	Failed    int
+	Bytes     int64
+	AvgTimeMs float64
+}
+
+// MetricsService 材质同步健康指标服务
+type MetricsService struct {
+	db       *gorm.DB
+	logger   *logrus.Logger
+	ticker   *time.Ticker
+	stopChan chan bool
+}
+
+// NewMetricsService 创建指标服务
+func NewMetricsService(db *gorm.DB, logger *logrus.Logger) *MetricsService {
+	return &MetricsService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// RecordSync 记录单个材质的同步结果
+func (s *MetricsService) RecordSync(source, assetID string, startedAt time.Time, err error) {
+	s.record(MetricsKindSync, source, assetID, 0, startedAt, err)
+}
+
+// RecordDownload 记录单个材质的下载结果
+func (s *MetricsService) RecordDownload(source, assetID string, bytes int64, startedAt time.Time, err error) {
+	s.record(MetricsKindDownload, source, assetID, bytes, startedAt, err)
+}
+
+// record 写入事件明细（失败只记日志，不影响业务流程）
+func (s *MetricsService) record(kind, source, assetID string, bytes int64, startedAt time.Time, err error) {
+	if source == "" {
+		source = "polyhaven" // 兼容未标记来源的旧数据
+	}
+
+	event := models.TextureSyncEvent{
+		Date:       startedAt.Format(metricsDateLayout),
+		Source:     source,
+		Kind:       kind,
+		AssetID:    assetID,
+		Success:    err == nil,
+		Bytes:      bytes,
+		DurationMs: time.Since(startedAt).Milliseconds(),
+	}
+	if err != nil {
+		event.ErrorMsg = err.Error()
+	}
+
+	if err := s.db.Create(&event).Error; err != nil {
+		s.logger.Warnf("[Metrics] 记录%s事件失败 %s: %v", kind, assetID, err)
+	}
+}
+
+// Rollup 将指定日期的事件明细汇总到 TextureMetrics 和 TextureSourceMetrics
+func (s *MetricsService) Rollup(date string) error {
+	day, err := time.ParseInLocation(metricsDateLayout, date, time.Local)
+	if err != nil {
+		return ErrInvalidMetricsDate
+	}
+	date = day.Format(metricsDateLayout)
+
+	var rows []eventAggregate
+	if err := s.db.Model(&models.TextureSyncEvent{}).
+		Select(`source, kind, COUNT(*) AS total,
+			SUM(CASE WHEN success THEN 0 ELSE 1 END) AS failed,
+			COALESCE(SUM(CASE WHEN success THEN bytes ELSE 0 END), 0) AS bytes,
+			COALESCE(AVG(CASE WHEN success THEN duration_ms END), 0) AS avg_time_ms`).
+		Where("date = ?", date).
+		Group("source, kind").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		perSource := make(map[string]*models.TextureSourceMetrics)
+		daily := models.TextureMetrics{Date: date}
+		var downloadTime float64
+
+		for _, row := range rows {
+			sm, ok := perSource[row.Source]
+			if !ok {
+				sm = &models.TextureSourceMetrics{Date: date, Source: row.Source}
+				perSource[row.Source] = sm
+			}
+
+			switch row.Kind {
+			case MetricsKindSync:
+				sm.SyncCount = row.Total
+				sm.SyncFailed = row.Failed
+				sm.AvgSyncTime = row.AvgTimeMs
+			case MetricsKindDownload:
+				sm.DownloadCount = row.Total
+				sm.DownloadFailed = row.Failed
+				sm.DownloadSize = row.Bytes
+				sm.AvgDownloadTime = row.AvgTimeMs
+
+				succeeded := row.Total - row.Failed
+				daily.DownloadCount += succeeded
+				daily.DownloadSize += row.Bytes
+				downloadTime += row.AvgTimeMs * float64(succeeded)
+			}
+			daily.FailedCount += row.Failed
+		}
+		if daily.DownloadCount > 0 {
+			daily.AvgDownloadTime = downloadTime / float64(daily.DownloadCount)
+		}
+
+		for _, sm := range perSource {
+			var existing models.TextureSourceMetrics
+			if err := tx.Where("date = ? AND source = ?", date, sm.Source).
+				FirstOrCreate(&existing, models.TextureSourceMetrics{Date: date, Source: sm.Source}).Error; err != nil {
+				return err
+			}
+			sm.ID = existing.ID
+			sm.CreatedAt = existing.CreatedAt
+			if err := tx.Save(sm).Error; err != nil {
+				return err
+			}
+		}
+
+		var metrics models.TextureMetrics
+		result := tx.Where("date = ?", date).Limit(1).Find(&metrics)
+		if result.Error != nil {
+			return result.Error
+		}
+		isNew := result.RowsAffected == 0
+
+		metrics.Date = date
+		metrics.DownloadCount = daily.DownloadCount
+		metrics.DownloadSize = daily.DownloadSize
+		metrics.FailedCount = daily.FailedCount
+		metrics.AvgDownloadTime = daily.AvgDownloadTime
+
+		// 库存总量为快照，只在当天或首次生成时刷新，避免回溯汇总时覆盖历史值
+		if isNew || date == time.Now().Format(metricsDateLayout) {
+			if err := s.fillTotals(tx, &metrics); err != nil {
+				return err
+			}
+		}
+
+		return tx.Save(&metrics).Error
+	})
+}
+
+// fillTotals 统计材质库当前总量
+func (s *MetricsService) fillTotals(tx *gorm.DB, metrics *models.TextureMetrics) error {
+	var totalTextures int64
+	if err := tx.Model(&models.Texture{}).Count(&totalTextures).Error; err != nil {
+		return err
+	}
+
+	var files struct {
+		Count int64
+		Size  int64
+	}
+	if err := tx.Model(&models.File{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(file_size), 0) AS size").
+		Where("related_type = ?", "Texture").
+		Scan(&files).Error; err != nil {
+		return err
+	}
+
+	metrics.TotalTextures = int(totalTextures)
+	metrics.TotalFiles = int(files.Count)
+	metrics.TotalSize = files.Size
+	return nil
+}
+
+// RollupRecent 汇总今天和昨天的指标（跨天后补全昨天的数据），并清理过期事件
+func (s *MetricsService) RollupRecent() {
+	now := time.Now()
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if err := s.Rollup(day.Format(metricsDateLayout)); err != nil {
+			s.logger.Errorf("[Metrics] 汇总 %s 指标失败: %v", day.Format(metricsDateLayout), err)
+		}
+	}
+
+	cutoff := now.AddDate(0, 0, -metricsEventRetention).Format(metricsDateLayout)
+	if err := s.db.Where("date < ?", cutoff).Delete(&models.TextureSyncEvent{}).Error; err != nil {
+		s.logger.Warnf("[Metrics] 清理过期事件失败: %v", err)
+	}
+}
+
+// StartRollupJob 启动定时汇总任务
+func (s *MetricsService) StartRollupJob() {
+	if s.ticker != nil {
+		return
+	}
+
+	s.ticker = time.NewTicker(metricsRollupInterval)
+	s.stopChan = make(chan bool)
+	go func() {
+		s.RollupRecent()
+		for {
+			select {
+			case <-s.ticker.C:
+				s.RollupRecent()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopRollupJob 停止定时汇总任务
+func (s *MetricsService) StopRollupJob() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+		close(s.stopChan)
+		s.ticker = nil
+	}
+}
+
+// Series 查询时间范围内的每日指标和各数据源失败率，from/to 为空时默认最近 30 天
+func (s *MetricsService) Series(from, to string) (*MetricsSeries, error) {
+	start, end, err := metricsRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	fromDate, toDate := start.Format(metricsDateLayout), end.Format(metricsDateLayout)
+
+	// 范围包含今天时先汇总，保证看板数据实时
+	if today := time.Now().Format(metricsDateLayout); today >= fromDate && today <= toDate {
+		if err := s.Rollup(today); err != nil {
+			return nil, err
+		}
+	}
+
+	var dailyRows []models.TextureMetrics
+	if err := s.db.Where("date >= ? AND date <= ?", fromDate, toDate).
+		Order("date ASC").Find(&dailyRows).Error; err != nil {
+		return nil, err
+	}
+	var sourceRows []models.TextureSourceMetrics
+	if err := s.db.Where("date >= ? AND date <= ?", fromDate, toDate).
+		Order("date ASC").Find(&sourceRows).Error; err != nil {
+		return nil, err
+	}
+
+	dailyByDate := make(map[string]models.TextureMetrics, len(dailyRows))
+	for _, row := range dailyRows {
+		dailyByDate[row.Date] = row
+	}
+	sourceByKey := make(map[string]map[string]models.TextureSourceMetrics)
+	for _, row := range sourceRows {
+		if sourceByKey[row.Source] == nil {
+			sourceByKey[row.Source] = make(map[string]models.TextureSourceMetrics)
+		}
+		sourceByKey[row.Source][row.Date] = row
+	}
+
+	names := make([]string, 0, len(sourceByKey))
+	for name := range sourceByKey {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	series := &MetricsSeries{From: fromDate, To: toDate, Daily: []models.TextureMetrics{}}
+	summaries := make([]SourceMetricsSummary, len(names))
+	for i, name := range names {
+		summaries[i] = SourceMetricsSummary{Source: name, Series: []SourceMetricsPoint{}}
+	}
+
+	// 逐日补齐缺失日期，便于前端直接绘图
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(metricsDateLayout)
+
+		daily, ok := dailyByDate[date]
+		if !ok {
+			daily = models.TextureMetrics{Date: date}
+		}
+		series.Daily = append(series.Daily, daily)
+
+		for i, name := range names {
+			row := sourceByKey[name][date]
+			summary := &summaries[i]
+			summary.SyncCount += row.SyncCount
+			summary.SyncFailed += row.SyncFailed
+			summary.DownloadCount += row.DownloadCount
+			summary.DownloadFailed += row.DownloadFailed
+			summary.DownloadSize += row.DownloadSize
+			summary.Series = append(summary.Series, SourceMetricsPoint{
+				Date:                date,
+				SyncCount:           row.SyncCount,
+				SyncFailed:          row.SyncFailed,
+				DownloadCount:       row.DownloadCount,
+				DownloadFailed:      row.DownloadFailed,
+				DownloadSize:        row.DownloadSize,
+				AvgSyncTime:         row.AvgSyncTime,
+				AvgDownloadTime:     row.AvgDownloadTime,
+				SyncFailureRate:     failureRate(row.SyncFailed, row.SyncCount),
+				DownloadFailureRate: failureRate(row.DownloadFailed, row.DownloadCount),
+				FailureRate:         failureRate(row.SyncFailed+row.DownloadFailed, row.SyncCount+row.DownloadCount),
+			})
+		}
+	}
+
+	for i := range summaries {
+		summaries[i].FailureRate = failureRate(summaries[i].SyncFailed+summaries[i].DownloadFailed,
+			summaries[i].SyncCount+summaries[i].DownloadCount)
+	}
+	series.Sources = summaries
+	return series, nil
+}
+
+// metricsRange 解析查询时间范围
+func metricsRange(from, to string) (time.Time, time.Time, error) {
+	today, _ := time.ParseInLocation(metricsDateLayout, time.Now().Format(metricsDateLayout), time.Local)
+
+	end := today
+	if to != "" {
+		parsed, err := time.ParseInLocation(metricsDateLayout, to, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidMetricsDate
+		}
+		end = parsed
+	}
+
+	start := end.AddDate(0, 0, -(metricsDefaultDays - 1))
+	if from != "" {
+		parsed, err := time.ParseInLocation(metricsDateLayout, from, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidMetricsDate
+		}
+		start = parsed
+	}
+
+	if start.After(end) || end.Sub(start) >= metricsMaxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, ErrInvalidMetricsRange
+	}
+	return start, end, nil
+}
+
+// failureRate 计算失败率（0-1）
+func failureRate(failed, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(failed) / float64(total)
+}
@@ -0,0 +1,301 @@
+package texture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+	"go_wails_project_manager/utils"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidNormalConvention = errors.New("无效的法线约定")
+	ErrNoNormalMap             = errors.New("材质没有法线贴图")
+)
+
+// 法线贴图约定
+const (
+	NormalConventionGL = "gl"
+	NormalConventionDX = "dx"
+)
+
+// NormalConvention 根据原始贴图类型判断法线约定（nor_gl / NormalGL -> gl，nor_dx / NormalDX -> dx）
+func NormalConvention(originalType string) string {
+	lower := strings.ToLower(originalType)
+	switch {
+	case strings.HasSuffix(lower, "gl"):
+		return NormalConventionGL
+	case strings.HasSuffix(lower, "dx"):
+		return NormalConventionDX
+	}
+	return ""
+}
+
+// ParseNormalConvention 解析请求中的法线约定参数（支持 gl/opengl、dx/directx）
+func ParseNormalConvention(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return "", nil
+	case "gl", "opengl":
+		return NormalConventionGL, nil
+	case "dx", "directx":
+		return NormalConventionDX, nil
+	}
+	return "", ErrInvalidNormalConvention
+}
+
+// swapNormalType 生成另一约定的贴图类型名，保持原有大小写风格
+// 例如: nor_dx -> nor_gl, NormalGL -> NormalDX
+func swapNormalType(originalType string) string {
+	suffix := originalType[len(originalType)-2:]
+	base := originalType[:len(originalType)-2]
+
+	var swapped string
+	switch strings.ToLower(suffix) {
+	case NormalConventionGL:
+		swapped = "dx"
+	case NormalConventionDX:
+		swapped = "gl"
+	default:
+		return originalType
+	}
+	if suffix == strings.ToUpper(suffix) {
+		swapped = strings.ToUpper(swapped)
+	}
+	return base + swapped
+}
+
+// NormalService 法线贴图约定转换服务
+type NormalService struct {
+	db              *gorm.DB
+	logger          *logrus.Logger
+	downloadService *DownloadService
+}
+
+// NewNormalService 创建法线贴图转换服务
+func NewNormalService(db *gorm.DB, logger *logrus.Logger) *NormalService {
+	return &NormalService{
+		db:              db,
+		logger:          logger,
+		downloadService: NewDownloadService(db, logger),
+	}
+}
+
+// normalFiles 获取材质的法线贴图文件
+func (s *NormalService) normalFiles(textureID uint) ([]models.File, error) {
+	var files []models.File
+	if err := s.db.Where("related_id = ? AND related_type = ? AND file_type = ?",
+		textureID, "Texture", "texture").Order("file_name ASC").Find(&files).Error; err != nil {
+		return nil, err
+	}
+
+	normals := make([]models.File, 0, 2)
+	for _, file := range files {
+		if IsNormalFile(&file) {
+			normals = append(normals, file)
+		}
+	}
+	return normals, nil
+}
+
+// IsNormalFile 判断文件是否为区分约定的法线贴图
+func IsNormalFile(file *models.File) bool {
+	return NormalConvention(fileTextureType(file)) != "" &&
+		config.TextureMapping.MapToThreeJS(fileTextureType(file)) == "normalMap"
+}
+
+// EnsureConvention 确保材质存在指定约定的法线贴图，缺失时由另一约定转换生成
+func (s *NormalService) EnsureConvention(texture *models.Texture, convention string) (*models.File, error) {
+	if convention != NormalConventionGL && convention != NormalConventionDX {
+		return nil, ErrInvalidNormalConvention
+	}
+
+	unlock := lockTexture(texture.ID)
+	defer unlock()
+
+	normals, err := s.normalFiles(texture.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(normals) == 0 {
+		return nil, ErrNoNormalMap
+	}
+
+	for i := range normals {
+		if NormalConvention(fileTextureType(&normals[i])) == convention {
+			return &normals[i], nil
+		}
+	}
+
+	return s.convert(texture, &normals[0])
+}
+
+// ConvertMissing 为只有一种约定法线贴图的材质生成另一种约定，已完整时返回 nil
+func (s *NormalService) ConvertMissing(texture *models.Texture) (*models.File, error) {
+	unlock := lockTexture(texture.ID)
+	defer unlock()
+
+	normals, err := s.normalFiles(texture.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	conventions := make(map[string]bool)
+	for i := range normals {
+		conventions[NormalConvention(fileTextureType(&normals[i]))] = true
+	}
+	if len(normals) == 0 || (conventions[NormalConventionGL] && conventions[NormalConventionDX]) {
+		return nil, nil
+	}
+
+	return s.convert(texture, &normals[0])
+}
+
+// ConvertAll 为所有已下载材质补齐缺失的法线约定
+func (s *NormalService) ConvertAll() (converted int, failed int, err error) {
+	var textures []models.Texture
+	if err := s.db.Where("download_completed = ?", true).Find(&textures).Error; err != nil {
+		return 0, 0, err
+	}
+
+	for i := range textures {
+		file, err := s.ConvertMissing(&textures[i])
+		if err != nil {
+			s.logger.Warnf("[Normal Convert] 转换失败 %s: %v", textures[i].AssetID, err)
+			failed++
+			continue
+		}
+		if file != nil {
+			converted++
+		}
+	}
+
+	s.logger.Infof("[Normal Convert] 批量转换完成: 成功 %d, 失败 %d, 共 %d 个材质", converted, failed, len(textures))
+	return converted, failed, nil
+}
+
+// convert 反转绿色通道生成另一约定的法线贴图，并保存为材质文件
+func (s *NormalService) convert(texture *models.Texture, source *models.File) (*models.File, error) {
+	sourceType := fileTextureType(source)
+	targetType := swapNormalType(sourceType)
+
+	sourcePath, err := ResolveFilePath(source)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := imaging.Open(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取法线贴图失败: %w", err)
+	}
+	converted := utils.InvertGreenChannel(img)
+
+	// 保持原文件格式，法线贴图使用高质量 JPEG 避免明显的压缩误差
+	var buf bytes.Buffer
+	ext := strings.ToLower(filepath.Ext(source.FileName))
+	if ext == ".jpg" || ext == ".jpeg" {
+		err = jpeg.Encode(&buf, converted, &jpeg.Options{Quality: 95})
+	} else {
+		ext = ".png"
+		err = png.Encode(&buf, converted)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("编码法线贴图失败: %w", err)
+	}
+
+	fileName := strings.TrimSuffix(source.FileName, filepath.Ext(source.FileName))
+	if idx := strings.LastIndex(fileName, sourceType); idx != -1 {
+		fileName = fileName[:idx] + targetType + fileName[idx+len(sourceType):]
+	} else {
+		fileName = fileName + "_" + targetType
+	}
+	fileName += ext
+
+	file, err := s.downloadService.saveFile(texture.ID, "Texture", "texture", buf.Bytes(), fileName, texture.AssetID)
+	if err != nil {
+		return nil, fmt.Errorf("保存法线贴图失败: %w", err)
+	}
+
+	if file.TextureType != targetType {
+		// 更新材质的贴图类型列表
+		types := strings.Split(texture.TextureTypes, ",")
+		if texture.TextureTypes == "" {
+			types = nil
+		}
+		types = append(types, targetType)
+
+		// 文件类型、贴图关联和材质的贴图类型列表一起提交，失败时下次转换会重新登记
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(file).Update("texture_type", targetType).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.TextureFile{
+				TextureID:  texture.ID,
+				FileID:     file.ID,
+				MapType:    targetType,
+				Resolution: fmt.Sprintf("%dx%d", file.Width, file.Height),
+			}).Error; err != nil {
+				return fmt.Errorf("创建贴图关联失败: %w", err)
+			}
+			if err := tx.Model(texture).Update("texture_types", strings.Join(types, ",")).Error; err != nil {
+				return fmt.Errorf("更新贴图类型失败: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		texture.TextureTypes = strings.Join(types, ",")
+
+		s.logger.Infof("[Normal Convert] %s: %s -> %s (%s)", texture.AssetID, sourceType, targetType, file.CDNPath)
+	}
+
+	if err := s.db.First(file, file.ID).Error; err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// FilterNormalFiles 只保留指定约定的法线贴图；材质缺少该约定时保留原有法线贴图
+func FilterNormalFiles(files []models.File, convention string) []models.File {
+	if convention == "" {
+		return files
+	}
+
+	hasConvention := false
+	for i := range files {
+		if IsNormalFile(&files[i]) && NormalConvention(fileTextureType(&files[i])) == convention {
+			hasConvention = true
+			break
+		}
+	}
+	if !hasConvention {
+		return files
+	}
+
+	result := make([]models.File, 0, len(files))
+	for i := range files {
+		if IsNormalFile(&files[i]) && NormalConvention(fileTextureType(&files[i])) != convention {
+			continue
+		}
+		result = append(result, files[i])
+	}
+	return result
+}
+
+// fileTextureType 获取文件的原始贴图类型，旧数据未记录时从文件名解析
+func fileTextureType(file *models.File) string {
+	if file.TextureType != "" {
+		return file.TextureType
+	}
+	return models.ExtractTextureType(file.FileName)
+}
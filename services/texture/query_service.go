@@ -3,6 +3,7 @@ package texture
 import (
 	"go_wails_project_manager/config"
 	"go_wails_project_manager/models"
+	"go_wails_project_manager/services/palette"
 	"strings"
 	"time"
 
@@ -60,6 +61,16 @@ func (s *QueryService) List(page, pageSize int, filters map[string]interface{})
 		}
 	}
 
+	// 按主色筛选（Lab 空间 ΔE 距离）
+	if color, ok := filters["color"].(string); ok && color != "" {
+		tolerance, _ := filters["tolerance"].(float64)
+		owners, err := palette.MatchingOwners(s.db, palette.OwnerTexture, color, tolerance)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("id IN (?)", owners)
+	}
+
 	// 排序
 	if sortBy, ok := filters["sort_by"].(string); ok {
 		switch sortBy {
@@ -32,6 +32,7 @@ type SyncService struct {
 	db              *gorm.DB
 	downloadService *DownloadService
 	tagService      *TagService
+	metricsService  *MetricsService
 	logger          *logrus.Logger
 	httpClient      *http.Client
 	ticker          *time.Ticker
@@ -65,6 +66,7 @@ func NewSyncService(db *gorm.DB, logger *logrus.Logger) *SyncService {
 		db:              db,
 		downloadService: downloadService,
 		tagService:      tagService,
+		metricsService:  NewMetricsService(db, logger),
 		logger:          logger,
 		httpClient:      client,
 		stopChan:        make(chan bool),
@@ -222,7 +224,10 @@ func (s *SyncService) FullSync() error {
 			s.updateProgress(syncLog.ID, currentProcessed, totalCount, j.AssetID)
 
 			// 处理单个材质（传入缩略图 URL）
-			if err := s.processTextureWithThumbnail(j.AssetID, j.ThumbnailURL); err != nil {
+			startedAt := time.Now()
+			err := s.processTextureWithThumbnail(j.AssetID, j.ThumbnailURL)
+			s.metricsService.RecordSync("polyhaven", j.AssetID, startedAt, err)
+			if err != nil {
 				s.logError("处理失败 %s: %v", err, j.AssetID)
 				mu.Lock()
 				failCount++
@@ -352,7 +357,10 @@ func (s *SyncService) IncrementalSync() error {
 		s.logInfo("处理材质 [%d/%d]: %s", i+1, totalCount, job.AssetID)
 		s.updateProgress(syncLog.ID, i+1, totalCount, job.AssetID)
 
-		if err := s.processTextureWithThumbnail(job.AssetID, job.ThumbnailURL); err != nil {
+		startedAt := time.Now()
+		err := s.processTextureWithThumbnail(job.AssetID, job.ThumbnailURL)
+		s.metricsService.RecordSync("polyhaven", job.AssetID, startedAt, err)
+		if err != nil {
 			s.logError("处理失败: %s - %v", err, job.AssetID)
 			failCount++
 		} else {
@@ -3,6 +3,7 @@ package texture
 import (
 	"fmt"
 	"go_wails_project_manager/models"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -14,6 +15,8 @@ type UnifiedDownloadService struct {
 	logger                   *logrus.Logger
 	polyhavenDownloadService *DownloadService
 	ambientcgDownloadService *AmbientCGDownloadService
+	normalService            *NormalService
+	metricsService           *MetricsService
 }
 
 // NewUnifiedDownloadService 创建统一下载服务
@@ -23,6 +26,8 @@ func NewUnifiedDownloadService(db *gorm.DB, logger *logrus.Logger) *UnifiedDownl
 		logger:                   logger,
 		polyhavenDownloadService: NewDownloadService(db, logger),
 		ambientcgDownloadService: NewAmbientCGDownloadService(db, logger),
+		normalService:            NewNormalService(db, logger),
+		metricsService:           NewMetricsService(db, logger),
 	}
 }
 
@@ -45,6 +50,7 @@ func (s *UnifiedDownloadService) DownloadTexture(assetID string, opts DownloadOp
 
 	var files []models.File
 	var err error
+	startedAt := time.Now()
 
 	switch texture.Source {
 	case "ambientcg":
@@ -56,10 +62,25 @@ func (s *UnifiedDownloadService) DownloadTexture(assetID string, opts DownloadOp
 		return nil, fmt.Errorf("不支持的数据源: %s", texture.Source)
 	}
 
+	var downloadSize int64
+	for _, file := range files {
+		downloadSize += file.FileSize
+	}
+	s.metricsService.RecordDownload(texture.Source, assetID, downloadSize, startedAt, err)
+
 	if err != nil {
 		return nil, err
 	}
 
+	// 4. 补齐缺失的法线约定（失败不影响下载结果）
+	if err := s.db.First(&texture, texture.ID).Error; err == nil {
+		if converted, err := s.normalService.ConvertMissing(&texture); err != nil {
+			s.logError("生成法线贴图失败: %v (%s)", err, assetID)
+		} else if converted != nil {
+			files = append(files, *converted)
+		}
+	}
+
 	s.logInfo("材质下载完成: %s (文件数: %d)", assetID, len(files))
 	return files, nil
 }
@@ -0,0 +1,59 @@
+// Package upload 提供统一的上传文件来源（multipart 表单或断点续传）和 tus 协议服务
+package upload
+
+import (
+	"mime/multipart"
+	"os"
+	"path/filepath"
+)
+
+// File 上传文件来源，字段与 multipart.FileHeader 保持一致，便于上传服务复用原有逻辑
+type File struct {
+	Filename string
+	Size     int64
+	open     func() (multipart.File, error)
+}
+
+// Open 打开文件内容
+func (f *File) Open() (multipart.File, error) {
+	return f.open()
+}
+
+// FromFileHeader 从 multipart 表单文件创建
+func FromFileHeader(header *multipart.FileHeader) *File {
+	if header == nil {
+		return nil
+	}
+	return &File{
+		Filename: header.Filename,
+		Size:     header.Size,
+		open:     header.Open,
+	}
+}
+
+// FromFileHeaders 批量从 multipart 表单文件创建
+func FromFileHeaders(headers []*multipart.FileHeader) []*File {
+	files := make([]*File, len(headers))
+	for i, header := range headers {
+		files[i] = FromFileHeader(header)
+	}
+	return files
+}
+
+// FromPath 从本地文件创建，filename 为空时使用路径中的文件名
+func FromPath(path, filename string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	return &File{
+		Filename: filename,
+		Size:     info.Size(),
+		open: func() (multipart.File, error) {
+			return os.Open(path)
+		},
+	}, nil
+}
@@ -0,0 +1,321 @@
+package upload
+
+import (
+	"encoding/base64"
+	"errors"
+	"go_wails_project_manager/config"
+	"go_wails_project_manager/models"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// tus 协议信息
+const (
+	TusVersion    = "1.0.0"
+	TusExtensions = "creation,creation-with-upload,termination,expiration"
+)
+
+// cleanupInterval 过期上传清理间隔
+const cleanupInterval = 30 * time.Minute
+
+var (
+	ErrUploadNotFound   = errors.New("上传不存在或已过期")
+	ErrInvalidLength    = errors.New("无效的上传长度")
+	ErrUploadTooLarge   = errors.New("上传文件超过大小限制")
+	ErrInvalidMetadata  = errors.New("无效的 Upload-Metadata")
+	ErrOffsetMismatch   = errors.New("上传偏移量不匹配")
+	ErrUploadLocked     = errors.New("上传正在写入中")
+	ErrUploadIncomplete = errors.New("上传尚未完成")
+)
+
+// uploadLocks 每个上传的写入锁（所有服务实例共享），避免同一上传并发 PATCH
+var uploadLocks sync.Map
+
+// TusService tus 断点续传服务
+type TusService struct {
+	db         *gorm.DB
+	logger     *logrus.Logger
+	storageDir string
+	maxSize    int64
+	expire     time.Duration
+	ticker     *time.Ticker
+	stopChan   chan bool
+}
+
+// NewTusService 创建 tus 断点续传服务
+func NewTusService(db *gorm.DB, logger *logrus.Logger) *TusService {
+	cfg := config.AppConfig.Upload
+	expireHours := cfg.ExpireHours
+	if expireHours <= 0 {
+		expireHours = 24
+	}
+
+	return &TusService{
+		db:         db,
+		logger:     logger,
+		storageDir: cfg.StorageDir,
+		maxSize:    cfg.MaxSize,
+		expire:     time.Duration(expireHours) * time.Hour,
+	}
+}
+
+// MaxSize 单个上传最大字节数（0 表示不限制）
+func (s *TusService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Create 创建上传（POST），metadata 为原始 Upload-Metadata 头，userID 为创建者
+func (s *TusService) Create(length int64, metadata string, userID uint) (*models.TusUpload, error) {
+	if length < 0 {
+		return nil, ErrInvalidLength
+	}
+	if s.maxSize > 0 && length > s.maxSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	values, err := ParseMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+	filename := values["filename"]
+	if filename == "" {
+		filename = values["name"]
+	}
+
+	if err := os.MkdirAll(s.storageDir, 0755); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	filePath := filepath.Join(s.storageDir, id)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	upload := &models.TusUpload{
+		ID:        id,
+		UserID:    userID,
+		Length:    length,
+		Filename:  filepath.Base(filename),
+		Metadata:  metadata,
+		FilePath:  filePath,
+		Completed: length == 0,
+		ExpiresAt: time.Now().Add(s.expire),
+	}
+	if err := s.db.Create(upload).Error; err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	s.logger.Infof("[Tus] 创建上传 %s: %s (%d 字节)", id, upload.Filename, length)
+	return upload, nil
+}
+
+// Get 查询上传状态（HEAD），已过期或不属于 userID 的上传视为不存在
+func (s *TusService) Get(id string, userID uint) (*models.TusUpload, error) {
+	upload, err := s.find(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+// find 查询属于 userID 的上传记录（不检查过期）
+func (s *TusService) find(id string, userID uint) (*models.TusUpload, error) {
+	var upload models.TusUpload
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&upload).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// WriteChunk 从指定偏移量追加数据（PATCH），连接中断时保留已写入的部分
+func (s *TusService) WriteChunk(id string, userID uint, offset int64, r io.Reader) (*models.TusUpload, error) {
+	lock, _ := uploadLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, ErrUploadLocked
+	}
+	defer mu.Unlock()
+
+	upload, err := s.Get(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return nil, ErrOffsetMismatch
+	}
+
+	file, err := os.OpenFile(upload.FilePath, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 丢弃上次中断时写入但未记录的数据
+	if err := file.Truncate(offset); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	written, copyErr := io.Copy(file, io.LimitReader(r, upload.Length-offset))
+
+	upload.Offset += written
+	upload.Completed = upload.Offset == upload.Length
+	upload.ExpiresAt = time.Now().Add(s.expire)
+	if err := s.db.Model(upload).Updates(map[string]interface{}{
+		"offset":     upload.Offset,
+		"completed":  upload.Completed,
+		"expires_at": upload.ExpiresAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	if copyErr != nil {
+		s.logger.Warnf("[Tus] 上传 %s 写入中断，已接收 %d/%d 字节: %v", id, upload.Offset, upload.Length, copyErr)
+		return upload, copyErr
+	}
+	if upload.Completed {
+		s.logger.Infof("[Tus] 上传完成 %s: %s", id, upload.Filename)
+	}
+	return upload, nil
+}
+
+// Terminate 终止上传并删除暂存文件（DELETE），也用于挂载到资源库后释放
+func (s *TusService) Terminate(id string, userID uint) error {
+	upload, err := s.find(id, userID)
+	if err != nil {
+		return err
+	}
+	s.remove(upload)
+	return nil
+}
+
+// Open 打开 userID 创建的已完成上传，供各资源库上传服务使用
+func (s *TusService) Open(id string, userID uint) (*File, error) {
+	upload, err := s.completed(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return FromPath(upload.FilePath, upload.Filename)
+}
+
+// FilePath 获取已完成上传的暂存文件路径（如项目版本压缩包需要按路径解压）
+func (s *TusService) FilePath(id string, userID uint) (string, error) {
+	upload, err := s.completed(id, userID)
+	if err != nil {
+		return "", err
+	}
+	return upload.FilePath, nil
+}
+
+// completed 获取已完成的上传
+func (s *TusService) completed(id string, userID uint) (*models.TusUpload, error) {
+	upload, err := s.Get(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !upload.Completed {
+		return nil, ErrUploadIncomplete
+	}
+	return upload, nil
+}
+
+// CleanupExpired 清理过期的上传（未完成或完成后未挂载）
+func (s *TusService) CleanupExpired() (int, error) {
+	var expired []models.TusUpload
+	if err := s.db.Where("expires_at < ?", time.Now()).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+
+	for i := range expired {
+		s.remove(&expired[i])
+	}
+	if len(expired) > 0 {
+		s.logger.Infof("[Tus] 已清理 %d 个过期上传", len(expired))
+	}
+	return len(expired), nil
+}
+
+// remove 删除上传记录和暂存文件
+func (s *TusService) remove(upload *models.TusUpload) {
+	if err := os.Remove(upload.FilePath); err != nil && !os.IsNotExist(err) {
+		s.logger.Warnf("[Tus] 删除暂存文件失败 %s: %v", upload.FilePath, err)
+	}
+	s.db.Delete(upload)
+	uploadLocks.Delete(upload.ID)
+}
+
+// StartCleanupJob 启动过期上传清理任务
+func (s *TusService) StartCleanupJob() {
+	if s.ticker != nil {
+		return
+	}
+
+	s.ticker = time.NewTicker(cleanupInterval)
+	s.stopChan = make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				if _, err := s.CleanupExpired(); err != nil {
+					s.logger.Errorf("[Tus] 清理过期上传失败: %v", err)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopCleanupJob 停止过期上传清理任务
+func (s *TusService) StopCleanupJob() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+		close(s.stopChan)
+		s.ticker = nil
+	}
+}
+
+// ParseMetadata 解析 Upload-Metadata 头（逗号分隔的 "key base64value"）
+func ParseMetadata(header string) (map[string]string, error) {
+	values := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return values, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		switch len(parts) {
+		case 1:
+			values[parts[0]] = ""
+		case 2:
+			decoded, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, ErrInvalidMetadata
+			}
+			values[parts[0]] = string(decoded)
+		default:
+			return nil, ErrInvalidMetadata
+		}
+	}
+	return values, nil
+}
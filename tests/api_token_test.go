@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"go_wails_project_manager/models"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// CreateTestAPIToken 通过接口为当前用户创建个人访问令牌
+func CreateTestAPIToken(t *testing.T, sessionToken string, scopes ...string) string {
+	w := MakeRequestWithBody(t, "POST", "/api/auth/tokens", map[string]interface{}{
+		"name":   "test token",
+		"scopes": scopes,
+	}, sessionToken)
+	resp := ParseResponse[any](t, w)
+	data, _ := resp.Data.(map[string]interface{})
+	token, _ := data["token"].(string)
+	assert.NotEmpty(t, token, "创建访问令牌失败: %s", w.Body.String())
+	return token
+}
+
+// TestAPITokenScope 测试个人访问令牌只能执行权限范围内的操作
+func TestAPITokenScope(t *testing.T) {
+	TestRouter = setupAppRouter(t)
+
+	CreateTestUserWithPermissions(t, "token_user", "password123", "documents:read", "documents:update", "documents:delete")
+	sessionToken := LoginTestUser(t, "token_user", "password123")
+	apiToken := CreateTestAPIToken(t, sessionToken, "documents:read")
+
+	doc := CreateTestDocument(t, "token-scope.txt", "token_user", false)
+	path := "/api/documents/" + strconv.FormatUint(uint64(doc.ID), 10)
+
+	t.Run("权限范围内的操作成功", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", path, nil, apiToken)
+		AssertSuccess(t, w, http.StatusOK)
+	})
+
+	t.Run("超出权限范围的修改返回403", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "PUT", path, map[string]string{"name": "renamed.txt"}, apiToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+
+		var current models.Document
+		assert.NoError(t, TestDB.First(&current, doc.ID).Error)
+		assert.Equal(t, "token-scope.txt", current.Name)
+	})
+
+	t.Run("超出权限范围的删除返回403", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "DELETE", path, nil, apiToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+		assert.NoError(t, TestDB.First(&models.Document{}, doc.ID).Error)
+	})
+
+	t.Run("WebDAV 同样按权限范围检查", func(t *testing.T) {
+		w := makeWebDAVRequest("DELETE", "/dav/documents/"+doc.Name, "token_user", apiToken, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NoError(t, TestDB.First(&models.Document{}, doc.ID).Error)
+	})
+
+	t.Run("用户本人的会话不受令牌范围限制", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "PUT", path, map[string]string{"name": "renamed.txt"}, sessionToken)
+		AssertSuccess(t, w, http.StatusOK)
+	})
+
+	t.Run("不能创建超出自身权限的令牌", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "POST", "/api/auth/tokens", map[string]interface{}{
+			"name":   "escalated",
+			"scopes": []string{"users:admin"},
+		}, sessionToken)
+		AssertError(t, w, http.StatusOK, http.StatusBadRequest)
+	})
+}
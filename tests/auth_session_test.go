@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDisabledUserToken 测试禁用用户后已签发的访问令牌和刷新令牌立即失效
+func TestDisabledUserToken(t *testing.T) {
+	TestRouter = setupAppRouter(t)
+
+	CreateTestUserWithPermissions(t, "session_admin", "password123", "users:admin")
+	user := CreateTestUserWithPermissions(t, "session_user", "password123", "documents:read")
+	adminToken := LoginTestUser(t, "session_admin", "password123")
+
+	w := MakeRequestWithBody(t, "POST", "/api/auth/login", map[string]string{
+		"username": "session_user",
+		"password": "password123",
+	}, "")
+	resp := ParseResponse[any](t, w)
+	data, _ := resp.Data.(map[string]interface{})
+	accessToken, _ := data["access_token"].(string)
+	refreshToken, _ := data["refresh_token"].(string)
+	assert.NotEmpty(t, accessToken)
+	assert.NotEmpty(t, refreshToken)
+
+	t.Run("禁用前令牌有效", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/api/auth/profile", nil, accessToken)
+		AssertSuccess(t, w, http.StatusOK)
+	})
+
+	w = MakeRequestWithBody(t, "POST", "/api/users/"+strconv.FormatUint(uint64(user.ID), 10)+"/disable", nil, adminToken)
+	AssertSuccess(t, w, http.StatusOK)
+
+	t.Run("禁用后访问令牌被拒绝", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/api/auth/profile", nil, accessToken)
+		AssertError(t, w, http.StatusOK, http.StatusUnauthorized)
+	})
+
+	t.Run("禁用后刷新令牌被拒绝", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "POST", "/api/auth/refresh", map[string]string{"refresh_token": refreshToken}, "")
+		AssertError(t, w, http.StatusOK, http.StatusUnauthorized)
+	})
+
+	t.Run("禁用后不能重新登录", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "POST", "/api/auth/login", map[string]string{
+			"username": "session_user",
+			"password": "password123",
+		}, "")
+		resp := ParseResponse[any](t, w)
+		assert.NotEqual(t, 200, resp.Code)
+	})
+}
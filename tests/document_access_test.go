@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"go_wails_project_manager/api"
+	"go_wails_project_manager/logger"
+	"go_wails_project_manager/middleware"
+	"go_wails_project_manager/models"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupAppRouter 注册完整的应用路由（包括认证、会话、权限和审计中间件）
+// 路由注册时设置的全局认证组件在测试结束后还原，避免影响只注册部分路由的测试
+func setupAppRouter(t *testing.T) *gin.Engine {
+	t.Cleanup(func() {
+		middleware.SetSessionValidator(nil)
+		middleware.SetAPITokenAuthenticator(nil)
+		middleware.SetPermissionCalculator(nil)
+		middleware.SetResourcePermissionChecker(nil)
+		middleware.SetAnonymousRead(nil)
+	})
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	api.RegisterRoutes(router, logger.Log, nil, nil, nil, nil, nil)
+	return router
+}
+
+// CreateTestUserWithPermissions 创建已启用的测试用户，并通过专属角色授予指定权限
+func CreateTestUserWithPermissions(t *testing.T, username, password string, permissions ...string) *models.User {
+	hash, err := middleware.HashPassword(password)
+	assert.NoError(t, err)
+
+	user := &models.User{
+		Username: username,
+		Password: hash,
+		Email:    username + "@test.com",
+		Status:   models.UserStatusActive,
+	}
+	assert.NoError(t, TestDB.Create(user).Error)
+
+	role := &models.Role{Code: "test_" + username, Name: "test_" + username}
+	assert.NoError(t, TestDB.Create(role).Error)
+	var perms []models.Permission
+	assert.NoError(t, TestDB.Where("code IN ?", permissions).Find(&perms).Error)
+	assert.Len(t, perms, len(permissions))
+	assert.NoError(t, TestDB.Model(role).Association("Permissions").Append(perms))
+	assert.NoError(t, TestDB.Model(user).Association("Roles").Append(role))
+	return user
+}
+
+// LoginTestUser 通过登录接口获取访问令牌
+func LoginTestUser(t *testing.T, username, password string) string {
+	w := MakeRequestWithBody(t, "POST", "/api/auth/login", map[string]string{
+		"username": username,
+		"password": password,
+	}, "")
+	resp := ParseResponse[any](t, w)
+	data, _ := resp.Data.(map[string]interface{})
+	token, _ := data["access_token"].(string)
+	assert.NotEmpty(t, token, "登录失败: %s", w.Body.String())
+	return token
+}
+
+// CreateTestDocument 直接写入一条最新版本的文档记录（不需要物理文件）
+func CreateTestDocument(t *testing.T, name, uploadedBy string, isPublic bool) *models.Document {
+	doc := &models.Document{
+		Name:       name,
+		Type:       "document",
+		FilePath:   "static/documents/test/" + name,
+		Format:     "txt",
+		Version:    "v1.0",
+		IsLatest:   true,
+		IsPublic:   isPublic,
+		UploadedBy: uploadedBy,
+	}
+	assert.NoError(t, TestDB.Create(doc).Error)
+	return doc
+}
+
+// TestDocumentViewOnly 测试公开文档对其他用户只读：可以查看，不能修改或删除
+func TestDocumentViewOnly(t *testing.T) {
+	TestRouter = setupAppRouter(t)
+
+	CreateTestUserWithPermissions(t, "doc_owner", "password123", "documents:read", "documents:update", "documents:delete")
+	CreateTestUserWithPermissions(t, "doc_viewer", "password123", "documents:read", "documents:update", "documents:delete")
+	ownerToken := LoginTestUser(t, "doc_owner", "password123")
+	viewerToken := LoginTestUser(t, "doc_viewer", "password123")
+
+	doc := CreateTestDocument(t, "view-only.txt", "doc_owner", true)
+	path := "/api/documents/" + strconv.FormatUint(uint64(doc.ID), 10)
+
+	t.Run("其他用户可以查看公开文档", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", path, nil, viewerToken)
+		AssertSuccess(t, w, http.StatusOK)
+	})
+
+	t.Run("其他用户不能修改公开文档", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "PUT", path, map[string]string{"name": "renamed.txt"}, viewerToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+
+		var current models.Document
+		assert.NoError(t, TestDB.First(&current, doc.ID).Error)
+		assert.Equal(t, "view-only.txt", current.Name)
+	})
+
+	t.Run("其他用户不能删除公开文档", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "DELETE", path, nil, viewerToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+		assert.NoError(t, TestDB.First(&models.Document{}, doc.ID).Error)
+	})
+
+	t.Run("上传者可以修改", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "PUT", path, map[string]string{"name": "renamed.txt"}, ownerToken)
+		AssertSuccess(t, w, http.StatusOK)
+	})
+
+	t.Run("不可见的文档返回403", func(t *testing.T) {
+		private := CreateTestDocument(t, "private.txt", "doc_owner", false)
+		w := MakeRequestWithBody(t, "GET", "/api/documents/"+strconv.FormatUint(uint64(private.ID), 10), nil, viewerToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+	})
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"go_wails_project_manager/middleware"
+	"net/http"
+	"testing"
+)
+
+// TestLibraryRBAC 测试资源库接口按角色权限校验：未登录、缺少权限、缺少管理权限分别被拒绝
+func TestLibraryRBAC(t *testing.T) {
+	TestRouter = setupAppRouter(t)
+
+	CreateTestUserWithPermissions(t, "rbac_reader", "password123", "models:read")
+	readerToken := LoginTestUser(t, "rbac_reader", "password123")
+
+	t.Run("未登录不能查看非公开资源库", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/api/models", nil, "")
+		AssertError(t, w, http.StatusOK, http.StatusUnauthorized)
+	})
+
+	t.Run("未登录不能访问资源库静态文件", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/models/missing.glb", nil, "")
+		AssertError(t, w, http.StatusOK, http.StatusUnauthorized)
+	})
+
+	t.Run("未登录可以查看默认公开的文件库", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/api/documents", nil, "")
+		AssertSuccess(t, w, http.StatusOK)
+	})
+
+	t.Run("有查看权限可以查看", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/api/models", nil, readerToken)
+		AssertSuccess(t, w, http.StatusOK)
+	})
+
+	t.Run("没有其他资源库的权限", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/api/assets", nil, readerToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+	})
+
+	t.Run("只有查看权限不能删除", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "DELETE", "/api/models/999999", nil, readerToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+	})
+
+	t.Run("管理接口需要资源库的管理权限", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "POST", "/api/similarity/model/rebuild", nil, readerToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+
+		w = MakeRequestWithBody(t, "POST", "/api/documents/search/backfill", nil, readerToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+	})
+
+	t.Run("系统管理接口需要对应权限", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/api/users", nil, readerToken)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+	})
+
+	t.Run("开启匿名只读后未登录只能查看", func(t *testing.T) {
+		middleware.SetAnonymousRead([]string{"models"})
+
+		w := MakeRequestWithBody(t, "GET", "/api/models", nil, "")
+		AssertSuccess(t, w, http.StatusOK)
+
+		w = MakeRequestWithBody(t, "DELETE", "/api/models/999999", nil, "")
+		AssertError(t, w, http.StatusOK, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"go_wails_project_manager/models"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// CreateTestGrant 直接写入一条单独授权
+func CreateTestGrant(t *testing.T, userID uint, resourceType string, resourceID uint, permission string, expiresAt *time.Time) {
+	grant := &models.ResourcePermission{
+		UserID:       userID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Permission:   permission,
+		ExpiresAt:    expiresAt,
+	}
+	assert.NoError(t, TestDB.Create(grant).Error)
+}
+
+// TestExpiredGrant 测试过期的单独授权不再生效：不能查看、修改，也不出现在列表中
+func TestExpiredGrant(t *testing.T) {
+	TestRouter = setupAppRouter(t)
+
+	CreateTestUserWithPermissions(t, "grant_owner", "password123", "documents:read")
+	user := CreateTestUserWithPermissions(t, "grant_user", "password123", "models:read")
+	token := LoginTestUser(t, "grant_user", "password123")
+
+	active := CreateTestDocument(t, "grant-active.txt", "grant_owner", false)
+	expired := CreateTestDocument(t, "grant-expired.txt", "grant_owner", false)
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	for _, permission := range []string{models.ActionRead, models.ActionUpdate} {
+		CreateTestGrant(t, user.ID, models.ResourceDocuments, active.ID, permission, &future)
+		CreateTestGrant(t, user.ID, models.ResourceDocuments, expired.ID, permission, &past)
+	}
+
+	activePath := "/api/documents/" + strconv.FormatUint(uint64(active.ID), 10)
+	expiredPath := "/api/documents/" + strconv.FormatUint(uint64(expired.ID), 10)
+
+	t.Run("有效授权可以查看和修改", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", activePath, nil, token)
+		AssertSuccess(t, w, http.StatusOK)
+
+		w = MakeRequestWithBody(t, "PUT", activePath, map[string]string{"description": "updated"}, token)
+		AssertSuccess(t, w, http.StatusOK)
+	})
+
+	t.Run("过期授权不能查看", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", expiredPath, nil, token)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+	})
+
+	t.Run("过期授权不能修改", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "PUT", expiredPath, map[string]string{"name": "renamed.txt"}, token)
+		AssertError(t, w, http.StatusOK, http.StatusForbidden)
+
+		var current models.Document
+		assert.NoError(t, TestDB.First(&current, expired.ID).Error)
+		assert.Equal(t, "grant-expired.txt", current.Name)
+	})
+
+	t.Run("列表只包含有效授权的文档", func(t *testing.T) {
+		w := MakeRequestWithBody(t, "GET", "/api/documents", nil, token)
+		resp := ParseResponse[any](t, w)
+		data, _ := resp.Data.(map[string]interface{})
+		list, _ := data["list"].([]interface{})
+
+		var names []string
+		for _, item := range list {
+			if doc, ok := item.(map[string]interface{}); ok {
+				names = append(names, doc["name"].(string))
+			}
+		}
+		assert.Contains(t, names, "grant-active.txt")
+		assert.NotContains(t, names, "grant-expired.txt")
+	})
+}
@@ -83,6 +83,9 @@ func CleanupTestEnvironment() {
 	// 删除测试数据库
 	testDBPath := "./data/test.db"
 	os.Remove(testDBPath)
+
+	// 删除注册完整路由时生成的 API 文档模板目录
+	os.RemoveAll("./api")
 }
 
 // TestMain 测试入口
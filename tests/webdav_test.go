@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"go_wails_project_manager/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// makeWebDAVRequest 使用 Basic 认证发送 WebDAV 请求
+func makeWebDAVRequest(method, path, username, password string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(""))
+	req.SetBasicAuth(username, password)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	TestRouter.ServeHTTP(w, req)
+	return w
+}
+
+// TestWebDAVViewOnly 测试通过 WebDAV 同样不能修改只读的公开文档
+func TestWebDAVViewOnly(t *testing.T) {
+	TestRouter = setupAppRouter(t)
+
+	CreateTestUserWithPermissions(t, "dav_owner", "password123", "documents:read", "documents:update", "documents:delete")
+	CreateTestUserWithPermissions(t, "dav_viewer", "password123",
+		"documents:read", "documents:download", "documents:upload", "documents:create", "documents:update", "documents:delete")
+
+	doc := CreateTestDocument(t, "dav-view-only.txt", "dav_owner", true)
+	path := "/dav/documents/" + doc.Name
+	// PROPFIND 需要打开物理文件
+	assert.NoError(t, os.MkdirAll(filepath.Dir(doc.FilePath), 0755))
+	assert.NoError(t, os.WriteFile(doc.FilePath, []byte("view only"), 0644))
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(doc.FilePath)) })
+
+	t.Run("其他用户可以查看公开文档", func(t *testing.T) {
+		w := makeWebDAVRequest("PROPFIND", path, "dav_viewer", "password123", map[string]string{"Depth": "0"})
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+	})
+
+	t.Run("其他用户不能覆盖公开文档", func(t *testing.T) {
+		w := makeWebDAVRequest("PUT", path, "dav_viewer", "password123", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("其他用户不能删除公开文档", func(t *testing.T) {
+		w := makeWebDAVRequest("DELETE", path, "dav_viewer", "password123", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NoError(t, TestDB.First(&models.Document{}, doc.ID).Error)
+	})
+
+	t.Run("其他用户不能移动公开文档", func(t *testing.T) {
+		w := makeWebDAVRequest("MOVE", path, "dav_viewer", "password123", map[string]string{
+			"Destination": "/dav/documents/renamed.txt",
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var current models.Document
+		assert.NoError(t, TestDB.First(&current, doc.ID).Error)
+		assert.Equal(t, "dav-view-only.txt", current.Name)
+	})
+
+	t.Run("上传者可以修改属性", func(t *testing.T) {
+		w := makeWebDAVRequest("PROPPATCH", path, "dav_owner", "password123", nil)
+		assert.NotEqual(t, http.StatusForbidden, w.Code)
+	})
+}
+
+// TestWebDAVDisabledUser 测试账号被禁用后缓存的 WebDAV 凭据立即失效
+func TestWebDAVDisabledUser(t *testing.T) {
+	TestRouter = setupAppRouter(t)
+
+	user := CreateTestUserWithPermissions(t, "dav_disabled", "password123", "documents:read")
+
+	w := makeWebDAVRequest("PROPFIND", "/dav/documents/", "dav_disabled", "password123", map[string]string{"Depth": "0"})
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+	// 直接修改数据库，不经过用户管理接口的撤销通知
+	assert.NoError(t, TestDB.Model(user).Update("status", models.UserStatusDisabled).Error)
+
+	w = makeWebDAVRequest("PROPFIND", "/dav/documents/", "dav_disabled", "password123", map[string]string{"Depth": "0"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
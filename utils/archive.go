@@ -4,11 +4,16 @@ import (
 	"archive/zip"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
 )
 
 // CompressFolder 压缩文件夹
@@ -55,41 +60,134 @@ func CompressFolder(sourcePath string, destPath string) error {
 	})
 }
 
-// ExtractArchive 解压文件
+var (
+	ErrUnsafeArchivePath       = errors.New("非法的压缩包条目路径")
+	ErrArchiveTooManyEntries   = errors.New("压缩包文件数量超过限制")
+	ErrArchiveUncompressedSize = errors.New("压缩包解压后总大小超过限制")
+	ErrArchiveCompressionRatio = errors.New("压缩包压缩比异常，疑似压缩炸弹")
+)
+
+// ratioCheckThreshold 小于该大小的条目不检查压缩比（小文件的压缩比没有参考意义）
+const ratioCheckThreshold = 1 << 20
+
+// ArchiveLimits 解压限制，防止压缩炸弹（0 表示不限制）
+type ArchiveLimits struct {
+	MaxFiles     int   // 文件数量上限
+	MaxTotalSize int64 // 解压后总大小上限（字节）
+	MaxRatio     int64 // 单个条目解压后与压缩后大小之比的上限
+}
+
+// DefaultArchiveLimits 默认解压限制
+var DefaultArchiveLimits = ArchiveLimits{
+	MaxFiles:     100000,
+	MaxTotalSize: 50 << 30, // 50GB
+	MaxRatio:     1000,
+}
+
+// ArchiveEntryName 压缩包条目名称
+// 未设置 UTF-8 标志的条目（Windows 资源管理器等工具生成）按 GBK 解码，统一使用正斜杠
+func ArchiveEntryName(file *zip.File) string {
+	name := file.Name
+	if file.NonUTF8 && !utf8.ValidString(name) {
+		if decoded, err := simplifiedchinese.GBK.NewDecoder().String(name); err == nil {
+			name = decoded
+		}
+	}
+	return strings.ReplaceAll(name, "\\", "/")
+}
+
+// SafeArchivePath 规范化压缩包条目路径，拒绝绝对路径、盘符和跳出根目录的条目（zip-slip）
+// 返回不带首尾斜杠的相对路径，根目录返回空字符串
+func SafeArchivePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || strings.ContainsRune(name, 0) ||
+		len(name) >= 2 && name[1] == ':' {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+
+	cleaned := path.Clean("/" + name)
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+		}
+	}
+	return strings.TrimPrefix(cleaned, "/"), nil
+}
+
+// CheckArchiveLimits 按条目声明的大小预先检查解压限制（解压时还会按实际写入量再次检查）
+func CheckArchiveLimits(files []*zip.File, limits ArchiveLimits) error {
+	count := 0
+	var total uint64
+	for _, file := range files {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		count++
+		total += file.UncompressedSize64
+
+		if limits.MaxFiles > 0 && count > limits.MaxFiles {
+			return ErrArchiveTooManyEntries
+		}
+		if limits.MaxTotalSize > 0 && total > uint64(limits.MaxTotalSize) {
+			return ErrArchiveUncompressedSize
+		}
+		if limits.MaxRatio > 0 && file.UncompressedSize64 > ratioCheckThreshold &&
+			file.UncompressedSize64 > file.CompressedSize64*uint64(limits.MaxRatio) {
+			return fmt.Errorf("%w: %s", ErrArchiveCompressionRatio, ArchiveEntryName(file))
+		}
+	}
+	return nil
+}
+
+// ExtractArchive 解压文件（使用默认解压限制）
 func ExtractArchive(archivePath string, destPath string) error {
+	return ExtractArchiveWithLimits(archivePath, destPath, DefaultArchiveLimits)
+}
+
+// ExtractArchiveWithLimits 解压文件
+// 条目路径经过 SafeArchivePath 检查，符号链接条目直接跳过；
+// 条目声明的大小可以伪造，解压时按实际写入的字节数检查总大小上限
+func ExtractArchiveWithLimits(archivePath string, destPath string, limits ArchiveLimits) error {
 	reader, err := zip.OpenReader(archivePath)
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
 
-	for _, file := range reader.File {
-		path := filepath.Join(destPath, file.Name)
+	if err := CheckArchiveLimits(reader.File, limits); err != nil {
+		return err
+	}
 
-		if file.FileInfo().IsDir() {
-			os.MkdirAll(path, os.ModePerm)
+	root := filepath.Clean(destPath) + string(os.PathSeparator)
+	var written int64
+	for _, file := range reader.File {
+		relative, err := SafeArchivePath(ArchiveEntryName(file))
+		if err != nil {
+			return err
+		}
+		if relative == "" || file.Mode()&os.ModeSymlink != 0 {
 			continue
 		}
 
-		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
-			return err
+		target := filepath.Join(destPath, filepath.FromSlash(relative))
+		// 双重保护：拒绝解压到目标目录之外的条目
+		if !strings.HasPrefix(target+string(os.PathSeparator), root) {
+			return fmt.Errorf("%w: %s", ErrUnsafeArchivePath, file.Name)
 		}
 
-		outFile, err := os.Create(path)
-		if err != nil {
-			return err
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
 		}
 
-		rc, err := file.Open()
-		if err != nil {
-			outFile.Close()
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
 			return err
 		}
 
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
-
+		n, err := extractArchiveFile(file, target, limits.MaxTotalSize-written, limits.MaxTotalSize > 0)
+		written += n
 		if err != nil {
 			return err
 		}
@@ -98,6 +196,34 @@ func ExtractArchive(archivePath string, destPath string) error {
 	return nil
 }
 
+// extractArchiveFile 解压单个文件，limited 为 true 时最多写入 remaining 字节
+func extractArchiveFile(file *zip.File, target string, remaining int64, limited bool) (int64, error) {
+	outFile, err := os.Create(target)
+	if err != nil {
+		return 0, err
+	}
+	defer outFile.Close()
+
+	rc, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	if !limited {
+		return io.Copy(outFile, rc)
+	}
+
+	n, err := io.CopyN(outFile, rc, remaining+1)
+	if err == io.EOF {
+		return n, nil
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, ErrArchiveUncompressedSize
+}
+
 // CalculateHash 计算文件哈希
 func CalculateHash(filePath string) (string, error) {
 	file, err := os.Open(filePath)
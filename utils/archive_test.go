@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// archiveEntry 测试压缩包中的一个条目
+type archiveEntry struct {
+	name    string
+	content []byte
+}
+
+// buildArchive 在内存中生成压缩包并返回其条目
+func buildArchive(t *testing.T, entries ...archiveEntry) []*zip.File {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Deflate})
+		assert.NoError(t, err)
+		_, err = w.Write(entry.content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	return reader.File
+}
+
+// writeArchive 将压缩包写入临时文件
+func writeArchive(t *testing.T, entries ...archiveEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.zip")
+	file, err := os.Create(path)
+	assert.NoError(t, err)
+	writer := zip.NewWriter(file)
+	for _, entry := range entries {
+		w, err := writer.Create(entry.name)
+		assert.NoError(t, err)
+		_, err = w.Write(entry.content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, file.Close())
+	return path
+}
+
+// TestSafeArchivePath 测试条目路径规范化和 zip-slip 检查
+func TestSafeArchivePath(t *testing.T) {
+	t.Run("合法路径", func(t *testing.T) {
+		cases := map[string]string{
+			"a.txt":           "a.txt",
+			"dir/":            "dir",
+			"dir/sub/b.txt":   "dir/sub/b.txt",
+			"dir\\sub\\c.txt": "dir/sub/c.txt",
+			"./dir//d.txt":    "dir/d.txt",
+			"报告/设计.pdf":       "报告/设计.pdf",
+			"..a/b..":         "..a/b..",
+			"":                "",
+		}
+		for name, expected := range cases {
+			relative, err := SafeArchivePath(name)
+			assert.NoError(t, err, name)
+			assert.Equal(t, expected, relative, name)
+		}
+	})
+
+	t.Run("拒绝跳出根目录和绝对路径", func(t *testing.T) {
+		for _, name := range []string{
+			"../evil.txt",
+			"dir/../../evil.txt",
+			"dir/../a.txt",
+			"..\\evil.txt",
+			"dir\\..\\..\\evil.txt",
+			"/etc/passwd",
+			"\\Windows\\system.ini",
+			"C:/Windows/system.ini",
+			"c:evil.txt",
+			"a\x00.txt",
+		} {
+			_, err := SafeArchivePath(name)
+			assert.ErrorIs(t, err, ErrUnsafeArchivePath, name)
+		}
+	})
+}
+
+// TestArchiveEntryName 测试未设置 UTF-8 标志的条目按 GBK 解码
+func TestArchiveEntryName(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("资料\\说明.txt")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	_, err = writer.CreateHeader(&zip.FileHeader{Name: gbk, NonUTF8: true})
+	assert.NoError(t, err)
+	_, err = writer.Create("utf8/名称.txt")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, "资料/说明.txt", ArchiveEntryName(reader.File[0]))
+	assert.Equal(t, "utf8/名称.txt", ArchiveEntryName(reader.File[1]))
+}
+
+// TestCheckArchiveLimits 测试文件数量、总大小和压缩比限制（压缩炸弹）
+func TestCheckArchiveLimits(t *testing.T) {
+	small := []byte("hello")
+
+	t.Run("未超过限制", func(t *testing.T) {
+		files := buildArchive(t, archiveEntry{"dir/", nil}, archiveEntry{"a.txt", small}, archiveEntry{"b.txt", small})
+		assert.NoError(t, CheckArchiveLimits(files, ArchiveLimits{MaxFiles: 2, MaxTotalSize: 10, MaxRatio: 10}))
+	})
+
+	t.Run("文件数量超过限制（目录不计数）", func(t *testing.T) {
+		files := buildArchive(t, archiveEntry{"a.txt", small}, archiveEntry{"b.txt", small}, archiveEntry{"c.txt", small})
+		assert.ErrorIs(t, CheckArchiveLimits(files, ArchiveLimits{MaxFiles: 2}), ErrArchiveTooManyEntries)
+	})
+
+	t.Run("解压后总大小超过限制", func(t *testing.T) {
+		files := buildArchive(t, archiveEntry{"a.txt", small}, archiveEntry{"b.txt", small})
+		assert.ErrorIs(t, CheckArchiveLimits(files, ArchiveLimits{MaxTotalSize: 9}), ErrArchiveUncompressedSize)
+	})
+
+	t.Run("压缩比异常", func(t *testing.T) {
+		bomb := make([]byte, 8<<20) // 8MB 的零压缩后只有几 KB
+		files := buildArchive(t, archiveEntry{"bomb.bin", bomb})
+		err := CheckArchiveLimits(files, ArchiveLimits{MaxRatio: 100})
+		assert.ErrorIs(t, err, ErrArchiveCompressionRatio)
+		assert.Contains(t, err.Error(), "bomb.bin")
+	})
+
+	t.Run("小文件不检查压缩比", func(t *testing.T) {
+		files := buildArchive(t, archiveEntry{"zeros.bin", make([]byte, 512<<10)})
+		assert.NoError(t, CheckArchiveLimits(files, ArchiveLimits{MaxRatio: 10}))
+	})
+
+	t.Run("0 表示不限制", func(t *testing.T) {
+		files := buildArchive(t, archiveEntry{"bomb.bin", make([]byte, 8<<20)}, archiveEntry{"a.txt", small})
+		assert.NoError(t, CheckArchiveLimits(files, ArchiveLimits{}))
+	})
+}
+
+// TestExtractArchiveWithLimits 测试解压时拒绝 zip-slip 条目并按限制中止
+func TestExtractArchiveWithLimits(t *testing.T) {
+	t.Run("正常解压", func(t *testing.T) {
+		archive := writeArchive(t, archiveEntry{"dir/", nil}, archiveEntry{"dir/a.txt", []byte("hello")})
+		dest := t.TempDir()
+		assert.NoError(t, ExtractArchiveWithLimits(archive, dest, DefaultArchiveLimits))
+
+		content, err := os.ReadFile(filepath.Join(dest, "dir", "a.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(content))
+	})
+
+	t.Run("拒绝跳出目标目录的条目", func(t *testing.T) {
+		archive := writeArchive(t, archiveEntry{"ok.txt", []byte("ok")}, archiveEntry{"../evil.txt", []byte("evil")})
+		parent := t.TempDir()
+		dest := filepath.Join(parent, "out")
+		assert.NoError(t, os.Mkdir(dest, 0755))
+
+		err := ExtractArchiveWithLimits(archive, dest, DefaultArchiveLimits)
+		assert.ErrorIs(t, err, ErrUnsafeArchivePath)
+		_, statErr := os.Stat(filepath.Join(parent, "evil.txt"))
+		assert.True(t, errors.Is(statErr, os.ErrNotExist))
+	})
+
+	t.Run("压缩炸弹在解压前被拒绝", func(t *testing.T) {
+		archive := writeArchive(t, archiveEntry{"bomb.bin", make([]byte, 8<<20)})
+		dest := t.TempDir()
+
+		err := ExtractArchiveWithLimits(archive, dest, ArchiveLimits{MaxRatio: 100})
+		assert.ErrorIs(t, err, ErrArchiveCompressionRatio)
+		entries, _ := os.ReadDir(dest)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("超过总大小限制", func(t *testing.T) {
+		archive := writeArchive(t, archiveEntry{"a.txt", []byte(strings.Repeat("a", 100))})
+		err := ExtractArchiveWithLimits(archive, t.TempDir(), ArchiveLimits{MaxTotalSize: 99})
+		assert.ErrorIs(t, err, ErrArchiveUncompressedSize)
+	})
+}
@@ -0,0 +1,12 @@
+package utils
+
+import "strings"
+
+// CSVSafe 防止 CSV 公式注入：以 = + - @ 制表符或回车开头的单元格前加单引号，
+// 避免用户名、路径、请求体等用户可控内容在 Excel 中打开时被当作公式执行
+func CSVSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,326 @@
+// Package document Office Open XML 文本提取（docx/xlsx/pptx，无需外部工具）
+package document
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxOfficePartSize 单个 XML 部件最多读取的解压后字节数，防止压缩炸弹
+const maxOfficePartSize = 64 * 1024 * 1024
+
+// officePartPattern 幻灯片/工作表部件路径中的序号
+var officePartPattern = regexp.MustCompile(`(\d+)\.xml$`)
+
+// ExtractOfficeText 提取 docx/xlsx/pptx 文本，按页返回
+// docx 按 Word 保存时记录的分页位置分页，pptx 每张幻灯片一页，xlsx 每个工作表一页
+// 提取到 maxChars 个字符后停止解析（maxChars <= 0 表示不限制）
+func ExtractOfficeText(filePath, format string, maxChars int) ([]string, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开 Office 文件失败: %w", err)
+	}
+	defer reader.Close()
+
+	budget := &charBudget{remaining: maxChars, unlimited: maxChars <= 0}
+	switch strings.ToLower(format) {
+	case "docx":
+		return extractDocx(&reader.Reader, budget)
+	case "pptx":
+		return extractPptx(&reader.Reader, budget)
+	case "xlsx":
+		return extractXlsx(&reader.Reader, budget)
+	}
+	return nil, fmt.Errorf("不支持的 Office 格式: %s", format)
+}
+
+// charBudget 剩余可提取的字符数，所有页面共用
+type charBudget struct {
+	remaining int
+	unlimited bool
+}
+
+// exhausted 是否已达到字符上限
+func (b *charBudget) exhausted() bool {
+	return b != nil && !b.unlimited && b.remaining <= 0
+}
+
+// write 写入文本，超出剩余字符数的部分被丢弃（budget 为 nil 时不限制）
+func (b *charBudget) write(sb *strings.Builder, text string) {
+	if b == nil || b.unlimited {
+		sb.WriteString(text)
+		return
+	}
+	if b.remaining <= 0 {
+		return
+	}
+	count := utf8.RuneCountInString(text)
+	if count > b.remaining {
+		cut := 0
+		for i := 0; i < b.remaining; i++ {
+			_, size := utf8.DecodeRuneInString(text[cut:])
+			cut += size
+		}
+		text, count = text[:cut], b.remaining
+	}
+	sb.WriteString(text)
+	b.remaining -= count
+}
+
+// extractDocx 提取 Word 文档正文
+func extractDocx(reader *zip.Reader, budget *charBudget) ([]string, error) {
+	file := findZipFile(reader, "word/document.xml")
+	if file == nil {
+		return nil, fmt.Errorf("word/document.xml 不存在")
+	}
+
+	var pages []string
+	var current strings.Builder
+	// 手动分页符之后 Word 通常还会记录一个 lastRenderedPageBreak，避免重复分页
+	pageBroken := false
+	err := walkXML(file, budget, func(decoder *xml.Decoder, token xml.Token) error {
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				pageBroken = false
+				return appendCharData(decoder, &current, budget)
+			case "tab":
+				budget.write(&current, "\t")
+			case "lastRenderedPageBreak":
+				if !pageBroken {
+					pages = append(pages, current.String())
+					current.Reset()
+				}
+				pageBroken = false
+			case "br":
+				if xmlAttr(t, "type") == "page" {
+					pages = append(pages, current.String())
+					current.Reset()
+					pageBroken = true
+				} else {
+					budget.write(&current, "\n")
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "p" {
+				budget.write(&current, "\n")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(pages, current.String()), nil
+}
+
+// extractPptx 提取每张幻灯片的文本
+func extractPptx(reader *zip.Reader, budget *charBudget) ([]string, error) {
+	slides := sortedParts(reader, "ppt/slides/slide")
+	pages := make([]string, 0, len(slides))
+	for _, slide := range slides {
+		if budget.exhausted() {
+			break
+		}
+		var text strings.Builder
+		err := walkXML(slide, budget, func(decoder *xml.Decoder, token xml.Token) error {
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "t" {
+					return appendCharData(decoder, &text, budget)
+				}
+			case xml.EndElement:
+				if t.Name.Local == "p" {
+					budget.write(&text, "\n")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, text.String())
+	}
+	return pages, nil
+}
+
+// extractXlsx 提取每个工作表的单元格文本（共享字符串、内联字符串和数值）
+// 共享字符串表只受部件大小限制，写入工作表文本时才计入字符上限
+func extractXlsx(reader *zip.Reader, budget *charBudget) ([]string, error) {
+	var shared []string
+	if file := findZipFile(reader, "xl/sharedStrings.xml"); file != nil {
+		var item strings.Builder
+		err := walkXML(file, nil, func(decoder *xml.Decoder, token xml.Token) error {
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "t" {
+					return appendCharData(decoder, &item, nil)
+				}
+			case xml.EndElement:
+				if t.Name.Local == "si" {
+					shared = append(shared, item.String())
+					item.Reset()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sheets := sortedParts(reader, "xl/worksheets/sheet")
+	pages := make([]string, 0, len(sheets))
+	for _, sheet := range sheets {
+		if budget.exhausted() {
+			break
+		}
+		var text strings.Builder
+		cellType := ""
+		err := walkXML(sheet, budget, func(decoder *xml.Decoder, token xml.Token) error {
+			switch t := token.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "c":
+					cellType = xmlAttr(t, "t")
+				case "v":
+					var value strings.Builder
+					if err := appendCharData(decoder, &value, nil); err != nil {
+						return err
+					}
+					if cellType == "s" {
+						if index, err := strconv.Atoi(value.String()); err == nil && index >= 0 && index < len(shared) {
+							budget.write(&text, shared[index])
+						}
+					} else {
+						budget.write(&text, value.String())
+					}
+					budget.write(&text, "\t")
+				case "t":
+					// 内联字符串 <is><t>
+					if err := appendCharData(decoder, &text, budget); err != nil {
+						return err
+					}
+					budget.write(&text, "\t")
+				}
+			case xml.EndElement:
+				if t.Name.Local == "row" {
+					budget.write(&text, "\n")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, text.String())
+	}
+	return pages, nil
+}
+
+// walkXML 逐个 token 遍历压缩包中的 XML 部件
+// 最多读取 maxOfficePartSize 字节，超出部分视为截断；达到字符上限后停止遍历
+func walkXML(file *zip.File, budget *charBudget, visit func(decoder *xml.Decoder, token xml.Token) error) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	limited := &io.LimitedReader{R: rc, N: maxOfficePartSize}
+	decoder := xml.NewDecoder(limited)
+	for !budget.exhausted() {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if limited.N <= 0 {
+				// 部件超过读取上限，保留已提取的文本
+				return nil
+			}
+			return fmt.Errorf("解析 %s 失败: %w", file.Name, err)
+		}
+		if err := visit(decoder, token); err != nil {
+			if limited.N <= 0 {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// appendCharData 读取当前元素的文本内容直到元素结束（写入的字符计入 budget）
+func appendCharData(decoder *xml.Decoder, sb *strings.Builder, budget *charBudget) error {
+	depth := 1
+	for depth > 0 {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			budget.write(sb, string(t))
+		}
+	}
+	return nil
+}
+
+// xmlAttr 获取元素属性（忽略命名空间）
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// findZipFile 按路径查找压缩包中的文件
+func findZipFile(reader *zip.Reader, name string) *zip.File {
+	for _, file := range reader.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// sortedParts 按序号排序返回指定前缀的部件（slide1.xml、slide2.xml ...）
+func sortedParts(reader *zip.Reader, prefix string) []*zip.File {
+	var parts []*zip.File
+	for _, file := range reader.File {
+		if strings.HasPrefix(file.Name, prefix) && path.Dir(file.Name) == path.Dir(prefix) && officePartPattern.MatchString(file.Name) {
+			parts = append(parts, file)
+		}
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return partNumber(parts[i].Name) < partNumber(parts[j].Name)
+	})
+	return parts
+}
+
+// partNumber 部件路径中的序号
+func partNumber(name string) int {
+	matches := officePartPattern.FindStringSubmatch(name)
+	if matches == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(matches[1])
+	return n
+}
@@ -2,10 +2,13 @@
 package document
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // PDFTool PDF工具封装
@@ -117,3 +120,32 @@ func (p *PDFTool) GeneratePreview(input, outputPrefix string, pages []int, dpi i
 
 	return outputs, nil
 }
+
+// ExtractText 提取 PDF 文本，按页返回（下标 0 为第 1 页）
+func (p *PDFTool) ExtractText(filePath string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.timeout)*time.Second)
+	defer cancel()
+
+	// pdftotext -layout -enc UTF-8 input.pdf -
+	cmd := exec.CommandContext(ctx, p.siblingTool("pdftotext"), "-layout", "-enc", "UTF-8", filePath, "-")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pdftotext 执行失败: %w", err)
+	}
+
+	// 页之间以换页符分隔，最后一页后也有换页符
+	pages := strings.Split(string(output), "\f")
+	if len(pages) > 1 && strings.TrimSpace(pages[len(pages)-1]) == "" {
+		pages = pages[:len(pages)-1]
+	}
+	return pages, nil
+}
+
+// siblingTool 获取与 pdftoppm 同目录的 poppler 工具路径（未配置目录时从 PATH 查找）
+func (p *PDFTool) siblingTool(name string) string {
+	dir := filepath.Dir(p.binPath)
+	if dir == "." {
+		return name
+	}
+	return filepath.Join(dir, name+filepath.Ext(p.binPath))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"go_wails_project_manager/logger"
 	"image"
+	"image/color"
 	"image/jpeg"
 	"image/png"
 	"io"
@@ -179,6 +180,22 @@ func (p *ImageProcessor) ProcessImageFromReader(reader io.Reader, mimeType strin
 	return p.ProcessImage(data, mimeType)
 }
 
+// InvertGreenChannel 反转绿色通道（法线贴图 DirectX <-> OpenGL 约定转换）
+func InvertGreenChannel(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			c.G = 255 - c.G
+			result.SetNRGBA(x, y, c)
+		}
+	}
+
+	return result
+}
+
 // GetDefaultProcessor 获取默认的图片处理器
 func GetDefaultProcessor() *ImageProcessor {
 	return NewImageProcessor()
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math/bits"
+	"os"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+// DefaultSimilarityThreshold 默认相似度阈值（64 位感知哈希的汉明距离）
+// 0 表示视觉上完全一致，<=10 通常为同一图片的缩放/重新编码版本
+const DefaultSimilarityThreshold = 10
+
+// MaxSimilarityThreshold 相似度阈值上限，超过该值的结果基本不再相关
+const MaxSimilarityThreshold = 32
+
+// DHash 计算图片的差值哈希（dHash）
+// 将图片缩放为 9x8 灰度图，逐行比较相邻像素亮度，得到 64 位指纹
+func DHash(img image.Image) uint64 {
+	small := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Lanczos))
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := small.Pix[y*small.Stride+x*4]
+			right := small.Pix[y*small.Stride+(x+1)*4]
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// PerceptualHashFile 计算图片文件的感知哈希，返回 16 位十六进制字符串
+func PerceptualHashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return PerceptualHash(file)
+}
+
+// PerceptualHash 从图片数据流计算感知哈希
+func PerceptualHash(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", fmt.Errorf("解码图片失败: %w", err)
+	}
+	return FormatPerceptualHash(DHash(img)), nil
+}
+
+// FormatPerceptualHash 将感知哈希格式化为定长十六进制字符串
+func FormatPerceptualHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// HashDistance 计算两个感知哈希的汉明距离，哈希无效时返回 -1
+func HashDistance(a, b string) int {
+	ha, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return -1
+	}
+	hb, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return -1
+	}
+	return bits.OnesCount64(ha ^ hb)
+}
+
+// NormalizeSimilarityThreshold 规范化相似度阈值，非法值使用默认阈值
+func NormalizeSimilarityThreshold(threshold int) int {
+	if threshold < 0 {
+		return DefaultSimilarityThreshold
+	}
+	if threshold > MaxSimilarityThreshold {
+		return MaxSimilarityThreshold
+	}
+	return threshold
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfc6238Secret RFC 6238 附录 B 测试向量使用的 SHA1 密钥 "12345678901234567890"（Base32 编码）
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestTOTPCodeRFC6238 测试验证码与 RFC 6238 附录 B 的测试向量一致（取 8 位结果的后 6 位）
+func TestTOTPCodeRFC6238(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, v := range vectors {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(v.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, v.code, code, "T=%d", v.unix)
+	}
+
+	t.Run("密钥不区分大小写并忽略填充", func(t *testing.T) {
+		code, err := TOTPCode(strings.ToLower(rfc6238Secret)+"====", TOTPStep(time.Unix(59, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, "287082", code)
+	})
+
+	t.Run("非法密钥", func(t *testing.T) {
+		_, err := TOTPCode("not base32!", 1)
+		assert.Error(t, err)
+	})
+}
+
+// TestValidateTOTP 测试验证码校验的时间窗口容差和返回的窗口序号
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+
+	t.Run("当前窗口", func(t *testing.T) {
+		step, ok := ValidateTOTP(rfc6238Secret, "050471", now)
+		assert.True(t, ok)
+		assert.Equal(t, current, step)
+	})
+
+	t.Run("前后各一个窗口内有效", func(t *testing.T) {
+		for _, offset := range []int64{-1, 1} {
+			code, _ := TOTPCode(rfc6238Secret, current+offset)
+			step, ok := ValidateTOTP(rfc6238Secret, code, now)
+			assert.True(t, ok)
+			assert.Equal(t, current+offset, step)
+		}
+	})
+
+	t.Run("超出容差的窗口无效", func(t *testing.T) {
+		for _, offset := range []int64{-2, 2} {
+			code, _ := TOTPCode(rfc6238Secret, current+offset)
+			_, ok := ValidateTOTP(rfc6238Secret, code, now)
+			assert.False(t, ok)
+		}
+	})
+
+	t.Run("忽略空格", func(t *testing.T) {
+		_, ok := ValidateTOTP(rfc6238Secret, " 050 471 ", now)
+		assert.True(t, ok)
+	})
+
+	t.Run("错误或长度不符的验证码", func(t *testing.T) {
+		for _, code := range []string{"000000", "05047", "0504711", ""} {
+			_, ok := ValidateTOTP(rfc6238Secret, code, now)
+			assert.False(t, ok, code)
+		}
+	})
+}
+
+// TestGenerateTOTPSecret 测试生成的密钥为 160 位且可直接用于计算验证码
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	key, err := totpEncoding.DecodeString(secret)
+	assert.NoError(t, err)
+	assert.Len(t, key, 20)
+
+	another, _ := GenerateTOTPSecret()
+	assert.NotEqual(t, secret, another)
+
+	_, err = TOTPCode(secret, TOTPStep(time.Now()))
+	assert.NoError(t, err)
+}
+
+// TestTOTPProvisioningURI 测试 otpauth 地址的标签和参数
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TOTPProvisioningURI("项目管理", "alice@example.com", rfc6238Secret))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/项目管理:alice@example.com", uri.Path)
+
+	query := uri.Query()
+	assert.Equal(t, rfc6238Secret, query.Get("secret"))
+	assert.Equal(t, "项目管理", query.Get("issuer"))
+	assert.Equal(t, "SHA1", query.Get("algorithm"))
+	assert.Equal(t, "6", query.Get("digits"))
+	assert.Equal(t, "30", query.Get("period"))
+}